@@ -0,0 +1,340 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package archivefs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/archivefs/sevenzip"
+	"github.com/jetsetilly/gopher2600/archivefs/xz"
+)
+
+// the magic bytes at the start of each supported archive type
+const (
+	zipMagic  = "PK\x03\x04"
+	gzipMagic = "\x1f\x8b"
+
+	// an empty zip file begins with the end of central directory record
+	zipEmptyMagic = "PK\x05\x06"
+
+	// the tar magic is not at the start of the file
+	tarMagicOffset = 257
+	tarMagic       = "ustar"
+)
+
+// sentinal error returned by openArchive() when the data is not a recognised
+// archive type
+var errNotArchive = errors.New("not an archive")
+
+// archiveEntry is a single file or directory in an archive
+type archiveEntry struct {
+	// the full path of the entry inside the archive. path components are
+	// always separated by a forward slash
+	name  string
+	isDir bool
+
+	// returns the contents of the entry. nil for directories
+	read func() ([]byte, error)
+}
+
+// archive is the contents of an archive file. each supported archive type is
+// converted into this common form
+type archive struct {
+	entries []archiveEntry
+
+	// the file containing the archive. will be nil if the archive was opened
+	// from data in memory
+	f *os.File
+}
+
+// close the file associated with the archive
+func (arc *archive) close() {
+	if arc.f != nil {
+		arc.f.Close()
+		arc.f = nil
+	}
+}
+
+// find the entry with the specified name
+func (arc *archive) lookup(name string) (archiveEntry, bool) {
+	name = filepath.ToSlash(name)
+	for _, e := range arc.entries {
+		if e.name == name {
+			return e, true
+		}
+	}
+	return archiveEntry{}, false
+}
+
+// add entries to the archive. names are normalised and any directories that
+// are implied by the name are also added
+func (arc *archive) add(e archiveEntry) {
+	e.name = strings.TrimPrefix(path.Clean("/"+e.name), "/")
+	if e.name == "" {
+		return
+	}
+
+	// remove any existing entry for the directory. an explicit entry is
+	// preferred over an implied entry
+	if e.isDir {
+		if _, ok := arc.lookup(e.name); ok {
+			return
+		}
+	}
+
+	dir := path.Dir(e.name)
+	if dir != "." {
+		if _, ok := arc.lookup(dir); !ok {
+			arc.add(archiveEntry{name: dir, isDir: true})
+		}
+	}
+
+	arc.entries = append(arc.entries, e)
+}
+
+// join path components for a path inside an archive
+func joinArchivePath(dir string, name string) string {
+	return path.Join(dir, name)
+}
+
+// split a path inside an archive into the directory and the name. the
+// directory of an entry in the root of the archive is the empty string
+func splitArchivePath(name string) (string, string) {
+	dir, name := path.Split(name)
+	return strings.TrimSuffix(dir, "/"), name
+}
+
+// isArchiveData returns true if the data begins with the magic bytes of a
+// supported archive type
+func isArchiveData(b []byte) bool {
+	for _, m := range []string{zipMagic, zipEmptyMagic, sevenzip.Magic, gzipMagic, xz.Magic} {
+		if bytes.HasPrefix(b, []byte(m)) {
+			return true
+		}
+	}
+	return isTar(b)
+}
+
+func isTar(b []byte) bool {
+	return len(b) >= tarMagicOffset+len(tarMagic) && string(b[tarMagicOffset:tarMagicOffset+len(tarMagic)]) == tarMagic
+}
+
+// isArchiveFile returns true if the file begins with the magic bytes of a
+// supported archive type
+func isArchiveFile(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	b := make([]byte, tarMagicOffset+len(tarMagic))
+	n, _ := io.ReadFull(f, b)
+	return isArchiveData(b[:n])
+}
+
+// openArchiveFile opens the named file as an archive. returns errNotArchive if
+// the file is not a supported archive type
+func openArchiveFile(filename string) (*archive, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	arc, err := openArchive(f, fi.Size(), filepath.Base(filename))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// zip and 7z archives read from the file as required so the file must be
+	// kept open. other archive types are read entirely into memory
+	if len(arc.entries) > 0 && arc.f == nil {
+		arc.f = f
+	} else {
+		f.Close()
+	}
+
+	return arc, nil
+}
+
+// openArchive from an io.ReaderAt. the name of the archive is used to name the
+// file inside single file archives (such as gzip files)
+func openArchive(r io.ReaderAt, size int64, name string) (*archive, error) {
+	b := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := r.ReadAt(b, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	b = b[:n]
+
+	switch {
+	case bytes.HasPrefix(b, []byte(zipMagic)), bytes.HasPrefix(b, []byte(zipEmptyMagic)):
+		return openZip(r, size)
+	case bytes.HasPrefix(b, []byte(sevenzip.Magic)):
+		return openSevenZip(r, size)
+	case bytes.HasPrefix(b, []byte(gzipMagic)):
+		return openCompressed(r, size, name, func(r io.Reader) (string, []byte, error) {
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				return "", nil, err
+			}
+			d, err := io.ReadAll(zr)
+			return zr.Name, d, err
+		})
+	case bytes.HasPrefix(b, []byte(xz.Magic)):
+		return openCompressed(r, size, name, func(r io.Reader) (string, []byte, error) {
+			d, err := io.ReadAll(r)
+			if err != nil {
+				return "", nil, err
+			}
+			d, err = xz.Decode(d)
+			return "", d, err
+		})
+	case isTar(b):
+		return openTar(io.NewSectionReader(r, 0, size))
+	}
+
+	return nil, errNotArchive
+}
+
+func openZip(r io.ReaderAt, size int64) (*archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	arc := &archive{}
+	for _, f := range zr.File {
+		f := f
+		arc.add(archiveEntry{
+			name:  f.Name,
+			isDir: f.FileInfo().IsDir(),
+			read: func() ([]byte, error) {
+				r, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer r.Close()
+				return io.ReadAll(r)
+			},
+		})
+	}
+
+	return arc, nil
+}
+
+func openSevenZip(r io.ReaderAt, size int64) (*archive, error) {
+	zr, err := sevenzip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	arc := &archive{}
+	for i := range zr.Files {
+		f := &zr.Files[i]
+		arc.add(archiveEntry{
+			name:  f.Name,
+			isDir: f.IsDir,
+			read: func() ([]byte, error) {
+				return zr.ReadFile(f)
+			},
+		})
+	}
+
+	return arc, nil
+}
+
+func openTar(r io.Reader) (*archive, error) {
+	tr := tar.NewReader(r)
+
+	arc := &archive{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break // for loop
+			}
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			arc.add(archiveEntry{
+				name:  hdr.Name,
+				isDir: true,
+			})
+		case tar.TypeReg:
+			d, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			arc.add(archiveEntry{
+				name: hdr.Name,
+				read: func() ([]byte, error) {
+					return d, nil
+				},
+			})
+		}
+	}
+
+	return arc, nil
+}
+
+// gzip and xz files are compressed single files. if the decompressed data is
+// a tar file then the archive is the contents of the tar file
+func openCompressed(r io.ReaderAt, size int64, name string, decompress func(io.Reader) (string, []byte, error)) (*archive, error) {
+	inner, d, err := decompress(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if isTar(d) {
+		return openTar(bytes.NewReader(d))
+	}
+
+	// the name of the file inside the archive is the name of the archive
+	// without the extension, unless the archive specifies the name
+	if inner == "" {
+		inner = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	arc := &archive{}
+	arc.add(archiveEntry{
+		name: filepath.Base(inner),
+		read: func() ([]byte, error) {
+			return d, nil
+		},
+	})
+
+	return arc, nil
+}
@@ -125,3 +125,89 @@ func TestArchivefsOpen(t *testing.T) {
 	test.ExpectSuccess(t, err)
 	test.ExpectEquality(t, string(d), "archivefile1 contents\n")
 }
+
+func TestArchivefsFormats(t *testing.T) {
+	var afs archivefs.Path
+	var entries []archivefs.Entry
+	var err error
+
+	// entries in the directory of archives. archives are recognised by their
+	// contents and not by their file extension
+	err = afs.Set("testarchives", false)
+	test.ExpectSuccess(t, err)
+	entries, err = afs.List()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(entries), 4)
+	for _, e := range entries {
+		test.ExpectSuccess(t, e.IsArchive)
+	}
+
+	for _, arc := range []string{"testarchive.7z", "testarchive.tar.gz", "testarchive.tar.xz"} {
+		err = afs.Set(filepath.Join("testarchives", arc), false)
+		test.ExpectSuccess(t, err)
+		test.ExpectSuccess(t, afs.IsDir())
+		test.ExpectSuccess(t, afs.InArchive())
+
+		r, sz, err := archivefs.Open(filepath.Join("testarchives", arc, "rom1.bin"))
+		test.ExpectSuccess(t, err)
+		test.ExpectEquality(t, sz, 17)
+		d, err := io.ReadAll(r)
+		test.ExpectSuccess(t, err)
+		test.ExpectEquality(t, string(d), "rom one contents\n")
+	}
+
+	// directories in tar archives
+	err = afs.Set(filepath.Join("testarchives", "testarchive.tar.xz", "romdir"), false)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, afs.IsDir())
+	entries, err = afs.List()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, fmt.Sprintf("%s", entries), "[rom2.bin]")
+
+	// single file gzip archive
+	r, sz, err := archivefs.Open(filepath.Join("testarchives", "rom1.bin.gz", "rom1.bin"))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, sz, 17)
+	d, err := io.ReadAll(r)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(d), "rom one contents\n")
+}
+
+func TestArchivefsNested(t *testing.T) {
+	var afs archivefs.Path
+	var entries []archivefs.Entry
+	var err error
+
+	// a zip file inside a 7z archive is listed as an archive
+	err = afs.Set(filepath.Join("testarchives", "testarchive.7z", "subdir"), false)
+	test.ExpectSuccess(t, err)
+	entries, err = afs.List()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, fmt.Sprintf("%s", entries), "[inner.zip]")
+	test.ExpectSuccess(t, entries[0].IsDir)
+	test.ExpectSuccess(t, entries[0].IsArchive)
+
+	// the root of the nested archive
+	path := filepath.Join("testarchives", "testarchive.7z", "subdir", "inner.zip")
+	err = afs.Set(path, false)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, afs.String(), path)
+	test.ExpectSuccess(t, afs.IsDir())
+	test.ExpectSuccess(t, afs.InArchive())
+	entries, err = afs.List()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, fmt.Sprintf("%s", entries), "[rom1.bin romdir]")
+
+	// file in the nested archive
+	r, sz, err := archivefs.Open(filepath.Join(path, "romdir", "rom2.bin"))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, sz, 17)
+	d, err := io.ReadAll(r)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(d), "rom two contents\n")
+
+	// fallback from a non-existant file in the nested archive
+	err = afs.Set(filepath.Join(path, "foo"), true)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, afs.String(), path)
+}
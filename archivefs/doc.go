@@ -16,5 +16,13 @@
 // Package archivefs allows the traversal of a filetree and treating archive
 // files transparently.
 //
-// Supported archive formats is currently limited to zip.
+// Supported archive formats are zip, 7z, tar, and tar files compressed with
+// gzip or xz. A single file compressed with gzip or xz is treated as an archive
+// containing one file. The 7z, xz and LZMA decompressors are implemented in the
+// sub-packages of archivefs.
+//
+// Archive files in the filesystem are recognised by their contents. Archive
+// files inside another archive are recognised by their file extension (see
+// ArchiveExtensions) and can be traversed in the same way as any other
+// archive.
 package archivefs
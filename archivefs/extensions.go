@@ -17,12 +17,24 @@ package archivefs
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 )
 
-// list of file extensions for the supported archive types
-var ArchiveExtensions = [...]string{".ZIP"}
+// list of file extensions for the supported archive types. compound extensions
+// appear before the single extensions they end with
+var ArchiveExtensions = [...]string{".TAR.GZ", ".TAR.XZ", ".TGZ", ".TXZ", ".ZIP", ".7Z", ".TAR", ".GZ", ".XZ"}
+
+// HasArchiveExt returns true if the string ends with the file extension of a
+// supported/recognised archive type
+func HasArchiveExt(s string) bool {
+	t := strings.ToUpper(s)
+	for _, ext := range ArchiveExtensions {
+		if strings.HasSuffix(t, ext) {
+			return true
+		}
+	}
+	return false
+}
 
 // RemoveArchiveExt removes the file extension of any supported/recognised
 // archive type from within the string. Only the first instance of the extension
@@ -42,10 +54,10 @@ func RemoveArchiveExt(s string) string {
 // TrimArchiveExt removes the file extension of any supported/recognised archive
 // type from the end of the string
 func TrimArchiveExt(s string) string {
-	sext := strings.ToUpper(filepath.Ext(s))
+	t := strings.ToUpper(s)
 	for _, ext := range ArchiveExtensions {
-		if sext == ext {
-			return strings.TrimSuffix(s, filepath.Ext(s))
+		if strings.HasSuffix(t, ext) {
+			return s[:len(s)-len(ext)]
 		}
 	}
 	return s
@@ -0,0 +1,316 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lzma
+
+const (
+	numStates          = 12
+	numPosBitsMax      = 4
+	numLenToPosStates  = 4
+	numAlignBits       = 4
+	startPosModelIndex = 4
+	endPosModelIndex   = 14
+	numFullDistances   = 1 << (endPosModelIndex >> 1)
+	matchMinLen        = 2
+)
+
+// lenDecoder decodes the length of a match
+type lenDecoder struct {
+	choice  prob
+	choice2 prob
+	low     [1 << numPosBitsMax][1 << 3]prob
+	mid     [1 << numPosBitsMax][1 << 3]prob
+	high    [1 << 8]prob
+}
+
+func (ld *lenDecoder) init() {
+	ld.choice = probInit
+	ld.choice2 = probInit
+	initProbs(ld.high[:])
+	for i := range ld.low {
+		initProbs(ld.low[i][:])
+		initProbs(ld.mid[i][:])
+	}
+}
+
+func (ld *lenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&ld.choice) == 0 {
+		return rc.bitTree(ld.low[posState][:], 3)
+	}
+	if rc.bit(&ld.choice2) == 0 {
+		return 8 + rc.bitTree(ld.mid[posState][:], 3)
+	}
+	return 16 + rc.bitTree(ld.high[:], 8)
+}
+
+// properties of the LZMA stream
+type properties struct {
+	lc, lp, pb int
+}
+
+func (p *properties) decode(b byte) error {
+	d := int(b)
+	if d >= 9*5*5 {
+		return ErrCorrupt
+	}
+	p.lc = d % 9
+	d /= 9
+	p.lp = d % 5
+	p.pb = d / 5
+	return nil
+}
+
+// decoder is the state of the LZMA decoder. the entire output of the decoder
+// is kept in memory and acts as the dictionary
+type decoder struct {
+	props properties
+
+	// the output of the decoder. the dictionary is the output from dictStart
+	// onwards
+	out       []byte
+	dictStart int
+
+	literal []prob
+
+	posSlot    [numLenToPosStates][1 << 6]prob
+	posDecoder [1 + numFullDistances - endPosModelIndex]prob
+	align      [1 << numAlignBits]prob
+
+	isMatch    [numStates << numPosBitsMax]prob
+	isRep      [numStates]prob
+	isRepG0    [numStates]prob
+	isRepG1    [numStates]prob
+	isRepG2    [numStates]prob
+	isRep0Long [numStates << numPosBitsMax]prob
+
+	lenDecoder    lenDecoder
+	repLenDecoder lenDecoder
+
+	state                  uint32
+	rep0, rep1, rep2, rep3 uint32
+}
+
+// reset the state of the decoder. the dictionary is not affected
+func (d *decoder) reset() {
+	n := 0x300 << (d.props.lc + d.props.lp)
+	if len(d.literal) != n {
+		d.literal = make([]prob, n)
+	}
+	initProbs(d.literal)
+
+	for i := range d.posSlot {
+		initProbs(d.posSlot[i][:])
+	}
+	initProbs(d.posDecoder[:])
+	initProbs(d.align[:])
+	initProbs(d.isMatch[:])
+	initProbs(d.isRep[:])
+	initProbs(d.isRepG0[:])
+	initProbs(d.isRepG1[:])
+	initProbs(d.isRepG2[:])
+	initProbs(d.isRep0Long[:])
+	d.lenDecoder.init()
+	d.repLenDecoder.init()
+
+	d.state = 0
+	d.rep0 = 0
+	d.rep1 = 0
+	d.rep2 = 0
+	d.rep3 = 0
+}
+
+// the number of bytes in the dictionary
+func (d *decoder) dictLen() int {
+	return len(d.out) - d.dictStart
+}
+
+// byte in the dictionary at distance from the end. distance of one is the
+// most recent byte
+func (d *decoder) getByte(dist uint32) byte {
+	return d.out[len(d.out)-int(dist)]
+}
+
+func (d *decoder) decodeLiteral(rc *rangeDecoder) {
+	var prevByte uint32
+	if d.dictLen() > 0 {
+		prevByte = uint32(d.getByte(1))
+	}
+
+	totalPos := uint32(len(d.out) - d.dictStart)
+	litState := ((totalPos & ((1 << d.props.lp) - 1)) << d.props.lc) + (prevByte >> (8 - d.props.lc))
+	probs := d.literal[0x300*litState:]
+
+	symbol := uint32(1)
+	if d.state >= 7 {
+		matchByte := uint32(d.getByte(d.rep0 + 1))
+		for symbol < 0x100 {
+			matchBit := (matchByte >> 7) & 1
+			matchByte <<= 1
+			bit := rc.bit(&probs[((1+matchBit)<<8)+symbol])
+			symbol = (symbol << 1) | bit
+			if matchBit != bit {
+				break
+			}
+		}
+	}
+	for symbol < 0x100 {
+		symbol = (symbol << 1) | rc.bit(&probs[symbol])
+	}
+
+	d.out = append(d.out, byte(symbol-0x100))
+}
+
+func (d *decoder) decodeDistance(rc *rangeDecoder, length uint32) uint32 {
+	lenState := min(length, numLenToPosStates-1)
+
+	posSlot := rc.bitTree(d.posSlot[lenState][:], 6)
+	if posSlot < 4 {
+		return posSlot
+	}
+
+	numDirectBits := int((posSlot >> 1) - 1)
+	dist := (2 | (posSlot & 1)) << numDirectBits
+	if posSlot < endPosModelIndex {
+		dist += rc.bitTreeReverse(d.posDecoder[dist-posSlot:], numDirectBits)
+	} else {
+		dist += rc.directBits(numDirectBits-numAlignBits) << numAlignBits
+		dist += rc.bitTreeReverse(d.align[:], numAlignBits)
+	}
+	return dist
+}
+
+// decode data from the range decoder until unpackSize bytes have been
+// decoded. if unpackSize is negative then decoding continues until the end
+// marker is found. the end marker is optional if the unpackSize is known
+func (d *decoder) decode(rc *rangeDecoder, unpackSize int) error {
+	sizeDefined := unpackSize >= 0
+	pbMask := uint32(1<<d.props.pb) - 1
+
+	for {
+		if rc.truncated {
+			return ErrTruncated
+		}
+
+		if sizeDefined && unpackSize == 0 && rc.finishedOK() {
+			return nil
+		}
+
+		posState := uint32(len(d.out)-d.dictStart) & pbMask
+
+		if rc.bit(&d.isMatch[(d.state<<numPosBitsMax)+posState]) == 0 {
+			if sizeDefined && unpackSize == 0 {
+				return ErrCorrupt
+			}
+			d.decodeLiteral(rc)
+			if d.state < 4 {
+				d.state = 0
+			} else if d.state < 10 {
+				d.state -= 3
+			} else {
+				d.state -= 6
+			}
+			unpackSize--
+			continue // for loop
+		}
+
+		var length uint32
+
+		if rc.bit(&d.isRep[d.state]) != 0 {
+			if sizeDefined && unpackSize == 0 {
+				return ErrCorrupt
+			}
+			if d.dictLen() == 0 {
+				return ErrCorrupt
+			}
+
+			if rc.bit(&d.isRepG0[d.state]) == 0 {
+				if rc.bit(&d.isRep0Long[(d.state<<numPosBitsMax)+posState]) == 0 {
+					// short rep
+					if d.state < 7 {
+						d.state = 9
+					} else {
+						d.state = 11
+					}
+					d.out = append(d.out, d.getByte(d.rep0+1))
+					unpackSize--
+					continue // for loop
+				}
+			} else {
+				var dist uint32
+				if rc.bit(&d.isRepG1[d.state]) == 0 {
+					dist = d.rep1
+				} else {
+					if rc.bit(&d.isRepG2[d.state]) == 0 {
+						dist = d.rep2
+					} else {
+						dist = d.rep3
+						d.rep3 = d.rep2
+					}
+					d.rep2 = d.rep1
+				}
+				d.rep1 = d.rep0
+				d.rep0 = dist
+			}
+
+			length = d.repLenDecoder.decode(rc, posState)
+			if d.state < 7 {
+				d.state = 8
+			} else {
+				d.state = 11
+			}
+		} else {
+			d.rep3 = d.rep2
+			d.rep2 = d.rep1
+			d.rep1 = d.rep0
+			length = d.lenDecoder.decode(rc, posState)
+			if d.state < 7 {
+				d.state = 7
+			} else {
+				d.state = 10
+			}
+
+			d.rep0 = d.decodeDistance(rc, length)
+			if d.rep0 == 0xffffffff {
+				if rc.finishedOK() {
+					if sizeDefined && unpackSize != 0 {
+						return ErrCorrupt
+					}
+					return nil
+				}
+				return ErrCorrupt
+			}
+
+			if sizeDefined && unpackSize == 0 {
+				return ErrCorrupt
+			}
+			if int(d.rep0) >= d.dictLen() {
+				return ErrCorrupt
+			}
+		}
+
+		n := int(length + matchMinLen)
+		if sizeDefined && unpackSize < n {
+			return ErrCorrupt
+		}
+
+		// copy match one byte at a time because the source and destination
+		// can overlap
+		dist := int(d.rep0) + 1
+		for i := 0; i < n; i++ {
+			d.out = append(d.out, d.out[len(d.out)-dist])
+		}
+		unpackSize -= n
+	}
+}
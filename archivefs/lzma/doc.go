@@ -0,0 +1,29 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package lzma decompresses data that has been compressed with the LZMA and
+// LZMA2 algorithms. These are the algorithms used by the 7z and xz archive
+// formats.
+//
+// The decoder works on data that is entirely in memory and the result of
+// decompression is returned in its entirety. There is no streaming interface.
+// This is because the archivefs package, which is the only user of this
+// package, always reads the entire contents of a file into memory.
+//
+// The implementation follows the reference decoder described in the LZMA
+// specification that is distributed with the LZMA SDK.
+//
+// https://www.7-zip.org/sdk.html
+package lzma
@@ -0,0 +1,187 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lzma
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// the maximum capacity of the output buffer allocated before decoding starts.
+// the buffer will grow as required
+const maxPrealloc = 1 << 20
+
+// Decode LZMA compressed data. The props argument is the five bytes of
+// properties that describe the compressed data. If the size of the
+// uncompressed data is not known then size should be negative and the data
+// must finish with an end marker.
+func Decode(in []byte, props []byte, size int) ([]byte, error) {
+	if len(props) < 5 {
+		return nil, fmt.Errorf("lzma: properties too short")
+	}
+
+	var d decoder
+	err := d.props.decode(props[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// the dictionary size isn't required because the entire output is kept
+	// in memory. it is used only as a hint for the size of the output buffer.
+	// neither the dictionary size or the size argument can be trusted so the
+	// initial capacity is limited
+	dictSize := binary.LittleEndian.Uint32(props[1:])
+	if size >= 0 {
+		d.out = make([]byte, 0, min(size, maxPrealloc))
+	} else {
+		d.out = make([]byte, 0, min(dictSize, maxPrealloc))
+	}
+	d.reset()
+
+	var rc rangeDecoder
+	err = rc.init(in)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.decode(&rc, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.out, nil
+}
+
+// DictSize2 returns the dictionary size indicated by the LZMA2 properties
+// byte.
+func DictSize2(prop byte) (int, error) {
+	if prop > 40 {
+		return 0, fmt.Errorf("lzma2: invalid dictionary size")
+	}
+	if prop == 40 {
+		return 0xffffffff, nil
+	}
+	return (2 | int(prop&1)) << (prop/2 + 11), nil
+}
+
+// Decode2 decompresses LZMA2 compressed data. LZMA2 data is self-terminating
+// so the function returns the number of input bytes that were consumed in
+// addition to the uncompressed data.
+func Decode2(in []byte) ([]byte, int, error) {
+	var d decoder
+	var rc rangeDecoder
+
+	// the first chunk must reset the dictionary and the first LZMA chunk
+	// must set new properties
+	needDictReset := true
+	needProps := true
+
+	pos := 0
+	for {
+		if pos >= len(in) {
+			return nil, 0, ErrTruncated
+		}
+
+		control := in[pos]
+		pos++
+
+		// end of data
+		if control == 0x00 {
+			return d.out, pos, nil
+		}
+
+		// uncompressed chunk
+		if control == 0x01 || control == 0x02 {
+			if control == 0x01 {
+				d.dictStart = len(d.out)
+				needDictReset = false
+			} else if needDictReset {
+				return nil, 0, ErrCorrupt
+			}
+
+			if pos+2 > len(in) {
+				return nil, 0, ErrTruncated
+			}
+			n := int(binary.BigEndian.Uint16(in[pos:])) + 1
+			pos += 2
+
+			if pos+n > len(in) {
+				return nil, 0, ErrTruncated
+			}
+			d.out = append(d.out, in[pos:pos+n]...)
+			pos += n
+			continue // for loop
+		}
+
+		if control < 0x80 {
+			return nil, 0, ErrCorrupt
+		}
+
+		// LZMA chunk
+		if pos+4 > len(in) {
+			return nil, 0, ErrTruncated
+		}
+		unpackSize := int(control&0x1f)<<16 + int(binary.BigEndian.Uint16(in[pos:])) + 1
+		packSize := int(binary.BigEndian.Uint16(in[pos+2:])) + 1
+		pos += 4
+
+		reset := (control >> 5) & 0x03
+
+		if reset == 3 {
+			d.dictStart = len(d.out)
+			needDictReset = false
+		} else if needDictReset {
+			return nil, 0, ErrCorrupt
+		}
+
+		if reset >= 2 {
+			if pos >= len(in) {
+				return nil, 0, ErrTruncated
+			}
+			err := d.props.decode(in[pos])
+			if err != nil {
+				return nil, 0, err
+			}
+			if d.props.lc+d.props.lp > 4 {
+				return nil, 0, ErrCorrupt
+			}
+			pos++
+			needProps = false
+		} else if needProps {
+			return nil, 0, ErrCorrupt
+		}
+
+		if reset >= 1 {
+			d.reset()
+		}
+
+		if pos+packSize > len(in) {
+			return nil, 0, ErrTruncated
+		}
+
+		// the range decoder is initialised for every LZMA chunk
+		err := rc.init(in[pos : pos+packSize])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = d.decode(&rc, unpackSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		pos += packSize
+	}
+}
@@ -0,0 +1,82 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lzma_test
+
+import (
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/archivefs/lzma"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// the default properties used by the xz and 7z tools. lc=3 lp=0 pb=2 with a
+// dictionary size of 64k
+var props = []byte{0x5d, 0x00, 0x00, 0x01, 0x00}
+
+// an LZMA2 stream containing a single uncompressed chunk
+var uncompressed = []byte{0x01, 0x00, 0x04, 'h', 'e', 'l', 'l', 'o', 0x00}
+
+func TestDecode2(t *testing.T) {
+	d, n, err := lzma.Decode2(uncompressed)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(d), "hello")
+	test.ExpectEquality(t, n, len(uncompressed))
+}
+
+func TestCorrupt(t *testing.T) {
+	// the size argument must not be trusted. a corrupt 7z file can specify
+	// any size
+	_, err := lzma.Decode([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, props, 1<<40)
+	test.ExpectFailure(t, err)
+
+	// a dictionary size in the properties is also untrustworthy
+	_, err = lzma.Decode([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, []byte{0x5d, 0xff, 0xff, 0xff, 0xff}, -1)
+	test.ExpectFailure(t, err)
+
+	// every truncation of the LZMA2 stream must fail
+	for i := range uncompressed {
+		_, _, err = lzma.Decode2(uncompressed[:i])
+		test.ExpectFailure(t, err)
+	}
+
+	// the first chunk of an LZMA2 stream must reset the dictionary
+	_, _, err = lzma.Decode2([]byte{0x02, 0x00, 0x00, 'a', 0x00})
+	test.ExpectFailure(t, err)
+
+	// the first LZMA chunk must set the properties
+	_, _, err = lzma.Decode2([]byte{0x01, 0x00, 0x00, 'a', 0x80, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	test.ExpectFailure(t, err)
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte{0x00, 0x00, 0x00, 0x00, 0x00}, 5)
+	f.Fuzz(func(t *testing.T, in []byte, size int) {
+		d, err := lzma.Decode(in, props, size)
+		if err == nil && size >= 0 && len(d) != size {
+			t.Errorf("decoded %d bytes instead of %d", len(d), size)
+		}
+	})
+}
+
+func FuzzDecode2(f *testing.F) {
+	f.Add(uncompressed)
+	f.Fuzz(func(t *testing.T, in []byte) {
+		_, n, err := lzma.Decode2(in)
+		if err == nil && n > len(in) {
+			t.Errorf("consumed %d bytes of %d", n, len(in))
+		}
+	})
+}
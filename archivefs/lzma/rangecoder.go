@@ -0,0 +1,153 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lzma
+
+import "errors"
+
+// sentinal errors returned by the decoder
+var (
+	ErrCorrupt   = errors.New("lzma: corrupt data")
+	ErrTruncated = errors.New("lzma: unexpected end of data")
+)
+
+const (
+	numBitModelTotalBits = 11
+	bitModelTotal        = 1 << numBitModelTotalBits
+	numMoveBits          = 5
+	topValue             = 1 << 24
+)
+
+// probabilities are initialised to half of the total
+type prob uint16
+
+const probInit = prob(bitModelTotal / 2)
+
+func initProbs(p []prob) {
+	for i := range p {
+		p[i] = probInit
+	}
+}
+
+// rangeDecoder decodes bits from the compressed data
+type rangeDecoder struct {
+	in  []byte
+	pos int
+
+	rng  uint32
+	code uint32
+
+	// set if there is an attempt to read beyond the end of the input
+	truncated bool
+}
+
+// initialise range decoder with the input data. the first five bytes of the
+// data are consumed
+func (rc *rangeDecoder) init(in []byte) error {
+	rc.in = in
+	rc.pos = 0
+	rc.truncated = false
+	rc.rng = 0xffffffff
+	rc.code = 0
+
+	if rc.next() != 0 {
+		return ErrCorrupt
+	}
+	for i := 0; i < 4; i++ {
+		rc.code = (rc.code << 8) | uint32(rc.next())
+	}
+	if rc.code == rc.rng || rc.truncated {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+func (rc *rangeDecoder) next() byte {
+	if rc.pos >= len(rc.in) {
+		rc.truncated = true
+		return 0
+	}
+	b := rc.in[rc.pos]
+	rc.pos++
+	return b
+}
+
+// finishedOK returns true if the range decoder has reached a valid end point
+func (rc *rangeDecoder) finishedOK() bool {
+	return rc.code == 0
+}
+
+func (rc *rangeDecoder) normalise() {
+	if rc.rng < topValue {
+		rc.rng <<= 8
+		rc.code = (rc.code << 8) | uint32(rc.next())
+	}
+}
+
+func (rc *rangeDecoder) directBits(numBits int) uint32 {
+	var res uint32
+	for ; numBits > 0; numBits-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31)
+		rc.code += rc.rng & t
+		rc.normalise()
+		res <<= 1
+		res += t + 1
+	}
+	return res
+}
+
+func (rc *rangeDecoder) bit(p *prob) uint32 {
+	v := *p
+	bound := (rc.rng >> numBitModelTotalBits) * uint32(v)
+	var symbol uint32
+	if rc.code < bound {
+		v += (bitModelTotal - v) >> numMoveBits
+		rc.rng = bound
+		symbol = 0
+	} else {
+		v -= v >> numMoveBits
+		rc.code -= bound
+		rc.rng -= bound
+		symbol = 1
+	}
+	*p = v
+	rc.normalise()
+	return symbol
+}
+
+// decode numBits using a tree of probabilities. the probs slice should have
+// 1<<numBits entries
+func (rc *rangeDecoder) bitTree(probs []prob, numBits int) uint32 {
+	m := uint32(1)
+	for i := 0; i < numBits; i++ {
+		m = (m << 1) + rc.bit(&probs[m])
+	}
+	return m - (1 << numBits)
+}
+
+// as above but the bits are decoded least significant bit first
+func (rc *rangeDecoder) bitTreeReverse(probs []prob, numBits int) uint32 {
+	m := uint32(1)
+	var symbol uint32
+	for i := 0; i < numBits; i++ {
+		bit := rc.bit(&probs[m])
+		m <<= 1
+		m += bit
+		symbol |= bit << i
+	}
+	return symbol
+}
@@ -16,7 +16,6 @@
 package archivefs
 
 import (
-	"archive/zip"
 	"bytes"
 	"errors"
 	"fmt"
@@ -47,12 +46,13 @@ type Path struct {
 	current string
 	isDir   bool
 
-	zf *zip.ReadCloser
+	arc *archive
 
-	// if the path is inside a zip file, we split the in-zip path into the path
-	// to a file and the file itself
-	inZipPath string
-	inZipFile string
+	// if the path is inside an archive, we split the in-archive path into the
+	// path to a file and the file itself. path components are always separated
+	// by a forward slash
+	inArcPath string
+	inArcFile string
 }
 
 // String returns the current path
@@ -81,7 +81,7 @@ func (afs Path) IsDir() bool {
 
 // InArchive returns true if path is currently inside an archive
 func (afs Path) InArchive() bool {
-	return afs.zf != nil
+	return afs.arc != nil
 }
 
 // Open and return an io.ReadSeeker for the filename previously set by the Set()
@@ -90,16 +90,15 @@ func (afs Path) InArchive() bool {
 // Returns the io.ReadSeeker, the size of the data behind the ReadSeeker and any
 // errors.
 func (afs Path) Open() (io.ReadSeeker, int, error) {
-	if afs.zf != nil {
-		f, err := afs.zf.Open(filepath.Join(afs.inZipPath, afs.inZipFile))
-		if err != nil {
-			return nil, 0, err
+	if afs.arc != nil {
+		e, ok := afs.arc.lookup(joinArchivePath(afs.inArcPath, afs.inArcFile))
+		if !ok || e.isDir {
+			return nil, 0, fmt.Errorf("archivefs: open: %s is not a file in the archive", afs.current)
 		}
-		defer f.Close()
 
-		b, err := io.ReadAll(f)
+		b, err := e.read()
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, fmt.Errorf("archivefs: open: %w", err)
 		}
 
 		return bytes.NewReader(b), len(b), nil
@@ -118,49 +117,51 @@ func (afs Path) Open() (io.ReadSeeker, int, error) {
 	return f, int(info.Size()), nil
 }
 
-// Close any open zip files and reset path
+// Close any open archive files and reset path
 func (afs *Path) Close() {
 	afs.current = ""
 	afs.isDir = false
-	afs.inZipPath = ""
-	afs.inZipFile = ""
-	if afs.zf != nil {
-		afs.zf.Close()
-		afs.zf = nil
+	afs.inArcPath = ""
+	afs.inArcFile = ""
+	if afs.arc != nil {
+		afs.arc.close()
+		afs.arc = nil
 	}
 }
 
 func (afs *Path) list(listEnt chan Entry, listErr chan error, listCancel chan bool) {
-	if afs.zf != nil {
-		for _, f := range afs.zf.File {
+	if afs.arc != nil {
+		for _, e := range afs.arc.entries {
 			select {
 			case <-listCancel:
 				return
 			default:
 			}
 
-			// split file name into parts. the list is joined together again
-			// below to create the path to the file. this is better than
-			// filepath.Dir() because that will add path components that make it
-			// awkward to compare with afs.inZipPath
-			flst := strings.Split(filepath.Clean(f.Name), string(filepath.Separator))
-			fdir := filepath.Join(flst[:len(flst)-1]...)
-
-			// if path to the file is not the same as inZipPath then continue
-			// with the next file
-			if fdir != afs.inZipPath {
+			// if path to the entry is not the same as inArcPath then continue
+			// with the next entry
+			dir, name := splitArchivePath(e.name)
+			if dir != afs.inArcPath {
 				continue
 			}
 
-			fi := f.FileInfo()
-			if fi.IsDir() {
+			// archives inside the archive are recognised by their file
+			// extension. checking the contents of every entry would require
+			// each entry to be decompressed
+			if e.isDir {
 				listEnt <- Entry{
-					Name:  fi.Name(),
+					Name:  name,
 					IsDir: true,
 				}
+			} else if HasArchiveExt(name) {
+				listEnt <- Entry{
+					Name:      name,
+					IsDir:     true,
+					IsArchive: true,
+				}
 			} else {
 				listEnt <- Entry{
-					Name: fi.Name(),
+					Name: name,
 				}
 			}
 		}
@@ -196,9 +197,7 @@ func (afs *Path) list(listEnt chan Entry, listErr chan error, listCancel chan bo
 					IsDir: true,
 				}
 			} else {
-				p := filepath.Join(path, d.Name())
-				_, err := zip.OpenReader(p)
-				if err == nil {
+				if isArchiveFile(filepath.Join(path, d.Name())) {
 					listEnt <- Entry{
 						Name:      d.Name(),
 						IsDir:     true,
@@ -293,10 +292,32 @@ func (afs *Path) Set(path string, fallback bool) error {
 		prevSearch = search
 		search = filepath.Join(search, l)
 
-		if afs.zf != nil {
-			p := filepath.Join(afs.inZipPath, l)
+		if afs.arc != nil {
+			p := joinArchivePath(afs.inArcPath, l)
+
+			e, ok := afs.arc.lookup(p)
+			if !ok {
+				if fallback {
+					return afs.Set(prevSearch, false)
+				}
+				return fmt.Errorf("archivefs: set: %s: file does not exist", search)
+			}
+
+			afs.isDir = e.isDir
+			if afs.isDir {
+				afs.inArcPath = p
+				afs.inArcFile = ""
+				continue
+			}
 
-			zf, err := afs.zf.Open(p)
+			afs.inArcFile = l
+
+			// a file inside the archive may itself be an archive
+			if !HasArchiveExt(l) {
+				continue
+			}
+
+			b, err := e.read()
 			if err != nil {
 				if fallback {
 					return afs.Set(prevSearch, false)
@@ -304,21 +325,23 @@ func (afs *Path) Set(path string, fallback bool) error {
 				return fmt.Errorf("archivefs: set: %v", err)
 			}
 
-			zfi, err := zf.Stat()
+			arc, err := openArchive(bytes.NewReader(b), int64(len(b)), l)
 			if err != nil {
+				if errors.Is(err, errNotArchive) {
+					continue
+				}
 				if fallback {
 					return afs.Set(prevSearch, false)
 				}
 				return fmt.Errorf("archivefs: set: %v", err)
 			}
 
-			afs.isDir = zfi.IsDir()
-			if afs.isDir {
-				afs.inZipPath = p
-				afs.inZipFile = ""
-			} else {
-				afs.inZipFile = l
-			}
+			// the root of the nested archive replaces the outer archive
+			afs.arc.close()
+			afs.arc = arc
+			afs.inArcPath = ""
+			afs.inArcFile = ""
+			afs.isDir = true
 
 		} else {
 			fi, err := os.Stat(search)
@@ -334,14 +357,14 @@ func (afs *Path) Set(path string, fallback bool) error {
 				continue
 			}
 
-			afs.zf, err = zip.OpenReader(search)
+			afs.arc, err = openArchiveFile(search)
 			if err == nil {
 				// the root of an archive file is considered to be a directory
 				afs.isDir = true
 				continue
 			}
 
-			if !errors.Is(err, zip.ErrFormat) {
+			if !errors.Is(err, errNotArchive) {
 				if fallback {
 					return afs.Set(prevSearch, false)
 				}
@@ -0,0 +1,33 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package sevenzip reads archives in the 7z format. The files in the archive
+// are listed in the Files field of the Reader type and the contents of a file
+// are returned by the ReadFile() function.
+//
+// Supported compression methods are LZMA, LZMA2, Deflate, BZip2 and Copy.
+// Encrypted archives and archives that use filters (such as the BCJ filters
+// used for executable files) are not supported.
+//
+// Files in a 7z archive are often compressed together in a single block (a
+// solid archive). To read a file from a solid block, the entire block must be
+// decompressed. The most recently decompressed block is cached so that reading
+// the files in a solid block one after the other is efficient.
+//
+// The 7z format is described in the 7zFormat.txt file distributed with the
+// LZMA SDK.
+//
+// https://www.7-zip.org/sdk.html
+package sevenzip
@@ -0,0 +1,193 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package sevenzip
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// property IDs used in the archive header
+const (
+	idEnd                   = 0x00
+	idHeader                = 0x01
+	idArchiveProperties     = 0x02
+	idAdditionalStreamsInfo = 0x03
+	idMainStreamsInfo       = 0x04
+	idFilesInfo             = 0x05
+	idPackInfo              = 0x06
+	idUnpackInfo            = 0x07
+	idSubStreamsInfo        = 0x08
+	idSize                  = 0x09
+	idCRC                   = 0x0a
+	idFolder                = 0x0b
+	idCodersUnpackSize      = 0x0c
+	idNumUnpackStream       = 0x0d
+	idEmptyStream           = 0x0e
+	idEmptyFile             = 0x0f
+	idName                  = 0x11
+	idWinAttributes         = 0x15
+	idEncodedHeader         = 0x17
+)
+
+// the windows file attribute for directories
+const attributeDirectory = 0x10
+
+// headerReader reads values from the header data. the first error encountered
+// is sticky and all subsequent reads return zero values
+type headerReader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *headerReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.b) {
+		r.err = ErrCorrupt
+		return 0
+	}
+	b := r.b[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *headerReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.b) {
+		r.err = ErrCorrupt
+		return nil
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *headerReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// a number is stored in one to nine bytes. the number of leading one bits in
+// the first byte is the number of additional bytes
+func (r *headerReader) number() uint64 {
+	first := r.byte()
+	mask := byte(0x80)
+	var v uint64
+	for i := 0; i < 8; i++ {
+		if first&mask == 0 {
+			high := uint64(first & (mask - 1))
+			v |= high << (8 * i)
+			return v
+		}
+		v |= uint64(r.byte()) << (8 * i)
+		mask >>= 1
+	}
+	return v
+}
+
+// number() for values that are used as a count or a size. the value is
+// limited to the size of the header data, which is a reasonable upper limit
+// for a count of items. sizes of streams are not limited in this way
+func (r *headerReader) count() int {
+	v := r.number()
+	if v > uint64(len(r.b)) {
+		r.err = ErrCorrupt
+		return 0
+	}
+	return int(v)
+}
+
+func (r *headerReader) size() int64 {
+	v := r.number()
+	if v > 1<<62 {
+		r.err = ErrCorrupt
+		return 0
+	}
+	return int64(v)
+}
+
+// expect the next byte to be the specified ID
+func (r *headerReader) expect(id byte) {
+	if r.byte() != id && r.err == nil {
+		r.err = ErrCorrupt
+	}
+}
+
+// bit vectors are stored most significant bit first
+func (r *headerReader) bitVector(n int) []bool {
+	v := make([]bool, n)
+	var b byte
+	var mask byte
+	for i := 0; i < n; i++ {
+		if mask == 0 {
+			b = r.byte()
+			mask = 0x80
+		}
+		v[i] = b&mask != 0
+		mask >>= 1
+	}
+	return v
+}
+
+// a bit vector that is preceded by a byte indicating whether all bits are set
+func (r *headerReader) optionalBitVector(n int) []bool {
+	if r.byte() != 0 {
+		v := make([]bool, n)
+		for i := range v {
+			v[i] = true
+		}
+		return v
+	}
+	return r.bitVector(n)
+}
+
+// a list of CRC values. returns the CRC values and whether each value is
+// defined
+func (r *headerReader) digests(n int) ([]uint32, []bool) {
+	defined := r.optionalBitVector(n)
+	crcs := make([]uint32, n)
+	for i := range crcs {
+		if defined[i] {
+			crcs[i] = r.uint32()
+		}
+	}
+	return crcs, defined
+}
+
+// names are stored as null terminated UTF-16 strings
+func (r *headerReader) name() string {
+	var s []uint16
+	for r.err == nil {
+		b := r.bytes(2)
+		if b == nil {
+			break // for loop
+		}
+		c := binary.LittleEndian.Uint16(b)
+		if c == 0 {
+			break // for loop
+		}
+		s = append(s, c)
+	}
+	return string(utf16.Decode(s))
+}
@@ -0,0 +1,795 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package sevenzip
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jetsetilly/gopher2600/archivefs/lzma"
+)
+
+// Magic is the sequence of bytes at the start of every 7z file.
+const Magic = "7z\xbc\xaf\x27\x1c"
+
+// sentinal errors
+var (
+	ErrFormat      = errors.New("7z: not a 7z file")
+	ErrCorrupt     = errors.New("7z: corrupt archive")
+	ErrUnsupported = errors.New("7z: unsupported feature")
+)
+
+// the length of the signature header at the start of the file
+const signatureHeaderLen = 32
+
+// the largest stream that will be decompressed. the files in an archive that
+// are of interest to the emulator are much smaller than this
+const maxStreamSize = 1 << 28
+
+// File is a single file or directory in the archive.
+type File struct {
+	// the name of the file including the path inside the archive. path
+	// components are separated with a forward slash
+	Name string
+
+	IsDir bool
+
+	// the uncompressed size of the file
+	Size int64
+
+	// the folder containing the file and the offset of the file in the
+	// uncompressed folder data. the folder is negative for empty files
+	folder int
+	offset int64
+
+	crc    uint32
+	hasCRC bool
+}
+
+type coder struct {
+	id     []byte
+	numIn  int
+	numOut int
+	props  []byte
+}
+
+type bindPair struct {
+	in  int
+	out int
+}
+
+// a folder is a block of data that is compressed with a single set of coders.
+// a folder can contain more than one file
+type folder struct {
+	coders        []coder
+	bindPairs     []bindPair
+	packedStreams []int
+	unpackSizes   []int64
+
+	crc    uint32
+	hasCRC bool
+
+	// the index of the first pack stream used by the folder
+	firstPack int
+}
+
+// the size of the final output of the folder
+func (f folder) unpackSize() int64 {
+	for i := len(f.unpackSizes) - 1; i >= 0; i-- {
+		bound := false
+		for _, bp := range f.bindPairs {
+			if bp.out == i {
+				bound = true
+				break // for loop
+			}
+		}
+		if !bound {
+			return f.unpackSizes[i]
+		}
+	}
+	return 0
+}
+
+// a substream is the part of the folder data that is a single file
+type substream struct {
+	folder int
+	offset int64
+	size   int64
+	crc    uint32
+	hasCRC bool
+}
+
+type streamsInfo struct {
+	packPos   int64
+	packSizes []int64
+	folders   []folder
+	streams   []substream
+}
+
+// Reader provides access to the files in a 7z archive.
+type Reader struct {
+	r    io.ReaderAt
+	size int64
+
+	// the files and directories in the archive
+	Files []File
+
+	info streamsInfo
+
+	// the most recently decompressed folder
+	crit        sync.Mutex
+	cacheFolder int
+	cache       []byte
+}
+
+// NewReader parses the headers of the 7z archive in the io.ReaderAt. The
+// size argument is the size of the archive in bytes.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	zr := &Reader{
+		r:           r,
+		size:        size,
+		cacheFolder: -1,
+	}
+
+	sig := make([]byte, signatureHeaderLen)
+	_, err := r.ReadAt(sig, 0)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(sig, []byte(Magic)) {
+		return nil, ErrFormat
+	}
+
+	if crc32.ChecksumIEEE(sig[12:]) != binary.LittleEndian.Uint32(sig[8:]) {
+		return nil, ErrCorrupt
+	}
+
+	nextOffset := int64(binary.LittleEndian.Uint64(sig[12:]))
+	nextSize := int64(binary.LittleEndian.Uint64(sig[20:]))
+	nextCRC := binary.LittleEndian.Uint32(sig[28:])
+
+	// an empty archive has no header
+	if nextSize == 0 {
+		return zr, nil
+	}
+
+	if nextOffset < 0 || nextSize < 0 || signatureHeaderLen+nextOffset+nextSize > size {
+		return nil, ErrCorrupt
+	}
+
+	header := make([]byte, nextSize)
+	_, err = r.ReadAt(header, signatureHeaderLen+nextOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(header) != nextCRC {
+		return nil, ErrCorrupt
+	}
+
+	err = zr.readHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return zr, nil
+}
+
+func (zr *Reader) readHeader(header []byte) error {
+	r := &headerReader{b: header}
+
+	switch r.byte() {
+	case idHeader:
+	case idEncodedHeader:
+		// the header has been compressed. decompress it and try again
+		info := readStreamsInfo(r)
+		if r.err != nil {
+			return r.err
+		}
+		if len(info.folders) == 0 {
+			return ErrCorrupt
+		}
+
+		header, err := zr.decodeFolder(info, 0)
+		if err != nil {
+			return err
+		}
+		return zr.readHeader(header)
+	default:
+		return ErrCorrupt
+	}
+
+	for r.err == nil {
+		switch r.byte() {
+		case idEnd:
+			return nil
+		case idArchiveProperties:
+			for r.err == nil {
+				if r.byte() == idEnd {
+					break // for loop
+				}
+				r.bytes(r.count())
+			}
+		case idAdditionalStreamsInfo:
+			_ = readStreamsInfo(r)
+		case idMainStreamsInfo:
+			zr.info = readStreamsInfo(r)
+		case idFilesInfo:
+			zr.readFilesInfo(r)
+		default:
+			return ErrCorrupt
+		}
+	}
+
+	return r.err
+}
+
+func readStreamsInfo(r *headerReader) streamsInfo {
+	var info streamsInfo
+
+	subStreams := false
+
+	for r.err == nil {
+		switch r.byte() {
+		case idEnd:
+			// substreams info is optional. if it is missing then there is one
+			// file in every folder
+			if !subStreams {
+				for i, f := range info.folders {
+					info.streams = append(info.streams, substream{
+						folder: i,
+						size:   f.unpackSize(),
+						crc:    f.crc,
+						hasCRC: f.hasCRC,
+					})
+				}
+			}
+			return info
+
+		case idPackInfo:
+			info.packPos = r.size()
+			n := r.count()
+			info.packSizes = make([]int64, n)
+			for r.err == nil {
+				id := r.byte()
+				if id == idEnd {
+					break // for loop
+				}
+				switch id {
+				case idSize:
+					for i := range info.packSizes {
+						info.packSizes[i] = r.size()
+					}
+				case idCRC:
+					_, _ = r.digests(n)
+				default:
+					r.err = ErrCorrupt
+				}
+			}
+
+		case idUnpackInfo:
+			r.expect(idFolder)
+			info.folders = make([]folder, r.count())
+			if r.byte() != 0 {
+				r.err = fmt.Errorf("%w: external folders", ErrUnsupported)
+				return info
+			}
+
+			var firstPack int
+			for i := range info.folders {
+				info.folders[i] = readFolder(r)
+				info.folders[i].firstPack = firstPack
+				firstPack += len(info.folders[i].packedStreams)
+			}
+
+			r.expect(idCodersUnpackSize)
+			for i := range info.folders {
+				for j := range info.folders[i].unpackSizes {
+					info.folders[i].unpackSizes[j] = r.size()
+				}
+			}
+
+			for r.err == nil {
+				id := r.byte()
+				if id == idEnd {
+					break // for loop
+				}
+				if id != idCRC {
+					r.err = ErrCorrupt
+					break // for loop
+				}
+				crcs, defined := r.digests(len(info.folders))
+				for i := range info.folders {
+					info.folders[i].crc = crcs[i]
+					info.folders[i].hasCRC = defined[i]
+				}
+			}
+
+		case idSubStreamsInfo:
+			subStreams = true
+			info.streams = readSubStreamsInfo(r, info.folders)
+
+		default:
+			r.err = ErrCorrupt
+		}
+	}
+
+	return info
+}
+
+func readFolder(r *headerReader) folder {
+	var f folder
+
+	var numIn int
+	var numOut int
+
+	f.coders = make([]coder, r.count())
+	for i := range f.coders {
+		flags := r.byte()
+		if flags&0x80 != 0 {
+			r.err = fmt.Errorf("%w: alternative methods", ErrUnsupported)
+			return f
+		}
+
+		c := &f.coders[i]
+		c.id = r.bytes(int(flags & 0x0f))
+		c.numIn = 1
+		c.numOut = 1
+		if flags&0x10 != 0 {
+			c.numIn = r.count()
+			c.numOut = r.count()
+		}
+		if flags&0x20 != 0 {
+			c.props = r.bytes(r.count())
+		}
+
+		numIn += c.numIn
+		numOut += c.numOut
+	}
+
+	if numOut == 0 {
+		r.err = ErrCorrupt
+		return f
+	}
+
+	f.bindPairs = make([]bindPair, numOut-1)
+	for i := range f.bindPairs {
+		f.bindPairs[i].in = r.count()
+		f.bindPairs[i].out = r.count()
+	}
+
+	numPacked := numIn - len(f.bindPairs)
+	if numPacked < 1 {
+		r.err = ErrCorrupt
+		return f
+	}
+
+	if numPacked == 1 {
+		// the single packed stream is the input stream that isn't bound
+		for i := 0; i < numIn; i++ {
+			bound := false
+			for _, bp := range f.bindPairs {
+				if bp.in == i {
+					bound = true
+					break // for loop
+				}
+			}
+			if !bound {
+				f.packedStreams = append(f.packedStreams, i)
+				break // for loop
+			}
+		}
+	} else {
+		for i := 0; i < numPacked; i++ {
+			f.packedStreams = append(f.packedStreams, r.count())
+		}
+	}
+
+	f.unpackSizes = make([]int64, numOut)
+
+	return f
+}
+
+func readSubStreamsInfo(r *headerReader, folders []folder) []substream {
+	numStreams := make([]int, len(folders))
+	for i := range numStreams {
+		numStreams[i] = 1
+	}
+
+	id := r.byte()
+
+	if id == idNumUnpackStream {
+		for i := range numStreams {
+			numStreams[i] = r.count()
+		}
+		id = r.byte()
+	}
+
+	var streams []substream
+
+	for i, f := range folders {
+		var offset int64
+		for j := 0; j < numStreams[i]; j++ {
+			s := substream{
+				folder: i,
+				offset: offset,
+			}
+
+			// the size of the last stream in a folder is not stored
+			if j == numStreams[i]-1 {
+				s.size = f.unpackSize() - offset
+				if s.size < 0 {
+					r.err = ErrCorrupt
+					return nil
+				}
+			} else if id == idSize {
+				s.size = r.size()
+			} else {
+				r.err = ErrCorrupt
+				return nil
+			}
+
+			// a folder containing one file has the same CRC as the file
+			if numStreams[i] == 1 {
+				s.crc = f.crc
+				s.hasCRC = f.hasCRC
+			}
+
+			offset += s.size
+			streams = append(streams, s)
+		}
+	}
+
+	if id == idSize {
+		id = r.byte()
+	}
+
+	for r.err == nil && id != idEnd {
+		if id != idCRC {
+			r.err = ErrCorrupt
+			return nil
+		}
+
+		// CRC values are stored for streams that don't already have one
+		var unknown []int
+		for i := range streams {
+			if !streams[i].hasCRC {
+				unknown = append(unknown, i)
+			}
+		}
+
+		crcs, defined := r.digests(len(unknown))
+		for i, s := range unknown {
+			streams[s].crc = crcs[i]
+			streams[s].hasCRC = defined[i]
+		}
+
+		id = r.byte()
+	}
+
+	return streams
+}
+
+func (zr *Reader) readFilesInfo(r *headerReader) {
+	zr.Files = make([]File, r.count())
+
+	var emptyStream []bool
+	var emptyFile []bool
+	var attributes []uint32
+
+	for r.err == nil {
+		id := r.byte()
+		if id == idEnd {
+			break // for loop
+		}
+
+		p := &headerReader{b: r.bytes(r.count())}
+		if r.err != nil {
+			return
+		}
+
+		switch id {
+		case idEmptyStream:
+			emptyStream = p.bitVector(len(zr.Files))
+		case idEmptyFile:
+			var n int
+			for _, e := range emptyStream {
+				if e {
+					n++
+				}
+			}
+			emptyFile = p.bitVector(n)
+		case idName:
+			if p.byte() != 0 {
+				r.err = fmt.Errorf("%w: external names", ErrUnsupported)
+				return
+			}
+			for i := range zr.Files {
+				zr.Files[i].Name = p.name()
+			}
+		case idWinAttributes:
+			defined := p.optionalBitVector(len(zr.Files))
+			if p.byte() != 0 {
+				r.err = fmt.Errorf("%w: external attributes", ErrUnsupported)
+				return
+			}
+			attributes = make([]uint32, len(zr.Files))
+			for i := range attributes {
+				if defined[i] {
+					attributes[i] = p.uint32()
+				}
+			}
+		}
+
+		if p.err != nil {
+			r.err = p.err
+			return
+		}
+	}
+
+	var stream int
+	var empty int
+
+	for i := range zr.Files {
+		f := &zr.Files[i]
+
+		// path separators are normalised to the forward slash
+		f.Name = strings.ReplaceAll(f.Name, "\\", "/")
+		f.Name = strings.TrimPrefix(path.Clean("/"+f.Name), "/")
+
+		if attributes != nil && attributes[i]&attributeDirectory != 0 {
+			f.IsDir = true
+		}
+
+		if emptyStream != nil && emptyStream[i] {
+			// an empty stream is a directory unless it is marked as an empty
+			// file
+			if emptyFile == nil || !emptyFile[empty] {
+				f.IsDir = true
+			}
+			empty++
+			f.folder = -1
+			continue // for loop
+		}
+
+		if stream >= len(zr.info.streams) {
+			r.err = ErrCorrupt
+			return
+		}
+
+		s := zr.info.streams[stream]
+		f.folder = s.folder
+		f.offset = s.offset
+		f.Size = s.size
+		f.crc = s.crc
+		f.hasCRC = s.hasCRC
+		stream++
+	}
+}
+
+// decompress a folder
+func (zr *Reader) decodeFolder(info streamsInfo, idx int) ([]byte, error) {
+	f := info.folders[idx]
+
+	// the main output stream is the one that is not bound to any input
+	main := -1
+	for i := range f.unpackSizes {
+		bound := false
+		for _, bp := range f.bindPairs {
+			if bp.out == i {
+				bound = true
+				break // for loop
+			}
+		}
+		if !bound {
+			main = i
+			break // for loop
+		}
+	}
+	if main == -1 {
+		return nil, ErrCorrupt
+	}
+
+	d, err := zr.decodeStream(info, f, main, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.hasCRC && crc32.ChecksumIEEE(d) != f.crc {
+		return nil, ErrCorrupt
+	}
+
+	return d, nil
+}
+
+// the maximum number of coders that can be chained together in a folder
+const maxCoderDepth = 4
+
+// decode an output stream of a folder. only coders with one input stream and
+// one output stream are supported
+func (zr *Reader) decodeStream(info streamsInfo, f folder, out int, depth int) ([]byte, error) {
+	if depth > maxCoderDepth {
+		return nil, ErrCorrupt
+	}
+
+	// find the coder that produces the output stream and the index of the
+	// coder's input stream
+	var c coder
+	var in int
+	var found bool
+
+	var numIn, numOut int
+	for _, c = range f.coders {
+		if out >= numOut && out < numOut+c.numOut {
+			found = true
+			break // for loop
+		}
+		numIn += c.numIn
+		numOut += c.numOut
+	}
+	if !found {
+		return nil, ErrCorrupt
+	}
+	if c.numIn != 1 || c.numOut != 1 {
+		return nil, fmt.Errorf("%w: complex coder", ErrUnsupported)
+	}
+	in = numIn
+
+	// the input is either the output of another coder or a packed stream
+	var input []byte
+	var err error
+
+	bound := false
+	for _, bp := range f.bindPairs {
+		if bp.in == in {
+			bound = true
+			input, err = zr.decodeStream(info, f, bp.out, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			break // for loop
+		}
+	}
+
+	if !bound {
+		packed := -1
+		for i, p := range f.packedStreams {
+			if p == in {
+				packed = f.firstPack + i
+				break // for loop
+			}
+		}
+		if packed == -1 || packed >= len(info.packSizes) {
+			return nil, ErrCorrupt
+		}
+
+		// the packed stream must be inside the archive
+		offset := signatureHeaderLen + info.packPos
+		for _, sz := range info.packSizes[:packed] {
+			if offset > zr.size {
+				return nil, ErrCorrupt
+			}
+			offset += sz
+		}
+		sz := info.packSizes[packed]
+		if offset > zr.size || sz > zr.size-offset || sz > maxStreamSize {
+			return nil, ErrCorrupt
+		}
+
+		input = make([]byte, sz)
+		_, err = zr.r.ReadAt(input, offset)
+		if err != nil {
+			return nil, fmt.Errorf("7z: %w", err)
+		}
+	}
+
+	if out >= len(f.unpackSizes) || f.unpackSizes[out] > maxStreamSize {
+		return nil, ErrCorrupt
+	}
+
+	return decompress(c, input, f.unpackSizes[out])
+}
+
+// decompression methods
+var (
+	methodCopy    = []byte{0x00}
+	methodLZMA    = []byte{0x03, 0x01, 0x01}
+	methodLZMA2   = []byte{0x21}
+	methodDeflate = []byte{0x04, 0x01, 0x08}
+	methodBZip2   = []byte{0x04, 0x02, 0x02}
+	methodAES     = []byte{0x06, 0xf1, 0x07, 0x01}
+)
+
+func decompress(c coder, in []byte, size int64) ([]byte, error) {
+	var out []byte
+	var err error
+
+	switch {
+	case bytes.Equal(c.id, methodCopy):
+		out = in
+	case bytes.Equal(c.id, methodLZMA):
+		out, err = lzma.Decode(in, c.props, int(size))
+	case bytes.Equal(c.id, methodLZMA2):
+		out, _, err = lzma.Decode2(in)
+	case bytes.Equal(c.id, methodDeflate):
+		out, err = io.ReadAll(flate.NewReader(bytes.NewReader(in)))
+	case bytes.Equal(c.id, methodBZip2):
+		out, err = io.ReadAll(bzip2.NewReader(bytes.NewReader(in)))
+	case bytes.Equal(c.id, methodAES):
+		return nil, fmt.Errorf("%w: encrypted archive", ErrUnsupported)
+	default:
+		return nil, fmt.Errorf("%w: compression method %x", ErrUnsupported, c.id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("7z: %w", err)
+	}
+
+	if int64(len(out)) != size {
+		return nil, ErrCorrupt
+	}
+
+	return out, nil
+}
+
+// ReadFile returns the uncompressed contents of the file.
+func (zr *Reader) ReadFile(f *File) ([]byte, error) {
+	if f.IsDir {
+		return nil, fmt.Errorf("7z: %s is a directory", f.Name)
+	}
+
+	if f.folder < 0 {
+		return []byte{}, nil
+	}
+
+	zr.crit.Lock()
+	defer zr.crit.Unlock()
+
+	if zr.cacheFolder != f.folder {
+		d, err := zr.decodeFolder(zr.info, f.folder)
+		if err != nil {
+			return nil, err
+		}
+		zr.cache = d
+		zr.cacheFolder = f.folder
+	}
+
+	if f.offset+f.Size > int64(len(zr.cache)) {
+		return nil, ErrCorrupt
+	}
+
+	d := make([]byte, f.Size)
+	copy(d, zr.cache[f.offset:])
+
+	if f.hasCRC && crc32.ChecksumIEEE(d) != f.crc {
+		return nil, ErrCorrupt
+	}
+
+	return d, nil
+}
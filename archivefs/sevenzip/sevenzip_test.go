@@ -0,0 +1,121 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package sevenzip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// number in the 7z variable length format. always uses the nine byte form
+func number(v uint64) []byte {
+	return binary.LittleEndian.AppendUint64([]byte{0xff}, v)
+}
+
+// archive creates a 7z archive containing a single file called "a" stored
+// with the copy method. the pack position, pack size and unpack size are
+// written to the header as given so that they can be made to disagree with
+// the data
+func archive(data []byte, packPos uint64, packSize uint64, unpackSize uint64) []byte {
+	var hdr []byte
+	hdr = append(hdr, idHeader, idMainStreamsInfo)
+	hdr = append(hdr, idPackInfo)
+	hdr = append(hdr, number(packPos)...)
+	hdr = append(hdr, 0x01, idSize)
+	hdr = append(hdr, number(packSize)...)
+	hdr = append(hdr, idEnd)
+	hdr = append(hdr, idUnpackInfo, idFolder, 0x01, 0x00)
+	hdr = append(hdr, 0x01, 0x01, methodCopy[0])
+	hdr = append(hdr, idCodersUnpackSize)
+	hdr = append(hdr, number(unpackSize)...)
+	hdr = append(hdr, idEnd)
+	hdr = append(hdr, idEnd)
+	hdr = append(hdr, idFilesInfo, 0x01, idName, 0x05, 0x00, 'a', 0x00, 0x00, 0x00, idEnd)
+	hdr = append(hdr, idEnd)
+
+	sig := make([]byte, signatureHeaderLen)
+	copy(sig, Magic)
+	sig[7] = 0x04
+	binary.LittleEndian.PutUint64(sig[12:], uint64(len(data)))
+	binary.LittleEndian.PutUint64(sig[20:], uint64(len(hdr)))
+	binary.LittleEndian.PutUint32(sig[28:], crc32.ChecksumIEEE(hdr))
+	binary.LittleEndian.PutUint32(sig[8:], crc32.ChecksumIEEE(sig[12:]))
+
+	return append(append(sig, data...), hdr...)
+}
+
+func readFile(b []byte) ([]byte, error) {
+	zr, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	if len(zr.Files) != 1 {
+		return nil, ErrCorrupt
+	}
+	return zr.ReadFile(&zr.Files[0])
+}
+
+func TestArchive(t *testing.T) {
+	d, err := readFile(archive([]byte("hello"), 0, 5, 5))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(d), "hello")
+}
+
+func TestCorruptSizes(t *testing.T) {
+	data := []byte("hello")
+
+	// packed stream larger than the archive
+	_, err := readFile(archive(data, 0, 1<<40, 5))
+	test.ExpectSuccess(t, errors.Is(err, ErrCorrupt))
+
+	// packed stream starts after the end of the archive
+	_, err = readFile(archive(data, 1<<40, 5, 5))
+	test.ExpectSuccess(t, errors.Is(err, ErrCorrupt))
+
+	// unpacked size is too large
+	_, err = readFile(archive(data, 0, 5, 1<<40))
+	test.ExpectSuccess(t, errors.Is(err, ErrCorrupt))
+
+	// unpacked size doesn't match the data
+	_, err = readFile(archive(data, 0, 5, 4))
+	test.ExpectSuccess(t, errors.Is(err, ErrCorrupt))
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add(archive([]byte("hello"), 0, 5, 5))
+
+	b, err := os.ReadFile(filepath.Join("..", "testarchives", "testarchive.7z"))
+	if err == nil {
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		zr, err := NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return
+		}
+		for i := range zr.Files {
+			_, _ = zr.ReadFile(&zr.Files[i])
+		}
+	})
+}
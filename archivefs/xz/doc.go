@@ -0,0 +1,27 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package xz decompresses data in the xz format. Only the LZMA2 filter is
+// supported, which is the filter used by default by the xz tool. The
+// integrity of the decompressed data is checked if the check type is CRC32 or
+// CRC64.
+//
+// Like the lzma package, which is used for the LZMA2 decompression, the data
+// is decompressed entirely in memory.
+//
+// The xz file format is described at:
+//
+// https://tukaani.org/xz/xz-file-format.txt
+package xz
@@ -0,0 +1,313 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package xz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/crc64"
+
+	"github.com/jetsetilly/gopher2600/archivefs/lzma"
+)
+
+// Magic is the sequence of bytes at the start of every xz file.
+const Magic = "\xfd7zXZ\x00"
+
+// sentinal errors
+var (
+	ErrFormat      = errors.New("xz: not an xz file")
+	ErrCorrupt     = errors.New("xz: corrupt data")
+	ErrUnsupported = errors.New("xz: unsupported feature")
+)
+
+const (
+	streamHeaderLen = 12
+	streamFooterLen = 12
+)
+
+// the check types that are verified. other check types are skipped
+const (
+	checkCRC32 = 0x01
+	checkCRC64 = 0x04
+)
+
+// the size of the check field for each check type
+func checkSize(checkType byte) int {
+	switch checkType {
+	case 0x00:
+		return 0
+	case 0x01, 0x02, 0x03:
+		return 4
+	case 0x04, 0x05, 0x06:
+		return 8
+	case 0x07, 0x08, 0x09:
+		return 16
+	case 0x0a, 0x0b, 0x0c:
+		return 32
+	}
+	return 64
+}
+
+// the ID of the only supported filter
+const filterLZMA2 = 0x21
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Decode decompresses the xz data. Concatenated streams are supported.
+func Decode(in []byte) ([]byte, error) {
+	if !bytes.HasPrefix(in, []byte(Magic)) {
+		return nil, ErrFormat
+	}
+
+	var out []byte
+
+	for len(in) > 0 {
+		// stream padding is allowed between streams
+		if bytes.HasPrefix(in, []byte{0, 0, 0, 0}) {
+			in = in[4:]
+			continue // for loop
+		}
+
+		d, n, err := decodeStream(in)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d...)
+		in = in[n:]
+	}
+
+	return out, nil
+}
+
+// decode a single stream. returns the decompressed data and the number of
+// bytes consumed
+func decodeStream(in []byte) ([]byte, int, error) {
+	if len(in) < streamHeaderLen || !bytes.HasPrefix(in, []byte(Magic)) {
+		return nil, 0, ErrFormat
+	}
+
+	flags := in[len(Magic) : len(Magic)+2]
+	if crc32.ChecksumIEEE(flags) != binary.LittleEndian.Uint32(in[len(Magic)+2:]) {
+		return nil, 0, ErrCorrupt
+	}
+	if flags[0] != 0x00 || flags[1]&0xf0 != 0x00 {
+		return nil, 0, ErrUnsupported
+	}
+	checkType := flags[1]
+
+	var out []byte
+	pos := streamHeaderLen
+
+	for {
+		if pos >= len(in) {
+			return nil, 0, ErrCorrupt
+		}
+
+		// an index indicator of zero means that there are no more blocks
+		if in[pos] == 0x00 {
+			break // for loop
+		}
+
+		d, n, err := decodeBlock(in[pos:], checkType)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, d...)
+		pos += n
+	}
+
+	// the index isn't needed because the blocks have already been decoded
+	n, err := skipIndex(in[pos:])
+	if err != nil {
+		return nil, 0, err
+	}
+	pos += n
+
+	if pos+streamFooterLen > len(in) {
+		return nil, 0, ErrCorrupt
+	}
+
+	// the footer must agree with the index and with the stream header
+	footer := in[pos : pos+streamFooterLen]
+	if string(footer[10:]) != "YZ" ||
+		crc32.ChecksumIEEE(footer[4:10]) != binary.LittleEndian.Uint32(footer) ||
+		binary.LittleEndian.Uint32(footer[4:]) != uint32(n/4-1) ||
+		!bytes.Equal(footer[8:10], flags) {
+		return nil, 0, ErrCorrupt
+	}
+	pos += streamFooterLen
+
+	return out, pos, nil
+}
+
+// read a variable length integer. returns the value and the number of bytes
+// consumed
+func readVLI(in []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < 9 && i < len(in); i++ {
+		v |= uint64(in[i]&0x7f) << (7 * i)
+		if in[i]&0x80 == 0x00 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrCorrupt
+}
+
+// decode a single block. returns the decompressed data and the number of bytes
+// consumed
+func decodeBlock(in []byte, checkType byte) ([]byte, int, error) {
+	headerLen := (int(in[0]) + 1) * 4
+	if headerLen > len(in) {
+		return nil, 0, ErrCorrupt
+	}
+
+	header := in[:headerLen]
+	if crc32.ChecksumIEEE(header[:headerLen-4]) != binary.LittleEndian.Uint32(header[headerLen-4:]) {
+		return nil, 0, ErrCorrupt
+	}
+
+	flags := header[1]
+	numFilters := int(flags&0x03) + 1
+	p := header[2 : headerLen-4]
+
+	compressedSize := -1
+	uncompressedSize := -1
+
+	if flags&0x40 == 0x40 {
+		v, n, err := readVLI(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		compressedSize = int(v)
+		p = p[n:]
+	}
+
+	if flags&0x80 == 0x80 {
+		v, n, err := readVLI(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		uncompressedSize = int(v)
+		p = p[n:]
+	}
+
+	for i := 0; i < numFilters; i++ {
+		id, n, err := readVLI(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		p = p[n:]
+
+		propsSize, n, err := readVLI(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		p = p[n:]
+
+		if id != filterLZMA2 || i != numFilters-1 {
+			return nil, 0, fmt.Errorf("%w: filter %#x", ErrUnsupported, id)
+		}
+		if propsSize != 1 || len(p) < 1 {
+			return nil, 0, ErrCorrupt
+		}
+		_, err = lzma.DictSize2(p[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		p = p[1:]
+	}
+
+	pos := headerLen
+
+	out, n, err := lzma.Decode2(in[pos:])
+	if err != nil {
+		return nil, 0, fmt.Errorf("xz: %w", err)
+	}
+	if compressedSize >= 0 && compressedSize != n {
+		return nil, 0, ErrCorrupt
+	}
+	if uncompressedSize >= 0 && uncompressedSize != len(out) {
+		return nil, 0, ErrCorrupt
+	}
+	pos += n
+
+	// block padding
+	for pos%4 != 0 {
+		if pos >= len(in) || in[pos] != 0x00 {
+			return nil, 0, ErrCorrupt
+		}
+		pos++
+	}
+
+	sz := checkSize(checkType)
+	if pos+sz > len(in) {
+		return nil, 0, ErrCorrupt
+	}
+	check := in[pos : pos+sz]
+
+	switch checkType {
+	case checkCRC32:
+		if crc32.ChecksumIEEE(out) != binary.LittleEndian.Uint32(check) {
+			return nil, 0, ErrCorrupt
+		}
+	case checkCRC64:
+		if crc64.Checksum(out, crc64Table) != binary.LittleEndian.Uint64(check) {
+			return nil, 0, ErrCorrupt
+		}
+	}
+	pos += sz
+
+	return out, pos, nil
+}
+
+// skip the index. returns the number of bytes consumed
+func skipIndex(in []byte) (int, error) {
+	// index indicator
+	pos := 1
+
+	numRecords, n, err := readVLI(in[pos:])
+	if err != nil {
+		return 0, err
+	}
+	pos += n
+
+	for i := uint64(0); i < numRecords*2; i++ {
+		_, n, err := readVLI(in[pos:])
+		if err != nil {
+			return 0, err
+		}
+		pos += n
+	}
+
+	// index padding
+	for pos%4 != 0 {
+		pos++
+	}
+
+	// CRC32 of index
+	pos += 4
+	if pos > len(in) {
+		return 0, ErrCorrupt
+	}
+	if crc32.ChecksumIEEE(in[:pos-4]) != binary.LittleEndian.Uint32(in[pos-4:]) {
+		return 0, ErrCorrupt
+	}
+
+	return pos, nil
+}
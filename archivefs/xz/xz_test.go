@@ -0,0 +1,69 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package xz_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/archivefs/xz"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+var testArchive = filepath.Join("..", "testarchives", "testarchive.tar.xz")
+
+func TestDecode(t *testing.T) {
+	b, err := os.ReadFile(testArchive)
+	test.ExpectSuccess(t, err)
+
+	_, err = xz.Decode(b)
+	test.ExpectSuccess(t, err)
+}
+
+func TestCorrupt(t *testing.T) {
+	b, err := os.ReadFile(testArchive)
+	test.ExpectSuccess(t, err)
+
+	_, err = xz.Decode(b[1:])
+	test.ExpectSuccess(t, errors.Is(err, xz.ErrFormat))
+
+	// every truncation of the data must fail
+	for i := len(xz.Magic); i < len(b); i++ {
+		_, err = xz.Decode(b[:i])
+		test.ExpectFailure(t, err)
+	}
+
+	// changing any byte after the magic string must fail. the xz format
+	// protects every part of the file with a CRC
+	for i := len(xz.Magic); i < len(b); i++ {
+		c := append([]byte{}, b...)
+		c[i] ^= 0x55
+		_, err = xz.Decode(c)
+		test.ExpectFailure(t, err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	b, err := os.ReadFile(testArchive)
+	if err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		_, _ = xz.Decode(b)
+	})
+}
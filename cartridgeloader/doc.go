@@ -35,11 +35,17 @@
 //	Atari 16k (RAM) "F6+"
 //	Atari 32k (RAM) "F4+"
 //	CBS             "FA"
+//	CBS (Harmony)   "FA2"
+//	Chetiry         "CTY"
+//	4A50            "4A50"
 //	Parker Bros     "E0"
 //	M-Network       "E7"
 //	Tigervision     "3F"
+//	EconoBanking    "0840"
+//	X07             "X07"
 //	Supercharger    "AR", "MP3, "WAV"
-//	DF              "DF"
+//	DF              "DF", "DFSC"
+//	BF              "BF", "BFSC"
 //	3E              "3E"
 //	3E+             "3E+"
 //	Superbank       "SB"
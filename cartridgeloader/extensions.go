@@ -27,11 +27,12 @@ var autoFileExtensions = []string{
 
 // explicit extensions specify a mapping explicitly
 var explicitFileExtensions = []string{
-	".2K", ".4K", ".F8", ".WF8", ".F6", ".F4", ".2K+", ".2KSC",
-	".4K+", ".4KSC", ".F8+", ".F8SC", ".F6+", ".F6SC", ".F4+", ".F4SC", ".CV",
-	".FA", ".FE", ".E0", ".E7", ".3F", ".UA", ".AR", ".DF", ".3E", ".E3P",
-	".E3+", ".3E+", ".EF", ".EFSC", ".SB", ".WD", ".ACE", ".CDF0", ".CDF1", ".CDFJ",
-	".CDFJ+", ".DP+", ".DPC", ".CDF", ".MVC",
+	".2K", ".4K", ".F8", ".WF8", ".F6", ".F4", ".2K+", ".2KSC", ".4K+", ".4KSC",
+	".F8+", ".F8SC", ".F6+", ".F6SC", ".F4+", ".F4SC", ".CV", ".FA", ".FA2",
+	".CTY", ".4A50", ".FE", ".E0", ".E7", ".3F", ".UA", ".0840", ".X07", ".AR",
+	".DF", ".DFSC", ".BF", ".BFSC", ".3E", ".E3P", ".E3+", ".3E+", ".EF",
+	".EFSC", ".SB", ".WD", ".ACE", ".CDF0", ".CDF1", ".CDFJ", ".CDFJ+", ".DP+",
+	".DPC", ".CDF", ".MVC",
 }
 
 // special file extensions. files with these extensions are treated very
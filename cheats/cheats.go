@@ -0,0 +1,354 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cheats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/resources"
+)
+
+// the directory in the resources folder in which cheat files are stored
+const cheatsPath = "cheats"
+
+// FileExtension is the extension given to cheat files.
+const FileExtension = ".cht"
+
+// Filename returns the filename of the cheats file for the cartridge with
+// the specified hash. The path will be in the resources directory.
+func Filename(hash string) (string, error) {
+	pth, err := resources.JoinPath(cheatsPath, fmt.Sprintf("%s%s", hash, FileExtension))
+	if err != nil {
+		return "", fmt.Errorf("cheats: %w", err)
+	}
+	return pth, nil
+}
+
+// Mode specifies how a cheat changes memory.
+type Mode int
+
+// List of valid Mode values.
+const (
+	// the value is written at the start of every frame
+	Freeze Mode = iota
+
+	// the value is written once
+	Poke
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Freeze:
+		return "freeze"
+	case Poke:
+		return "poke"
+	}
+	return "unknown"
+}
+
+func parseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "freeze":
+		return Freeze, nil
+	case "poke":
+		return Poke, nil
+	}
+	return Freeze, fmt.Errorf("unrecognised mode (%s)", s)
+}
+
+// Cheat is a single cheat code.
+type Cheat struct {
+	Name    string
+	Address uint16
+	Value   uint8
+	Mode    Mode
+	Enabled bool
+
+	// a poke cheat that has not yet been applied
+	pending bool
+}
+
+// Code returns the cheat code in addr:value format.
+func (c *Cheat) Code() string {
+	return fmt.Sprintf("%04x:%02x", c.Address, c.Value)
+}
+
+func (c *Cheat) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Code(), c.Mode, c.Name)
+	if !c.Enabled {
+		s = fmt.Sprintf("%s (disabled)", s)
+	}
+	return s
+}
+
+// ParseCode parses a cheat code in addr:value format. The address and value
+// are hexadecimal and may be prefixed with either $ or 0x.
+func ParseCode(code string) (uint16, uint8, error) {
+	a, v, ok := strings.Cut(code, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("cheats: cheat code must be in addr:value format (%s)", code)
+	}
+
+	trim := func(s string) string {
+		s = strings.TrimPrefix(s, "$")
+		s = strings.TrimPrefix(strings.ToLower(s), "0x")
+		return s
+	}
+
+	addr, err := strconv.ParseUint(trim(a), 16, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cheats: invalid address in cheat code (%s)", code)
+	}
+	value, err := strconv.ParseUint(trim(v), 16, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cheats: invalid value in cheat code (%s)", code)
+	}
+
+	return uint16(addr), uint8(value), nil
+}
+
+// Memory defines the memory operations required by the cheats.
+type Memory interface {
+	Poke(address uint16, value uint8) error
+}
+
+// Cheats is the list of cheats for the current cartridge.
+type Cheats struct {
+	mem Memory
+
+	// the filename of the cheats file. the cheats will not be saved if this
+	// is empty
+	filename string
+
+	List []*Cheat
+}
+
+// NewCheats is the preferred method of initialisation for the Cheats type.
+func NewCheats(mem Memory) *Cheats {
+	return &Cheats{
+		mem: mem,
+	}
+}
+
+// Load the cheats for the cartridge with the specified hash, replacing any
+// existing cheats. It is not an error for there to be no cheats file.
+func (c *Cheats) Load(hash string) error {
+	c.List = c.List[:0]
+	c.filename = ""
+
+	if hash == "" {
+		return nil
+	}
+
+	filename, err := Filename(hash)
+	if err != nil {
+		return err
+	}
+	c.filename = filename
+
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("cheats: %w", err)
+	}
+	defer f.Close()
+
+	l, err := read(f)
+	if err != nil {
+		return err
+	}
+	c.List = l
+
+	return nil
+}
+
+// read cheats from the reader. the format of each line is:
+//
+//	<code> <mode> <on|off> <name>
+//
+// lines beginning with # are comments
+func read(r io.Reader) ([]*Cheat, error) {
+	var l []*Cheat
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		ln := strings.TrimSpace(scanner.Text())
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue // for loop
+		}
+
+		p := strings.SplitN(ln, " ", 4)
+		if len(p) < 4 {
+			return nil, fmt.Errorf("cheats: malformed line (%s)", ln)
+		}
+
+		addr, value, err := ParseCode(p[0])
+		if err != nil {
+			return nil, err
+		}
+
+		mode, err := parseMode(p[1])
+		if err != nil {
+			return nil, fmt.Errorf("cheats: %w", err)
+		}
+
+		c := &Cheat{
+			Name:    p[3],
+			Address: addr,
+			Value:   value,
+			Mode:    mode,
+			Enabled: p[2] == "on",
+		}
+		c.pending = c.Enabled
+		l = append(l, c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cheats: %w", err)
+	}
+
+	return l, nil
+}
+
+// write cheats to the writer in the format expected by read()
+func write(w io.Writer, l []*Cheat) error {
+	var s strings.Builder
+	s.WriteString("# gopher2600 cheats\n")
+	for _, c := range l {
+		enabled := "off"
+		if c.Enabled {
+			enabled = "on"
+		}
+		s.WriteString(fmt.Sprintf("%s %s %s %s\n", c.Code(), c.Mode, enabled, c.Name))
+	}
+
+	_, err := io.WriteString(w, s.String())
+	if err != nil {
+		return fmt.Errorf("cheats: %w", err)
+	}
+	return nil
+}
+
+// save cheats to the cheats file for the current cartridge
+func (c *Cheats) save() error {
+	if c.filename == "" {
+		return nil
+	}
+
+	f, err := os.Create(c.filename)
+	if err != nil {
+		return fmt.Errorf("cheats: %w", err)
+	}
+	defer f.Close()
+
+	return write(f, c.List)
+}
+
+// find the cheat with the name. the name comparison is case insensitive
+func (c *Cheats) find(name string) (int, bool) {
+	for i, ch := range c.List {
+		if strings.EqualFold(ch.Name, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Add a new cheat. The cheat is enabled immediately. A cheat with the same
+// name will be replaced.
+func (c *Cheats) Add(name string, code string, mode Mode) error {
+	if name == "" {
+		return fmt.Errorf("cheats: cheat must have a name")
+	}
+
+	addr, value, err := ParseCode(code)
+	if err != nil {
+		return err
+	}
+
+	ch := &Cheat{
+		Name:    name,
+		Address: addr,
+		Value:   value,
+		Mode:    mode,
+		Enabled: true,
+		pending: true,
+	}
+
+	if i, ok := c.find(name); ok {
+		c.List[i] = ch
+	} else {
+		c.List = append(c.List, ch)
+	}
+
+	return c.save()
+}
+
+// Remove the named cheat.
+func (c *Cheats) Remove(name string) error {
+	i, ok := c.find(name)
+	if !ok {
+		return fmt.Errorf("cheats: no cheat named %s", name)
+	}
+	c.List = append(c.List[:i], c.List[i+1:]...)
+	return c.save()
+}
+
+// Enable or disable the named cheat. A poke cheat will be applied again when
+// it is enabled.
+func (c *Cheats) Enable(name string, enable bool) error {
+	i, ok := c.find(name)
+	if !ok {
+		return fmt.Errorf("cheats: no cheat named %s", name)
+	}
+	c.List[i].Enabled = enable
+	c.List[i].pending = enable
+	return c.save()
+}
+
+// Apply the enabled cheats to memory. Freeze cheats are applied every time
+// and poke cheats are applied only once.
+func (c *Cheats) Apply() error {
+	for _, ch := range c.List {
+		if !ch.Enabled {
+			continue // for loop
+		}
+		if ch.Mode == Poke && !ch.pending {
+			continue // for loop
+		}
+		ch.pending = false
+
+		err := c.mem.Poke(ch.Address, ch.Value)
+		if err != nil {
+			return fmt.Errorf("cheats: %s: %w", ch.Name, err)
+		}
+	}
+	return nil
+}
+
+// NewFrame implements the television.FrameTrigger interface.
+func (c *Cheats) NewFrame(_ television.FrameInfo) error {
+	return c.Apply()
+}
@@ -0,0 +1,121 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cheats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+type mockMem struct {
+	pokes map[uint16]uint8
+	count int
+}
+
+func (m *mockMem) Poke(address uint16, value uint8) error {
+	m.pokes[address] = value
+	m.count++
+	return nil
+}
+
+func TestParseCode(t *testing.T) {
+	addr, value, err := ParseCode("0085:09")
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, addr, 0x85)
+	test.ExpectEquality(t, value, 0x09)
+
+	addr, value, err = ParseCode("$f0:0xff")
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, addr, 0xf0)
+	test.ExpectEquality(t, value, 0xff)
+
+	_, _, err = ParseCode("0085")
+	test.ExpectFailure(t, err)
+	_, _, err = ParseCode("0085:100")
+	test.ExpectFailure(t, err)
+}
+
+func TestApply(t *testing.T) {
+	mem := &mockMem{pokes: make(map[uint16]uint8)}
+	c := NewCheats(mem)
+
+	test.ExpectSuccess(t, c.Add("lives", "0085:09", Freeze))
+	test.ExpectSuccess(t, c.Add("level", "0086:04", Poke))
+
+	// poke cheats are applied once. freeze cheats are applied every frame
+	test.ExpectSuccess(t, c.Apply())
+	test.ExpectSuccess(t, c.Apply())
+	test.ExpectEquality(t, mem.pokes[0x85], 0x09)
+	test.ExpectEquality(t, mem.pokes[0x86], 0x04)
+	test.ExpectEquality(t, mem.count, 3)
+
+	// disabled cheats are not applied
+	test.ExpectSuccess(t, c.Enable("LIVES", false))
+	test.ExpectSuccess(t, c.Apply())
+	test.ExpectEquality(t, mem.count, 3)
+
+	// poke cheats are applied again when they are re-enabled
+	test.ExpectSuccess(t, c.Enable("level", true))
+	test.ExpectSuccess(t, c.Apply())
+	test.ExpectEquality(t, mem.count, 4)
+
+	test.ExpectSuccess(t, c.Remove("level"))
+	test.ExpectFailure(t, c.Remove("level"))
+	test.ExpectEquality(t, len(c.List), 1)
+}
+
+func TestReadWrite(t *testing.T) {
+	l := []*Cheat{
+		{Name: "infinite lives", Address: 0x85, Value: 0x09, Mode: Freeze, Enabled: true},
+		{Name: "level", Address: 0xf0, Value: 0x04, Mode: Poke},
+	}
+
+	var s strings.Builder
+	test.ExpectSuccess(t, write(&s, l))
+	test.ExpectEquality(t, s.String(), `# gopher2600 cheats
+0085:09 freeze on infinite lives
+00f0:04 poke off level
+`)
+
+	r, err := read(strings.NewReader(s.String()))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(r), 2)
+	test.ExpectEquality(t, r[0].Name, "infinite lives")
+	test.ExpectEquality(t, r[0].Enabled, true)
+	test.ExpectEquality(t, r[1].Mode, Poke)
+	test.ExpectEquality(t, r[1].Enabled, false)
+}
+
+func TestSearch(t *testing.T) {
+	ram := make([]uint8, 128)
+	ram[0x05] = 3
+	ram[0x06] = 3
+
+	s := NewSearch(ram, 10)
+	test.ExpectEquality(t, len(s.Candidates), 128)
+
+	ram[0x05] = 2
+	ram[0x07] = 1
+	test.ExpectEquality(t, s.Narrow(ram, 20, Decreased, 0), 1)
+	test.ExpectEquality(t, s.Candidates[0], 0x85)
+	test.ExpectEquality(t, s.Frame(), 20)
+
+	ram[0x05] = 2
+	test.ExpectEquality(t, s.Narrow(ram, 30, Value, 2), 1)
+	test.ExpectEquality(t, s.Narrow(ram, 30, NotEqual, 0), 0)
+}
@@ -0,0 +1,46 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package cheats implements cheat codes and a RAM search for finding the
+// addresses that cheat codes should modify.
+//
+// A cheat code is an address and a value. Cheat codes are written in the
+// same addr:value format used by Stella. For example:
+//
+//	0085:09
+//
+// A cheat either freezes the address, in which case the value is written to
+// the address at the start of every frame, or pokes the address, in which
+// case the value is written once when the cheat is enabled (or when the
+// cheats are loaded). Values are written with the Poke() function of the
+// memory and so do not affect the address or data busses.
+//
+// Cheats are stored in the resources directory. There is one file for each
+// cartridge, identified by the cartridge hash. The cheats for a cartridge
+// should be loaded with the Load() function when the cartridge is attached.
+// Changes to the cheats are saved immediately.
+//
+// The Search type helps to find the addresses of interesting values in RAM
+// by narrowing down a list of candidate addresses. A search starts with
+// every RAM address as a candidate. The candidates are narrowed by comparing
+// the contents of RAM with the contents when the search started, or when the
+// previous comparison was made. For example, to find the address of the
+// number of lives remaining in a game, a search could be started at the
+// beginning of the game and narrowed every time a life is lost by searching
+// for decreased values.
+//
+// The contents of RAM used by the search do not need to be the current
+// contents. They can be taken from any state in the rewind history.
+package cheats
@@ -0,0 +1,126 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cheats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// Comparison specifies how candidate addresses are narrowed by the Search
+// type.
+type Comparison int
+
+// List of valid Comparison values.
+const (
+	Equal Comparison = iota
+	NotEqual
+	Increased
+	Decreased
+
+	// the value at the address is a specific value
+	Value
+)
+
+// Search narrows down the addresses in RAM that might contain an interesting
+// value.
+type Search struct {
+	// the candidate addresses, in address order
+	Candidates []uint16
+
+	// the contents of RAM and the frame number from when the search started
+	// or from when the previous comparison was made
+	ram   []uint8
+	frame int
+}
+
+// NewSearch starts a search with every RAM address as a candidate. The ram
+// argument is the contents of RAM, starting with the first RAM address.
+func NewSearch(ram []uint8, frame int) *Search {
+	s := &Search{
+		Candidates: make([]uint16, len(ram)),
+		frame:      frame,
+	}
+	for i := range ram {
+		s.Candidates[i] = memorymap.OriginRAM + uint16(i)
+	}
+	s.ram = append(s.ram, ram...)
+	return s
+}
+
+// Frame returns the frame number of the RAM used for the most recent
+// comparison.
+func (s *Search) Frame() int {
+	return s.frame
+}
+
+// Previous returns the value at the address in the RAM used for the most
+// recent comparison.
+func (s *Search) Previous(address uint16) uint8 {
+	return s.ram[address-memorymap.OriginRAM]
+}
+
+// Narrow the candidate addresses by comparing the contents of RAM with the
+// contents used for the most recent comparison. The value argument is only
+// used with the Value comparison. Returns the number of remaining
+// candidates.
+func (s *Search) Narrow(ram []uint8, frame int, cmp Comparison, value uint8) int {
+	candidates := s.Candidates[:0]
+	for _, a := range s.Candidates {
+		idx := a - memorymap.OriginRAM
+		if int(idx) >= len(ram) {
+			continue // for loop
+		}
+
+		prev := s.ram[idx]
+		cur := ram[idx]
+
+		var keep bool
+		switch cmp {
+		case Equal:
+			keep = cur == prev
+		case NotEqual:
+			keep = cur != prev
+		case Increased:
+			keep = cur > prev
+		case Decreased:
+			keep = cur < prev
+		case Value:
+			keep = cur == value
+		}
+
+		if keep {
+			candidates = append(candidates, a)
+		}
+	}
+	s.Candidates = candidates
+
+	s.ram = append(s.ram[:0], ram...)
+	s.frame = frame
+
+	return len(s.Candidates)
+}
+
+func (s *Search) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%d candidates (frame %d)", len(s.Candidates), s.frame))
+	for _, a := range s.Candidates {
+		b.WriteString(fmt.Sprintf("\n%04x:%02x", a, s.Previous(a)))
+	}
+	return b.String()
+}
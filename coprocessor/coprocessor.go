@@ -185,6 +185,9 @@ type CartCoProc interface {
 
 	// read coprocessor memory address for 32bit value. return false if address is out of range
 	Peek(addr uint32) (uint32, bool)
+
+	// write 32bit value to coprocessor memory address. return false if address is out of range
+	Poke(addr uint32, value uint32) bool
 }
 
 // CartCoProcBus is implemented by cartridge mappers that have a coprocessor
@@ -40,22 +40,27 @@ func (bp *Breakpoints) Write(w io.Writer) {
 	}
 }
 
-// HasBreakpoint returns true if there is a breakpoint on the specified line
+// Check returns true if there is a breakpoint on the specified address
 func (bp *Breakpoints) Check(addr uint32) bool {
 	return bp.breakpoints[addr]
 }
 
-// addBreakpoint adds an address to the list of addresses that will be checked
+// AddBreakpoint adds an address to the list of addresses that will be checked
 // each PC iteration
-func (bp *Breakpoints) addBreakpoint(addr uint32) {
+func (bp *Breakpoints) AddBreakpoint(addr uint32) {
 	bp.breakpoints[addr] = true
 }
 
-// removeBreakpoint removes an address from the list of breakpoint addresses
-func (bp *Breakpoints) removeBreakpoint(addr uint32) {
+// RemoveBreakpoint removes an address from the list of breakpoint addresses
+func (bp *Breakpoints) RemoveBreakpoint(addr uint32) {
 	delete(bp.breakpoints, addr)
 }
 
+// Len returns the number of breakpoint addresses
+func (bp *Breakpoints) Len() int {
+	return len(bp.breakpoints)
+}
+
 // ToggleBreakpoint adds or removes a breakpoint depending on whether the
 // breakpoint already exists
 func (bp *Breakpoints) ToggleBreakpoint(ln *dwarf.SourceLine) {
@@ -68,9 +73,9 @@ func (bp *Breakpoints) ToggleBreakpoint(ln *dwarf.SourceLine) {
 
 	for _, addr := range ln.BreakAddresses {
 		if has {
-			bp.removeBreakpoint(addr)
+			bp.RemoveBreakpoint(addr)
 		} else {
-			bp.addBreakpoint(addr)
+			bp.AddBreakpoint(addr)
 		}
 	}
 }
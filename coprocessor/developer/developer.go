@@ -18,6 +18,7 @@ package developer
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jetsetilly/gopher2600/coprocessor"
@@ -80,6 +81,16 @@ type Developer struct {
 	breakNextInstruction bool
 	breakAddress         uint32
 
+	// the number of breakpoints in the breakpoints map. used to check address
+	// breakpoints quickly when there is no source. see CheckBreakpoint()
+	numBreakpoints atomic.Int32
+
+	// the address of the most recent breakpoint to be triggered by
+	// CheckBreakpoint() when there is no source. resumeBreakpoint is true
+	// until execution has moved past the breakpoint
+	prevBreakpointAddr uint32
+	resumeBreakpoint   bool
+
 	// profiler instance. measures cycles counts for executed address
 	profiler coprocessor.CartCoProcProfiler
 
@@ -121,6 +132,8 @@ func (dev *Developer) AttachCartridge(cart Cartridge, romFile string, elfFile st
 
 	dev.breakpointsLock.Lock()
 	dev.breakpoints = breakpoints.NewBreakpoints()
+	dev.numBreakpoints.Store(0)
+	dev.resumeBreakpoint = false
 	dev.breakpointsLock.Unlock()
 
 	dev.framesSinceLastUpdate = 0
@@ -176,16 +189,41 @@ func (dev *Developer) HighAddress() uint32 {
 
 // CheckBreakpoint implements the coprocessor.CartCoProcDeveloper interface.
 func (dev *Developer) CheckBreakpoint(addr uint32) bool {
-	if dev.source == nil {
-		return false
-	}
-
 	if dev.breakNextInstruction && dev.breakAddress != addr {
 		dev.breakNextInstruction = false
 		dev.breakAddress = addr
 		return true
 	}
 
+	// without source there is no line information so breakpoints can only be
+	// checked by address. the address of the triggered breakpoint is
+	// remembered so that we don't break immediately on resumption from the
+	// same address. it is forgotten once the instruction at that address has
+	// been executed, so a breakpoint in a loop will trigger on every iteration
+	if dev.source == nil {
+		if dev.resumeBreakpoint {
+			dev.resumeBreakpoint = false
+			if addr == dev.prevBreakpointAddr {
+				return false
+			}
+		}
+
+		if dev.numBreakpoints.Load() == 0 {
+			return false
+		}
+
+		dev.breakpointsLock.Lock()
+		defer dev.breakpointsLock.Unlock()
+
+		if dev.breakpoints.Check(addr) {
+			dev.breakAddress = addr
+			dev.prevBreakpointAddr = addr
+			dev.resumeBreakpoint = true
+			return true
+		}
+		return false
+	}
+
 	dev.sourceLock.Lock()
 	defer dev.sourceLock.Unlock()
 
@@ -350,3 +388,21 @@ func (dev *Developer) SetEmulationState(state govern.State) {
 func (dev *Developer) BreakNextInstruction() {
 	dev.breakNextInstruction = true
 }
+
+// AddBreakpoint adds a breakpoint for the specified address. Unlike
+// breakpoints added with Breakpoints.ToggleBreakpoint() the address does not
+// need to be associated with a line of source code.
+func (dev *Developer) AddBreakpoint(addr uint32) {
+	dev.breakpointsLock.Lock()
+	defer dev.breakpointsLock.Unlock()
+	dev.breakpoints.AddBreakpoint(addr)
+	dev.numBreakpoints.Store(int32(dev.breakpoints.Len()))
+}
+
+// RemoveBreakpoint removes the breakpoint for the specified address.
+func (dev *Developer) RemoveBreakpoint(addr uint32) {
+	dev.breakpointsLock.Lock()
+	defer dev.breakpointsLock.Unlock()
+	dev.breakpoints.RemoveBreakpoint(addr)
+	dev.numBreakpoints.Store(int32(dev.breakpoints.Len()))
+}
@@ -0,0 +1,52 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package developer
+
+import (
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/coprocessor/developer/breakpoints"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func TestBreakpointWithoutSource(t *testing.T) {
+	dev := &Developer{
+		breakpoints: breakpoints.NewBreakpoints(),
+	}
+	dev.AddBreakpoint(0x100)
+
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x0fe), false)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), true)
+
+	// the first check after the breakpoint is the resumption from the
+	// breakpoint and so should not trigger again
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), false)
+
+	// a loop back to the same address should trigger the breakpoint on every
+	// iteration
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), true)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), false)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), true)
+
+	// moving past the breakpoint and returning to it later
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), false)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x102), false)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), true)
+
+	dev.RemoveBreakpoint(0x100)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x102), false)
+	test.ExpectEquality(t, dev.CheckBreakpoint(0x100), false)
+}
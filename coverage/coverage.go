@@ -0,0 +1,117 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package coverage
+
+import (
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// Coverage records the number of times each address in the cartridge has been
+// executed.
+type Coverage struct {
+	// the cartridge the coverage applies to
+	Filename string
+	Name     string
+	Hash     string
+	Mapper   string
+
+	// execution counts indexed by bank and by address. the address should be
+	// masked with memorymap.CartridgeBits before indexing
+	banks [][]int
+
+	// state noted by Begin()
+	pending bool
+	bank    mapper.BankInfo
+}
+
+// NewCoverage is the preferred method of initialisation for the Coverage type.
+// The Coverage instance must be reset with a cartridge before it can be used.
+func NewCoverage() *Coverage {
+	return &Coverage{}
+}
+
+// Reset all execution counts and prepare for the specified cartridge.
+func (cov *Coverage) Reset(cart *cartridge.Cartridge) {
+	cov.Filename = cart.Filename
+	cov.Name = cart.ShortName
+	cov.Hash = cart.Hash
+	cov.Mapper = cart.ID()
+
+	cov.banks = make([][]int, cart.NumBanks())
+	for b := range cov.banks {
+		cov.banks[b] = make([]int, memorymap.CartridgeBits+1)
+	}
+
+	cov.pending = false
+}
+
+// Matches returns true if the coverage is for the specified cartridge.
+func (cov *Coverage) Matches(cart *cartridge.Cartridge) bool {
+	return cov.banks != nil && cov.Hash == cart.Hash
+}
+
+// NumBanks returns the number of banks in the cartridge.
+func (cov *Coverage) NumBanks() int {
+	return len(cov.banks)
+}
+
+// Count returns the number of times the address in the bank has been
+// executed.
+func (cov *Coverage) Count(bank int, addr uint16) int {
+	if bank < 0 || bank >= len(cov.banks) {
+		return 0
+	}
+	return cov.banks[bank][addr&memorymap.CartridgeBits]
+}
+
+// Execute records the execution of the instruction at the address in the
+// bank.
+func (cov *Coverage) Execute(bank mapper.BankInfo, addr uint16) {
+	if bank.NonCart || bank.ExecutingCoprocessor {
+		return
+	}
+	if bank.Number < 0 || bank.Number >= len(cov.banks) {
+		return
+	}
+	cov.banks[bank.Number][addr&memorymap.CartridgeBits]++
+}
+
+// Begin notes the bank that the next instruction will be executed from. The
+// bank can't be known after the instruction has executed because the
+// instruction might cause a bank switch.
+func (cov *Coverage) Begin(vcs *hardware.VCS) {
+	mc := vcs.CPU
+	cov.pending = mc.RdyFlg && !mc.Killed && (mc.LastResult.Final || mc.Interrupted)
+	if cov.pending {
+		cov.bank = vcs.Mem.Cart.GetBank(mc.PC.Address())
+	}
+}
+
+// End marks the address of the instruction as executed if the instruction
+// started with Begin() has completed.
+func (cov *Coverage) End(vcs *hardware.VCS) {
+	if !cov.pending {
+		return
+	}
+	cov.pending = false
+
+	if vcs.CPU.LastResult.Final {
+		cov.Execute(cov.bank, vcs.CPU.LastResult.Address)
+	}
+}
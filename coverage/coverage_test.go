@@ -0,0 +1,66 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package coverage_test
+
+import (
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/coverage"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := coverage.Summary{
+		Hash:     "abcd",
+		Coverage: 50,
+		Banks: []coverage.BankSummary{
+			{Bank: 0, Coverage: 75},
+			{Bank: 1, Coverage: 25},
+		},
+	}
+
+	s := baseline
+	test.ExpectSuccess(t, s.Compare(baseline))
+
+	// increased coverage is fine
+	s.Coverage = 60
+	s.Banks = []coverage.BankSummary{
+		{Bank: 0, Coverage: 75},
+		{Bank: 1, Coverage: 45},
+	}
+	test.ExpectSuccess(t, s.Compare(baseline))
+
+	// reduction in a single bank is a failure even if the overall coverage
+	// has increased
+	s.Banks[0].Coverage = 70
+	test.ExpectFailure(t, s.Compare(baseline))
+
+	// reduction in overall coverage
+	s.Banks[0].Coverage = 75
+	s.Coverage = 49.99
+	test.ExpectFailure(t, s.Compare(baseline))
+
+	// different cartridge
+	s = baseline
+	s.Hash = "efgh"
+	test.ExpectFailure(t, s.Compare(baseline))
+}
+
+func TestListingFilename(t *testing.T) {
+	test.ExpectEquality(t, coverage.ListingFilename("coverage.info"), "coverage.lst")
+	test.ExpectEquality(t, coverage.ListingFilename("out/coverage"), "out/coverage.lst")
+	test.ExpectEquality(t, coverage.ListingFilename("coverage.lst"), "coverage.lst.lst")
+}
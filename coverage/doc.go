@@ -0,0 +1,35 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package coverage records which 6507 instructions in a cartridge have been
+// executed. Execution counts are accumulated for every address in every bank
+// of the cartridge, over the course of an emulation session or over any number
+// of playback files.
+//
+// The Begin() and End() functions should be called either side of every call
+// to CPU.ExecuteInstruction(). Instructions executed from outside of the
+// cartridge, or while a coprocessor is executing, are not recorded.
+//
+// Coverage is measured against the disassembly of the cartridge. An
+// instruction is any address in the disassembly that has been blessed, or any
+// address that has been executed. The Summary type gives the number of
+// instructions and the number of executed instructions for each bank, and can
+// be written to and read from JSON. Summaries can be compared so that a
+// reduction in coverage can be detected.
+//
+// The coverage can also be exported as an annotated listing, in the style of
+// a DASM listing, and as an lcov tracefile. The lcov tracefile refers to the
+// lines of the annotated listing, which must be written alongside it.
+package coverage
@@ -0,0 +1,150 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly"
+)
+
+// a line in the annotated listing
+type listingLine struct {
+	text string
+
+	// whether the line is an instruction. only instruction lines appear in
+	// the lcov tracefile
+	instruction bool
+	count       int
+}
+
+// the annotated listing of the cartridge. the number of times an instruction
+// has been executed is shown in the first column. instructions that have
+// never been executed are marked with hashes
+func (cov *Coverage) listing(dsm *disassembly.Disassembly) []listingLine {
+	var lines []listingLine
+
+	text := func(s string, a ...any) {
+		lines = append(lines, listingLine{text: fmt.Sprintf(s, a...)})
+	}
+
+	s := cov.Summary(dsm)
+	text("; coverage of %s (%s)", cov.Name, cov.Mapper)
+	text("; %d/%d instructions executed (%.2f%%)", s.Executed, s.Instructions, s.Coverage)
+
+	for b, bank := range cov.instructions(dsm) {
+		text("")
+		text("; bank %d: %d/%d instructions executed (%.2f%%)", b, s.Banks[b].Executed, s.Banks[b].Instructions, s.Banks[b].Coverage)
+
+		for _, i := range bank {
+			count := "#####"
+			if i.count > 0 {
+				count = fmt.Sprintf("%d", i.count)
+			}
+
+			var ins string
+			if i.entry == nil {
+				ins = fmt.Sprintf("%9s $%04x ???", "", i.addr)
+			} else {
+				if l := i.entry.Label.Resolve(); l != "" {
+					text("%9s %s", "", l)
+				}
+				ins = strings.TrimRight(fmt.Sprintf("%s %s %s %s",
+					i.entry.GetField(disassembly.FldBytecode),
+					i.entry.GetField(disassembly.FldAddress),
+					i.entry.GetField(disassembly.FldOperator),
+					i.entry.GetField(disassembly.FldOperand)), " ")
+			}
+
+			lines = append(lines, listingLine{
+				text:        fmt.Sprintf("%9s %s", count, ins),
+				instruction: true,
+				count:       i.count,
+			})
+		}
+	}
+
+	return lines
+}
+
+// WriteListing writes the annotated listing to the io.Writer. The disassembly
+// should be for the same cartridge as the coverage.
+func (cov *Coverage) WriteListing(w io.Writer, dsm *disassembly.Disassembly) error {
+	for _, l := range cov.listing(dsm) {
+		_, err := fmt.Fprintln(w, l.text)
+		if err != nil {
+			return fmt.Errorf("coverage: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteLCOV writes an lcov tracefile to the io.Writer. The line numbers in the
+// tracefile refer to the annotated listing, which should be written to the
+// file named by the listingFilename argument with WriteListing().
+func (cov *Coverage) WriteLCOV(w io.Writer, dsm *disassembly.Disassembly, listingFilename string) error {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("TN:%s\n", lcovTestName(cov.Name)))
+	b.WriteString(fmt.Sprintf("SF:%s\n", listingFilename))
+
+	var found, hit int
+	for n, l := range cov.listing(dsm) {
+		if !l.instruction {
+			continue
+		}
+		found++
+		if l.count > 0 {
+			hit++
+		}
+		b.WriteString(fmt.Sprintf("DA:%d,%d\n", n+1, l.count))
+	}
+
+	b.WriteString(fmt.Sprintf("LF:%d\n", found))
+	b.WriteString(fmt.Sprintf("LH:%d\n", hit))
+	b.WriteString("end_of_record\n")
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return fmt.Errorf("coverage: %w", err)
+	}
+	return nil
+}
+
+// ListingFilename returns the filename of the annotated listing that
+// accompanies the named lcov tracefile. The listing has the same name as the
+// tracefile but with the .lst extension.
+func ListingFilename(lcov string) string {
+	ext := filepath.Ext(lcov)
+	if ext == ".lst" {
+		return fmt.Sprintf("%s.lst", lcov)
+	}
+	return fmt.Sprintf("%s.lst", strings.TrimSuffix(lcov, ext))
+}
+
+// the test name in an lcov tracefile can only contain letters, digits and
+// underscores
+func lcovTestName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,80 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package coverage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/headless"
+)
+
+// Playback runs the playback file and records the coverage. Progress
+// information is written to the output argument.
+//
+// The coverage is reset with the cartridge named in the playback file if it
+// has not been used before. Otherwise the cartridge in the playback file must
+// be the same as the cartridge used by previous playbacks.
+//
+// The returned disassembly is of the cartridge used by the playback.
+func (cov *Coverage) Playback(output io.Writer, filename string) (*disassembly.Disassembly, error) {
+	em, err := headless.NewEmulation("", headless.Options{
+		Playback: filename,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("coverage: %w", err)
+	}
+	defer em.End()
+
+	vcs := em.VCS
+
+	if cov.banks == nil {
+		cov.Reset(vcs.Mem.Cart)
+	} else if !cov.Matches(vcs.Mem.Cart) {
+		return nil, fmt.Errorf("coverage: %s: playback is for a different cartridge (%s)", filename, vcs.Mem.Cart.ShortName)
+	}
+
+	dsm, err := disassembly.FromCartridge(em.Cartridge)
+	if err != nil {
+		return nil, fmt.Errorf("coverage: %w", err)
+	}
+
+	output.Write([]byte(fmt.Sprintf("running %s\n", filename)))
+
+	cov.Begin(vcs)
+
+	err = em.Run(func() (govern.State, error) {
+		cov.End(vcs)
+
+		// if the CPU is in the KIL state then the playback will never end
+		// normally. the coverage up to that point is still valid
+		state, err := em.Continue(0)
+		if state != govern.Running {
+			return state, err
+		}
+
+		cov.Begin(vcs)
+
+		return govern.Running, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("coverage: %w", err)
+	}
+
+	return dsm, nil
+}
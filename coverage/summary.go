@@ -0,0 +1,192 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly"
+)
+
+// BankSummary is the coverage of a single cartridge bank.
+type BankSummary struct {
+	Bank         int     `json:"bank"`
+	Instructions int     `json:"instructions"`
+	Executed     int     `json:"executed"`
+	Coverage     float64 `json:"coverage"`
+}
+
+// Summary is the coverage of the entire cartridge.
+type Summary struct {
+	Cartridge    string        `json:"cartridge"`
+	Hash         string        `json:"hash"`
+	Mapper       string        `json:"mapper"`
+	Instructions int           `json:"instructions"`
+	Executed     int           `json:"executed"`
+	Coverage     float64       `json:"coverage"`
+	Banks        []BankSummary `json:"banks"`
+}
+
+// percentage rounded to two decimal places. the rounding means that the
+// figures survive the round trip to and from JSON intact
+func percentage(executed int, instructions int) float64 {
+	if instructions == 0 {
+		return 0
+	}
+	return math.Round(float64(executed)/float64(instructions)*10000) / 100
+}
+
+func (s Summary) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s: %d/%d instructions executed (%.2f%%)", s.Cartridge, s.Executed, s.Instructions, s.Coverage))
+	for _, bk := range s.Banks {
+		b.WriteString(fmt.Sprintf("\nbank %2d: %d/%d (%.2f%%)", bk.Bank, bk.Executed, bk.Instructions, bk.Coverage))
+	}
+	return b.String()
+}
+
+// an instruction in the disassembly and the number of times it has been
+// executed
+type instruction struct {
+	entry *disassembly.Entry
+	addr  uint16
+	count int
+}
+
+// the instructions in each bank of the disassembly. an instruction is an
+// entry that has been blessed or an entry that has been executed
+func (cov *Coverage) instructions(dsm *disassembly.Disassembly) [][]instruction {
+	ins := make([][]instruction, len(cov.banks))
+
+	dsm.BorrowDisasm(func(d *disassembly.DisasmEntries) {
+		for b := range cov.banks {
+			for a := range cov.banks[b] {
+				var e *disassembly.Entry
+				if b < len(d.Entries) && a < len(d.Entries[b]) {
+					e = d.Entries[b][a]
+				}
+
+				count := cov.banks[b][a]
+				if count == 0 && (e == nil || e.Level < disassembly.EntryLevelBlessed) {
+					continue
+				}
+
+				ins[b] = append(ins[b], instruction{
+					entry: e,
+					addr:  uint16(a),
+					count: count,
+				})
+			}
+		}
+	})
+
+	return ins
+}
+
+// Summary returns the coverage summary. The disassembly should be for the same
+// cartridge as the coverage.
+func (cov *Coverage) Summary(dsm *disassembly.Disassembly) Summary {
+	s := Summary{
+		Cartridge: cov.Name,
+		Hash:      cov.Hash,
+		Mapper:    cov.Mapper,
+	}
+
+	for b, bank := range cov.instructions(dsm) {
+		bs := BankSummary{
+			Bank:         b,
+			Instructions: len(bank),
+		}
+		for _, i := range bank {
+			if i.count > 0 {
+				bs.Executed++
+			}
+		}
+		bs.Coverage = percentage(bs.Executed, bs.Instructions)
+
+		s.Instructions += bs.Instructions
+		s.Executed += bs.Executed
+		s.Banks = append(s.Banks, bs)
+	}
+	s.Coverage = percentage(s.Executed, s.Instructions)
+
+	return s
+}
+
+// WriteJSON writes the coverage summary as JSON to the io.Writer.
+func (s Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(s)
+	if err != nil {
+		return fmt.Errorf("coverage: %w", err)
+	}
+	return nil
+}
+
+// ReadSummary reads a coverage summary from a JSON file previously written by
+// WriteJSON().
+func ReadSummary(filename string) (Summary, error) {
+	var s Summary
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return s, fmt.Errorf("coverage: %w", err)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&s)
+	if err != nil {
+		return s, fmt.Errorf("coverage: %s: %w", filename, err)
+	}
+
+	return s, nil
+}
+
+// Compare the summary with a baseline summary. An error is returned if the
+// overall coverage, or the coverage of any bank, is lower than in the
+// baseline. The summaries must be for the same cartridge.
+func (s Summary) Compare(baseline Summary) error {
+	if s.Hash != baseline.Hash {
+		return fmt.Errorf("coverage: baseline is for a different cartridge (%s)", baseline.Cartridge)
+	}
+
+	var drops []string
+
+	if s.Coverage < baseline.Coverage {
+		drops = append(drops, fmt.Sprintf("overall %.2f%% -> %.2f%%", baseline.Coverage, s.Coverage))
+	}
+
+	for _, bs := range baseline.Banks {
+		if bs.Bank >= len(s.Banks) {
+			continue
+		}
+		if c := s.Banks[bs.Bank].Coverage; c < bs.Coverage {
+			drops = append(drops, fmt.Sprintf("bank %d %.2f%% -> %.2f%%", bs.Bank, bs.Coverage, c))
+		}
+	}
+
+	if len(drops) > 0 {
+		return fmt.Errorf("coverage: reduced coverage: %s", strings.Join(drops, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/debugger/terminal"
+	"github.com/jetsetilly/gopher2600/disassembly/assembler"
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// a single ASSEMBLE command. the original bytes are kept so that the command
+// can be undone
+type assembleEdit struct {
+	bank     int
+	address  uint16
+	original []uint8
+}
+
+// assemble the instructions and write the machine code to the cartridge at the
+// specified address. if the bank is negative then the bank currently mapped
+// to the address is used
+func (dbg *Debugger) assemble(address string, bank int, instructions []string) error {
+	// labels take priority when resolving the address
+	var addr uint16
+	if res := dbg.Disasm.Sym.SearchBySymbol(address, symbols.SearchLabel); res != nil {
+		addr = res.Address
+	} else {
+		ai := dbg.dbgmem.GetAddressInfo(address, true)
+		if ai == nil || ai.Area != memorymap.Cartridge {
+			return fmt.Errorf("not a cartridge address (%s)", address)
+		}
+		addr = ai.Address
+	}
+
+	if bank < 0 {
+		bank = dbg.vcs.Mem.Cart.GetBank(addr).Number
+	}
+
+	var data []uint8
+	var listing []string
+
+	pc := addr
+	for _, ins := range instructions {
+		ins = strings.TrimSpace(ins)
+		if ins == "" {
+			continue
+		}
+
+		b, err := assembler.Assemble(pc, ins, &dbg.Disasm.Sym)
+		if err != nil {
+			return err
+		}
+
+		listing = append(listing, fmt.Sprintf("$%04x  % -8x  %s", pc, b, ins))
+		data = append(data, b...)
+		pc += uint16(len(b))
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("no instructions to assemble")
+	}
+
+	original, err := dbg.writeCartridge(bank, addr, data)
+	if err != nil {
+		return err
+	}
+
+	dbg.assembleUndo = append(dbg.assembleUndo, assembleEdit{
+		bank:     bank,
+		address:  addr,
+		original: original,
+	})
+
+	for _, l := range listing {
+		dbg.printLine(terminal.StyleInstrument, l)
+	}
+	dbg.printLine(terminal.StyleFeedback, "%d bytes assembled into bank %d", len(data), bank)
+
+	return nil
+}
+
+// undo the most recent ASSEMBLE command
+func (dbg *Debugger) undoAssemble() error {
+	if len(dbg.assembleUndo) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+
+	edit := dbg.assembleUndo[len(dbg.assembleUndo)-1]
+
+	_, err := dbg.writeCartridge(edit.bank, edit.address, edit.original)
+	if err != nil {
+		return err
+	}
+
+	dbg.assembleUndo = dbg.assembleUndo[:len(dbg.assembleUndo)-1]
+	dbg.printLine(terminal.StyleFeedback, "restored %d bytes at $%04x in bank %d", len(edit.original), edit.address, edit.bank)
+
+	return nil
+}
+
+// write data to the cartridge bank at the address. returns the data that was
+// in the cartridge before the write
+//
+// the data is written with the cartridge's Patch() function if possible.
+// cartridges that can't be patched can only be written to if the bank is
+// currently mapped, in which case the data is poked into memory
+func (dbg *Debugger) writeCartridge(bank int, addr uint16, data []uint8) ([]uint8, error) {
+	banks, err := dbg.vcs.Mem.Cart.CopyBanks()
+	if err != nil {
+		return nil, err
+	}
+
+	// the offset of the bank in the cartridge data. this assumes that
+	// CopyBanks() returns the banks in order, which is true of all mappers
+	var offset int
+	var found bool
+	var content []uint8
+	var origins []uint16
+
+	for _, b := range banks {
+		if b.Number == bank {
+			found = true
+			content = b.Data
+			origins = b.Origins
+			break
+		}
+		offset += len(b.Data)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no bank %d in cartridge", bank)
+	}
+
+	// index of address in the bank data
+	idx := -1
+	a := int(addr & memorymap.CartridgeBits)
+	for _, o := range origins {
+		o := int(o & memorymap.CartridgeBits)
+		if a >= o && a < o+len(content) {
+			idx = a - o
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, fmt.Errorf("address $%04x is not in bank %d", addr, bank)
+	}
+
+	if idx+len(data) > len(content) {
+		return nil, fmt.Errorf("assembled code does not fit in bank %d", bank)
+	}
+
+	original := make([]uint8, len(data))
+	copy(original, content[idx:])
+
+	for i, v := range data {
+		err = dbg.vcs.Mem.Cart.Patch(offset+idx+i, v)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		return original, nil
+	}
+
+	if dbg.vcs.Mem.Cart.GetBank(addr).Number != bank {
+		return nil, fmt.Errorf("%w: bank %d is not currently mapped", err, bank)
+	}
+
+	for i, v := range data {
+		_, err = dbg.dbgmem.Poke(addr+uint16(i), v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return original, nil
+}
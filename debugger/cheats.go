@@ -0,0 +1,94 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/cheats"
+	"github.com/jetsetilly/gopher2600/debugger/terminal"
+)
+
+// the maximum number of RAM search candidates to list
+const maxSearchCandidates = 32
+
+// the contents of RAM for the RAM search. if the frame is negative then the
+// current contents of RAM are returned, otherwise the contents are taken from
+// the rewind history. returns the frame number of the RAM contents
+func (dbg *Debugger) searchRAM(frame int) ([]uint8, int) {
+	if frame < 0 {
+		ram := make([]uint8, len(dbg.vcs.Mem.RAM.RAM))
+		copy(ram, dbg.vcs.Mem.RAM.RAM)
+		return ram, dbg.vcs.TV.GetCoords().Frame
+	}
+
+	st := dbg.Rewind.GetFrameState(frame)
+	return st.VCS.Mem.RAM.RAM, st.TV.GetCoords().Frame
+}
+
+// start a new RAM search
+func (dbg *Debugger) startRAMSearch(frame int) {
+	ram, frame := dbg.searchRAM(frame)
+	dbg.ramSearch = cheats.NewSearch(ram, frame)
+	dbg.printLine(terminal.StyleFeedback, "ram search started (frame %d)", frame)
+}
+
+// narrow the current RAM search
+func (dbg *Debugger) narrowRAMSearch(cmp cheats.Comparison, value uint8, frame int) error {
+	if dbg.ramSearch == nil {
+		return fmt.Errorf("ram search has not been started")
+	}
+
+	from := dbg.ramSearch.Frame()
+	ram, frame := dbg.searchRAM(frame)
+	n := dbg.ramSearch.Narrow(ram, frame, cmp, value)
+
+	dbg.printLine(terminal.StyleFeedback, "%d candidates (frame %d compared to frame %d)", n, frame, from)
+	if n <= maxSearchCandidates {
+		dbg.printRAMSearch()
+	}
+
+	return nil
+}
+
+// list the candidates of the current RAM search
+func (dbg *Debugger) printRAMSearch() {
+	if dbg.ramSearch == nil {
+		dbg.printLine(terminal.StyleFeedback, "ram search has not been started")
+		return
+	}
+
+	if len(dbg.ramSearch.Candidates) > maxSearchCandidates {
+		dbg.printLine(terminal.StyleFeedback, "%d candidates", len(dbg.ramSearch.Candidates))
+		return
+	}
+
+	for _, a := range dbg.ramSearch.Candidates {
+		ai := dbg.dbgmem.GetAddressInfo(a, true)
+		dbg.printLine(terminal.StyleFeedbackSecondary, "%s = %#02x", ai.String(), dbg.ramSearch.Previous(a))
+	}
+}
+
+// list the cheats for the current cartridge
+func (dbg *Debugger) printCheats() {
+	if len(dbg.cheats.List) == 0 {
+		dbg.printLine(terminal.StyleFeedback, "no cheats for this cartridge")
+		return
+	}
+	for _, c := range dbg.cheats.List {
+		dbg.printLine(terminal.StyleFeedback, c.String())
+	}
+}
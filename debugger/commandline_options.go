@@ -34,13 +34,16 @@ type CommandLineOptions struct {
 	Swap      bool
 	Profile   string
 	ELF       string
+	GDB       string
+	DAP       string
+	TraceFile string
+	PatchFile string
 
 	// playmode only
 	ComparisonROM    string
 	ComparisonPrefs  string
 	Record           bool
 	PlaybackCheckROM bool
-	PatchFile        string
 	Wav              bool
 	NoEject          bool
 	Macro            string
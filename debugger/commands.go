@@ -25,14 +25,17 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jetsetilly/gopher2600/cheats"
 	"github.com/jetsetilly/gopher2600/coprocessor"
 	coproc_breakpoints "github.com/jetsetilly/gopher2600/coprocessor/developer/breakpoints"
 	"github.com/jetsetilly/gopher2600/coprocessor/developer/callstack"
 	"github.com/jetsetilly/gopher2600/coprocessor/developer/dwarf"
 	"github.com/jetsetilly/gopher2600/coprocessor/developer/faults"
+	"github.com/jetsetilly/gopher2600/coprocessor/developer/profiling"
 	"github.com/jetsetilly/gopher2600/coprocessor/developer/yield"
 	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
 	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/debugger/rammap"
 	"github.com/jetsetilly/gopher2600/debugger/script"
 	"github.com/jetsetilly/gopher2600/debugger/terminal"
 	"github.com/jetsetilly/gopher2600/debugger/terminal/commandline"
@@ -470,6 +473,58 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 			return nil
 		})
 
+	case cmdState:
+		option, _ := tokens.Get()
+		switch strings.ToUpper(option) {
+		case "SAVE":
+			filename, _ := tokens.Get()
+			filename, err := dbg.saveState(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "state saved to %s", filename)
+
+		case "LOAD":
+			filename, _ := tokens.Get()
+
+			// loading a state in the middle of a CPU instruction requires the
+			// input loop to be unwound before continuing
+			dbg.unwindLoop(func() error {
+				err := dbg.loadState(filename)
+				if err != nil {
+					dbg.printLine(terminal.StyleError, err.Error())
+					return nil
+				}
+				dbg.printLine(terminal.StyleFeedback, "state loaded from %s", filename)
+				return nil
+			})
+		}
+
+	case cmdDAP:
+		arg, ok := tokens.Get()
+		if !ok {
+			if dbg.dap == nil {
+				dbg.printLine(terminal.StyleFeedback, "DAP server is not running")
+			} else if dbg.dap.Connected() {
+				dbg.printLine(terminal.StyleFeedback, "DAP server on %s (connected)", dbg.dap.Address())
+			} else {
+				dbg.printLine(terminal.StyleFeedback, "DAP server on %s", dbg.dap.Address())
+			}
+			return nil
+		}
+
+		if strings.ToUpper(arg) == "OFF" {
+			dbg.stopDAP()
+			dbg.printLine(terminal.StyleFeedback, "DAP server stopped")
+			return nil
+		}
+
+		err := dbg.startDAP(arg)
+		if err != nil {
+			return err
+		}
+		dbg.printLine(terminal.StyleFeedback, "DAP server on %s", dbg.dap.Address())
+
 	case cmdInsert:
 		dbg.unwindLoop(func() error {
 			filename, _ := tokens.Get()
@@ -577,6 +632,16 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 
 	case cmdPatch:
 		f, _ := tokens.Get()
+		if strings.ToUpper(f) == "CREATE" {
+			format, _ := tokens.Get()
+			filename, _ := tokens.Get()
+			err := dbg.createPatch(strings.ToUpper(format), filename)
+			if err != nil {
+				dbg.printLine(terminal.StyleError, "%v", err)
+			}
+			return nil
+		}
+
 		patched, err := patch.CartridgeMemory(dbg.vcs.Mem.Cart, f)
 		if err != nil {
 			dbg.printLine(terminal.StyleError, "%v", err)
@@ -589,6 +654,34 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 			dbg.printLine(terminal.StyleFeedback, "cartridge patched")
 		}
 
+	case cmdAssemble:
+		arg, _ := tokens.Get()
+		if strings.ToUpper(arg) == "UNDO" {
+			err := dbg.undoAssemble()
+			if err != nil {
+				dbg.printLine(terminal.StyleError, "%v", err)
+			}
+			return nil
+		}
+
+		bank := -1
+		if b, ok := tokens.Peek(); ok && strings.ToUpper(b) == "BANK" {
+			tokens.Get()
+			b, _ = tokens.Get()
+			n, err := strconv.Atoi(b)
+			if err != nil {
+				dbg.printLine(terminal.StyleError, "invalid bank number (%s)", b)
+				return nil
+			}
+			bank = n
+		}
+
+		// multiple instructions are separated by a colon
+		err := dbg.assemble(arg, bank, strings.Split(tokens.Remainder(), ":"))
+		if err != nil {
+			dbg.printLine(terminal.StyleError, "%v", err)
+		}
+
 	case cmdDisasm:
 		bytecode := false
 
@@ -675,6 +768,33 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 			dbg.printLine(terminal.StyleFeedback, output.String())
 		}
 
+	case cmdSource:
+		option, _ := tokens.Get()
+		switch strings.ToUpper(option) {
+		case "FILES":
+			dbg.printSourceFiles()
+		case "LOAD":
+			filename, _ := tokens.Get()
+			err := dbg.Disasm.LoadListing(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "using listing file (%s)", filename)
+		case "UNLOAD":
+			dbg.Disasm.UnloadListing()
+			dbg.printLine(terminal.StyleFeedback, "listing file removed")
+		default:
+			context := 5
+			if option != "" {
+				n, err := strconv.Atoi(option)
+				if err != nil || n < 0 {
+					return fmt.Errorf("invalid number of context lines (%s)", option)
+				}
+				context = n
+			}
+			dbg.printSource(context)
+		}
+
 	case cmdSymbol:
 		tok, _ := tokens.Get()
 		switch strings.ToUpper(tok) {
@@ -698,6 +818,40 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 				dbg.dbgmem.Sym.ListSymbols(dbg.writerInStyle(terminal.StyleFeedback))
 			}
 
+		case "LABEL":
+			address, _ := tokens.Get()
+			label, _ := tokens.Get()
+			err := dbg.setLabel(address, label)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "label %s set for %s", label, address)
+
+		case "REMOVE":
+			address, _ := tokens.Get()
+			err := dbg.removeLabel(address)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "label removed for %s", address)
+
+		case "LOAD":
+			filename, _ := tokens.Get()
+			err := dbg.Disasm.Sym.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "symbols loaded from %s", filename)
+
+		case "SAVE":
+			format, _ := tokens.Get()
+			filename, _ := tokens.Get()
+			err := dbg.saveSymbols(strings.ToUpper(format), filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "symbols saved to %s", filename)
+
 		default:
 			symbol := tok
 
@@ -989,6 +1143,156 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 			dbg.printLine(terminal.StyleSubStep, s.String())
 		}
 
+	case cmdProfile:
+		option, _ := tokens.Get()
+		option = strings.ToUpper(option)
+
+		switch option {
+		case "ON":
+			if dbg.profiler == nil {
+				dbg.startProfiler()
+			}
+			dbg.printLine(terminal.StyleFeedback, "profiling is on")
+			return nil
+		case "OFF":
+			dbg.stopProfiler()
+			dbg.printLine(terminal.StyleFeedback, "profiling is off")
+			return nil
+		case "":
+			if dbg.profiler == nil {
+				dbg.printLine(terminal.StyleFeedback, "profiling is off")
+			} else {
+				dbg.printLine(terminal.StyleFeedback, "profiling is on (%d frames)", dbg.profiler.NumFrames())
+			}
+			return nil
+		}
+
+		if dbg.profiler == nil {
+			dbg.printLine(terminal.StyleError, "profiling is not on")
+			return nil
+		}
+
+		w := dbg.writerInStyle(terminal.StyleFeedback)
+
+		switch option {
+		case "RESET":
+			dbg.profiler.Reset()
+			dbg.printLine(terminal.StyleFeedback, "profiling has been reset")
+
+		case "ROUTINES", "LABELS":
+			focus := profiling.FocusAll
+			top := 10
+
+			arg, ok := tokens.Get()
+			if ok {
+				switch strings.ToUpper(arg) {
+				case "VBLANK":
+					focus = profiling.FocusVBLANK
+				case "SCREEN":
+					focus = profiling.FocusScreen
+				case "OVERSCAN":
+					focus = profiling.FocusOverscan
+				default:
+					tokens.Unget()
+				}
+			}
+
+			arg, ok = tokens.Get()
+			if ok {
+				n, err := strconv.ParseInt(arg, 0, 32)
+				if err != nil {
+					dbg.printLine(terminal.StyleError, fmt.Sprintf("%s is not a number", arg))
+					return nil
+				}
+				top = int(n)
+			}
+
+			var err error
+			if option == "ROUTINES" {
+				err = dbg.profiler.WriteRoutines(w, focus, top)
+			} else {
+				err = dbg.profiler.WriteLabels(w, focus, top)
+			}
+			if err != nil {
+				return err
+			}
+
+		case "SCANLINES":
+			err := dbg.profiler.WriteScanlines(w)
+			if err != nil {
+				return err
+			}
+
+		case "BUDGET":
+			err := dbg.profiler.WriteBudget(w)
+			if err != nil {
+				return err
+			}
+		}
+
+	case cmdCoverage:
+		option, _ := tokens.Get()
+		option = strings.ToUpper(option)
+
+		switch option {
+		case "ON":
+			if dbg.coverage == nil {
+				dbg.startCoverage()
+			}
+			dbg.printLine(terminal.StyleFeedback, "coverage is on")
+			return nil
+		case "OFF":
+			dbg.stopCoverage()
+			dbg.printLine(terminal.StyleFeedback, "coverage is off")
+			return nil
+		}
+
+		if dbg.coverage == nil {
+			if option == "" {
+				dbg.printLine(terminal.StyleFeedback, "coverage is off")
+			} else {
+				dbg.printLine(terminal.StyleError, "coverage is not on")
+			}
+			return nil
+		}
+
+		switch option {
+		case "":
+			dbg.printLine(terminal.StyleFeedback, dbg.coverage.Summary(dbg.Disasm).String())
+
+		case "RESET":
+			dbg.coverage.Reset(dbg.vcs.Mem.Cart)
+			dbg.printLine(terminal.StyleFeedback, "coverage has been reset")
+
+		case "LISTING":
+			filename, _ := tokens.Get()
+			err := dbg.writeCoverage(filename, func(w io.Writer) error {
+				return dbg.coverage.WriteListing(w, dbg.Disasm)
+			})
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "coverage listing written to %s", filename)
+
+		case "LCOV":
+			filename, _ := tokens.Get()
+			listing, err := dbg.writeCoverageLCOV(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "lcov tracefile written to %s (listing in %s)", filename, listing)
+
+		case "JSON":
+			filename, _ := tokens.Get()
+			err := dbg.writeCoverage(filename, func(w io.Writer) error {
+				return dbg.coverage.Summary(dbg.Disasm).WriteJSON(w)
+			})
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "coverage summary written to %s", filename)
+		}
+
 	case cmdMemMap:
 		address, ok := tokens.Get()
 		if ok {
@@ -1243,6 +1547,156 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 	case cmdRAM:
 		dbg.printLine(terminal.StyleInstrument, dbg.vcs.Mem.RAM.String())
 
+	case cmdRAMMap:
+		option, _ := tokens.Get()
+		option = strings.ToUpper(option)
+
+		switch option {
+		case "ON":
+			if dbg.ramMap == nil {
+				dbg.startRAMMap()
+			}
+			dbg.printLine(terminal.StyleFeedback, "ram map is on")
+			return nil
+		case "OFF":
+			dbg.stopRAMMap()
+			dbg.printLine(terminal.StyleFeedback, "ram map is off")
+			return nil
+		case "":
+			if dbg.ramMap == nil {
+				dbg.printLine(terminal.StyleFeedback, "ram map is off")
+			} else {
+				dbg.printLine(terminal.StyleFeedback, "ram map is on (%d frames)", dbg.ramMap.NumFrames())
+			}
+			return nil
+		}
+
+		if dbg.ramMap == nil {
+			dbg.printLine(terminal.StyleError, "ram map is not on")
+			return nil
+		}
+
+		w := dbg.writerInStyle(terminal.StyleFeedback)
+
+		switch option {
+		case "RESET":
+			dbg.ramMap.Reset()
+			dbg.printLine(terminal.StyleFeedback, "ram map has been reset")
+
+		case "USED":
+			return dbg.ramMap.WriteReport(w, rammap.FilterUsed)
+
+		case "FREE":
+			return dbg.ramMap.WriteReport(w, rammap.FilterFree)
+
+		case "SHARED":
+			return dbg.ramMap.WriteReport(w, rammap.FilterShared)
+
+		case "ALL":
+			return dbg.ramMap.WriteReport(w, rammap.FilterAll)
+
+		case "CSV":
+			filename, _ := tokens.Get()
+			err := dbg.writeRAMMap(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "ram map written to %s", filename)
+		}
+
+	case cmdCheat:
+		option, _ := tokens.Get()
+		option = strings.ToUpper(option)
+
+		switch option {
+		case "", "LIST":
+			dbg.printCheats()
+
+		case "ADD":
+			code, _ := tokens.Get()
+			mode, _ := tokens.Get()
+
+			m := cheats.Freeze
+			if strings.ToUpper(mode) == "POKE" {
+				m = cheats.Poke
+			}
+
+			name := strings.TrimSpace(tokens.Remainder())
+			err := dbg.cheats.Add(name, code, m)
+			if err != nil {
+				return err
+			}
+
+			// apply cheat immediately rather than waiting for the next frame
+			err = dbg.cheats.Apply()
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "cheat %s added", name)
+
+		case "REMOVE":
+			name := strings.TrimSpace(tokens.Remainder())
+			err := dbg.cheats.Remove(name)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "cheat %s removed", name)
+
+		case "ENABLE", "DISABLE":
+			name := strings.TrimSpace(tokens.Remainder())
+			err := dbg.cheats.Enable(name, option == "ENABLE")
+			if err != nil {
+				return err
+			}
+			err = dbg.cheats.Apply()
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "cheat %s %sd", name, strings.ToLower(option))
+
+		case "SEARCH":
+			cmp, _ := tokens.Get()
+			cmp = strings.ToUpper(cmp)
+
+			var value uint8
+			if cmp == "VALUE" {
+				arg, _ := tokens.Get()
+				v, err := strconv.ParseUint(arg, 0, 8)
+				if err != nil {
+					return fmt.Errorf("value must be an 8bit number (%s)", arg)
+				}
+				value = uint8(v)
+			}
+
+			// the frame is taken from the rewind history if it is specified
+			frame := -1
+			arg, ok := tokens.Get()
+			if ok {
+				v, err := strconv.ParseUint(arg, 0, 32)
+				if err != nil {
+					return fmt.Errorf("frame must be a number (%s)", arg)
+				}
+				frame = int(v)
+			}
+
+			switch cmp {
+			case "":
+				dbg.printRAMSearch()
+			case "NEW":
+				dbg.startRAMSearch(frame)
+			case "EQUAL":
+				return dbg.narrowRAMSearch(cheats.Equal, value, frame)
+			case "NOTEQUAL":
+				return dbg.narrowRAMSearch(cheats.NotEqual, value, frame)
+			case "INCREASED":
+				return dbg.narrowRAMSearch(cheats.Increased, value, frame)
+			case "DECREASED":
+				return dbg.narrowRAMSearch(cheats.Decreased, value, frame)
+			case "VALUE":
+				return dbg.narrowRAMSearch(cheats.Value, value, frame)
+			}
+		}
+
 	case cmdTIA:
 		arg, _ := tokens.Get()
 		switch arg {
@@ -1588,6 +2042,32 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 			dbg.runUntilHalt = true
 			dbg.continueEmulation = true
 
+		case "GDB":
+			arg, ok := tokens.Get()
+			if !ok {
+				if dbg.gdb == nil {
+					dbg.printLine(terminal.StyleFeedback, "GDB stub is not running")
+				} else if dbg.gdb.Connected() {
+					dbg.printLine(terminal.StyleFeedback, fmt.Sprintf("GDB stub on %s (connected)", dbg.gdb.Address()))
+				} else {
+					dbg.printLine(terminal.StyleFeedback, fmt.Sprintf("GDB stub on %s", dbg.gdb.Address()))
+				}
+				return nil
+			}
+
+			if strings.ToUpper(arg) == "OFF" {
+				dbg.stopGDB()
+				dbg.printLine(terminal.StyleFeedback, "GDB stub stopped")
+				return nil
+			}
+
+			err := dbg.startGDB(arg)
+			if err != nil {
+				dbg.printLine(terminal.StyleError, err.Error())
+				return nil
+			}
+			dbg.printLine(terminal.StyleFeedback, fmt.Sprintf("GDB stub on %s", dbg.gdb.Address()))
+
 		case "ID":
 			fallthrough
 		default:
@@ -1996,6 +2476,37 @@ func (dbg *Debugger) processTokens(tokens *commandline.Tokens) error {
 		}
 
 	case cmdTrace:
+		arg, _ := tokens.Get()
+		if strings.ToUpper(arg) == "FILE" {
+			filename, ok := tokens.Get()
+			if !ok {
+				if dbg.traceFile == nil {
+					dbg.printLine(terminal.StyleFeedback, "no trace file")
+				} else {
+					dbg.printLine(terminal.StyleFeedback, "tracing to %s", dbg.traceFile)
+				}
+				return nil
+			}
+
+			if strings.ToUpper(filename) == "OFF" {
+				if dbg.traceFile == nil {
+					dbg.printLine(terminal.StyleFeedback, "no trace file")
+				} else {
+					dbg.printLine(terminal.StyleFeedback, "trace file %s closed", dbg.traceFile)
+					dbg.stopTraceFile()
+				}
+				return nil
+			}
+
+			err := dbg.startTraceFile(filename)
+			if err != nil {
+				return err
+			}
+			dbg.printLine(terminal.StyleFeedback, "tracing to %s", dbg.traceFile)
+			return nil
+		}
+		tokens.Unget()
+
 		err := dbg.traces.parseCommand(tokens)
 		if err != nil {
 			return err
@@ -35,7 +35,10 @@ the emulation can be stepped backwards too.
 The OVER option changes how the STEP command works with JSR opcodes. Stepping OVER a JSR opcode causes
 the STEP to end on the programme after the corresponding RTS. Note that if there is no RTS then the program
 will run forever and you will need to stop the execution with the HALT command (or through the debugging GUI
-or with a CTRL-C on some terminals)`,
+or with a CTRL-C on some terminals)
+
+The LINE option steps until a different line in the source listing is reached (see SOURCE command).
+A macro invocation is treated as a single line.`,
 
 	cmdQuantum: `Change or view the stepping quantum. The stepping quantum defines the
 frequency at which the emulation is checked and reported upon by the emulation when
@@ -86,6 +89,28 @@ frame values are optional.
 
 May leave the emulation mid CPU instruction but will not change the stepping quantum.`,
 
+	cmdState: `Save the state of the emulation to a file or load a previously saved state.
+If no filename is given to SAVE then a unique filename in the resources directory
+will be used.
+
+A state file can only be loaded if the inserted cartridge is the same as the one
+used when the state file was created. Loading a state file will clear the rewind
+history.
+
+The state can only be saved on a CPU instruction boundary.`,
+
+	cmdDAP: `Start a Debug Adapter Protocol server on the specified address. If the address is just
+a port number then the server will listen on localhost. DAP OFF stops the server. Without an
+argument the state of the server is shown.
+
+A DAP client, such as Visual Studio Code, can then connect to the server. For Visual Studio
+Code the port should be specified with the "debugServer" field of the launch configuration.
+
+The disassembly of each cartridge bank is presented to the client as a source file.
+Breakpoints can be placed on any instruction in the disassembly or on any line in the original
+assembly source that starts with a label. The CPU registers and the VCS RAM are shown as
+variables.`,
+
 	cmdInsert: `Insert cartridge into emulation. Cartridge names (with paths) beginning with
 http:// will loaded via the http protocol. If no such protocol is present, the
 cartridge will be loaded from disk.`,
@@ -94,7 +119,28 @@ cartridge will be loaded from disk.`,
 will show where the game was loaded from, the cartridge type and bank number. The BANK
 argument meanwhile can be used to switch banks (if possible).`,
 
-	cmdPatch: "Apply a patch file to the loaded cartridge",
+	cmdPatch: `Apply a patch file to the loaded cartridge. The patch file can be in the IPS, BPS, cmp or
+neo format. If the patch file can not be found then the patches directory in the resources
+directory is searched.
+
+The CREATE argument writes a new IPS or BPS patch file. The patch will contain the difference
+between the cartridge file and the current contents of the cartridge. For example, after changing the
+cartridge with the ASSEMBLE command.
+
+	PATCH CREATE IPS fix.ips`,
+
+	cmdAssemble: `Assemble 6502 instructions and write the machine code into the cartridge at the
+specified address. Multiple instructions are separated by a colon. Operands can refer to symbols
+and labels and undocumented instructions are supported.
+
+	ASSEMBLE $f000 LDA #$10 : STA COLUBK
+
+By default the code is written to the bank currently mapped to the address. A different bank can be
+specified with the BANK argument. Banks that are not currently mapped can only be written to if the
+cartridge supports patching.
+
+The UNDO argument restores the cartridge to how it was before the most recent ASSEMBLE command. The
+disassembly is not updated until the new code is executed or until DISASM REDUX is used.`,
 
 	cmdDisasm: `Display cartridge disassembly. By default, all banks will be displayed. Single
 banks can be displayed by specifying the bank number. Use BYTECODE to display raw bytes alongside
@@ -109,6 +155,20 @@ in the disassembly to the termain.
 The scope of the GREP can be restricted to the OPERATOR and OPERAND columns. By
 default GREP will consider the entire line.`,
 
+	cmdSource: `Display the source of the program around the current instruction. The source is
+taken from a DASM listing file or a ca65/ld65 debug file. A listing file with the same name as the
+cartridge but with the .lst or .dbg extension is loaded automatically. Other files can be loaded
+with the LOAD option and removed with UNLOAD.
+
+The optional numeric argument specifies the number of lines to show either side of the current
+line. If the current instruction is part of a macro expansion then the expansion is shown with the
+macro arguments substituted.
+
+The FILES option lists the source files in the listing.
+
+When a listing is available, breakpoints can be set on a source line with BREAK file:line and the
+program can be stepped one source line at a time with STEP LINE.`,
+
 	cmdSymbol: `The SYMBOL command displays symbolic information about a memory address. Addresses can be
 specified by symbol.
 
@@ -128,7 +188,25 @@ Which will show, in this case, the two symbols for this address and the context
 	0x0001 (CXM1P) (TIA) [READ]
 	0x0001 (VBLANK) (TIA) [WRITE]
 
-The SYMBOL command also LIST all symbols in the LABELS, READ or WRITE tables.`,
+The SYMBOL command also LIST all symbols in the LABELS, READ or WRITE tables.
+
+Labels for cartridge addresses can be added, changed or removed. The label applies to the address
+in the current bank:
+
+	SYMBOL LABEL 0xf000 reset
+	SYMBOL REMOVE 0xf000
+
+Additional symbols can be loaded from a file with the LOAD argument. The file can be a DASM or
+Stella symbols file, an ld65 debug information file, or a VICE label file.
+
+	SYMBOL LOAD extra.sym
+
+User defined symbols, including any symbols loaded from a symbols file, can be saved with the SAVE
+argument. The DASM format can be read by Stella. The VICE format can be read by most tools that
+understand ca65 label files.
+
+	SYMBOL SAVE DASM game.sym
+	SYMBOL SAVE VICE game.lbl`,
 
 	cmdOnHalt: `Define commands to run whenever emulation is halted. A halt is
 caused by a BREAK, a TRAP, a WATCH or a manual interrupt. Specify multiple
@@ -154,6 +232,43 @@ ON argument to resume ONTRACE reporting.`,
 to display the raw bytes alongside the disassembly. The DEFN argument meanwhile
 will display the definition of the opcode that was used during execution.`,
 
+	cmdProfile: `Profile the 6507 program. Profiling is started with PROFILE ON and stopped with PROFILE OFF.
+Profiling information is collected in both the debugger and in playmode. PROFILE RESET discards the
+information collected so far.
+
+Cycles are accounted for by routine and by label. A routine is entered with JSR and left with RTS.
+Code that is not inside any routine is accounted to the "(main)" routine. The ROUTINES option shows the
+number of cycles taken by each routine in the most recent frame, along with the average and maximum
+over all frames. These figures are exclusive of any routines called by the routine. The inclusive
+average, and the average number of calls per frame, are also shown.
+
+The LABELS option is similar but instructions are accounted to the most recently executed label.
+
+Both the ROUTINES and LABELS options can be limited to the VBLANK, SCREEN or OVERSCAN part of the frame
+and to a number of entries. For example, the ten most expensive routines in the VBLANK:
+
+	PROFILE ROUTINES VBLANK 10
+
+The SCANLINES option shows how the 76 CPU cycles of every scanline in the most recent frame were used.
+The number of cycles used by the CPU, and the number of cycles lost waiting for WSYNC, are shown along
+with the instruction that triggered the WSYNC.
+
+The BUDGET option summarises the cycles available and used in each part of the most recent frame and
+shows the most expensive routine in each part.`,
+
+	cmdCoverage: `Record which instructions in the cartridge have been executed. Recording is started with
+COVERAGE ON and stopped with COVERAGE OFF. Coverage is recorded in both the debugger and in playmode.
+COVERAGE RESET discards the coverage recorded so far. Coverage is also reset when a new cartridge
+is inserted.
+
+With no arguments the number of instructions executed in each bank of the cartridge is shown.
+
+The coverage can be exported in three forms. The LISTING option writes a disassembly of the
+cartridge annotated with the number of times each instruction has been executed. The LCOV option
+writes an lcov tracefile. The lines in the tracefile refer to an annotated listing, which is written
+alongside the tracefile with the .lst extension. The JSON option writes a summary of the coverage
+that can be used as the baseline for the COVERAGE mode.`,
+
 	cmdMemMap: `Display high-level VCS memory map. With the optional address argument information
 about the address will be displayed.`,
 
@@ -179,6 +294,52 @@ Poke does not result in a change to the address or data busses.
 	cmdRAM: `Display the current contents of RAM. The optional CART argument will display any
 additional RAM in the cartridge.`,
 
+	cmdRAMMap: `Record how the 6507 program uses RIOT RAM and any RAM in the cartridge. Recording is started
+with RAMMAP ON and stopped with RAMMAP OFF. RAMMAP RESET discards the information recorded so far. For
+the most accurate results, recording should be started before the console is reset.
+
+For every byte of RAM, the number of reads and writes are recorded along with the routines that made
+those accesses. A routine is entered with JSR and code that is not inside any routine is accounted
+to the "(main)" routine. The frame in which the byte was first written is also recorded, as is whether
+the byte was ever read before it had been written.
+
+The USED option lists every byte that has been accessed. The FREE option lists every byte that has
+never been accessed and the SHARED option lists bytes that have been written by more than one routine.
+The ALL option lists every byte.
+
+The CSV option writes the information for every byte to the named file in CSV format.
+
+	RAMMAP CSV ram.csv`,
+
+	cmdCheat: `Manage cheats for the current cartridge and search RAM for the addresses that cheats
+should change. Cheats are saved automatically and are loaded whenever the cartridge is inserted.
+
+A cheat is added with a name and a cheat code. Cheat codes are in the addr:value format used by
+Stella. A FREEZE cheat writes the value to the address at the start of every frame. A POKE cheat
+writes the value only once, when the cheat is added or enabled, or when the cartridge is inserted.
+
+	CHEAT ADD 0085:09 FREEZE infinite lives
+	CHEAT ADD 00f0:04 POKE start on level 4
+
+Cheats can be disabled and enabled by name, and removed by name. With no arguments, or with the LIST
+argument, all cheats for the cartridge are listed.
+
+The SEARCH argument finds the RAM addresses of interesting values. A search is started with SEARCH NEW,
+at which point every RAM address is a candidate. The candidates are narrowed by comparing the contents
+of RAM with the contents when the search was started or when the previous comparison was made. The
+comparisons are EQUAL, NOTEQUAL, INCREASED and DECREASED. The VALUE comparison keeps the addresses
+that contain a specific value. For example, to find the number of lives:
+
+	CHEAT SEARCH NEW
+	(lose a life)
+	CHEAT SEARCH DECREASED
+	(lose another life)
+	CHEAT SEARCH DECREASED
+
+Both NEW and the comparisons take an optional frame number. When a frame number is given the contents
+of RAM are taken from the rewind history rather than from the current state of the emulation. SEARCH
+with no other arguments lists the remaining candidates.`,
+
 	cmdTIA: `Display current state of the TIA video signal:
 
         111011 (09) _.--*__.--._ 39 13.0
@@ -374,6 +535,11 @@ will have a "FPU" group.
 
 The SET argument will set a register value. The 'register' number must be the 'extended register'
 number rather than the display number.
+
+The GDB argument starts a GDB remote stub on the specified address. If the address is just a port
+number then the stub will listen on localhost. A GDB for the coprocessor (eg. arm-none-eabi-gdb)
+can then connect with the "target remote" command. The emulation will wait while GDB has stopped
+the coprocessor. GDB OFF stops the stub. Without an argument the state of the stub is shown.
 	`,
 
 	cmdDWARF: `Debugging information for cartridge types that support DWARF debugging.
@@ -420,6 +586,7 @@ about its state. Current targets are:
 	the TV state (FRAMENUM, SCANLINE, CLOCK)
 	cartidge BANK
 	CPU result (RESULT OPERATOR, RESULT EFFECT, RESULT PAGEFAULT, RESULT BUG)
+	source LINE (for traps only)
 
 Specifying an address without a target will be assumed to be break on the PC
 and the current cartridge bank. So:
@@ -449,6 +616,39 @@ execution breaks on SL 10 & X 255. After resumption, the break will not apply
 until X changes from 255 to something else and then back again, or SL is hit on
 the next frame and X again (or still) has a value of 255.i
 
+If a source listing is available (see SOURCE command) then a break can be set on a line in the
+source. For example:
+
+	BREAK kernel.asm:123
+
+If the line does not produce any code then the break is set on the next line that does. A break on
+a line inside a macro definition applies to every expansion of the macro.
+
+More complex conditions can be specified with the IF keyword, followed by an
+expression. For example:
+
+	BREAK IF PC == kernel && RAM[score] > $50 && SL > 190
+
+Expressions can use any of the targets listed above, symbols from the symbols
+table and the arithmetic, bitwise, comparison and logical operators of the Go
+language (with the same precedence). Memory can be read with RAM[], TIA[],
+RIOT[] and MEM[]. The RAM, TIA and RIOT index can be an address or an offset
+from the start of that area. Unlike the PC target for simple breaks, the PC in
+an expression is the unmodified program counter. Labels in an expression are
+adjusted to match the mirror the PC is currently in.
+
+As with simple breaks, a conditional break will halt execution when the
+expression changes from false to true.
+
+The IGNORE option specifies the number of times the break should be ignored
+before it halts execution. The ONCE option will cause the break to be disabled
+after it has halted execution. For example:
+
+	BREAK IGNORE 299 ONCE IF PC == kernel
+
+The number of times a break has been matched is shown by the LIST command.
+Adding a break that is equivalent to a disabled break will enable it again.
+
 Existing breakpoints can be reviewed with the LIST command and deleted with the
 DROP or CLEAR commands`,
 
@@ -519,7 +719,22 @@ There is no way to isolate one activity from the other. Use WATCH for that.
 Generally, WATCH is a more flexible instrument but TRACE can be useful to quickly gather information
 about an address.
 
-The ONTRACE command can be used to supplement the TRACE output with contextual information.`,
+The ONTRACE command can be used to supplement the TRACE output with contextual information.
+
+The FILE argument is different. It writes every instruction executed by the CPU to the named file,
+whether the emulation is in the debugger or in playmode. The file is created, or truncated if it
+already exists, and tracing continues until TRACE FILE OFF is issued or the emulator is closed.
+TRACE FILE with no filename shows whether a trace file is active.
+
+	TRACE FILE cpu.trace
+
+Each line shows the state of the emulation before the instruction is executed: the TV frame,
+scanline and clock; the cartridge bank; the program counter; the A, X, Y and SP registers; and the
+status flags (upper case when set). These are followed by the number of cycles taken by the
+instruction, the cumulative cycle count, the bytecode and the disassembly. The columns are fixed
+width so that the file can be compared with the trace output from other emulators, such as Stella.
+
+Tracing can also be started from the command line with the -tracefile flag.`,
 
 	cmdList:  "List currently defined BREAKS, TRAPS, WATCHES and TRACES.",
 	cmdDrop:  "Drop a specific BREAK, TRAP, WATCH or TRACE condition, using the number of the condition reported by LIST.",
@@ -35,17 +35,23 @@ const (
 	cmdRewind     = "REWIND"
 	cmdComparison = "COMPARISON"
 	cmdGoto       = "GOTO"
+	cmdState      = "STATE"
+	cmdDAP        = "DAP"
 
 	cmdInsert    = "INSERT"
 	cmdCartridge = "CARTRIDGE"
 	cmdPatch     = "PATCH"
+	cmdAssemble  = "ASSEMBLE"
 	cmdDisasm    = "DISASM"
 	cmdGrep      = "GREP"
+	cmdSource    = "SOURCE"
 	cmdSymbol    = "SYMBOL"
 	cmdOnHalt    = "ONHALT"
 	cmdOnStep    = "ONSTEP"
 	cmdOnTrace   = "ONTRACE"
 	cmdLast      = "LAST"
+	cmdProfile   = "PROFILE"
+	cmdCoverage  = "COVERAGE"
 	cmdMemMap    = "MEMMAP"
 	cmdCPU       = "CPU"
 	cmdBus       = "BUS"
@@ -53,6 +59,8 @@ const (
 	cmdPoke      = "POKE"
 	cmdSwap      = "SWAP"
 	cmdRAM       = "RAM"
+	cmdRAMMap    = "RAMMAP"
+	cmdCheat     = "CHEAT"
 	cmdTIA       = "TIA"
 	cmdRIOT      = "RIOT"
 	cmdAudio     = "AUDIO"
@@ -94,24 +102,30 @@ var commandTemplate = []string{
 	cmdQuit,
 
 	cmdRun,
-	cmdStep + " (BACK|OVER) (INSTRUCTION|CLOCK|SCANLINE|FRAME)",
+	cmdStep + " (BACK|OVER) (INSTRUCTION|CLOCK|SCANLINE|FRAME|LINE)",
 	cmdHalt,
 	cmdQuantum + " (INSTRUCTION|CYCLE|CLOCK)",
 	cmdScript + " [RECORD %<new file>F|END|%<file>F]",
 	cmdRewind + " [%<frame>N|LAST|SUMMARY]",
 	cmdComparison + " [%<frame>N|LOCK|UNLOCK]",
 	cmdGoto + " [%<clock>N] (%<scanline>N) (%<frame>N)",
+	cmdState + " [SAVE (%<new file>F)|LOAD %<file>F]",
+	cmdDAP + " (OFF|%<address>S)",
 
 	cmdInsert + " %<cartridge>F",
 	cmdCartridge + " (PATH|NAME|MAPPER|CONTAINER|MAPPEDBANKS|HASH|STATIC|REGISTERS|RAM|DUMP)",
-	cmdPatch + " %<patch file>S",
+	cmdPatch + " [CREATE [IPS|BPS] %<new file>F|%<patch file>F]",
+	cmdAssemble + " [UNDO|%<address>S (BANK %<bank>N) %<instruction>S {%<instruction>S}]",
 	cmdDisasm + " (BYTECODE|REDUX)",
 	cmdGrep + " (OPERATOR|OPERAND|COPROC) %<search>S",
-	cmdSymbol + " [LIST (LABELS|READ|WRITE)|%<symbol>S]",
+	cmdSource + " (FILES|LOAD %<listing>F|UNLOAD|%<context>N)",
+	cmdSymbol + " [LIST (LABELS|READ|WRITE)|LABEL %<address>S %<label>S|REMOVE %<address>S|LOAD %<file>F|SAVE (DASM|VICE) %<new file>F|%<symbol>S]",
 	cmdOnHalt + " (OFF|ON|%<command>S {%<commands>S})",
 	cmdOnStep + " (OFF|ON|%<command>S {%<commands>S})",
 	cmdOnTrace + " (OFF|ON|%<command>S {%<commands>S})",
 	cmdLast + " (DEFN|BYTECODE)",
+	cmdProfile + " (ON|OFF|RESET|ROUTINES (VBLANK|SCREEN|OVERSCAN) (%<top>N)|LABELS (VBLANK|SCREEN|OVERSCAN) (%<top>N)|SCANLINES|BUDGET)",
+	cmdCoverage + " (ON|OFF|RESET|LISTING %<new file>F|LCOV %<new file>F|JSON %<new file>F)",
 	cmdMemMap + " (%<address>S)",
 	cmdCPU + " (STATUS ([SET|UNSET|TOGGLE] [S|O|B|D|I|Z|C])|(SET [PC|A|X|Y|SP] [%<register value>S]))",
 	cmdBus + " (DETAIL)",
@@ -119,6 +133,8 @@ var commandTemplate = []string{
 	cmdPoke + " %<address>S [%<value>N] {%<values>N}",
 	cmdSwap + " %<address>S %<address>S",
 	cmdRAM,
+	cmdRAMMap + " (ON|OFF|RESET|USED|FREE|SHARED|ALL|CSV %<new file>F)",
+	cmdCheat + " (LIST|ADD %<code>S [FREEZE|POKE] %<name>S {%<name>S}|REMOVE %<name>S {%<name>S}|ENABLE %<name>S {%<name>S}|DISABLE %<name>S {%<name>S}|SEARCH (NEW (%<frame>N)|EQUAL (%<frame>N)|NOTEQUAL (%<frame>N)|INCREASED (%<frame>N)|DECREASED (%<frame>N)|VALUE %<value>N (%<frame>N)))",
 	cmdTIA + " (HMOVE)",
 	cmdRIOT + " (PORTS|TIMER)",
 	cmdAudio,
@@ -130,7 +146,7 @@ var commandTemplate = []string{
 
 	// peripherals (components that might not be present)
 	cmdPlusROM + " (NICK [%<name>S]|ID [%<id>S]|HOST [%<host>S]|PATH [%<path>S])",
-	cmdCoProc + " (ID|LIST [FAULTS|SOURCEFILES|FUNCTIONS]|TOP (%<top>N)|MEM {DUMP {%<area>S}}|REGS %<group>S|SET %<register>N %<value>N|STEP)",
+	cmdCoProc + " (ID|LIST [FAULTS|SOURCEFILES|FUNCTIONS]|TOP (%<top>N)|MEM {DUMP {%<area>S}}|REGS %<group>S|SET %<register>N %<value>N|STEP|GDB [OFF|%<address>S])",
 	cmdDWARF + " [FUNCTIONS|GLOBALS|LOCALS {DERIVATION|RANGES|ERROR}|FRAMEBASE {DERIVATION}|LINE %<file:line>S|CALLSTACK|CALLERS %<function>S]",
 
 	// user input
@@ -140,10 +156,10 @@ var commandTemplate = []string{
 	cmdKeypad + " [LEFT|RIGHT] [NONE|0|1|2|3|4|5|6|7|8|9|*|#]",
 
 	// halt conditions
-	cmdBreak + " [%<pc value>S|%<target>S %<value>N] {& %<value>S|%<target>S %<value>S}",
+	cmdBreak + " (IGNORE %<count>N) (ONCE) [IF %<expression>S {%<expression>S}|%<pc value>S|%<target>S %<value>N] {& %<value>S|%<target>S %<value>S}",
 	cmdTrap + " [%<target>S] {%<targets>S}",
 	cmdWatch + " (READ|WRITE) (STRICT) (PHANTOM|GHOST) [%<address>S] (%<value>S)",
-	cmdTrace + " (STRICT) (%<address>S)",
+	cmdTrace + " (FILE (OFF|%<new file>F)|STRICT %<address>S|%<address>S)",
 	cmdList + " [BREAKS|TRAPS|WATCHES|TRACES|ALL]",
 	cmdDrop + " [BREAK|TRAP|WATCH|TRACE] %<number in list>N",
 	cmdClear + " [BREAKS|TRAPS|WATCHES|TRACES|ALL]",
@@ -0,0 +1,67 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"io"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/coverage"
+)
+
+// start recording code coverage of the 6507 program. coverage from any
+// previous session is discarded
+func (dbg *Debugger) startCoverage() {
+	dbg.coverage = coverage.NewCoverage()
+	dbg.coverage.Reset(dbg.vcs.Mem.Cart)
+}
+
+// stop recording code coverage
+func (dbg *Debugger) stopCoverage() {
+	dbg.coverage = nil
+}
+
+// write coverage information to the named file using the supplied function
+func (dbg *Debugger) writeCoverage(filename string, write func(io.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
+// write the lcov tracefile and the annotated listing it refers to. returns the
+// filename of the listing
+func (dbg *Debugger) writeCoverageLCOV(filename string) (string, error) {
+	listing := coverage.ListingFilename(filename)
+
+	err := dbg.writeCoverage(listing, func(w io.Writer) error {
+		return dbg.coverage.WriteListing(w, dbg.Disasm)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = dbg.writeCoverage(filename, func(w io.Writer) error {
+		return dbg.coverage.WriteLCOV(w, dbg.Disasm, listing)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return listing, nil
+}
@@ -0,0 +1,154 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/debugger/dap"
+	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// dapTarget is an implementation of the dap.Target interface.
+type dapTarget struct {
+	dbg *Debugger
+}
+
+// PushFunction implements the dap.Target interface.
+func (t dapTarget) PushFunction(f func()) {
+	t.dbg.PushFunction(f)
+}
+
+// PushFunctionImmediate implements the dap.Target interface.
+func (t dapTarget) PushFunctionImmediate(f func()) {
+	t.dbg.PushFunctionImmediate(f)
+}
+
+// VCS implements the dap.Target interface.
+func (t dapTarget) VCS() *hardware.VCS {
+	return t.dbg.vcs
+}
+
+// Disassembly implements the dap.Target interface.
+func (t dapTarget) Disassembly() *disassembly.Disassembly {
+	return t.dbg.Disasm
+}
+
+// Peek implements the dap.Target interface.
+func (t dapTarget) Peek(address string) (*dbgmem.AddressInfo, error) {
+	return t.dbg.dbgmem.Peek(address)
+}
+
+// Poke implements the dap.Target interface.
+func (t dapTarget) Poke(address string, data uint8) (*dbgmem.AddressInfo, error) {
+	return t.dbg.dbgmem.Poke(address, data)
+}
+
+// Running implements the dap.Target interface.
+func (t dapTarget) Running() bool {
+	return t.dbg.State() == govern.Running
+}
+
+// Run implements the dap.Target interface.
+func (t dapTarget) Run() {
+	if t.dbg.Mode() != govern.ModeDebugger {
+		return
+	}
+	t.command(cmdRun)
+}
+
+// Halt implements the dap.Target interface.
+func (t dapTarget) Halt() {
+	if t.dbg.Mode() != govern.ModeDebugger {
+		// changing to the debugger mode will pause the emulation
+		err := t.dbg.setMode(govern.ModeDebugger)
+		if err != nil {
+			logger.Log(logger.Allow, "dap", err.Error())
+		}
+		return
+	}
+	t.command(cmdHalt)
+}
+
+// Step implements the dap.Target interface.
+func (t dapTarget) Step(over bool) {
+	if t.dbg.Mode() != govern.ModeDebugger {
+		t.Halt()
+		return
+	}
+	if over {
+		t.command(fmt.Sprintf("%s OVER", cmdStep))
+	} else {
+		t.command(cmdStep)
+	}
+}
+
+func (t dapTarget) command(cmd string) {
+	err := t.dbg.parseInput(cmd, false, true)
+	if err != nil {
+		logger.Log(logger.Allow, "dap", err.Error())
+	}
+}
+
+// AddPCBreak implements the dap.Target interface.
+func (t dapTarget) AddPCBreak(addr uint16, bank int) bool {
+	return t.dbg.halting.breakpoints.addPCBreak(addr, bank)
+}
+
+// RemovePCBreak implements the dap.Target interface.
+func (t dapTarget) RemovePCBreak(addr uint16, bank int) {
+	t.dbg.halting.breakpoints.removePCBreak(addr, bank)
+}
+
+// the reason for the emulation stopping as understood by the DAP client
+func (dbg *Debugger) dapStopReason() string {
+	if dbg.halting.halt {
+		return dap.StopBreakpoint
+	}
+	return dap.StopPause
+}
+
+// start the DAP server on the specified address. any existing server will be
+// stopped first
+func (dbg *Debugger) startDAP(address string) error {
+	dbg.stopDAP()
+
+	var err error
+	dbg.dap, err = dap.NewServer(address, dapTarget{dbg: dbg})
+	if err != nil {
+		return fmt.Errorf("debugger: %w", err)
+	}
+	logger.Logf(logger.Allow, "debugger", "DAP server listening on %s", dbg.dap.Address())
+
+	return nil
+}
+
+// stop the DAP server if it is running
+func (dbg *Debugger) stopDAP() {
+	if dbg.dap == nil {
+		return
+	}
+
+	err := dbg.dap.Close()
+	if err != nil {
+		logger.Log(logger.Allow, "debugger", err.Error())
+	}
+	dbg.dap = nil
+}
@@ -0,0 +1,235 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/debugger/dap"
+	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+type pcBreak struct {
+	addr uint16
+	bank int
+}
+
+// mockTarget implements the dap.Target interface. pushed functions are run on
+// the goroutine running the emulation() function
+type mockTarget struct {
+	crit        sync.Mutex
+	breakpoints map[pcBreak]bool
+	pushed      chan func()
+}
+
+func (t *mockTarget) PushFunction(f func())                 { t.pushed <- f }
+func (t *mockTarget) PushFunctionImmediate(f func())        { t.pushed <- f }
+func (t *mockTarget) VCS() *hardware.VCS                    { return nil }
+func (t *mockTarget) Disassembly() *disassembly.Disassembly { return nil }
+func (t *mockTarget) Running() bool                         { return false }
+func (t *mockTarget) Run()                                  {}
+func (t *mockTarget) Halt()                                 {}
+func (t *mockTarget) Step(bool)                             {}
+
+func (t *mockTarget) Peek(address string) (*dbgmem.AddressInfo, error) {
+	return nil, fmt.Errorf("cannot peek")
+}
+
+func (t *mockTarget) Poke(address string, data uint8) (*dbgmem.AddressInfo, error) {
+	return nil, fmt.Errorf("cannot poke")
+}
+
+func (t *mockTarget) AddPCBreak(addr uint16, bank int) bool {
+	t.crit.Lock()
+	defer t.crit.Unlock()
+	b := pcBreak{addr: addr, bank: bank}
+	if t.breakpoints[b] {
+		return false
+	}
+	t.breakpoints[b] = true
+	return true
+}
+
+func (t *mockTarget) RemovePCBreak(addr uint16, bank int) {
+	t.crit.Lock()
+	defer t.crit.Unlock()
+	delete(t.breakpoints, pcBreak{addr: addr, bank: bank})
+}
+
+func (t *mockTarget) numBreakpoints() int {
+	t.crit.Lock()
+	defer t.crit.Unlock()
+	return len(t.breakpoints)
+}
+
+// the emulation goroutine
+func (t *mockTarget) emulation(quit chan bool) {
+	for {
+		select {
+		case f := <-t.pushed:
+			f()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// client is a minimal DAP client
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+func (c *client) send(command string, arguments any) {
+	c.seq++
+	b, err := json.Marshal(map[string]any{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	test.ExpectSuccess(c.t, err)
+	_, err = fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	test.ExpectSuccess(c.t, err)
+}
+
+func (c *client) receive() map[string]any {
+	hdr, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	test.ExpectSuccess(c.t, err)
+	l, err := strconv.Atoi(hdr.Get("Content-Length"))
+	test.ExpectSuccess(c.t, err)
+	b := make([]byte, l)
+	_, err = io.ReadFull(c.r, b)
+	test.ExpectSuccess(c.t, err)
+
+	var msg map[string]any
+	test.ExpectSuccess(c.t, json.Unmarshal(b, &msg))
+	return msg
+}
+
+// receive the next message and check that it is a response to the command
+func (c *client) response(command string, success bool) map[string]any {
+	msg := c.receive()
+	test.ExpectEquality(c.t, msg["type"], "response")
+	test.ExpectEquality(c.t, msg["command"], any(command))
+	test.ExpectEquality(c.t, msg["success"], any(success))
+	body, _ := msg["body"].(map[string]any)
+	return body
+}
+
+// receive the next message and check that it is the event
+func (c *client) event(event string) map[string]any {
+	msg := c.receive()
+	test.ExpectEquality(c.t, msg["type"], "event")
+	test.ExpectEquality(c.t, msg["event"], any(event))
+	body, _ := msg["body"].(map[string]any)
+	return body
+}
+
+func TestServer(t *testing.T) {
+	target := &mockTarget{
+		breakpoints: make(map[pcBreak]bool),
+		pushed:      make(chan func(), 10),
+	}
+
+	quit := make(chan bool)
+	defer close(quit)
+	go target.emulation(quit)
+
+	srv, err := dap.NewServer("localhost:0", target)
+	test.ExpectSuccess(t, err)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Address())
+	test.ExpectSuccess(t, err)
+	defer conn.Close()
+
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	c.send("initialize", map[string]any{"adapterID": "gopher2600"})
+	body := c.response("initialize", true)
+	test.ExpectEquality(t, body["supportsConfigurationDoneRequest"], true)
+	c.event("initialized")
+
+	c.send("setInstructionBreakpoints", map[string]any{
+		"breakpoints": []map[string]any{
+			{"instructionReference": "0xf000"},
+			{"instructionReference": "0xf000", "offset": 2},
+			{"instructionReference": "foo"},
+		},
+	})
+	body = c.response("setInstructionBreakpoints", true)
+	brks := body["breakpoints"].([]any)
+	test.ExpectEquality(t, len(brks), 3)
+	test.ExpectEquality(t, brks[0].(map[string]any)["verified"], true)
+	test.ExpectEquality(t, brks[1].(map[string]any)["instructionReference"], "0xf002")
+	test.ExpectEquality(t, brks[2].(map[string]any)["verified"], false)
+	test.ExpectEquality(t, target.numBreakpoints(), 2)
+
+	// breakpoints are replaced by subsequent requests
+	c.send("setInstructionBreakpoints", map[string]any{
+		"breakpoints": []map[string]any{
+			{"instructionReference": "$f010"},
+		},
+	})
+	c.response("setInstructionBreakpoints", true)
+	test.ExpectEquality(t, target.numBreakpoints(), 1)
+
+	// the emulation is not running so a stopped event is sent after
+	// configuration
+	c.send("configurationDone", nil)
+	c.response("configurationDone", true)
+	body = c.event("stopped")
+	test.ExpectEquality(t, body["reason"], dap.StopEntry)
+
+	c.send("threads", nil)
+	body = c.response("threads", true)
+	test.ExpectEquality(t, len(body["threads"].([]any)), 1)
+
+	srv.Stopped(dap.StopBreakpoint, "")
+	body = c.event("stopped")
+	test.ExpectEquality(t, body["reason"], dap.StopBreakpoint)
+
+	// a stop following a step request is always reported as a step
+	c.send("next", nil)
+	c.response("next", true)
+	srv.Stopped(dap.StopBreakpoint, "")
+	body = c.event("stopped")
+	test.ExpectEquality(t, body["reason"], dap.StopStep)
+
+	c.send("stepOut", nil)
+	c.response("stepOut", false)
+
+	// breakpoints are removed when the client disconnects
+	c.send("disconnect", nil)
+	c.response("disconnect", true)
+	_, err = c.r.ReadByte()
+	test.ExpectFailure(t, err)
+	test.ExpectEquality(t, target.numBreakpoints(), 0)
+}
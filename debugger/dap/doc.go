@@ -0,0 +1,49 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package dap implements a Debug Adapter Protocol server for the 6507 in the
+// emulated VCS. It allows DAP clients, such as Visual Studio Code, to connect
+// to the debugger over TCP.
+//
+// The DAP requests are mapped onto the existing debugger functions. The
+// continue request is equivalent to the RUN command, next and stepIn are
+// equivalent to the STEP OVER and STEP commands, and pause is equivalent to
+// HALT. Breakpoints are added as PC breakpoints, in the same way as the BREAK
+// command.
+//
+// Source lines are presented in two ways. Each cartridge bank is presented as
+// a source whose content is the disassembly of that bank. Labels from the
+// symbols table appear on their own line in that disassembly. Breakpoints can
+// be set on any instruction in those sources and stack frames will always
+// refer to them.
+//
+// Breakpoints can also be set on lines in the original assembly source files.
+// In this instance the line must start with a label that is present in the
+// symbols table. Function breakpoints are also supported and will resolve the
+// name using the symbols table.
+//
+// The CPU registers and the VCS RAM are presented as variables. RAM addresses
+// are named with the read symbol if it is available. Both registers and RAM can
+// be changed with the setVariable request.
+//
+// Once connected, the client is informed of the emulation being stopped or
+// continued regardless of how that happened. In other words, a HALT command
+// issued from the debugger's own terminal will result in a stopped event being
+// sent to the client.
+//
+// Specification of the protocol can be found at:
+//
+// https://microsoft.github.io/debug-adapter-protocol/specification
+package dap
@@ -0,0 +1,534 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// variable references for the two scopes presented to the client
+const (
+	varRegisters = 1
+	varRAM       = 2
+)
+
+// keys for the breakpoints map that aren't associated with a source
+const (
+	keyFunctions    = "functions"
+	keyInstructions = "instructions"
+)
+
+// handle the request and return the body of the response. must only be called
+// from the connection goroutine
+func (s *Server) handle(req request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsFunctionBreakpoints:      true,
+			SupportsInstructionBreakpoints:   true,
+			SupportsSetVariable:              true,
+			SupportsEvaluateForHovers:        true,
+		}, nil
+
+	case "launch", "attach", "configurationDone", "disconnect":
+		// the emulation is already running so there is nothing to do for
+		// launch or attach. disconnect is handled by serve()
+		return nil, nil
+
+	case "threads":
+		return threadsBody{
+			Threads: []thread{{ID: threadID, Name: "6507"}},
+		}, nil
+
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args)
+
+	case "setFunctionBreakpoints":
+		var args setFunctionBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setFunctionBreakpoints(args)
+
+	case "setInstructionBreakpoints":
+		var args setInstructionBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setInstructionBreakpoints(args)
+
+	case "stackTrace":
+		return s.stackTrace()
+
+	case "scopes":
+		return scopesBody{
+			Scopes: []scope{
+				{Name: "Registers", PresentationHint: "registers", VariablesReference: varRegisters},
+				{Name: "RAM", VariablesReference: varRAM, NamedVariables: int(memorymap.MemtopRAM-memorymap.OriginRAM) + 1},
+			},
+		}, nil
+
+	case "variables":
+		var args variablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args)
+
+	case "setVariable":
+		var args setVariableArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setVariable(args)
+
+	case "source":
+		var args sourceArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		ref := args.SourceReference
+		if args.Source != nil && args.Source.SourceReference != 0 {
+			ref = args.Source.SourceReference
+		}
+		src := s.bankSource(sourceBank(ref))
+		if src == nil {
+			return nil, fmt.Errorf("no source for reference %d", ref)
+		}
+		return sourceBody{Content: src.content, MimeType: "text/x-asm"}, nil
+
+	case "evaluate":
+		var args evaluateArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+
+	case "continue":
+		return continueBody{AllThreadsContinued: true}, s.execImmediate(s.target.Run)
+
+	case "next":
+		s.stepping.Store(true)
+		return nil, s.execImmediate(func() {
+			s.target.Step(true)
+		})
+
+	case "stepIn":
+		s.stepping.Store(true)
+		return nil, s.execImmediate(func() {
+			s.target.Step(false)
+		})
+
+	case "pause":
+		return nil, s.execImmediate(s.target.Halt)
+	}
+
+	return nil, fmt.Errorf("%s request is not supported", req.Command)
+}
+
+// replace the breakpoints associated with the key with the new list of
+// breakpoints
+func (s *Server) replaceBreakpoints(key string, brks []pcBreak) {
+	old := s.breakpoints[key]
+	s.breakpoints[key] = nil
+
+	_ = s.exec(func() {
+		for _, b := range old {
+			s.target.RemovePCBreak(b.addr, b.bank)
+		}
+		for _, b := range brks {
+			// only take ownership of the breakpoint if it didn't already exist
+			if s.target.AddPCBreak(b.addr, b.bank) {
+				s.breakpoints[key] = append(s.breakpoints[key], b)
+			}
+		}
+	})
+}
+
+func (s *Server) setBreakpoints(args setBreakpointsArguments) (any, error) {
+	var brks []pcBreak
+	rsp := breakpointsBody{Breakpoints: []breakpoint{}}
+
+	if args.Source.SourceReference != 0 {
+		// breakpoints in the disassembly of a bank
+		src := s.bankSource(sourceBank(args.Source.SourceReference))
+		if src == nil {
+			return nil, fmt.Errorf("no source for reference %d", args.Source.SourceReference)
+		}
+
+		for _, b := range args.Breakpoints {
+			addr, ok := src.address(b.Line)
+			if !ok {
+				rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{Message: "no instruction on line"})
+				continue
+			}
+			brks = append(brks, pcBreak{addr: addr, bank: src.bank})
+			line, _ := src.line(addr)
+			rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{
+				Verified: true,
+				Source:   src.source(),
+				Line:     line,
+			})
+		}
+
+		s.replaceBreakpoints(fmt.Sprintf("%d", args.Source.SourceReference), brks)
+		return rsp, nil
+	}
+
+	// breakpoints in an assembly source file. the line must begin with a label
+	// that can be found in the symbols table
+	var lines []string
+	if b, err := os.ReadFile(args.Source.Path); err == nil {
+		lines = strings.Split(string(b), "\n")
+	}
+
+	for _, b := range args.Breakpoints {
+		if b.Line < 1 || b.Line > len(lines) {
+			rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{Message: "line not in source file"})
+			continue
+		}
+
+		addr, ok := s.lookupLabel(sourceLabel(lines[b.Line-1]))
+		if !ok {
+			rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{Message: "line does not begin with a known label"})
+			continue
+		}
+
+		brks = append(brks, pcBreak{addr: addr, bank: -1})
+		rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{
+			Verified:             true,
+			Line:                 b.Line,
+			InstructionReference: fmt.Sprintf("0x%04x", addr),
+		})
+	}
+
+	s.replaceBreakpoints(args.Source.Path, brks)
+	return rsp, nil
+}
+
+// sourceLabel returns the label at the start of a line of assembly source.
+// returns the empty string if the line doesn't start with a label
+func sourceLabel(line string) string {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == ';' {
+		return ""
+	}
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(f[0], ":")
+}
+
+// lookupLabel returns the address of the label in the symbols table
+func (s *Server) lookupLabel(label string) (uint16, bool) {
+	if label == "" {
+		return 0, false
+	}
+
+	var res *symbols.SearchResults
+	err := s.exec(func() {
+		if dsm := s.target.Disassembly(); dsm != nil {
+			res = dsm.Sym.SearchBySymbol(label, symbols.SearchLabel)
+		}
+	})
+	if err != nil || res == nil {
+		return 0, false
+	}
+	return res.Address, true
+}
+
+func (s *Server) setFunctionBreakpoints(args setFunctionBreakpointsArguments) (any, error) {
+	var brks []pcBreak
+	rsp := breakpointsBody{Breakpoints: []breakpoint{}}
+
+	for _, b := range args.Breakpoints {
+		addr, ok := s.lookupLabel(b.Name)
+		if !ok {
+			rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{Message: "unknown label"})
+			continue
+		}
+		brks = append(brks, pcBreak{addr: addr, bank: -1})
+		rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{
+			Verified:             true,
+			InstructionReference: fmt.Sprintf("0x%04x", addr),
+		})
+	}
+
+	s.replaceBreakpoints(keyFunctions, brks)
+	return rsp, nil
+}
+
+func (s *Server) setInstructionBreakpoints(args setInstructionBreakpointsArguments) (any, error) {
+	var brks []pcBreak
+	rsp := breakpointsBody{Breakpoints: []breakpoint{}}
+
+	for _, b := range args.Breakpoints {
+		addr, err := parseValue(b.InstructionReference, 16)
+		if err != nil {
+			rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{Message: err.Error()})
+			continue
+		}
+		a := uint16(int(addr) + b.Offset)
+		brks = append(brks, pcBreak{addr: a, bank: -1})
+		rsp.Breakpoints = append(rsp.Breakpoints, breakpoint{
+			Verified:             true,
+			InstructionReference: fmt.Sprintf("0x%04x", a),
+		})
+	}
+
+	s.replaceBreakpoints(keyInstructions, brks)
+	return rsp, nil
+}
+
+func (s *Server) stackTrace() (any, error) {
+	var pc uint16
+	var bank int
+	var nonCart bool
+
+	err := s.exec(func() {
+		vcs := s.target.VCS()
+		pc = vcs.CPU.PC.Address()
+		bi := vcs.Mem.Cart.GetBank(pc)
+		bank = bi.Number
+		nonCart = bi.NonCart
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	frame := stackFrame{
+		ID:                          1,
+		Name:                        fmt.Sprintf("$%04x", pc),
+		Column:                      1,
+		InstructionPointerReference: fmt.Sprintf("0x%04x", pc),
+	}
+
+	if !nonCart {
+		if src := s.bankSource(bank); src != nil {
+			line, ok := src.line(pc)
+			if !ok {
+				// the disassembly may have changed since the source was
+				// created. this is likely if the PC is in code that has only
+				// just been executed for the first time
+				delete(s.sources, bank)
+				src = s.bankSource(bank)
+				if src != nil {
+					line, ok = src.line(pc)
+				}
+			}
+			if ok {
+				if r := src.routine(line); r != "" {
+					frame.Name = r
+				}
+				frame.Source = src.source()
+				frame.Line = line
+			}
+		}
+	}
+
+	return stackTraceBody{
+		StackFrames: []stackFrame{frame},
+		TotalFrames: 1,
+	}, nil
+}
+
+func (s *Server) variables(args variablesArguments) (any, error) {
+	rsp := variablesBody{Variables: []variable{}}
+
+	var err error
+
+	switch args.VariablesReference {
+	case varRegisters:
+		err = s.exec(func() {
+			cpu := s.target.VCS().CPU
+			rsp.Variables = append(rsp.Variables,
+				variable{Name: "A", Value: fmt.Sprintf("$%02x", cpu.A.Value()), EvaluateName: "A"},
+				variable{Name: "X", Value: fmt.Sprintf("$%02x", cpu.X.Value()), EvaluateName: "X"},
+				variable{Name: "Y", Value: fmt.Sprintf("$%02x", cpu.Y.Value()), EvaluateName: "Y"},
+				variable{Name: "SP", Value: fmt.Sprintf("$%02x", cpu.SP.Value()), EvaluateName: "SP"},
+				variable{Name: "PC", Value: fmt.Sprintf("$%04x", cpu.PC.Value()), EvaluateName: "PC"},
+				variable{Name: "SR", Value: fmt.Sprintf("%s ($%02x)", cpu.Status.String(), cpu.Status.Value()), EvaluateName: "SR"},
+			)
+		})
+
+	case varRAM:
+		err = s.exec(func() {
+			for a := memorymap.OriginRAM; a <= memorymap.MemtopRAM; a++ {
+				ai, err := s.target.Peek(fmt.Sprintf("%#02x", a))
+				if err != nil {
+					continue
+				}
+				v := variable{
+					Name:         fmt.Sprintf("$%02x", a),
+					Value:        fmt.Sprintf("$%02x", ai.Data),
+					EvaluateName: fmt.Sprintf("$%02x", a),
+				}
+				if ai.Symbol != "" {
+					v.Name = ai.Symbol
+				}
+				rsp.Variables = append(rsp.Variables, v)
+			}
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown variables reference (%d)", args.VariablesReference)
+	}
+
+	return rsp, err
+}
+
+func (s *Server) setVariable(args setVariableArguments) (any, error) {
+	var rsp setVariableBody
+	var err error
+
+	switch args.VariablesReference {
+	case varRegisters:
+		bits := 8
+		if args.Name == "PC" {
+			bits = 16
+		}
+		v, perr := parseValue(args.Value, bits)
+		if perr != nil {
+			return nil, perr
+		}
+
+		err = s.exec(func() {
+			cpu := s.target.VCS().CPU
+			switch args.Name {
+			case "A":
+				cpu.A.Load(uint8(v))
+			case "X":
+				cpu.X.Load(uint8(v))
+			case "Y":
+				cpu.Y.Load(uint8(v))
+			case "SP":
+				cpu.SP.Load(uint8(v))
+			case "PC":
+				cpu.PC.Load(uint16(v))
+				rsp.Value = fmt.Sprintf("$%04x", v)
+				return
+			case "SR":
+				cpu.Status.Load(uint8(v))
+				rsp.Value = fmt.Sprintf("%s ($%02x)", cpu.Status.String(), cpu.Status.Value())
+				return
+			default:
+				err = fmt.Errorf("unknown register (%s)", args.Name)
+				return
+			}
+			rsp.Value = fmt.Sprintf("$%02x", v)
+		})
+
+	case varRAM:
+		v, perr := parseValue(args.Value, 8)
+		if perr != nil {
+			return nil, perr
+		}
+
+		err = s.exec(func() {
+			_, err = s.target.Poke(normaliseAddress(args.Name), uint8(v))
+			rsp.Value = fmt.Sprintf("$%02x", v)
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown variables reference (%d)", args.VariablesReference)
+	}
+
+	return rsp, err
+}
+
+func (s *Server) evaluate(args evaluateArguments) (any, error) {
+	var rsp evaluateBody
+	var err error
+
+	expr := strings.TrimSpace(args.Expression)
+
+	err = s.exec(func() {
+		cpu := s.target.VCS().CPU
+		switch strings.ToUpper(expr) {
+		case "A":
+			rsp.Result = fmt.Sprintf("$%02x", cpu.A.Value())
+		case "X":
+			rsp.Result = fmt.Sprintf("$%02x", cpu.X.Value())
+		case "Y":
+			rsp.Result = fmt.Sprintf("$%02x", cpu.Y.Value())
+		case "SP":
+			rsp.Result = fmt.Sprintf("$%02x", cpu.SP.Value())
+		case "PC":
+			rsp.Result = fmt.Sprintf("$%04x", cpu.PC.Value())
+		case "SR":
+			rsp.Result = fmt.Sprintf("%s ($%02x)", cpu.Status.String(), cpu.Status.Value())
+		default:
+			ai, perr := s.target.Peek(normaliseAddress(expr))
+			if perr != nil {
+				err = perr
+				return
+			}
+			rsp.Result = fmt.Sprintf("$%02x", ai.Data)
+		}
+	})
+
+	return rsp, err
+}
+
+// normaliseAddress converts an address using the $ prefix for hexadecimal
+// numbers into a form understood by the Target
+func normaliseAddress(address string) string {
+	if strings.HasPrefix(address, "$") {
+		return fmt.Sprintf("0x%s", address[1:])
+	}
+	return address
+}
+
+// parseValue parses a numeric value entered by the user. hexadecimal values
+// can be prefixed with $ or 0x and binary values with %
+func parseValue(s string, bits int) (uint64, error) {
+	s = strings.TrimSpace(s)
+
+	var v uint64
+	var err error
+
+	switch {
+	case strings.HasPrefix(s, "$"):
+		v, err = strconv.ParseUint(s[1:], 16, bits)
+	case strings.HasPrefix(s, "%"):
+		v, err = strconv.ParseUint(s[1:], 2, bits)
+	default:
+		v, err = strconv.ParseUint(s, 0, bits)
+	}
+
+	if err != nil {
+		var nerr *strconv.NumError
+		if errors.As(err, &nerr) {
+			return 0, fmt.Errorf("%s: %w", s, nerr.Err)
+		}
+		return 0, err
+	}
+
+	return v, nil
+}
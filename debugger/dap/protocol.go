@@ -0,0 +1,105 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// request is a message sent by the client
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// response is sent by the server in reply to a request. the success field
+// must always be present
+type response struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Command    string `json:"command"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+// event is sent by the server without a corresponding request
+type event struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// readMessage reads the next request from the reader. messages consist of a
+// header (of which only the Content-Length field is required) and a JSON
+// encoded body
+func readMessage(r *bufio.Reader) (request, error) {
+	var req request
+
+	hdr, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return req, err
+	}
+
+	l, err := strconv.Atoi(strings.TrimSpace(hdr.Get("Content-Length")))
+	if err != nil {
+		return req, fmt.Errorf("invalid content length: %w", err)
+	}
+
+	b := make([]byte, l)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return req, err
+	}
+
+	err = json.Unmarshal(b, &req)
+	if err != nil {
+		return req, err
+	}
+
+	if req.Type != "request" {
+		return req, fmt.Errorf("unexpected message type (%s)", req.Type)
+	}
+
+	return req, nil
+}
+
+// writeMessage encodes the message as JSON and writes it with the required
+// header
+func writeMessage(w io.Writer, msg any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(b))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
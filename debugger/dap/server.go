@@ -0,0 +1,386 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// Target is the interface to the debugger required by the Server. With the
+// exception of PushFunction() and PushFunctionImmediate(), the functions will
+// only be called from the emulation goroutine.
+type Target interface {
+	// run the function on the emulation goroutine. the immediate variant
+	// should be used when the function changes the running state of the
+	// emulation
+	PushFunction(func())
+	PushFunctionImmediate(func())
+
+	VCS() *hardware.VCS
+	Disassembly() *disassembly.Disassembly
+
+	// Peek and Poke memory. the address can be numeric or symbolic
+	Peek(address string) (*dbgmem.AddressInfo, error)
+	Poke(address string, data uint8) (*dbgmem.AddressInfo, error)
+
+	// returns true if the emulation is running
+	Running() bool
+
+	// equivalent to the RUN, HALT and STEP commands
+	Run()
+	Halt()
+	Step(over bool)
+
+	// add or remove a PC breakpoint for the address. a negative bank value
+	// means that the breakpoint will match in any bank. AddPCBreak() returns
+	// false if an equivalent breakpoint already exists
+	AddPCBreak(addr uint16, bank int) bool
+	RemovePCBreak(addr uint16, bank int)
+}
+
+// the reasons given to the client for the emulation stopping. a subset of the
+// reasons described by the protocol specification
+const (
+	StopEntry      = "entry"
+	StopStep       = "step"
+	StopBreakpoint = "breakpoint"
+	StopPause      = "pause"
+)
+
+// the only thread presented to the client
+const threadID = 1
+
+// Server is a Debug Adapter Protocol server.
+type Server struct {
+	target   Target
+	listener net.Listener
+
+	// the current connection. only one connection is allowed at a time
+	crit sync.Mutex
+	conn net.Conn
+	seq  int
+
+	// closed when the server is closed
+	quit chan bool
+
+	// the client has finished configuration. events are only sent once this
+	// is true
+	configured atomic.Bool
+
+	// the client has requested a step. the next stopped event will be sent
+	// with the StopStep reason
+	stepping atomic.Bool
+
+	// PC breakpoints added by the client, indexed by the source they
+	// belong to. only accessed by the connection goroutine
+	breakpoints map[string][]pcBreak
+
+	// sources for cartridge banks. only accessed by the connection goroutine
+	sources map[int]*bankSource
+}
+
+// a PC breakpoint added on behalf of the client
+type pcBreak struct {
+	addr uint16
+	bank int
+}
+
+// NewServer is the preferred method of initialisation for the Server type. The
+// address is the TCP address to listen on. If the address is just a port
+// number then the server will listen on localhost.
+func NewServer(address string, target Target) (*Server, error) {
+	if !strings.Contains(address, ":") {
+		address = fmt.Sprintf("localhost:%s", address)
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("dap: %w", err)
+	}
+
+	s := &Server{
+		target:   target,
+		listener: l,
+		quit:     make(chan bool),
+	}
+
+	go s.listen()
+
+	return s, nil
+}
+
+// Address returns the address the server is listening on.
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Close the server. Any connected client will be sent a terminated event and
+// then disconnected.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	close(s.quit)
+
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	if s.conn != nil {
+		_ = s.sendEvent("terminated", nil)
+		_ = s.conn.Close()
+	}
+
+	return err
+}
+
+// Connected returns true if a client is connected to the server.
+func (s *Server) Connected() bool {
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	return s.conn != nil
+}
+
+// Stopped should be called when the emulation has stopped. The reason should
+// be one of the Stop* values. If the stop is the result of a step request from
+// the client then the reason will be replaced with StopStep.
+func (s *Server) Stopped(reason string, description string) {
+	if s.stepping.Swap(false) {
+		reason = StopStep
+	}
+
+	if !s.configured.Load() {
+		return
+	}
+
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	err := s.sendEvent("stopped", stoppedEvent{
+		Reason:            reason,
+		Description:       description,
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+	})
+	if err != nil {
+		logger.Logf(logger.Allow, "dap", err.Error())
+	}
+}
+
+// Continued should be called when the emulation has started running. It
+// should not be called when the emulation is only stepping.
+func (s *Server) Continued() {
+	if !s.configured.Load() {
+		return
+	}
+
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	err := s.sendEvent("continued", continuedEvent{
+		ThreadID:            threadID,
+		AllThreadsContinued: true,
+	})
+	if err != nil {
+		logger.Logf(logger.Allow, "dap", err.Error())
+	}
+}
+
+// sendEvent must be called with the critical section locked
+func (s *Server) sendEvent(name string, body any) error {
+	if s.conn == nil {
+		return nil
+	}
+	s.seq++
+	return writeMessage(s.conn, event{
+		Seq:   s.seq,
+		Type:  "event",
+		Event: name,
+		Body:  body,
+	})
+}
+
+func (s *Server) sendResponse(req request, body any, err error) error {
+	s.crit.Lock()
+	defer s.crit.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	s.seq++
+	rsp := response{
+		Seq:        s.seq,
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+		Success:    err == nil,
+		Body:       body,
+	}
+	if err != nil {
+		rsp.Message = err.Error()
+	}
+
+	return writeMessage(s.conn, rsp)
+}
+
+func (s *Server) listen() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Logf(logger.Allow, "dap", err.Error())
+			}
+			return
+		}
+
+		logger.Logf(logger.Allow, "dap", "connection from %s", conn.RemoteAddr())
+
+		s.crit.Lock()
+		s.conn = conn
+		s.seq = 0
+		s.crit.Unlock()
+
+		s.breakpoints = make(map[string][]pcBreak)
+		s.sources = make(map[int]*bankSource)
+
+		err = s.serve(conn)
+		if err != nil {
+			logger.Logf(logger.Allow, "dap", err.Error())
+		}
+
+		s.configured.Store(false)
+		s.clearBreakpoints()
+
+		s.crit.Lock()
+		_ = s.conn.Close()
+		s.conn = nil
+		s.crit.Unlock()
+
+		logger.Logf(logger.Allow, "dap", "connection closed")
+	}
+}
+
+// serve the connection until the client disconnects
+func (s *Server) serve(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+
+	for {
+		req, err := readMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		body, err := s.handle(req)
+		if errors.Is(err, errClosed) {
+			return nil
+		}
+
+		err = s.sendResponse(req, body, err)
+		if err != nil {
+			return err
+		}
+
+		// some requests require an event to be sent after the response
+		switch req.Command {
+		case "initialize":
+			s.crit.Lock()
+			err = s.sendEvent("initialized", nil)
+			s.crit.Unlock()
+			if err != nil {
+				return err
+			}
+		case "configurationDone":
+			s.configured.Store(true)
+
+			// tell the client if the emulation is already stopped
+			var running bool
+			err = s.exec(func() {
+				running = s.target.Running()
+			})
+			if err != nil {
+				return nil
+			}
+			if !running {
+				s.Stopped(StopEntry, "")
+			}
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+// the sentinal error returned by exec() if the server has been closed
+var errClosed = errors.New("server closed")
+
+// exec runs the function on the emulation goroutine and waits for it to
+// complete
+func (s *Server) exec(f func()) error {
+	done := make(chan bool)
+	s.target.PushFunction(func() {
+		f()
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-s.quit:
+		return errClosed
+	}
+	return nil
+}
+
+// execImmediate is the same as exec except that the function is pushed with
+// PushFunctionImmediate(). this is required for functions that change the
+// running state of the emulation
+func (s *Server) execImmediate(f func()) error {
+	done := make(chan bool)
+	s.target.PushFunctionImmediate(func() {
+		f()
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-s.quit:
+		return errClosed
+	}
+	return nil
+}
+
+// remove all breakpoints that have been added on behalf of the client
+func (s *Server) clearBreakpoints() {
+	var brks []pcBreak
+	for _, b := range s.breakpoints {
+		brks = append(brks, b...)
+	}
+	clear(s.breakpoints)
+
+	_ = s.exec(func() {
+		for _, b := range brks {
+			s.target.RemovePCBreak(b.addr, b.bank)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// bankSource is the disassembly of a single cartridge bank presented as a
+// source file. the source reference of a bank is the bank number plus one
+// because a source reference of zero has special meaning in the protocol
+type bankSource struct {
+	bank    int
+	content string
+
+	// the address of the instruction on each line. indexed by line number
+	// minus one. a label line refers to the address of the instruction that
+	// follows it
+	addresses []uint16
+
+	// the label on each line. an empty string if the line is not a label line
+	labels []string
+
+	// the line number of each instruction. indexed by address masked with
+	// memorymap.CartridgeBits
+	lines map[uint16]int
+}
+
+func sourceReference(bank int) int {
+	return bank + 1
+}
+
+func sourceBank(ref int) int {
+	return ref - 1
+}
+
+// newBankSource creates a bankSource for the bank. returns nil if the bank does
+// not exist in the disassembly
+func newBankSource(dsm *disassembly.Disassembly, bank int) *bankSource {
+	src := &bankSource{
+		bank:  bank,
+		lines: make(map[uint16]int),
+	}
+
+	var ok bool
+	var b strings.Builder
+
+	dsm.BorrowDisasm(func(d *disassembly.DisasmEntries) {
+		if d == nil || bank < 0 || bank >= len(d.Entries) {
+			return
+		}
+		ok = true
+
+		for _, e := range d.Entries[bank] {
+			if e == nil || e.Level < disassembly.EntryLevelBlessed {
+				continue
+			}
+
+			addr := e.Result.Address
+
+			if l := e.Label.Resolve(); l != "" {
+				b.WriteString(l)
+				b.WriteString("\n")
+				src.addresses = append(src.addresses, addr)
+				src.labels = append(src.labels, l)
+			}
+
+			b.WriteString("\t")
+			b.WriteString(strings.TrimRight(e.StringColumnated(disassembly.ColumnAttr{ByteCode: true}), " "))
+			b.WriteString("\n")
+			src.addresses = append(src.addresses, addr)
+			src.labels = append(src.labels, "")
+			src.lines[addr&memorymap.CartridgeBits] = len(src.addresses)
+		}
+	})
+
+	if !ok {
+		return nil
+	}
+
+	src.content = b.String()
+	return src
+}
+
+func (src *bankSource) source() *source {
+	return &source{
+		Name:            fmt.Sprintf("bank %d", src.bank),
+		SourceReference: sourceReference(src.bank),
+	}
+}
+
+// address returns the instruction address for the line. returns false if the
+// line does not exist
+func (src *bankSource) address(line int) (uint16, bool) {
+	if line < 1 || line > len(src.addresses) {
+		return 0, false
+	}
+	return src.addresses[line-1], true
+}
+
+// line returns the line number of the instruction at the address. returns
+// false if the address is not the start of an instruction in the source
+func (src *bankSource) line(addr uint16) (int, bool) {
+	l, ok := src.lines[addr&memorymap.CartridgeBits]
+	return l, ok
+}
+
+// routine returns the name of the routine that contains the line. the name is
+// the nearest label at or before the line with an offset if necessary. returns
+// the empty string if there is no label before the line
+func (src *bankSource) routine(line int) string {
+	addr, ok := src.address(line)
+	if !ok {
+		return ""
+	}
+
+	for l := line - 1; l >= 0; l-- {
+		if src.labels[l] != "" {
+			d := addr - src.addresses[l]
+			if d == 0 {
+				return src.labels[l]
+			}
+			return fmt.Sprintf("%s+%d", src.labels[l], d)
+		}
+	}
+
+	return ""
+}
+
+// the sources for cartridge banks are created on demand and remain the same
+// for the duration of the connection. this is so that line numbers remain
+// consistent with the source content already sent to the client
+func (s *Server) bankSource(bank int) *bankSource {
+	if src, ok := s.sources[bank]; ok {
+		return src
+	}
+
+	var dsm *disassembly.Disassembly
+	if err := s.exec(func() {
+		dsm = s.target.Disassembly()
+	}); err != nil || dsm == nil {
+		return nil
+	}
+
+	src := newBankSource(dsm, bank)
+	if src != nil {
+		s.sources[bank] = src
+	}
+	return src
+}
@@ -0,0 +1,167 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package dap
+
+// the types in this file are the request arguments and response bodies used by
+// the server. only the fields used by the server are defined
+
+type capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsFunctionBreakpoints      bool `json:"supportsFunctionBreakpoints"`
+	SupportsInstructionBreakpoints   bool `json:"supportsInstructionBreakpoints"`
+	SupportsSetVariable              bool `json:"supportsSetVariable"`
+	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+type stoppedEvent struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description,omitempty"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+}
+
+type continuedEvent struct {
+	ThreadID            int  `json:"threadId"`
+	AllThreadsContinued bool `json:"allThreadsContinued"`
+}
+
+type source struct {
+	Name            string `json:"name,omitempty"`
+	Path            string `json:"path,omitempty"`
+	SourceReference int    `json:"sourceReference,omitempty"`
+}
+
+type sourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+type setBreakpointsArguments struct {
+	Source      source             `json:"source"`
+	Breakpoints []sourceBreakpoint `json:"breakpoints"`
+}
+
+type functionBreakpoint struct {
+	Name string `json:"name"`
+}
+
+type setFunctionBreakpointsArguments struct {
+	Breakpoints []functionBreakpoint `json:"breakpoints"`
+}
+
+type instructionBreakpoint struct {
+	InstructionReference string `json:"instructionReference"`
+	Offset               int    `json:"offset"`
+}
+
+type setInstructionBreakpointsArguments struct {
+	Breakpoints []instructionBreakpoint `json:"breakpoints"`
+}
+
+type breakpoint struct {
+	Verified             bool    `json:"verified"`
+	Message              string  `json:"message,omitempty"`
+	Source               *source `json:"source,omitempty"`
+	Line                 int     `json:"line,omitempty"`
+	InstructionReference string  `json:"instructionReference,omitempty"`
+}
+
+type breakpointsBody struct {
+	Breakpoints []breakpoint `json:"breakpoints"`
+}
+
+type thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type threadsBody struct {
+	Threads []thread `json:"threads"`
+}
+
+type stackFrame struct {
+	ID                          int     `json:"id"`
+	Name                        string  `json:"name"`
+	Source                      *source `json:"source,omitempty"`
+	Line                        int     `json:"line"`
+	Column                      int     `json:"column"`
+	InstructionPointerReference string  `json:"instructionPointerReference,omitempty"`
+}
+
+type stackTraceBody struct {
+	StackFrames []stackFrame `json:"stackFrames"`
+	TotalFrames int          `json:"totalFrames"`
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	NamedVariables     int    `json:"namedVariables,omitempty"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type scopesBody struct {
+	Scopes []scope `json:"scopes"`
+}
+
+type variablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	EvaluateName       string `json:"evaluateName,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type variablesBody struct {
+	Variables []variable `json:"variables"`
+}
+
+type setVariableArguments struct {
+	VariablesReference int    `json:"variablesReference"`
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+}
+
+type setVariableBody struct {
+	Value string `json:"value"`
+}
+
+type sourceArguments struct {
+	Source          *source `json:"source"`
+	SourceReference int     `json:"sourceReference"`
+}
+
+type sourceBody struct {
+	Content  string `json:"content"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+type evaluateArguments struct {
+	Expression string `json:"expression"`
+}
+
+type evaluateBody struct {
+	Result             string `json:"result"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type continueBody struct {
+	AllThreadsContinued bool `json:"allThreadsContinued"`
+}
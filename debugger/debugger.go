@@ -24,19 +24,26 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/jetsetilly/gopher2600/bots/wrangler"
 	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/cheats"
 	"github.com/jetsetilly/gopher2600/comparison"
 	"github.com/jetsetilly/gopher2600/coprocessor"
 	coproc_dev "github.com/jetsetilly/gopher2600/coprocessor/developer"
 	coproc_dwarf "github.com/jetsetilly/gopher2600/coprocessor/developer/dwarf"
 	coproc_disasm "github.com/jetsetilly/gopher2600/coprocessor/disassembly"
+	"github.com/jetsetilly/gopher2600/coverage"
+	"github.com/jetsetilly/gopher2600/debugger/dap"
 	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/debugger/gdbstub"
 	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/debugger/profiler"
+	"github.com/jetsetilly/gopher2600/debugger/rammap"
 	"github.com/jetsetilly/gopher2600/debugger/script"
 	"github.com/jetsetilly/gopher2600/debugger/terminal"
 	"github.com/jetsetilly/gopher2600/debugger/terminal/commandline"
@@ -51,6 +58,7 @@ import (
 	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
 	"github.com/jetsetilly/gopher2600/hardware/television"
 	"github.com/jetsetilly/gopher2600/hardware/television/coords"
+	"github.com/jetsetilly/gopher2600/library"
 	"github.com/jetsetilly/gopher2600/logger"
 	"github.com/jetsetilly/gopher2600/macro"
 	"github.com/jetsetilly/gopher2600/notifications"
@@ -126,6 +134,12 @@ type Debugger struct {
 	// stella.Properties file support
 	Properties properties.Properties
 
+	// the ROM library. the library is not loaded until it is required. use
+	// the Library() function to access it
+	library     *library.Library
+	libraryErr  error
+	libraryCrit sync.Mutex
+
 	// bots coordinator
 	bots *wrangler.Bots
 
@@ -143,6 +157,38 @@ type Debugger struct {
 	CoProcDisasm coproc_disasm.Disassembly
 	CoProcDev    coproc_dev.Developer
 
+	// GDB remote stub for the coprocessor. will be nil if the stub has not
+	// been started
+	gdb *gdbstub.Stub
+
+	// Debug Adapter Protocol server for the 6507. will be nil if the server
+	// has not been started
+	dap *dap.Server
+
+	// file that every executed CPU instruction is written to. will be nil if
+	// tracing to file is not active
+	traceFile *traceFile
+
+	// profiler for the 6507 program. will be nil if profiling is not active
+	profiler *profiler.Profiler
+
+	// code coverage of the 6507 program. will be nil if coverage is not being
+	// recorded
+	coverage *coverage.Coverage
+
+	// usage of RIOT RAM and cartridge RAM. will be nil if the RAM map is not
+	// being recorded
+	ramMap *rammap.Map
+
+	// cheats for the current cartridge and the current RAM search. the RAM
+	// search will be nil if a search has not been started
+	cheats    *cheats.Cheats
+	ramSearch *cheats.Search
+
+	// changes made to the cartridge with the ASSEMBLE command. the most
+	// recent change is at the end of the list
+	assembleUndo []assembleEdit
+
 	// the live disassembly entry. updated every CPU step or on halt (which may
 	// be mid instruction). it is also updated by the LAST command when the
 	// debugger is in the CLOCK quantum
@@ -395,6 +441,10 @@ func NewDebugger(opts CommandLineOptions, create CreateUserInterface) (*Debugger
 	dbg.CoProcDev = coproc_dev.NewDeveloper(dbg, dbg.vcs.TV)
 	dbg.vcs.TV.AddFrameTrigger(&dbg.CoProcDev)
 
+	// cheats are applied at the start of every frame
+	dbg.cheats = cheats.NewCheats(dbg.vcs.Mem)
+	dbg.vcs.TV.AddFrameTrigger(dbg.cheats)
+
 	// create a minimal lastResult for initialisation
 	dbg.liveDisasmEntry = &disassembly.Entry{Result: execution.Result{Final: true}}
 
@@ -487,6 +537,30 @@ func NewDebugger(opts CommandLineOptions, create CreateUserInterface) (*Debugger
 		return nil, fmt.Errorf("debugger: %w", err)
 	}
 
+	// start GDB stub if requested
+	if opts.GDB != "" {
+		err = dbg.startGDB(opts.GDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// start DAP server if requested
+	if opts.DAP != "" {
+		err = dbg.startDAP(opts.DAP)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// start writing CPU trace if requested
+	if opts.TraceFile != "" {
+		err = dbg.startTraceFile(opts.TraceFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return dbg, nil
 }
 
@@ -505,6 +579,25 @@ func (dbg *Debugger) Debugger() *Debugger {
 	return dbg
 }
 
+// Library returns the ROM library. The library is loaded the first time the
+// function is called. Returns nil if the library could not be loaded.
+//
+// Safe to call from any goroutine.
+func (dbg *Debugger) Library() *library.Library {
+	dbg.libraryCrit.Lock()
+	defer dbg.libraryCrit.Unlock()
+
+	// don't try to load the library again if it has failed once
+	if dbg.library == nil && dbg.libraryErr == nil {
+		dbg.library, dbg.libraryErr = library.Load()
+		if dbg.libraryErr != nil {
+			logger.Logf(logger.Allow, "debugger", dbg.libraryErr.Error())
+		}
+	}
+
+	return dbg.library
+}
+
 // UserInput implements the emulation.Emulation interface.
 func (dbg *Debugger) UserInput() chan userinput.Event {
 	return dbg.events.UserInput
@@ -567,8 +660,18 @@ func (dbg *Debugger) setState(state govern.State, subState govern.SubState) {
 	}
 	dbg.CoProcDev.SetEmulationState(state)
 
+	prevState := dbg.State()
 	dbg.state.Store(state)
 	dbg.subState.Store(subState)
+
+	// inform DAP client of the change in state
+	if dbg.dap != nil {
+		if state == govern.Paused && prevState != govern.Paused {
+			dbg.dap.Stopped(dbg.dapStopReason(), "")
+		} else if state == govern.Running && prevState == govern.Paused {
+			dbg.dap.Continued()
+		}
+	}
 }
 
 // set the emulation mode
@@ -665,6 +768,9 @@ func (dbg *Debugger) setMode(mode govern.Mode) error {
 
 // End cleans up any resources that may be dangling.
 func (dbg *Debugger) end() {
+	dbg.stopGDB()
+	dbg.stopDAP()
+	dbg.stopTraceFile()
 	dbg.endPlayback()
 	dbg.endRecording()
 	dbg.endComparison()
@@ -719,6 +825,15 @@ func (dbg *Debugger) StartInDebugMode(filename string) error {
 		return fmt.Errorf("debugger: %w", err)
 	}
 
+	// apply patch if requested. note that this will be in addition to any
+	// patches applied during setup.AttachCartridge
+	if dbg.opts.PatchFile != "" {
+		_, err := patch.CartridgeMemory(dbg.vcs.Mem.Cart, dbg.opts.PatchFile)
+		if err != nil {
+			return fmt.Errorf("debugger: %w", err)
+		}
+	}
+
 	err = dbg.setMode(govern.ModeDebugger)
 	if err != nil {
 		return fmt.Errorf("debugger: %w", err)
@@ -919,6 +1034,11 @@ func (dbg *Debugger) CartYield(yield coprocessor.CoProcYieldType) coprocessor.Yi
 		return coprocessor.YieldHookContinue
 	}
 
+	// if GDB is connected then it takes control of the coprocessor
+	if dbg.gdb != nil && dbg.gdb.Connected() {
+		return dbg.gdb.Stop(yield)
+	}
+
 	dbg.halting.cartridgeYield = true
 	dbg.continueEmulation = dbg.halting.check()
 
@@ -1211,6 +1331,25 @@ func (dbg *Debugger) attachCartridge(cartload cartridgeloader.Loader) (e error)
 	// clear existing reflection and counter data
 	dbg.ref.Clear()
 	dbg.counter.Clear()
+	if dbg.profiler != nil {
+		dbg.profiler.Reset()
+	}
+	if dbg.coverage != nil {
+		dbg.coverage.Reset(dbg.vcs.Mem.Cart)
+	}
+	if dbg.ramMap != nil {
+		dbg.ramMap.Reset()
+	}
+
+	// changes made with ASSEMBLE can't be undone once the cartridge changes
+	dbg.assembleUndo = dbg.assembleUndo[:0]
+
+	// cheats are specific to the cartridge
+	dbg.ramSearch = nil
+	err = dbg.cheats.Load(dbg.vcs.Mem.Cart.Hash)
+	if err != nil {
+		logger.Logf(logger.Allow, "debugger", err.Error())
+	}
 
 	err = dbg.Disasm.FromMemory()
 	if err != nil {
@@ -1264,6 +1403,18 @@ func (dbg *Debugger) attachCartridge(cartload cartridgeloader.Loader) (e error)
 	// record the most filename as the most recent ROM loaded if appropriate
 	if !dbg.vcs.Mem.Cart.IsEjected() {
 		dbg.Prefs.RecentROM.Set(cartload.Filename)
+
+		// the cartridge is being played so update the entry in the ROM
+		// library. a cartridge can't be in the library if there is no library
+		// file so there is no need to load the library in that case
+		if library.Exists() {
+			if lib := dbg.Library(); lib != nil {
+				err = lib.Played(cartload.HashMD5)
+				if err != nil {
+					logger.Logf(logger.Allow, "debugger", err.Error())
+				}
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,46 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package expression implements a simple expression language for use by the
+// debugger. Expressions are made up of numbers, identifiers, memory references
+// and operators. For example:
+//
+//	PC == kernel && RAM[score] > $50 && SL > 190
+//
+// Numbers can be specified in decimal, in hexadecimal with either the $ or 0x
+// prefix, or in binary with the % prefix.
+//
+// Identifiers are resolved by the Resolver that is passed to Parse(). An
+// identifier is either a target, the value of which may change every time the
+// expression is evaluated, or a symbol, the value of which is fixed when the
+// expression is parsed. Targets take precedence over symbols.
+//
+// Memory references consist of an identifier and an index in square brackets.
+// The identifier names an area of memory and is resolved by the Resolver. The
+// index is an expression.
+//
+// The operators are the same as in the Go language with the same precedence.
+// From highest to lowest precedence:
+//
+//	unary:  - ! ^
+//	5:      * / % << >> &
+//	4:      + - | ^
+//	3:      == != < <= > >=
+//	2:      &&
+//	1:      ||
+//
+// All values are integers. Comparison and logical operators produce a value of
+// 1 for true and 0 for false. Any non-zero value is considered to be true.
+package expression
@@ -0,0 +1,214 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Resolver is used by Parse() to resolve the identifiers in an expression.
+// Names are passed to the Resolver exactly as they appear in the expression.
+type Resolver interface {
+	// Target returns a function that returns the current value of the named
+	// target. The bool return value is false if the name is not a target
+	Target(name string) (func() (int, error), bool)
+
+	// Symbol returns the value of the named symbol. The bool return value is
+	// false if the name is not a symbol
+	Symbol(name string) (int, bool)
+
+	// Memory returns a function that returns the value in the named area of
+	// memory at the index. The bool return value is false if the name is not
+	// an area of memory
+	Memory(name string) (func(index int) (int, error), bool)
+}
+
+// Expression is a parsed expression that can be evaluated any number of times.
+type Expression struct {
+	root node
+}
+
+// sentinal error returned by Evaluate() when the expression divides by zero
+var DivideByZero = errors.New("divide by zero")
+
+// Parse the string and return an Expression. Identifiers are resolved using
+// the Resolver.
+func Parse(s string, r Resolver) (*Expression, error) {
+	toks, err := tokenise(s)
+	if err != nil {
+		return nil, fmt.Errorf("expression: %w", err)
+	}
+
+	p := &parser{toks: toks, resolver: r}
+
+	root, err := p.parseBinary(1)
+	if err != nil {
+		return nil, fmt.Errorf("expression: %w", err)
+	}
+
+	if p.peek().typ != tokEnd {
+		return nil, fmt.Errorf("expression: unexpected %s", p.peek().text)
+	}
+
+	return &Expression{root: root}, nil
+}
+
+// Evaluate the expression and return the result.
+func (e *Expression) Evaluate() (int, error) {
+	return e.root.eval()
+}
+
+// True returns true if the expression evaluates to a non-zero value. An error
+// during evaluation is treated as a false result.
+func (e *Expression) True() bool {
+	v, err := e.root.eval()
+	return err == nil && v != 0
+}
+
+// String returns a normalised representation of the expression. Two
+// expressions that differ only in white space or in the case of identifiers
+// will have the same normalised representation.
+func (e *Expression) String() string {
+	return e.root.String()
+}
+
+type parser struct {
+	toks     []token
+	pos      int
+	resolver Resolver
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEnd {
+		p.pos++
+	}
+	return t
+}
+
+// binary operator precedence. higher values bind more tightly
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4, "|": 4, "^": 4,
+	"*": 5, "/": 5, "%": 5, "<<": 5, ">>": 5, "&": 5,
+}
+
+const maxPrecedence = 5
+
+// parse binary operators of the specified precedence or higher
+func (p *parser) parseBinary(prec int) (node, error) {
+	if prec > maxPrecedence {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(prec + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.typ != tokOperator || precedence[t.text] != prec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t.typ == tokOperator {
+		switch t.text {
+		case "-", "!", "^":
+			p.next()
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &unary{op: t.text, operand: operand}, nil
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.typ {
+	case tokNumber:
+		return &number{text: t.text, value: t.value}, nil
+
+	case tokOpenParen:
+		n, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		if p.next().typ != tokCloseParen {
+			return nil, fmt.Errorf("missing )")
+		}
+		return &paren{n: n}, nil
+
+	case tokIdent:
+		if p.peek().typ == tokOpenBracket {
+			p.next()
+
+			mem, ok := p.resolver.Memory(t.text)
+			if !ok {
+				return nil, fmt.Errorf("unknown memory area: %s", t.text)
+			}
+
+			index, err := p.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+			if p.next().typ != tokCloseBracket {
+				return nil, fmt.Errorf("missing ]")
+			}
+
+			return &memory{name: strings.ToUpper(t.text), index: index, peek: mem}, nil
+		}
+
+		if f, ok := p.resolver.Target(t.text); ok {
+			return &target{name: strings.ToUpper(t.text), value: f}, nil
+		}
+
+		if v, ok := p.resolver.Symbol(t.text); ok {
+			return &symbol{name: strings.ToUpper(t.text), value: v}, nil
+		}
+
+		return nil, fmt.Errorf("unknown identifier: %s", t.text)
+
+	case tokEnd:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %s", t.text)
+}
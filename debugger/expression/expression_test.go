@@ -0,0 +1,148 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package expression_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/debugger/expression"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+type resolver struct {
+	pc  int
+	sl  int
+	ram [128]int
+}
+
+func (r *resolver) Target(name string) (func() (int, error), bool) {
+	switch strings.ToUpper(name) {
+	case "PC":
+		return func() (int, error) { return r.pc, nil }, true
+	case "SL":
+		return func() (int, error) { return r.sl, nil }, true
+	}
+	return nil, false
+}
+
+func (r *resolver) Symbol(name string) (int, bool) {
+	switch strings.ToUpper(name) {
+	case "KERNEL":
+		return 0xf100, true
+	case "SCORE":
+		return 0x81, true
+	}
+	return 0, false
+}
+
+func (r *resolver) Memory(name string) (func(int) (int, error), bool) {
+	if strings.ToUpper(name) != "RAM" {
+		return nil, false
+	}
+	return func(idx int) (int, error) {
+		idx &= 0x7f
+		return r.ram[idx], nil
+	}, true
+}
+
+func TestArithmetic(t *testing.T) {
+	r := &resolver{}
+
+	for _, c := range []struct {
+		expr  string
+		value int
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"$10 + 0x10 + %11 + 10", 0x10 + 0x10 + 3 + 10},
+		{"10 % 3", 1},
+		{"10%%11", 1},
+		{"1 << 4 | 1", 17},
+		{"$f0 & $3c", 0x30},
+		{"-3 + 5", 2},
+		{"^0 & $ff", 0xff},
+		{"!0", 1},
+		{"!5", 0},
+		{"3 > 2 && 2 > 1", 1},
+		{"3 > 2 && 2 > 3", 0},
+		{"0 || 7", 1},
+		{"$80 & $80 == $80", 1},
+		{"KERNEL", 0xf100},
+	} {
+		e, err := expression.Parse(c.expr, r)
+		test.ExpectSuccess(t, err)
+		v, err := e.Evaluate()
+		test.ExpectSuccess(t, err)
+		test.ExpectEquality(t, v, c.value)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	r := &resolver{}
+
+	e, err := expression.Parse("PC == kernel && RAM[score] > $50 && sl > 190", r)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, e.String(), "PC == KERNEL && RAM[SCORE] > $50 && SL > 190")
+	test.ExpectEquality(t, e.True(), false)
+
+	r.pc = 0xf100
+	r.ram[1] = 0x51
+	test.ExpectEquality(t, e.True(), false)
+
+	r.sl = 191
+	test.ExpectEquality(t, e.True(), true)
+
+	r.ram[1] = 0x50
+	test.ExpectEquality(t, e.True(), false)
+}
+
+func TestErrors(t *testing.T) {
+	r := &resolver{}
+
+	for _, s := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"RAM[1",
+		"TIA[1]",
+		"unknown",
+		"1 2",
+		"1 = 2",
+		"$",
+		"%2",
+	} {
+		_, err := expression.Parse(s, r)
+		test.ExpectFailure(t, err)
+	}
+
+	e, err := expression.Parse("1 / (PC - PC)", r)
+	test.ExpectSuccess(t, err)
+	_, err = e.Evaluate()
+	test.ExpectSuccess(t, errors.Is(err, expression.DivideByZero))
+	test.ExpectEquality(t, e.True(), false)
+}
+
+func ExampleParse() {
+	r := &resolver{sl: 200}
+	e, _ := expression.Parse("sl>190&&(RAM[score]|1)==1", r)
+	fmt.Println(e)
+	fmt.Println(e.True())
+	// Output: SL > 190 && (RAM[SCORE] | 1) == 1
+	// true
+}
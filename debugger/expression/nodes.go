@@ -0,0 +1,211 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package expression
+
+import "fmt"
+
+type node interface {
+	eval() (int, error)
+	String() string
+}
+
+type number struct {
+	text  string
+	value int
+}
+
+func (n *number) eval() (int, error) {
+	return n.value, nil
+}
+
+func (n *number) String() string {
+	return n.text
+}
+
+type symbol struct {
+	name  string
+	value int
+}
+
+func (n *symbol) eval() (int, error) {
+	return n.value, nil
+}
+
+func (n *symbol) String() string {
+	return n.name
+}
+
+type target struct {
+	name  string
+	value func() (int, error)
+}
+
+func (n *target) eval() (int, error) {
+	return n.value()
+}
+
+func (n *target) String() string {
+	return n.name
+}
+
+type memory struct {
+	name  string
+	index node
+	peek  func(int) (int, error)
+}
+
+func (n *memory) eval() (int, error) {
+	i, err := n.index.eval()
+	if err != nil {
+		return 0, err
+	}
+	return n.peek(i)
+}
+
+func (n *memory) String() string {
+	return fmt.Sprintf("%s[%s]", n.name, n.index)
+}
+
+type paren struct {
+	n node
+}
+
+func (n *paren) eval() (int, error) {
+	return n.n.eval()
+}
+
+func (n *paren) String() string {
+	return fmt.Sprintf("(%s)", n.n)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type unary struct {
+	op      string
+	operand node
+}
+
+func (n *unary) eval() (int, error) {
+	v, err := n.operand.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "-":
+		return -v, nil
+	case "!":
+		return boolToInt(v == 0), nil
+	case "^":
+		return ^v, nil
+	}
+
+	panic(fmt.Sprintf("unknown unary operator: %s", n.op))
+}
+
+func (n *unary) String() string {
+	return fmt.Sprintf("%s%s", n.op, n.operand)
+}
+
+type binary struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binary) eval() (int, error) {
+	l, err := n.left.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	// logical operators are short-circuited
+	switch n.op {
+	case "&&":
+		if l == 0 {
+			return 0, nil
+		}
+	case "||":
+		if l != 0 {
+			return 1, nil
+		}
+	}
+
+	r, err := n.right.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return boolToInt(r != 0), nil
+	case "==":
+		return boolToInt(l == r), nil
+	case "!=":
+		return boolToInt(l != r), nil
+	case "<":
+		return boolToInt(l < r), nil
+	case "<=":
+		return boolToInt(l <= r), nil
+	case ">":
+		return boolToInt(l > r), nil
+	case ">=":
+		return boolToInt(l >= r), nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, DivideByZero
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, DivideByZero
+		}
+		return l % r, nil
+	case "<<":
+		if r < 0 {
+			return 0, fmt.Errorf("negative shift")
+		}
+		return l << r, nil
+	case ">>":
+		if r < 0 {
+			return 0, fmt.Errorf("negative shift")
+		}
+		return l >> r, nil
+	case "&":
+		return l & r, nil
+	}
+
+	panic(fmt.Sprintf("unknown binary operator: %s", n.op))
+}
+
+func (n *binary) String() string {
+	return fmt.Sprintf("%s %s %s", n.left, n.op, n.right)
+}
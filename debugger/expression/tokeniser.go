@@ -0,0 +1,168 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokEnd tokenType = iota
+	tokNumber
+	tokIdent
+	tokOperator
+	tokOpenParen
+	tokCloseParen
+	tokOpenBracket
+	tokCloseBracket
+)
+
+type token struct {
+	typ   tokenType
+	text  string
+	value int
+}
+
+// operators in order of length. two character operators must be matched first
+var operators = []string{
+	"==", "!=", "<=", ">=", "<<", ">>", "&&", "||",
+	"<", ">", "+", "-", "*", "/", "%", "&", "|", "^", "!",
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// tokenise the string. the % character is treated as the prefix to a binary
+// number if it appears where an operand is expected, otherwise it is the
+// modulo operator
+func tokenise(s string) ([]token, error) {
+	var toks []token
+
+	// whether the next token is expected to be an operand
+	operand := true
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+
+		case c == '(':
+			toks = append(toks, token{typ: tokOpenParen, text: "("})
+			operand = true
+			i++
+			continue
+
+		case c == ')':
+			toks = append(toks, token{typ: tokCloseParen, text: ")"})
+			operand = false
+			i++
+			continue
+
+		case c == '[':
+			toks = append(toks, token{typ: tokOpenBracket, text: "["})
+			operand = true
+			i++
+			continue
+
+		case c == ']':
+			toks = append(toks, token{typ: tokCloseBracket, text: "]"})
+			operand = false
+			i++
+			continue
+		}
+
+		// numbers
+		var base int
+		var start int
+		switch {
+		case c == '$':
+			base = 16
+			start = i + 1
+		case c == '0' && i+1 < len(s) && (s[i+1] == 'x' || s[i+1] == 'X'):
+			base = 16
+			start = i + 2
+		case c == '%' && operand:
+			base = 2
+			start = i + 1
+		case c >= '0' && c <= '9':
+			base = 10
+			start = i
+		}
+
+		if base != 0 {
+			end := start
+			for end < len(s) && isHex(s[end]) {
+				end++
+			}
+			v, err := strconv.ParseUint(s[start:end], base, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number: %s", s[i:end])
+			}
+			toks = append(toks, token{typ: tokNumber, text: s[i:end], value: int(v)})
+			operand = false
+			i = end
+			continue
+		}
+
+		// identifiers
+		if isIdentStart(c) {
+			end := i + 1
+			for end < len(s) && isIdent(s[end]) {
+				end++
+			}
+			toks = append(toks, token{typ: tokIdent, text: s[i:end]})
+			operand = false
+			i = end
+			continue
+		}
+
+		// operators
+		var op string
+		for _, o := range operators {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("unexpected character: %c", c)
+		}
+		toks = append(toks, token{typ: tokOperator, text: op})
+		operand = true
+		i += len(op)
+	}
+
+	toks = append(toks, token{typ: tokEnd})
+
+	return toks, nil
+}
@@ -0,0 +1,86 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/coprocessor"
+	"github.com/jetsetilly/gopher2600/debugger/gdbstub"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// gdbTarget is an implementation of the gdbstub.Target interface.
+type gdbTarget struct {
+	dbg *Debugger
+}
+
+// CoProc implements the gdbstub.Target interface.
+func (t gdbTarget) CoProc() coprocessor.CartCoProc {
+	bus := t.dbg.vcs.Mem.Cart.GetCoProcBus()
+	if bus == nil {
+		return nil
+	}
+	return bus.GetCoProc()
+}
+
+// AddBreakpoint implements the gdbstub.Target interface.
+func (t gdbTarget) AddBreakpoint(addr uint32) {
+	t.dbg.CoProcDev.AddBreakpoint(addr)
+}
+
+// RemoveBreakpoint implements the gdbstub.Target interface.
+func (t gdbTarget) RemoveBreakpoint(addr uint32) {
+	t.dbg.CoProcDev.RemoveBreakpoint(addr)
+}
+
+// BreakNextInstruction implements the gdbstub.Target interface.
+func (t gdbTarget) BreakNextInstruction() {
+	t.dbg.CoProcDev.BreakNextInstruction()
+}
+
+// PushFunction implements the gdbstub.Target interface.
+func (t gdbTarget) PushFunction(f func()) {
+	t.dbg.PushFunction(f)
+}
+
+// start the GDB stub on the specified address. any existing stub will be
+// stopped first
+func (dbg *Debugger) startGDB(address string) error {
+	dbg.stopGDB()
+
+	var err error
+	dbg.gdb, err = gdbstub.NewStub(address, gdbTarget{dbg: dbg})
+	if err != nil {
+		return fmt.Errorf("debugger: %w", err)
+	}
+	logger.Logf(logger.Allow, "debugger", "GDB stub listening on %s", dbg.gdb.Address())
+
+	return nil
+}
+
+// stop the GDB stub if it is running
+func (dbg *Debugger) stopGDB() {
+	if dbg.gdb == nil {
+		return
+	}
+
+	err := dbg.gdb.Close()
+	if err != nil {
+		logger.Log(logger.Allow, "debugger", err.Error())
+	}
+	dbg.gdb = nil
+}
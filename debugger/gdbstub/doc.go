@@ -0,0 +1,41 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package gdbstub implements the GDB remote serial protocol for the coprocessor
+// in a cartridge. It allows arm-none-eabi-gdb (or any front end that uses GDB)
+// to connect to the emulation over TCP.
+//
+// Once connected, GDB can read and write registers and memory, set software
+// breakpoints, single step and continue the coprocessor program.
+//
+// The Stub is created with NewStub() and will accept one connection at a time.
+// The emulation is informed of a coprocessor stop by calling the Stop()
+// function from the emulation goroutine. Stop() will not return until GDB has
+// instructed the coprocessor to continue (or until GDB disconnects). While the
+// coprocessor is stopped the rest of the emulation will also be stopped.
+//
+// Requests from GDB that require access to the emulation are always serviced
+// on the emulation goroutine. If the coprocessor is not stopped then the
+// requests are queued with the Target.PushFunction() function.
+//
+// The coprocessor is expected to be an ARM processor. The registers presented
+// to GDB are the core registers and, for ARMv7-M processors, the VFP registers.
+// The status register is not available and is reported with only the Thumb bit
+// set.
+//
+// Reference for the protocol:
+//
+// https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+package gdbstub
@@ -0,0 +1,369 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/jetsetilly/gopher2600/coprocessor"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// Target is the interface to the emulation required by the Stub. With the
+// exception of PushFunction(), the functions will only be called from the
+// emulation goroutine.
+type Target interface {
+	// the coprocessor in the current cartridge. returns nil if the cartridge
+	// does not have a coprocessor
+	CoProc() coprocessor.CartCoProc
+
+	// add and remove breakpoints on coprocessor addresses
+	AddBreakpoint(addr uint32)
+	RemoveBreakpoint(addr uint32)
+
+	// break on the next coprocessor instruction to be executed
+	BreakNextInstruction()
+
+	// run the function on the emulation goroutine. this function can be
+	// called from any goroutine
+	PushFunction(func())
+}
+
+// POSIX signal numbers used in stop replies
+const (
+	sigInt  = 2
+	sigIll  = 4
+	sigTrap = 5
+	sigSegv = 11
+)
+
+// a request from GDB waiting to be handled on the emulation goroutine
+type request struct {
+	data  string
+	reply chan string
+}
+
+// Stub is a GDB remote serial protocol server.
+type Stub struct {
+	target   Target
+	listener net.Listener
+
+	// requests from the connection goroutine to the emulation goroutine
+	requests chan request
+
+	// the current connection. only one connection is allowed at a time
+	crit sync.Mutex
+	conn net.Conn
+
+	// closed when the current connection ends
+	disconnected chan bool
+
+	// closed when the stub is closed
+	quit chan bool
+
+	// whether GDB believes the target is running. a stop reply is only sent
+	// when GDB is waiting for one. only accessed on the emulation goroutine
+	running bool
+
+	// the coprocessor was stopped because GDB sent an interrupt. only
+	// accessed on the emulation goroutine
+	interrupted bool
+
+	// the breakpoints added by GDB. these are removed from the target when
+	// GDB disconnects. only accessed on the emulation goroutine
+	breakpoints map[uint32]bool
+}
+
+// NewStub is the preferred method of initialisation for the Stub type. The
+// address is the TCP address to listen on. If the address is just a port
+// number then the stub will listen on localhost.
+func NewStub(address string, target Target) (*Stub, error) {
+	if !strings.Contains(address, ":") {
+		address = fmt.Sprintf("localhost:%s", address)
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("gdbstub: %w", err)
+	}
+
+	s := &Stub{
+		target:      target,
+		listener:    l,
+		requests:    make(chan request, 1),
+		quit:        make(chan bool),
+		breakpoints: make(map[uint32]bool),
+	}
+
+	go s.listen()
+
+	return s, nil
+}
+
+// Address returns the address the stub is listening on.
+func (s *Stub) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Close the stub. Any connected GDB will be disconnected.
+func (s *Stub) Close() error {
+	err := s.listener.Close()
+	close(s.quit)
+
+	s.crit.Lock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+	s.crit.Unlock()
+
+	return err
+}
+
+// Connected returns true if GDB is connected to the stub.
+func (s *Stub) Connected() bool {
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	return s.conn != nil
+}
+
+func (s *Stub) listen() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Logf(logger.Allow, "gdbstub", err.Error())
+			}
+			return
+		}
+
+		logger.Logf(logger.Allow, "gdbstub", "connection from %s", conn.RemoteAddr())
+
+		s.crit.Lock()
+		s.conn = conn
+		s.disconnected = make(chan bool)
+		s.crit.Unlock()
+
+		// stop the coprocessor as soon as possible so that GDB sees a
+		// consistent state
+		s.target.PushFunction(func() {
+			s.running = false
+			s.target.BreakNextInstruction()
+		})
+
+		err = s.serve(conn)
+		if err != nil {
+			logger.Logf(logger.Allow, "gdbstub", err.Error())
+		}
+
+		s.crit.Lock()
+		_ = s.conn.Close()
+		s.conn = nil
+		close(s.disconnected)
+		s.crit.Unlock()
+
+		// breakpoints belonging to GDB are no longer required
+		s.target.PushFunction(s.removeBreakpoints)
+
+		logger.Logf(logger.Allow, "gdbstub", "connection closed")
+	}
+}
+
+// serve the connection until GDB detaches or the connection is closed
+func (s *Stub) serve(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+
+	for {
+		data, ok, err := readPacket(r)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if !ok {
+			_, err = conn.Write([]byte("-"))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if data != string([]byte{interrupt}) {
+			_, err = conn.Write([]byte("+"))
+			if err != nil {
+				return err
+			}
+		}
+
+		// the kill request has no reply
+		if data == "k" {
+			s.target.PushFunction(func() {
+				s.running = false
+			})
+			return nil
+		}
+
+		reply, ok := s.request(data)
+		if !ok {
+			return nil
+		}
+
+		// an empty reply is sent for unsupported packets. a nil reply
+		// (represented by noReply) means that no reply should be sent
+		if reply != noReply {
+			err = s.send(reply)
+			if err != nil {
+				return err
+			}
+		}
+
+		if data == "D" || strings.HasPrefix(data, "D;") {
+			return nil
+		}
+	}
+}
+
+// send a packet to GDB
+func (s *Stub) send(data string) error {
+	s.crit.Lock()
+	defer s.crit.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	_, err := s.conn.Write(frame(data))
+	return err
+}
+
+// the reply used by the packet handler to indicate that nothing should be sent
+// to GDB. this is used for packets that resume execution of the coprocessor.
+// the reply in that case is sent by Stop()
+const noReply = "\x00"
+
+// request forwards the packet to the emulation goroutine and waits for the
+// reply. returns false if the stub has been closed
+func (s *Stub) request(data string) (string, bool) {
+	req := request{
+		data:  data,
+		reply: make(chan string, 1),
+	}
+
+	select {
+	case s.requests <- req:
+	case <-s.quit:
+		return "", false
+	}
+
+	// the request will be picked up either by the Stop() loop or by the
+	// service() function. it doesn't matter which
+	s.target.PushFunction(s.service)
+
+	select {
+	case reply := <-req.reply:
+		return reply, true
+	case <-s.quit:
+		return "", false
+	}
+}
+
+// service all pending requests. called on the emulation goroutine when the
+// coprocessor is not stopped
+func (s *Stub) service() {
+	for {
+		select {
+		case req := <-s.requests:
+			reply, _ := s.handle(req.data)
+			req.reply <- reply
+		default:
+			return
+		}
+	}
+}
+
+// Stop should be called on the emulation goroutine when the coprocessor has
+// yielded because of a breakpoint or an error. The function will not return
+// until GDB resumes the coprocessor or GDB disconnects.
+//
+// The return value indicates whether the coprocessor program should continue.
+func (s *Stub) Stop(yield coprocessor.CoProcYieldType) coprocessor.YieldHookResponse {
+	s.crit.Lock()
+	disconnected := s.disconnected
+	connected := s.conn != nil
+	s.crit.Unlock()
+
+	if !connected {
+		return coprocessor.YieldHookEnd
+	}
+
+	// only send a stop reply if GDB is expecting one
+	if s.running {
+		s.running = false
+
+		sig := sigTrap
+		switch yield {
+		case coprocessor.YieldBreakpoint:
+			if s.interrupted {
+				sig = sigInt
+			}
+		case coprocessor.YieldMemoryAccessError, coprocessor.YieldStackError:
+			sig = sigSegv
+		case coprocessor.YieldExecutionError, coprocessor.YieldUnimplementedFeature:
+			sig = sigIll
+		}
+
+		err := s.send(fmt.Sprintf("S%02x", sig))
+		if err != nil {
+			logger.Logf(logger.Allow, "gdbstub", err.Error())
+		}
+	}
+	s.interrupted = false
+
+	// the coprocessor can only continue after a breakpoint. for all other
+	// yield reasons the coprocessor program ends
+	resume := coprocessor.YieldHookEnd
+	if yield == coprocessor.YieldBreakpoint {
+		resume = coprocessor.YieldHookContinue
+	}
+
+	for {
+		select {
+		case req := <-s.requests:
+			reply, ok := s.handle(req.data)
+			req.reply <- reply
+			if ok {
+				return resume
+			}
+		case <-disconnected:
+			return resume
+		case <-s.quit:
+			return coprocessor.YieldHookEnd
+		}
+	}
+}
+
+// remove breakpoints added by GDB from the target
+func (s *Stub) removeBreakpoints() {
+	for addr := range s.breakpoints {
+		s.target.RemoveBreakpoint(addr)
+	}
+	clear(s.breakpoints)
+}
@@ -0,0 +1,225 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/coprocessor"
+	"github.com/jetsetilly/gopher2600/debugger/gdbstub"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// mockCoProc implements the coprocessor.CartCoProc interface
+type mockCoProc struct {
+	registers [16]uint32
+	memory    [0x100]byte
+	origin    uint32
+}
+
+func (c *mockCoProc) ProcessorID() string                                { return "ARM7TDMI" }
+func (c *mockCoProc) SetDisassembler(coprocessor.CartCoProcDisassembler) {}
+func (c *mockCoProc) SetDeveloper(coprocessor.CartCoProcDeveloper)       {}
+func (c *mockCoProc) BreakpointsEnable(bool)                             {}
+func (c *mockCoProc) StackFrame() uint32                                 { return 0 }
+
+func (c *mockCoProc) RegisterSpec() coprocessor.ExtendedRegisterSpec {
+	return coprocessor.ExtendedRegisterSpec{
+		{Name: coprocessor.ExtendedRegisterCoreGroup, Start: 0, End: 15},
+	}
+}
+
+func (c *mockCoProc) Register(register int) (uint32, bool) {
+	if register < 0 || register > 15 {
+		return 0, false
+	}
+	return c.registers[register], true
+}
+
+func (c *mockCoProc) RegisterFormatted(register int) (uint32, string, bool) {
+	v, ok := c.Register(register)
+	return v, fmt.Sprintf("%08x", v), ok
+}
+
+func (c *mockCoProc) RegisterSet(register int, value uint32) bool {
+	if register < 0 || register > 15 {
+		return false
+	}
+	c.registers[register] = value
+	return true
+}
+
+func (c *mockCoProc) Peek(addr uint32) (uint32, bool) {
+	addr -= c.origin
+	if addr >= uint32(len(c.memory)-3) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(c.memory[addr:]), true
+}
+
+func (c *mockCoProc) Poke(addr uint32, value uint32) bool {
+	addr -= c.origin
+	if addr >= uint32(len(c.memory)-3) {
+		return false
+	}
+	binary.LittleEndian.PutUint32(c.memory[addr:], value)
+	return true
+}
+
+// mockTarget implements the gdbstub.Target interface. pushed functions are
+// run on the goroutine running the emulation() function
+type mockTarget struct {
+	coproc      *mockCoProc
+	breakpoints map[uint32]bool
+	breakNext   bool
+	pushed      chan func()
+}
+
+func (t *mockTarget) CoProc() coprocessor.CartCoProc { return t.coproc }
+func (t *mockTarget) AddBreakpoint(addr uint32)      { t.breakpoints[addr] = true }
+func (t *mockTarget) RemoveBreakpoint(addr uint32)   { delete(t.breakpoints, addr) }
+func (t *mockTarget) BreakNextInstruction()          { t.breakNext = true }
+func (t *mockTarget) PushFunction(f func())          { t.pushed <- f }
+
+// the emulation goroutine
+func (t *mockTarget) emulation(quit chan bool) {
+	for {
+		select {
+		case f := <-t.pushed:
+			f()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// client is a minimal GDB client
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(data string) {
+	var sum uint8
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	_, err := c.conn.Write([]byte(fmt.Sprintf("$%s#%02x", data, sum)))
+	test.ExpectSuccess(c.t, err)
+
+	ack, err := c.r.ReadByte()
+	test.ExpectSuccess(c.t, err)
+	test.ExpectEquality(c.t, ack, '+')
+}
+
+func (c *client) receive() string {
+	_, err := c.r.ReadString('$')
+	test.ExpectSuccess(c.t, err)
+	data, err := c.r.ReadString('#')
+	test.ExpectSuccess(c.t, err)
+	_, err = c.r.Discard(2)
+	test.ExpectSuccess(c.t, err)
+	_, err = c.conn.Write([]byte("+"))
+	test.ExpectSuccess(c.t, err)
+	return strings.TrimSuffix(data, "#")
+}
+
+func (c *client) exchange(data string) string {
+	c.send(data)
+	return c.receive()
+}
+
+func TestStub(t *testing.T) {
+	coproc := &mockCoProc{origin: 0x1000}
+	coproc.registers[0] = 0x12345678
+	coproc.registers[15] = 0x1012
+	copy(coproc.memory[:], []byte{0x01, 0x02, 0x03, 0x04})
+
+	tgt := &mockTarget{
+		coproc:      coproc,
+		breakpoints: make(map[uint32]bool),
+		pushed:      make(chan func(), 10),
+	}
+
+	quit := make(chan bool)
+	defer close(quit)
+	go tgt.emulation(quit)
+
+	stub, err := gdbstub.NewStub("localhost:0", tgt)
+	test.ExpectSuccess(t, err)
+	defer stub.Close()
+
+	conn, err := net.Dial("tcp", stub.Address())
+	test.ExpectSuccess(t, err)
+	defer conn.Close()
+
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	test.ExpectEquality(t, strings.HasPrefix(c.exchange("qSupported:swbreak+"), "PacketSize="), true)
+	test.ExpectEquality(t, c.exchange("?"), "S05")
+	test.ExpectEquality(t, c.exchange("vMustReplyEmpty"), "")
+
+	// program counter is adjusted to point to the executing instruction
+	test.ExpectEquality(t, c.exchange("p0"), "78563412")
+	test.ExpectEquality(t, c.exchange("pf"), "10100000")
+	test.ExpectEquality(t, c.exchange("Pf=20100000"), "OK")
+	test.ExpectEquality(t, coproc.registers[15], uint32(0x1022))
+
+	// all registers plus the status register
+	test.ExpectEquality(t, len(c.exchange("g")), 17*8)
+
+	// memory
+	test.ExpectEquality(t, c.exchange("m1001,2"), "0203")
+	test.ExpectEquality(t, c.exchange("M1002,2:aabb"), "OK")
+	test.ExpectEquality(t, c.exchange("m1000,4"), "0102aabb")
+	test.ExpectEquality(t, c.exchange("m5000,4"), "E03")
+
+	// target description
+	test.ExpectEquality(t, strings.Contains(c.exchange("qXfer:features:read:target.xml:0,fff"), "org.gnu.gdb.arm.core"), true)
+
+	// breakpoints
+	test.ExpectEquality(t, c.exchange("Z0,1041,2"), "OK")
+	test.ExpectEquality(t, tgt.breakpoints[0x1040], true)
+
+	// continue has no immediate reply. the reply is sent when the
+	// coprocessor stops
+	c.send("c")
+	response := make(chan coprocessor.YieldHookResponse)
+	tgt.PushFunction(func() {
+		response <- stub.Stop(coprocessor.YieldBreakpoint)
+	})
+	test.ExpectEquality(t, c.receive(), "S05")
+
+	// step sets break on next instruction and resumes the coprocessor
+	c.send("s")
+	test.ExpectEquality(t, <-response, coprocessor.YieldHookContinue)
+	test.ExpectEquality(t, tgt.breakNext, true)
+
+	// detaching removes breakpoints
+	tgt.PushFunction(func() {
+		response <- stub.Stop(coprocessor.YieldBreakpoint)
+	})
+	test.ExpectEquality(t, c.receive(), "S05")
+	test.ExpectEquality(t, c.exchange("D"), "OK")
+	test.ExpectEquality(t, <-response, coprocessor.YieldHookContinue)
+	test.ExpectEquality(t, len(tgt.breakpoints), 0)
+}
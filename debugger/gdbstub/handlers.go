@@ -0,0 +1,280 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// error replies. the numbers have no meaning to GDB
+const (
+	errNoCoProc = "E01"
+	errArgument = "E02"
+	errMemory   = "E03"
+	errRegister = "E04"
+)
+
+// handle the packet data and return the reply. must only be called from the
+// emulation goroutine
+//
+// the returned bool is true if the coprocessor should resume execution
+func (s *Stub) handle(data string) (string, bool) {
+	if data == string([]byte{interrupt}) {
+		if !s.running {
+			return fmt.Sprintf("S%02x", sigInt), false
+		}
+		s.interrupted = true
+		s.target.BreakNextInstruction()
+		return noReply, false
+	}
+
+	if len(data) == 0 {
+		return "", false
+	}
+
+	args := data[1:]
+
+	switch data[0] {
+	case '?':
+		return fmt.Sprintf("S%02x", sigTrap), false
+
+	case 'q':
+		return s.query(args), false
+
+	case 'H', 'T':
+		// there is only one thread
+		return "OK", false
+
+	case 'D':
+		s.removeBreakpoints()
+		s.running = false
+		return "OK", true
+
+	case 'c':
+		if args != "" {
+			return errArgument, false
+		}
+		s.running = true
+		return noReply, true
+
+	case 's':
+		if args != "" {
+			return errArgument, false
+		}
+		s.running = true
+		s.target.BreakNextInstruction()
+		return noReply, true
+
+	case 'Z', 'z':
+		return s.breakpoint(data[0] == 'Z', args), false
+	}
+
+	// the remaining packets require a coprocessor
+	coproc := s.target.CoProc()
+	if coproc == nil {
+		switch data[0] {
+		case 'g', 'G', 'p', 'P', 'm', 'M':
+			return errNoCoProc, false
+		}
+		return "", false
+	}
+
+	switch data[0] {
+	case 'g':
+		var b strings.Builder
+		for r := 0; r < numRegisters(coproc); r++ {
+			v, ok := readRegister(coproc, r)
+			if !ok {
+				return errRegister, false
+			}
+			b.WriteString(v)
+		}
+		return b.String(), false
+
+	case 'G':
+		for r := 0; r < numRegisters(coproc); r++ {
+			w := registerWidth(r)
+			if len(args) < w {
+				return errArgument, false
+			}
+			if !writeRegister(coproc, r, args[:w]) {
+				return errRegister, false
+			}
+			args = args[w:]
+		}
+		return "OK", false
+
+	case 'p':
+		r, err := strconv.ParseUint(args, 16, 32)
+		if err != nil {
+			return errArgument, false
+		}
+		v, ok := readRegister(coproc, int(r))
+		if !ok {
+			return errRegister, false
+		}
+		return v, false
+
+	case 'P':
+		rs, v, ok := strings.Cut(args, "=")
+		if !ok {
+			return errArgument, false
+		}
+		r, err := strconv.ParseUint(rs, 16, 32)
+		if err != nil {
+			return errArgument, false
+		}
+		if !writeRegister(coproc, int(r), v) {
+			return errRegister, false
+		}
+		return "OK", false
+
+	case 'm':
+		addr, length, err := parseAddrLen(args)
+		if err != nil {
+			return errArgument, false
+		}
+		if length > maxPacketSize/2 {
+			length = maxPacketSize / 2
+		}
+
+		b := make([]byte, 0, length)
+		for i := 0; i < length; i++ {
+			v, ok := peekByte(coproc, addr+uint32(i))
+			if !ok {
+				break
+			}
+			b = append(b, v)
+		}
+
+		// a partial read is allowed but there must be at least one byte
+		if len(b) == 0 {
+			return errMemory, false
+		}
+		return hex.EncodeToString(b), false
+
+	case 'M':
+		al, v, ok := strings.Cut(args, ":")
+		if !ok {
+			return errArgument, false
+		}
+		addr, length, err := parseAddrLen(al)
+		if err != nil {
+			return errArgument, false
+		}
+		b, err := hex.DecodeString(v)
+		if err != nil || len(b) != length {
+			return errArgument, false
+		}
+		for i := range b {
+			if !pokeByte(coproc, addr+uint32(i), b[i]) {
+				return errMemory, false
+			}
+		}
+		return "OK", false
+	}
+
+	// an empty reply indicates that the packet is not supported
+	return "", false
+}
+
+// handle general query packets
+func (s *Stub) query(args string) string {
+	name, params, _ := strings.Cut(args, ":")
+
+	switch name {
+	case "Supported":
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+", maxPacketSize)
+	case "Attached":
+		return "1"
+	case "C":
+		return "QC1"
+	case "fThreadInfo":
+		return "m1"
+	case "sThreadInfo":
+		return "l"
+	case "Symbol":
+		return "OK"
+	case "Xfer":
+		// the only object supported is the target description
+		p := strings.Split(params, ":")
+		if len(p) != 4 || p[0] != "features" || p[1] != "read" {
+			return ""
+		}
+		if p[2] != "target.xml" {
+			return errArgument
+		}
+
+		coproc := s.target.CoProc()
+		if coproc == nil {
+			return errNoCoProc
+		}
+
+		offset, length, err := parseAddrLen(p[3])
+		if err != nil {
+			return errArgument
+		}
+
+		doc := targetDescription(coproc)
+		if int(offset) >= len(doc) {
+			return "l"
+		}
+		doc = doc[offset:]
+		if len(doc) <= length {
+			return fmt.Sprintf("l%s", doc)
+		}
+		return fmt.Sprintf("m%s", doc[:length])
+	}
+
+	return ""
+}
+
+// handle breakpoint insertion and removal. software and hardware breakpoints
+// are treated the same. watchpoints are not supported
+func (s *Stub) breakpoint(insert bool, args string) string {
+	p := strings.Split(args, ",")
+	if len(p) < 3 {
+		return errArgument
+	}
+
+	switch p[0] {
+	case "0", "1":
+	default:
+		return ""
+	}
+
+	addr, err := strconv.ParseUint(p[1], 16, 32)
+	if err != nil {
+		return errArgument
+	}
+
+	// the least significant bit of the address indicates Thumb mode and is
+	// not part of the address
+	a := uint32(addr) &^ 0x01
+
+	if insert {
+		s.breakpoints[a] = true
+		s.target.AddBreakpoint(a)
+	} else {
+		delete(s.breakpoints, a)
+		s.target.RemoveBreakpoint(a)
+	}
+
+	return "OK"
+}
@@ -0,0 +1,41 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub
+
+import "github.com/jetsetilly/gopher2600/coprocessor"
+
+// the coprocessor interface only allows access to memory in 32bit words. these
+// functions read and write individual bytes using the word that contains them.
+// the coprocessor is assumed to be little-endian
+
+func peekByte(coproc coprocessor.CartCoProc, addr uint32) (uint8, bool) {
+	v, ok := coproc.Peek(addr &^ 0x03)
+	if !ok {
+		return 0, false
+	}
+	return uint8(v >> ((addr & 0x03) * 8)), true
+}
+
+func pokeByte(coproc coprocessor.CartCoProc, addr uint32, data uint8) bool {
+	v, ok := coproc.Peek(addr &^ 0x03)
+	if !ok {
+		return false
+	}
+	shift := (addr & 0x03) * 8
+	v &^= 0xff << shift
+	v |= uint32(data) << shift
+	return coproc.Poke(addr&^0x03, v)
+}
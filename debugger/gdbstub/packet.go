@@ -0,0 +1,148 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the byte sent by GDB to interrupt a running target
+const interrupt = 0x03
+
+// the maximum packet size reported to GDB in response to qSupported
+const maxPacketSize = 0x4000
+
+// checksum of packet data as defined by the protocol
+func checksum(data string) uint8 {
+	var sum uint8
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// frame packet data ready for sending. characters that have special meaning in
+// the protocol are escaped
+func frame(data string) []byte {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '$', '#', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(data[i] ^ 0x20)
+		default:
+			b.WriteByte(data[i])
+		}
+	}
+	esc := b.String()
+	return []byte(fmt.Sprintf("$%s#%02x", esc, checksum(esc)))
+}
+
+// readPacket returns the data of the next packet from the reader. interrupt
+// bytes received outside of a packet are returned as a packet consisting of a
+// single interrupt byte. acknowledgement bytes are ignored
+//
+// the returned bool is false if the packet was malformed or the checksum did
+// not match
+func readPacket(r *bufio.Reader) (string, bool, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", false, err
+		}
+
+		switch c {
+		case interrupt:
+			return string([]byte{interrupt}), true, nil
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return "", false, err
+			}
+			data = strings.TrimSuffix(data, "#")
+
+			var cs [2]byte
+			_, err = r.Read(cs[:1])
+			if err == nil {
+				_, err = r.Read(cs[1:])
+			}
+			if err != nil {
+				return "", false, err
+			}
+
+			v, err := strconv.ParseUint(string(cs[:]), 16, 8)
+			if err != nil || uint8(v) != checksum(data) {
+				return "", false, nil
+			}
+
+			return unescape(data), true, nil
+		}
+	}
+}
+
+// remove escape sequences from packet data
+func unescape(data string) string {
+	if !strings.Contains(data, "}") {
+		return data
+	}
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			b.WriteByte(data[i] ^ 0x20)
+		} else {
+			b.WriteByte(data[i])
+		}
+	}
+	return b.String()
+}
+
+// registers and memory are transmitted as little-endian hex strings
+func encodeUint32(v uint32) string {
+	return hex.EncodeToString([]byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)})
+}
+
+func decodeUint32(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("register value must be 4 bytes")
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24, nil
+}
+
+// parse the "addr,length" arguments common to several packets
+func parseAddrLen(s string) (uint32, int, error) {
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("missing length")
+	}
+	addr, err := strconv.ParseUint(a, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := strconv.ParseUint(l, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(addr), int(length), nil
+}
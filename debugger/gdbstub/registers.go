@@ -0,0 +1,202 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package gdbstub
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/coprocessor"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/arm/architecture"
+)
+
+// register numbers as presented to GDB. these are the numbers used in the
+// target description and in the p and P packets
+const (
+	regPC     = 15
+	regStatus = 16
+
+	// the first of the 64bit VFP registers. there are sixteen of them
+	regD0 = 17
+
+	regFPSCR = regD0 + 16
+)
+
+// the program counter in the ARM emulation points to the instruction being
+// fetched rather than the instruction about to be executed
+const pcAdjust = 2
+
+// the value reported for the status register. the status flags are not
+// available through the coprocessor interface but the Thumb bit is important
+// because GDB uses it to decide how to disassemble instructions
+const (
+	cpsrThumb = 0x00000020
+	xpsrThumb = 0x01000000
+)
+
+// the extended register number of S0
+const extendedS0 = 64
+
+func isMProfile(coproc coprocessor.CartCoProc) bool {
+	return coproc.ProcessorID() == string(architecture.ARMv7_M)
+}
+
+func hasVFP(coproc coprocessor.CartCoProc) bool {
+	_, ok := coproc.RegisterSpec().Group("FPU")
+	return ok
+}
+
+// the number of registers presented to GDB
+func numRegisters(coproc coprocessor.CartCoProc) int {
+	if hasVFP(coproc) {
+		return regFPSCR + 1
+	}
+	return regStatus + 1
+}
+
+// targetDescription returns the XML document describing the registers of the
+// coprocessor
+func targetDescription(coproc coprocessor.CartCoProc) string {
+	var s strings.Builder
+
+	s.WriteString(`<?xml version="1.0"?>`)
+	s.WriteString(`<!DOCTYPE target SYSTEM "gdb-target.dtd">`)
+	s.WriteString(`<target version="1.0">`)
+	s.WriteString(`<architecture>arm</architecture>`)
+
+	if isMProfile(coproc) {
+		s.WriteString(`<feature name="org.gnu.gdb.arm.m-profile">`)
+	} else {
+		s.WriteString(`<feature name="org.gnu.gdb.arm.core">`)
+	}
+	for r := 0; r <= 12; r++ {
+		s.WriteString(fmt.Sprintf(`<reg name="r%d" bitsize="32" regnum="%d"/>`, r, r))
+	}
+	s.WriteString(`<reg name="sp" bitsize="32" type="data_ptr" regnum="13"/>`)
+	s.WriteString(`<reg name="lr" bitsize="32" regnum="14"/>`)
+	s.WriteString(fmt.Sprintf(`<reg name="pc" bitsize="32" type="code_ptr" regnum="%d"/>`, regPC))
+	if isMProfile(coproc) {
+		s.WriteString(fmt.Sprintf(`<reg name="xpsr" bitsize="32" regnum="%d"/>`, regStatus))
+	} else {
+		s.WriteString(fmt.Sprintf(`<reg name="cpsr" bitsize="32" regnum="%d"/>`, regStatus))
+	}
+	s.WriteString(`</feature>`)
+
+	if hasVFP(coproc) {
+		s.WriteString(`<feature name="org.gnu.gdb.arm.vfp">`)
+		for r := 0; r < 16; r++ {
+			s.WriteString(fmt.Sprintf(`<reg name="d%d" bitsize="64" type="ieee_double" regnum="%d"/>`, r, regD0+r))
+		}
+		s.WriteString(fmt.Sprintf(`<reg name="fpscr" bitsize="32" type="int" group="float" regnum="%d"/>`, regFPSCR))
+		s.WriteString(`</feature>`)
+	}
+
+	s.WriteString(`</target>`)
+
+	return s.String()
+}
+
+// readRegister returns the hex encoded value of the register
+func readRegister(coproc coprocessor.CartCoProc, reg int) (string, bool) {
+	switch {
+	case reg < regPC:
+		v, ok := coproc.Register(reg)
+		return encodeUint32(v), ok
+
+	case reg == regPC:
+		v, ok := coproc.Register(reg)
+		return encodeUint32(v - pcAdjust), ok
+
+	case reg == regStatus:
+		if isMProfile(coproc) {
+			return encodeUint32(xpsrThumb), true
+		}
+		return encodeUint32(cpsrThumb), true
+
+	case reg < regFPSCR && hasVFP(coproc):
+		// each double precision register is made up of two single precision
+		// registers with the lower numbered register in the least
+		// significant half
+		s := extendedS0 + (reg-regD0)*2
+		lo, ok := coproc.Register(s)
+		if !ok {
+			return "", false
+		}
+		hi, ok := coproc.Register(s + 1)
+		if !ok {
+			return "", false
+		}
+		return encodeUint32(lo) + encodeUint32(hi), true
+
+	case reg == regFPSCR && hasVFP(coproc):
+		return encodeUint32(0), true
+	}
+
+	return "", false
+}
+
+// writeRegister sets the register to the hex encoded value. writes to the
+// status registers are ignored
+func writeRegister(coproc coprocessor.CartCoProc, reg int, value string) bool {
+	switch {
+	case reg < regPC:
+		v, err := decodeUint32(value)
+		if err != nil {
+			return false
+		}
+		return coproc.RegisterSet(reg, v)
+
+	case reg == regPC:
+		v, err := decodeUint32(value)
+		if err != nil {
+			return false
+		}
+		return coproc.RegisterSet(reg, v+pcAdjust)
+
+	case reg == regStatus:
+		_, err := decodeUint32(value)
+		return err == nil
+
+	case reg < regFPSCR && hasVFP(coproc):
+		if len(value) != 16 {
+			return false
+		}
+		lo, err := decodeUint32(value[:8])
+		if err != nil {
+			return false
+		}
+		hi, err := decodeUint32(value[8:])
+		if err != nil {
+			return false
+		}
+		s := extendedS0 + (reg-regD0)*2
+		return coproc.RegisterSet(s, lo) && coproc.RegisterSet(s+1, hi)
+
+	case reg == regFPSCR && hasVFP(coproc):
+		_, err := decodeUint32(value)
+		return err == nil
+	}
+
+	return false
+}
+
+// the width of the register in characters when hex encoded
+func registerWidth(reg int) int {
+	if reg >= regD0 && reg < regFPSCR {
+		return 16
+	}
+	return 8
+}
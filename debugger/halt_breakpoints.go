@@ -61,16 +61,47 @@ type breaker struct {
 
 	// single linked list ANDs breakers together
 	next *breaker
+
+	// the number of times the break condition has been matched
+	hits int
+
+	// the number of matches to ignore before the breaker causes a halt
+	ignore int
+
+	// disable the breaker after it has caused a halt
+	once bool
+
+	// a disabled breaker is never checked. breakers are disabled
+	// automatically if the once flag is set
+	disabled bool
 }
 
 func (bk breaker) String() string {
 	s := strings.Builder{}
-	s.WriteString(fmt.Sprintf("%s->%s", bk.target.label, bk.target.stringValue(bk.value)))
+	if bk.target.conditional {
+		s.WriteString(bk.target.label)
+	} else {
+		s.WriteString(fmt.Sprintf("%s->%s", bk.target.label, bk.target.stringValue(bk.value)))
+	}
 	n := bk.next
 	for n != nil {
 		s.WriteString(fmt.Sprintf(" & %s->%s", n.target.label, n.target.stringValue(n.value)))
 		n = n.next
 	}
+
+	if bk.ignore > 0 {
+		s.WriteString(fmt.Sprintf(" [ignore %d]", bk.ignore))
+	}
+	if bk.once {
+		s.WriteString(" [once]")
+	}
+	if bk.hits > 0 {
+		s.WriteString(fmt.Sprintf(" [hits %d]", bk.hits))
+	}
+	if bk.disabled {
+		s.WriteString(" [disabled]")
+	}
+
 	return s.String()
 }
 
@@ -222,7 +253,18 @@ func (bp *breakpoints) check() string {
 			continue // for loop
 		}
 
+		if bp.breaks[i].disabled {
+			continue // for loop
+		}
+
 		if bp.breaks[i].check() == checkMatch {
+			bp.breaks[i].hits++
+			if bp.breaks[i].hits <= bp.breaks[i].ignore {
+				continue // for loop
+			}
+			if bp.breaks[i].once {
+				bp.breaks[i].disabled = true
+			}
 			checkString.WriteString(fmt.Sprintf("break on %s\n", bp.breaks[i]))
 		}
 	}
@@ -258,8 +300,59 @@ func (bp breakpoints) list() {
 //
 //	& SL 100 CL 0 X 10
 //
+// a break can be preceded by the IGNORE and ONCE options. IGNORE specifies the
+// number of matches to ignore before the break causes a halt. ONCE will cause
+// the break to be disabled after it has caused a halt.
+//
+// a conditional break is specified with the IF keyword. the remaining tokens
+// are parsed as an expression (see the expression package).
+//
+//	IGNORE 10 IF PC == kernel && SL > 190
+//
 // !!TODO: simplify breakpoints parser to match help description.
 func (bp *breakpoints) parseCommand(tokens *commandline.Tokens) error {
+	// options for all new breakers
+	var ignore int
+	var once bool
+
+	for parsingOptions := true; parsingOptions; {
+		tok, _ := tokens.Peek()
+		switch strings.ToUpper(tok) {
+		case "IGNORE":
+			tokens.Get()
+			tok, _ = tokens.Get()
+			n, err := strconv.Atoi(tok)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid IGNORE count (%s)", tok)
+			}
+			ignore = n
+		case "ONCE":
+			tokens.Get()
+			once = true
+		default:
+			parsingOptions = false
+		}
+	}
+
+	if tok, ok := tokens.Peek(); ok && strings.ToUpper(tok) == "IF" {
+		tokens.Get()
+		expr := tokens.Remainder()
+		tokens.End()
+
+		tgt, err := parseConditionalTarget(bp.dbg, expr)
+		if err != nil {
+			return err
+		}
+
+		return bp.addBreakers([]breaker{{target: tgt, value: true}}, ignore, once)
+	}
+
+	// a line in the source listing is specified as file:line
+	if tok, ok := tokens.Peek(); ok && strings.Contains(tok, ":") {
+		tokens.Get()
+		return bp.parseSourceLine(tok, ignore, once)
+	}
+
 	andBreaks := false
 
 	// default target of CPU PC. meaning that "BREAK n" will cause a breakpoint
@@ -397,7 +490,9 @@ func (bp *breakpoints) parseCommand(tokens *commandline.Tokens) error {
 
 	}
 
-	for _, nb := range newBreaks {
+	for i := range newBreaks {
+		nb := &newBreaks[i]
+
 		// if the break is a singular, undecorated PC target then add a BANK
 		// condition for the current BANK. this is arguably what the user
 		// intends to happen.
@@ -410,10 +505,52 @@ func (bp *breakpoints) parseCommand(tokens *commandline.Tokens) error {
 				nb.next.skipNext = true
 			}
 		}
+	}
+
+	return bp.addBreakers(newBreaks, ignore, once)
+}
+
+// add PC breakers for every address associated with the line in the source
+// listing. a line in a macro definition will have an address for every
+// expansion of the macro
+func (bp *breakpoints) parseSourceLine(fileLine string, ignore int, once bool) error {
+	lst := bp.dbg.Disasm.Listing()
+	if lst == nil {
+		return fmt.Errorf("no source listing for cartridge")
+	}
+
+	entries, err := lst.Breakpoints(fileLine)
+	if err != nil {
+		return err
+	}
+
+	newBreaks := make([]breaker, 0, len(entries))
+	for _, e := range entries {
+		newBreaks = append(newBreaks, bp.pcBreaker(e.Address, e.Bank))
+	}
+
+	return bp.addBreakers(newBreaks, ignore, once)
+}
+
+// add new breakers to the list of breakpoints with the ignore and once options.
+// if an equivalent breaker already exists and is disabled then it is enabled
+// again with the new options.
+func (bp *breakpoints) addBreakers(newBreaks []breaker, ignore int, once bool) error {
+	for _, nb := range newBreaks {
+		nb.ignore = ignore
+		nb.once = once
 
 		if i := bp.checkBreaker(nb); i != noBreakEqualivalent {
-			return fmt.Errorf("already exists (%s)", bp.breaks[i])
+			if !bp.breaks[i].disabled {
+				return fmt.Errorf("already exists (%s)", bp.breaks[i])
+			}
+			bp.breaks[i].disabled = false
+			bp.breaks[i].hits = 0
+			bp.breaks[i].ignore = ignore
+			bp.breaks[i].once = once
+			continue // for loop
 		}
+
 		bp.breaks = append(bp.breaks, nb)
 	}
 
@@ -487,7 +624,13 @@ func (bp *breakpoints) togglePCBreak(e *disassembly.Entry) {
 	}
 
 	// no equivalent breakpoint existed so add one
-	ai := bp.dbg.dbgmem.GetAddressInfo(e.Result.Address, true)
+	bp.addPCBreak(e.Result.Address, e.Bank)
+}
+
+// pcBreaker returns a breaker for the address and bank. a negative bank number
+// means that the breaker will match the address in any bank.
+func (bp *breakpoints) pcBreaker(addr uint16, bank int) breaker {
+	ai := bp.dbg.dbgmem.GetAddressInfo(addr, true)
 	nb := breaker{
 		target: bp.checkPcBreak,
 
@@ -495,16 +638,39 @@ func (bp *breakpoints) togglePCBreak(e *disassembly.Entry) {
 		value: int(ai.MappedAddress),
 	}
 
-	if bp.dbg.vcs.Mem.Cart.NumBanks() > 1 {
+	if bank >= 0 && bp.dbg.vcs.Mem.Cart.NumBanks() > 1 {
 		nb.next = &breaker{
 			target: bp.checkBankBreak,
 
 			// see above for casting commentary
-			value: e.Bank,
+			value: bank,
 		}
 	}
 
+	return nb
+}
+
+// addPCBreak adds a breakpoint for the address and bank. a negative bank
+// number means that the breakpoint will match the address in any bank. returns
+// false if an equivalent breakpoint already exists.
+func (bp *breakpoints) addPCBreak(addr uint16, bank int) bool {
+	nb := bp.pcBreaker(addr, bank)
+	if bp.checkBreaker(nb) != noBreakEqualivalent {
+		return false
+	}
 	bp.breaks = append(bp.breaks, nb)
+	return true
+}
+
+// removePCBreak removes the breakpoint added with addPCBreak(). returns false
+// if there was no equivalent breakpoint.
+func (bp *breakpoints) removePCBreak(addr uint16, bank int) bool {
+	i := bp.checkBreaker(bp.pcBreaker(addr, bank))
+	if i == noBreakEqualivalent {
+		return false
+	}
+	_ = bp.drop(i) // ignoring errors
+	return true
 }
 
 // CheckBreakpoints is a minimal interface to Breakpoints
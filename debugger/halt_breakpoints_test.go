@@ -53,4 +53,25 @@ func (trm *mockTerm) testBreakpoints() {
 
 	trm.sndInput("BREAK CL 100")
 	trm.cmpOutput("")
+
+	// conditional breaks
+	trm.sndInput("BREAK IF SL > 190 && RAM[$81] == $00")
+	trm.cmpOutput("")
+
+	trm.sndInput("LIST BREAKS")
+	trm.cmpOutput(" 3: IF SL > 190 && RAM[$81] == 0x00")
+
+	// the expression is normalised so this is the same condition
+	trm.sndInput("BREAK IF sl>190 && ram[$81]==0x00")
+	trm.cmpOutput("already exists (IF SL > 190 && RAM[$81] == 0x00)")
+
+	trm.sndInput("BREAK IF SL >")
+	trm.cmpOutput("expression: unexpected end of expression")
+
+	// break options
+	trm.sndInput("BREAK IGNORE 3 ONCE FR 10")
+	trm.cmpOutput("")
+
+	trm.sndInput("LIST BREAKS")
+	trm.cmpOutput(" 4: Frame->10 [ignore 3] [once]")
 }
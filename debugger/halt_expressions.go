@@ -0,0 +1,177 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/debugger/expression"
+	"github.com/jetsetilly/gopher2600/debugger/terminal/commandline"
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// exprResolver is an implementation of the expression.Resolver interface. it
+// notes the properties of the targets used in the expression so that a
+// conditional target can be created with the correct properties
+type exprResolver struct {
+	dbg *Debugger
+
+	// at least one target in the expression requires checking only on an
+	// instruction boundary
+	instructionBoundary bool
+
+	// at least one target in the expression is not suitable for playmode
+	notInPlaymode bool
+}
+
+// Target implements the expression.Resolver interface.
+func (r *exprResolver) Target(name string) (func() (int, error), bool) {
+	// the PC target for expressions is not normalised in the way it is for
+	// the PC target used by regular breakpoints. this is so that the PC can
+	// be compared with the addresses seen in the disassembly
+	if strings.ToUpper(name) == "PC" {
+		r.instructionBoundary = true
+		return func() (int, error) {
+			pc := r.dbg.vcs.CPU.PC.Address()
+			if r.dbg.vcs.Mem.Cart.GetBank(pc).ExecutingCoprocessor {
+				return 0, nil
+			}
+			return int(pc), nil
+		}, true
+	}
+
+	trg, err := parseTarget(r.dbg, commandline.TokeniseInput(name))
+	if err == nil && trg != nil {
+		// only targets with numeric or boolean values can be used in an
+		// expression
+		switch trg.value().(type) {
+		case int, bool:
+		default:
+			return nil, false
+		}
+
+		r.instructionBoundary = r.instructionBoundary || trg.instructionBoundary
+		r.notInPlaymode = r.notInPlaymode || trg.notInPlaymode
+
+		return func() (int, error) {
+			switch v := trg.value().(type) {
+			case int:
+				return v, nil
+			case bool:
+				if v {
+					return 1, nil
+				}
+				return 0, nil
+			}
+			return 0, fmt.Errorf("%s is not numeric", trg.label)
+		}, true
+	}
+
+	// labels are treated as targets rather than symbols. the value of the
+	// label is the address in the same mirror as the current PC value. this
+	// means that a label can be compared to the PC without regard for which
+	// mirror the program is running in
+	res := r.dbg.Disasm.Sym.SearchBySymbol(name, symbols.SearchLabel)
+	if res != nil {
+		r.instructionBoundary = true
+		return func() (int, error) {
+			pc := r.dbg.vcs.CPU.PC.Address()
+			return int(res.Address&memorymap.CartridgeBits | pc&^memorymap.CartridgeBits), nil
+		}, true
+	}
+
+	return nil, false
+}
+
+// Symbol implements the expression.Resolver interface.
+func (r *exprResolver) Symbol(name string) (int, bool) {
+	res := r.dbg.Disasm.Sym.SearchBySymbol(name, symbols.SearchRead)
+	if res == nil {
+		res = r.dbg.Disasm.Sym.SearchBySymbol(name, symbols.SearchWrite)
+	}
+	if res == nil {
+		return 0, false
+	}
+	return int(res.Address), true
+}
+
+// Memory implements the expression.Resolver interface.
+func (r *exprResolver) Memory(name string) (func(int) (int, error), bool) {
+	var area memorymap.Area
+	var origin uint16
+
+	switch strings.ToUpper(name) {
+	case "RAM":
+		area = memorymap.RAM
+		origin = memorymap.OriginRAM
+	case "TIA":
+		area = memorymap.TIA
+		origin = memorymap.OriginTIA
+	case "RIOT":
+		area = memorymap.RIOT
+		origin = memorymap.OriginRIOT
+	case "MEM":
+		return func(index int) (int, error) {
+			ai, err := r.dbg.dbgmem.Peek(uint16(index))
+			if err != nil {
+				return 0, err
+			}
+			return int(ai.Data), nil
+		}, true
+	default:
+		return nil, false
+	}
+
+	return func(index int) (int, error) {
+		// the index can be an address or an offset from the origin of the area
+		address := uint16(index)
+		if !memorymap.IsArea(address, area) {
+			address += origin
+		}
+		if !memorymap.IsArea(address, area) {
+			return 0, fmt.Errorf("%#04x is not a %s address", index, area)
+		}
+
+		ai, err := r.dbg.dbgmem.Peek(address)
+		if err != nil {
+			return 0, err
+		}
+		return int(ai.Data), nil
+	}, true
+}
+
+// parseConditionalTarget parses the expression and returns a target with a
+// value that is true when the expression is true
+func parseConditionalTarget(dbg *Debugger, s string) (*target, error) {
+	r := &exprResolver{dbg: dbg}
+
+	expr, err := expression.Parse(s, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &target{
+		label: fmt.Sprintf("IF %s", expr),
+		value: func() targetValue {
+			return expr.True()
+		},
+		instructionBoundary: r.instructionBoundary,
+		notInPlaymode:       r.notInPlaymode,
+		conditional:         true,
+	}, nil
+}
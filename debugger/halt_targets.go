@@ -48,6 +48,10 @@ type target struct {
 	// this target will always break in playmode almost immediately. we use
 	// this flag to decide whether to allow the debugger to switch to playmode
 	notInPlaymode bool
+
+	// the target is an expression and the value is true when the expression
+	// is true. see parseConditionalTarget()
+	conditional bool
 }
 
 // returns value() formated by the format string. accepts a target value as an
@@ -208,6 +212,31 @@ func parseTarget(dbg *Debugger, tokens *commandline.Tokens) (*target, error) {
 		case "BANK":
 			trg = bankTarget(dbg)
 
+		// the line in the source listing of the current instruction. only
+		// useful as a trap (and with STEP) because the value changes every
+		// time a different line is executed
+		case "LINE":
+			trg = &target{
+				label: "Line",
+				value: func() targetValue {
+					lst := dbg.Disasm.Listing()
+					if lst == nil {
+						return ""
+					}
+					bank := dbg.vcs.Mem.Cart.GetBank(dbg.vcs.CPU.PC.Address())
+					if bank.ExecutingCoprocessor || bank.NonCart {
+						return ""
+					}
+					e := lst.Entry(bank.Number, dbg.vcs.CPU.PC.Address())
+					if e == nil {
+						return ""
+					}
+					return e.Caller.String()
+				},
+				instructionBoundary: true,
+				notInPlaymode:       true,
+			}
+
 		// cpu instruction targeting was originally added as an experiment, to
 		// help investigate a bug in the emulation. I don't think it's much use
 		// but it was an instructive exercise and may come in useful one day.
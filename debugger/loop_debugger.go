@@ -481,10 +481,20 @@ func (dbg *Debugger) step(inputter terminal.Input, catchup bool) error {
 		dbg.cpuBoundaryLastInstruction = dbg.vcs.TV.GetCoords()
 	}
 
+	// instructions are not traced or profiled during catchup because they
+	// will have been seen already
+	if !catchup {
+		dbg.beginInstruction()
+	}
+
 	// not using the err variable because we'll clobber it before we
 	// get to check the result of VCS.Step()
 	stepErr := dbg.vcs.Step(callback)
 
+	if !catchup {
+		dbg.endInstruction()
+	}
+
 	// check halt condition again now that the instruction has finished (the
 	// Final flag is true). this does mean that some breakpoints/traps are
 	// matched twice but that's not currently a problem
@@ -634,3 +644,38 @@ func (dbg *Debugger) handleInterrupt(inputter terminal.Input) {
 		dbg.running = false
 	}
 }
+
+// beginInstruction and endInstruction are called either side of the CPU
+// executing an instruction (or a single cycle if the CPU is not ready). they
+// are used by the subsystems that need to see every instruction
+func (dbg *Debugger) beginInstruction() {
+	if dbg.traceFile != nil {
+		dbg.traceFile.begin()
+	}
+	if dbg.profiler != nil {
+		dbg.profiler.Begin()
+	}
+	if dbg.coverage != nil {
+		dbg.coverage.Begin(dbg.vcs)
+	}
+	if dbg.ramMap != nil {
+		dbg.ramMap.Begin()
+	}
+}
+
+func (dbg *Debugger) endInstruction() {
+	if dbg.traceFile != nil {
+		if !dbg.traceFile.end() {
+			dbg.stopTraceFile()
+		}
+	}
+	if dbg.profiler != nil {
+		dbg.profiler.End()
+	}
+	if dbg.coverage != nil {
+		dbg.coverage.End(dbg.vcs)
+	}
+	if dbg.ramMap != nil {
+		dbg.ramMap.End()
+	}
+}
@@ -61,8 +61,15 @@ func (dbg *Debugger) playLoop() error {
 	// update lastBank at the start of the play loop
 	dbg.liveBankInfo = dbg.vcs.Mem.Cart.GetBank(dbg.vcs.CPU.PC.Address())
 
+	// note the state of the CPU before the first instruction is executed
+	dbg.beginInstruction()
+
 	// run and handle events
 	return dbg.vcs.Run(func() (govern.State, error) {
+		// complete the instruction that has just been executed and note the
+		// state of the CPU for the next instruction
+		dbg.endInstruction()
+		dbg.beginInstruction()
 
 		// update counters. because of the way LastResult works we need to make
 		// sure we only use it in the event that the CPU RdyFlag is set
@@ -0,0 +1,78 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/terminal"
+	"github.com/jetsetilly/gopher2600/patch"
+)
+
+// create a patch file from the difference between the cartridge file and the
+// current contents of the cartridge. the format should be either IPS or BPS
+func (dbg *Debugger) createPatch(format string, filename string) error {
+	if dbg.cartload == nil || dbg.cartload.Filename == "" {
+		return fmt.Errorf("no cartridge inserted")
+	}
+
+	// the original data is always read from the cartridge file because the
+	// cartridge may have been patched when it was inserted
+	ld, err := cartridgeloader.NewLoaderFromFilename(dbg.cartload.Filename, dbg.cartload.Mapping, dbg.Properties)
+	if err != nil {
+		return err
+	}
+	defer ld.Close()
+
+	original, err := io.ReadAll(ld)
+	if err != nil {
+		return err
+	}
+
+	patched, err := patch.CartridgeData(dbg.vcs.Mem.Cart)
+	if err != nil {
+		return err
+	}
+
+	if len(original) != len(patched) {
+		return fmt.Errorf("cartridge data can not be compared with the cartridge file")
+	}
+
+	var data []byte
+	switch format {
+	case "IPS":
+		data, err = patch.CreateIPS(original, patched)
+	case "BPS":
+		data, err = patch.CreateBPS(original, patched)
+	default:
+		return fmt.Errorf("unsupported patch format (%s)", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filename, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	dbg.printLine(terminal.StyleFeedback, "%s patch written to %s", format, filename)
+
+	return nil
+}
@@ -0,0 +1,37 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"github.com/jetsetilly/gopher2600/debugger/profiler"
+)
+
+// start profiling the 6507 program. profiling information from any previous
+// profiling session is discarded
+func (dbg *Debugger) startProfiler() {
+	dbg.stopProfiler()
+	dbg.profiler = profiler.NewProfiler(dbg.vcs, &dbg.Disasm.Sym)
+	dbg.vcs.TV.AddFrameTrigger(dbg.profiler)
+}
+
+// stop profiling the 6507 program if the profiler is active
+func (dbg *Debugger) stopProfiler() {
+	if dbg.profiler == nil {
+		return
+	}
+	dbg.vcs.TV.RemoveFrameTrigger(dbg.profiler)
+	dbg.profiler = nil
+}
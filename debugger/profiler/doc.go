@@ -0,0 +1,51 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package profiler measures the performance of the 6507 program in the
+// cartridge. It is the 6507 equivalent of the coprocessor profiler and uses
+// the same profiling types, meaning that cycle counts are recorded for the
+// most recent frame and for the average and maximum cases, and are broken
+// down into the VBLANK, Screen and Overscan parts of the television frame.
+//
+// Cycles are accounted for in two ways. Firstly, by routine. A routine is
+// entered with a JSR instruction and left with an RTS instruction. The
+// exclusive cycles of a routine are the cycles taken by instructions while
+// the routine is at the top of the call stack. The inclusive cycles are the
+// cycles taken while the routine is anywhere in the call stack. The number of
+// times each routine is called is also recorded. Code that is outside of any
+// routine is accounted to a pseudo-routine named "(main)".
+//
+// The call stack is maintained by watching the stack pointer rather than by
+// matching RTS instructions with JSR instructions. This means that routines
+// which return by manipulating the stack, or by resetting the stack pointer,
+// are handled correctly.
+//
+// Secondly, cycles are accounted for by label. An instruction is accounted to
+// the most recent label to have been executed. In other words, the label
+// accounting follows the flow of the program rather than the layout of the
+// program in memory. When a routine returns, the label that was current when
+// the routine was called is restored. Labels are taken from the symbols
+// table, which means that labels generated by the disassembly for branch
+// targets are also used.
+//
+// In addition to the cycle accounting, the profiler records how each scanline
+// in the most recent frame was used. For each scanline the number of cycles
+// used by the CPU and the number of cycles lost waiting for WSYNC is
+// recorded, along with the instruction that triggered the WSYNC.
+//
+// The Profiler should be added to the television as a FrameTrigger. The
+// Begin() and End() functions should be called either side of every call to
+// CPU.ExecuteInstruction().
+package profiler
@@ -0,0 +1,309 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/coprocessor/developer/profiling"
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/hardware/television/coords"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the number of CPU cycles in a scanline
+const cyclesPerScanline = specification.ClksScanline / 3
+
+// the state of the emulation noted by Begin()
+type beginState struct {
+	coords coords.TelevisionCoords
+	bank   mapper.BankInfo
+	sp     uint8
+}
+
+// an entry in the call stack
+type frame struct {
+	routine *Routine
+
+	// the value of the stack pointer before the routine was called. the
+	// routine has returned once the stack pointer is back to this value
+	sp uint8
+
+	// the most recently executed label at the time the routine was called.
+	// the label is restored when the routine returns
+	label *Label
+}
+
+// Profiler records how the 6507 program uses the CPU.
+type Profiler struct {
+	vcs *hardware.VCS
+	sym *symbols.Symbols
+
+	// the program as a whole. the load figures for routines and labels are
+	// measured against this
+	Program profiling.Cycles
+
+	// the pseudo-routine for code that is not inside any other routine
+	root *Routine
+
+	routines map[location]*Routine
+	labels   map[location]*Label
+
+	// the call stack. the first entry is always the root routine
+	stack []frame
+
+	// the most recently executed label
+	label *Label
+
+	// state noted by Begin(). pending is true if the CPU is about to execute
+	// an instruction and stalled is true if the CPU is waiting for WSYNC
+	state   beginState
+	pending bool
+	stalled bool
+
+	// frame information from the most recent NewFrame()
+	frameInfo television.FrameInfo
+
+	// whether any cycles have been recorded since the most recent NewFrame()
+	active bool
+
+	// the number of frames that have been profiled
+	numFrames int
+
+	// scanline information for the current frame and for the most recently
+	// completed frame
+	current   []Scanline
+	scanlines []Scanline
+
+	// the instruction that caused the most recent WSYNC
+	wsync string
+}
+
+// NewProfiler is the preferred method of initialisation for the Profiler type.
+func NewProfiler(vcs *hardware.VCS, sym *symbols.Symbols) *Profiler {
+	p := &Profiler{
+		vcs: vcs,
+		sym: sym,
+	}
+	p.Reset()
+	return p
+}
+
+// Reset all profiling information.
+func (p *Profiler) Reset() {
+	p.Program.Reset()
+	p.root = &Routine{Name: "(main)"}
+	p.routines = make(map[location]*Routine)
+	p.labels = make(map[location]*Label)
+	p.stack = append(p.stack[:0], frame{routine: p.root, sp: 0xff})
+	p.label = nil
+	p.pending = false
+	p.stalled = false
+	p.active = false
+	p.numFrames = 0
+	p.current = p.current[:0]
+	p.scanlines = p.scanlines[:0]
+	p.wsync = ""
+}
+
+// NumFrames returns the number of frames that have been profiled.
+func (p *Profiler) NumFrames() int {
+	return p.numFrames
+}
+
+// Begin notes where the CPU is about to start an instruction. If the CPU is
+// waiting on WSYNC then the cycle is counted as a stall by End() rather than
+// as part of an instruction.
+func (p *Profiler) Begin() {
+	mc := p.vcs.CPU
+
+	p.pending = false
+	p.stalled = false
+
+	if mc.Killed {
+		return
+	}
+
+	if !mc.RdyFlg {
+		p.stalled = true
+	} else if mc.LastResult.Final || mc.Interrupted {
+		p.pending = true
+	} else {
+		return
+	}
+
+	p.state = beginState{
+		coords: p.vcs.TV.GetCoords(),
+		bank:   p.vcs.Mem.Cart.GetBank(mc.PC.Address()),
+		sp:     mc.SP.Value(),
+	}
+}
+
+// End accounts for the cycles of the instruction or stall noted by Begin()
+// and attributes them to the current routine.
+func (p *Profiler) End() {
+	if p.stalled {
+		p.stalled = false
+		p.stall(p.state.coords)
+		return
+	}
+
+	if !p.pending {
+		return
+	}
+	p.pending = false
+
+	mc := p.vcs.CPU
+	if !mc.LastResult.Final {
+		return
+	}
+
+	pc := mc.PC.Address()
+	p.instruction(p.state, mc.LastResult, mc.SP.Value(), pc, p.vcs.Mem.Cart.GetBank(pc), !mc.RdyFlg)
+}
+
+// the part of the frame that the scanline is in
+func (p *Profiler) focus(scanline int) profiling.Focus {
+	if !p.frameInfo.Stable {
+		return profiling.FocusAll
+	}
+	if scanline < p.frameInfo.VisibleTop {
+		return profiling.FocusVBLANK
+	}
+	if scanline < p.frameInfo.VisibleBottom {
+		return profiling.FocusScreen
+	}
+	return profiling.FocusOverscan
+}
+
+// instruction accounts for an instruction that has been executed. the
+// instruction began with the state noted by Begin() and the remaining
+// arguments describe the state of the CPU after the instruction. the wsync
+// argument is true if the instruction caused the CPU to wait for WSYNC
+func (p *Profiler) instruction(st beginState, res execution.Result, sp uint8, pc uint16, bank mapper.BankInfo, wsync bool) {
+	p.active = true
+
+	focus := p.focus(st.coords.Scanline)
+	cycles := float32(res.Cycles)
+
+	// the label for the instruction. if the instruction does not have a label
+	// then the previous label continues to be used
+	if e, ok := p.sym.GetLabel(st.bank.Number, res.Address); ok && !st.bank.NonCart {
+		p.label = p.getLabel(st.bank, res.Address, e.Symbol)
+	}
+
+	// cycles are accounted for before the call stack is changed. this means
+	// that the JSR instruction is accounted to the calling routine and the RTS
+	// instruction is accounted to the called routine
+	p.Program.Cycle(cycles, focus)
+	if p.label != nil {
+		p.label.Cycles.Cycle(cycles, focus)
+	}
+
+	top := p.stack[len(p.stack)-1].routine
+	top.Exclusive.Cycle(cycles, focus)
+	for i, f := range p.stack {
+		// a routine that appears in the stack more than once (a recursive
+		// routine) should only be counted once
+		var dup bool
+		for _, g := range p.stack[:i] {
+			if g.routine == f.routine {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			f.routine.Inclusive.Cycle(cycles, focus)
+		}
+	}
+
+	p.scanline(st.coords, res.Cycles)
+
+	// pop any routines that have returned
+	for len(p.stack) > 1 && sp >= p.stack[len(p.stack)-1].sp {
+		p.label = p.stack[len(p.stack)-1].label
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+
+	// push a new routine on to the call stack
+	if res.Defn != nil {
+		switch res.Defn.Operator {
+		case instructions.Jsr, instructions.Brk:
+			r := p.getRoutine(bank, pc)
+			r.Calls.Call(focus)
+			p.stack = append(p.stack, frame{routine: r, sp: st.sp, label: p.label})
+		}
+	}
+
+	// note the instruction that caused the WSYNC
+	if wsync {
+		p.wsync = p.describe(st.bank, res.Address)
+	}
+}
+
+// stall accounts for a cycle where the CPU is waiting for WSYNC
+func (p *Profiler) stall(c coords.TelevisionCoords) {
+	p.active = true
+	sl := p.scanlineEntry(c.Scanline)
+	sl.WSYNC++
+	sl.Source = p.wsync
+}
+
+// describe the address in terms of the most recent label
+func (p *Profiler) describe(bank mapper.BankInfo, addr uint16) string {
+	loc := newLocation(bank, addr)
+	if p.label == nil || p.label.bank != loc.bank {
+		return fmt.Sprintf("$%04x", addr)
+	}
+
+	offset := int(loc.addr) - int(p.label.addr)
+	if offset == 0 {
+		return fmt.Sprintf("%s ($%04x)", p.label.Name, addr)
+	}
+	return fmt.Sprintf("%s+%d ($%04x)", p.label.Name, offset, addr)
+}
+
+// NewFrame implements the television.FrameTrigger interface.
+func (p *Profiler) NewFrame(frameInfo television.FrameInfo) error {
+	// frames in which nothing was recorded are not counted. this will happen
+	// if the emulation has been rewound or if the profiler has just been
+	// started
+	if p.active {
+		p.Program.NewFrame(nil, nil, false)
+		p.root.newFrame(&p.Program)
+		for _, r := range p.routines {
+			r.newFrame(&p.Program)
+		}
+		for _, l := range p.labels {
+			l.Cycles.NewFrame(&p.Program, nil, false)
+		}
+
+		p.scanlines, p.current = p.current, p.scanlines[:0]
+		p.numFrames++
+	} else {
+		p.current = p.current[:0]
+	}
+
+	p.active = false
+	p.frameInfo = frameInfo
+
+	return nil
+}
@@ -0,0 +1,109 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/hardware/television/coords"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// execute a simulated instruction
+func execute(p *Profiler, addr uint16, operator instructions.Operator, cycles int, spBefore uint8, spAfter uint8, pcAfter uint16) {
+	st := beginState{sp: spBefore}
+	res := execution.Result{
+		Defn:    &instructions.Definition{Operator: operator},
+		Address: addr,
+		Cycles:  cycles,
+		Final:   true,
+	}
+	p.instruction(st, res, spAfter, pcAfter, mapper.BankInfo{}, false)
+}
+
+func TestCallStack(t *testing.T) {
+	p := NewProfiler(nil, &symbols.Symbols{})
+
+	execute(p, 0xf000, instructions.Nop, 2, 0xff, 0xff, 0xf001)
+	execute(p, 0xf001, instructions.Jsr, 6, 0xff, 0xfd, 0xf100)
+	execute(p, 0xf100, instructions.Nop, 2, 0xfd, 0xfd, 0xf101)
+	execute(p, 0xf101, instructions.Jsr, 6, 0xfd, 0xfb, 0xf200)
+	execute(p, 0xf200, instructions.Nop, 2, 0xfb, 0xfb, 0xf201)
+	execute(p, 0xf201, instructions.Rts, 6, 0xfb, 0xfd, 0xf104)
+	execute(p, 0xf104, instructions.Rts, 6, 0xfd, 0xff, 0xf004)
+	execute(p, 0xf004, instructions.Nop, 2, 0xff, 0xff, 0xf005)
+
+	err := p.NewFrame(television.FrameInfo{})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, p.NumFrames(), 1)
+
+	l := p.Routines(0)
+	test.ExpectEquality(t, len(l), 3)
+
+	// routines are sorted by exclusive cycles
+	test.ExpectEquality(t, l[0].Name, "$f100")
+	test.ExpectEquality(t, l[0].Exclusive.Overall.CyclesProgram.FrameCount, 14)
+	test.ExpectEquality(t, l[0].Inclusive.Overall.CyclesProgram.FrameCount, 22)
+	test.ExpectEquality(t, l[0].Calls.Overall.FrameCount, 1)
+
+	test.ExpectEquality(t, l[1].Name, "(main)")
+	test.ExpectEquality(t, l[1].Exclusive.Overall.CyclesProgram.FrameCount, 10)
+	test.ExpectEquality(t, l[1].Inclusive.Overall.CyclesProgram.FrameCount, 32)
+
+	test.ExpectEquality(t, l[2].Name, "$f200")
+	test.ExpectEquality(t, l[2].Exclusive.Overall.CyclesProgram.FrameCount, 8)
+	test.ExpectEquality(t, l[2].Inclusive.Overall.CyclesProgram.FrameCount, 8)
+
+	// call stack should be back to just the root routine
+	test.ExpectEquality(t, len(p.stack), 1)
+}
+
+func TestStackReset(t *testing.T) {
+	p := NewProfiler(nil, &symbols.Symbols{})
+
+	// a routine that never returns with RTS. the call stack is unwound when
+	// the stack pointer is reset
+	execute(p, 0xf000, instructions.Jsr, 6, 0xff, 0xfd, 0xf100)
+	execute(p, 0xf100, instructions.Jsr, 6, 0xfd, 0xfb, 0xf200)
+	test.ExpectEquality(t, len(p.stack), 3)
+
+	execute(p, 0xf200, instructions.Txs, 2, 0xfb, 0xff, 0xf201)
+	test.ExpectEquality(t, len(p.stack), 1)
+}
+
+func TestScanlines(t *testing.T) {
+	p := NewProfiler(nil, &symbols.Symbols{})
+
+	// an instruction that starts near the end of a scanline is spread over two
+	// scanlines
+	p.scanline(coords.TelevisionCoords{Scanline: 10, Clock: 150}, 6)
+	p.stall(coords.TelevisionCoords{Scanline: 11, Clock: -62})
+
+	p.active = true
+	err := p.NewFrame(television.FrameInfo{})
+	test.ExpectSuccess(t, err)
+
+	sl := p.Scanlines()
+	test.ExpectEquality(t, len(sl), 12)
+	test.ExpectEquality(t, sl[10].Cycles, 4)
+	test.ExpectEquality(t, sl[11].Cycles, 2)
+	test.ExpectEquality(t, sl[11].WSYNC, 1)
+}
@@ -0,0 +1,242 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jetsetilly/gopher2600/coprocessor/developer/profiling"
+)
+
+// the scope of the cycles for the focus
+func scope(cy *profiling.Cycles, focus profiling.Focus) *profiling.CyclesScope {
+	switch focus {
+	case profiling.FocusVBLANK:
+		return &cy.VBLANK
+	case profiling.FocusScreen:
+		return &cy.Screen
+	case profiling.FocusOverscan:
+		return &cy.Overscan
+	}
+	return &cy.Overall
+}
+
+// the scope of the calls for the focus
+func callsScope(cl *profiling.Calls, focus profiling.Focus) *profiling.CallsScope {
+	switch focus {
+	case profiling.FocusVBLANK:
+		return &cl.VBLANK
+	case profiling.FocusScreen:
+		return &cl.Screen
+	case profiling.FocusOverscan:
+		return &cl.Overscan
+	}
+	return &cl.Overall
+}
+
+// Routines returns all the routines that have been executed in the focus,
+// sorted by the average number of exclusive cycles.
+func (p *Profiler) Routines(focus profiling.Focus) []*Routine {
+	l := make([]*Routine, 0, len(p.routines)+1)
+	if scope(&p.root.Inclusive, focus).HasExecuted() {
+		l = append(l, p.root)
+	}
+	for _, r := range p.routines {
+		if scope(&r.Inclusive, focus).HasExecuted() {
+			l = append(l, r)
+		}
+	}
+
+	sort.SliceStable(l, func(i, j int) bool {
+		a := scope(&l[i].Exclusive, focus).CyclesProgram.AverageCount
+		b := scope(&l[j].Exclusive, focus).CyclesProgram.AverageCount
+		if a == b {
+			return l[i].Name < l[j].Name
+		}
+		return a > b
+	})
+
+	return l
+}
+
+// Labels returns all the labels that have been executed in the focus, sorted
+// by the average number of cycles.
+func (p *Profiler) Labels(focus profiling.Focus) []*Label {
+	l := make([]*Label, 0, len(p.labels))
+	for _, lb := range p.labels {
+		if scope(&lb.Cycles, focus).HasExecuted() {
+			l = append(l, lb)
+		}
+	}
+
+	sort.SliceStable(l, func(i, j int) bool {
+		a := scope(&l[i].Cycles, focus).CyclesProgram.AverageCount
+		b := scope(&l[j].Cycles, focus).CyclesProgram.AverageCount
+		if a == b {
+			return l[i].Name < l[j].Name
+		}
+		return a > b
+	})
+
+	return l
+}
+
+// WriteRoutines writes the most expensive routines in the focus to the
+// io.Writer. The top argument limits the number of routines written. A value
+// of zero or less means all routines are written.
+func (p *Profiler) WriteRoutines(w io.Writer, focus profiling.Focus, top int) error {
+	l := p.Routines(focus)
+	if top > 0 && top < len(l) {
+		l = l[:top]
+	}
+
+	_, err := fmt.Fprintf(w, "%-24s %8s %8s %8s %6s %8s %6s %6s\n",
+		"routine", "frame", "avg", "max", "load", "incl", "load", "calls")
+	if err != nil {
+		return err
+	}
+
+	for _, r := range l {
+		ex := scope(&r.Exclusive, focus).CyclesProgram
+		in := scope(&r.Inclusive, focus).CyclesProgram
+		calls := callsScope(&r.Calls, focus)
+		_, err = fmt.Fprintf(w, "%-24s %8.0f %8.1f %8.0f %5.1f%% %8.1f %5.1f%% %6.1f\n",
+			r.Name, ex.FrameCount, ex.AverageCount, ex.MaxCount, ex.AverageLoad,
+			in.AverageCount, in.AverageLoad, calls.AverageCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteLabels writes the most expensive labels in the focus to the
+// io.Writer. The top argument limits the number of labels written. A value of
+// zero or less means all labels are written.
+func (p *Profiler) WriteLabels(w io.Writer, focus profiling.Focus, top int) error {
+	l := p.Labels(focus)
+	if top > 0 && top < len(l) {
+		l = l[:top]
+	}
+
+	_, err := fmt.Fprintf(w, "%-24s %5s %8s %8s %8s %6s\n",
+		"label", "addr", "frame", "avg", "max", "load")
+	if err != nil {
+		return err
+	}
+
+	for _, lb := range l {
+		cy := scope(&lb.Cycles, focus).CyclesProgram
+		_, err = fmt.Fprintf(w, "%-24s $%04x %8.0f %8.1f %8.0f %5.1f%%\n",
+			lb.Name, lb.Address, cy.FrameCount, cy.AverageCount, cy.MaxCount, cy.AverageLoad)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteScanlines writes the scanline information for the most recently
+// completed frame to the io.Writer. Scanlines on which the CPU did nothing are
+// not written.
+func (p *Profiler) WriteScanlines(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%3s %-8s %4s %5s  %s\n", "sl", "part", "used", "wsync", "wsync source")
+	if err != nil {
+		return err
+	}
+
+	for i, sl := range p.scanlines {
+		if sl.Cycles == 0 && sl.WSYNC == 0 {
+			continue
+		}
+
+		source := sl.Source
+		if sl.WSYNC == 0 {
+			source = "no WSYNC"
+		}
+
+		_, err = fmt.Fprintf(w, "%3d %-8s %4d %5d  %s\n", i, p.focus(i), sl.Cycles, sl.WSYNC, source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteBudget writes a summary of how the cycles available in each part of
+// the most recently completed frame were used. The most expensive routine for
+// each part of the frame is also shown.
+func (p *Profiler) WriteBudget(w io.Writer) error {
+	type budget struct {
+		scanlines int
+		used      int
+		wsync     int
+	}
+
+	var parts [3]budget
+	foci := [3]profiling.Focus{profiling.FocusVBLANK, profiling.FocusScreen, profiling.FocusOverscan}
+
+	for i, sl := range p.scanlines {
+		var b *budget
+		switch p.focus(i) {
+		case profiling.FocusVBLANK:
+			b = &parts[0]
+		case profiling.FocusScreen:
+			b = &parts[1]
+		case profiling.FocusOverscan:
+			b = &parts[2]
+		default:
+			continue
+		}
+		b.scanlines++
+		b.used += sl.Cycles
+		b.wsync += sl.WSYNC
+	}
+
+	_, err := fmt.Fprintf(w, "%-8s %5s %9s %6s %6s %6s  %s\n",
+		"part", "lines", "available", "used", "wsync", "usage", "heaviest routine")
+	if err != nil {
+		return err
+	}
+
+	for i, b := range parts {
+		available := b.scanlines * cyclesPerScanline
+
+		var usage float32
+		if available > 0 {
+			usage = float32(b.used) / float32(available) * 100
+		}
+
+		var heaviest string
+		if l := p.Routines(foci[i]); len(l) > 0 {
+			cy := scope(&l[0].Exclusive, foci[i]).CyclesProgram
+			heaviest = fmt.Sprintf("%s (%.0f cycles)", l[0].Name, cy.FrameCount)
+		}
+
+		_, err = fmt.Fprintf(w, "%-8s %5d %9d %6d %6d %5.1f%%  %s\n",
+			foci[i], b.scanlines, available, b.used, b.wsync, usage, heaviest)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/coprocessor/developer/profiling"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// location of a routine or label in the cartridge
+type location struct {
+	bank int
+	addr uint16
+}
+
+// the bank number used for addresses that are not in the cartridge
+const nonCartBank = -1
+
+func newLocation(bank mapper.BankInfo, addr uint16) location {
+	if bank.NonCart {
+		return location{bank: nonCartBank, addr: addr}
+	}
+	addr, _ = memorymap.MapAddress(addr, true)
+	return location{bank: bank.Number, addr: addr}
+}
+
+// Routine is a section of the 6507 program that is called with JSR.
+type Routine struct {
+	location
+
+	Name    string
+	Address uint16
+
+	// cycles taken while the routine is at the top of the call stack
+	Exclusive profiling.Cycles
+
+	// cycles taken while the routine is anywhere in the call stack
+	Inclusive profiling.Cycles
+
+	// number of times the routine has been called
+	Calls profiling.Calls
+}
+
+// Bank returns the cartridge bank of the routine. Returns -1 if the routine is
+// not in the cartridge.
+func (r *Routine) Bank() int {
+	return r.bank
+}
+
+func (r *Routine) newFrame(program *profiling.Cycles) {
+	r.Exclusive.NewFrame(program, nil, false)
+	r.Inclusive.NewFrame(program, nil, false)
+	r.Calls.NewFrame(false)
+}
+
+// Label is a label in the symbols table that has been executed.
+type Label struct {
+	location
+
+	Name    string
+	Address uint16
+
+	// cycles taken by instructions following the label
+	Cycles profiling.Cycles
+}
+
+// Bank returns the cartridge bank of the label. Returns -1 if the label is not
+// in the cartridge.
+func (l *Label) Bank() int {
+	return l.bank
+}
+
+// get routine for the address, creating a new one if necessary
+func (p *Profiler) getRoutine(bank mapper.BankInfo, addr uint16) *Routine {
+	loc := newLocation(bank, addr)
+	if r, ok := p.routines[loc]; ok {
+		return r
+	}
+
+	r := &Routine{
+		location: loc,
+		Address:  addr,
+	}
+	if e, ok := p.sym.GetLabel(bank.Number, addr); ok && !bank.NonCart {
+		r.Name = e.Symbol
+	} else {
+		r.Name = fmt.Sprintf("$%04x", addr)
+	}
+	p.routines[loc] = r
+
+	return r
+}
+
+// get label for the address, creating a new one if necessary
+func (p *Profiler) getLabel(bank mapper.BankInfo, addr uint16, name string) *Label {
+	loc := newLocation(bank, addr)
+	if l, ok := p.labels[loc]; ok {
+		return l
+	}
+
+	l := &Label{
+		location: loc,
+		Name:     name,
+		Address:  addr,
+	}
+	p.labels[loc] = l
+
+	return l
+}
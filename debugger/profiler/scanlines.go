@@ -0,0 +1,63 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package profiler
+
+import (
+	"github.com/jetsetilly/gopher2600/hardware/television/coords"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// Scanline records how the CPU was used during a single scanline.
+type Scanline struct {
+	// number of cycles used by the CPU executing instructions
+	Cycles int
+
+	// number of cycles lost waiting for WSYNC
+	WSYNC int
+
+	// the instruction that caused the WSYNC. empty if there was no WSYNC on
+	// the scanline
+	Source string
+}
+
+// the entry in the current frame for the scanline
+func (p *Profiler) scanlineEntry(scanline int) *Scanline {
+	for len(p.current) <= scanline {
+		p.current = append(p.current, Scanline{})
+	}
+	return &p.current[scanline]
+}
+
+// account for the cycles of an instruction that began at the coordinates. the
+// cycles will be spread over more than one scanline if necessary
+func (p *Profiler) scanline(c coords.TelevisionCoords, cycles int) {
+	sl := c.Scanline
+	pos := (c.Clock + specification.ClksHBlank) / 3
+
+	for cycles > 0 {
+		n := min(cyclesPerScanline-pos, cycles)
+		p.scanlineEntry(sl).Cycles += n
+		cycles -= n
+		sl++
+		pos = 0
+	}
+}
+
+// Scanlines returns the scanline information for the most recently completed
+// frame. The index into the array is the scanline number.
+func (p *Profiler) Scanlines() []Scanline {
+	return p.scanlines
+}
@@ -0,0 +1,49 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"os"
+
+	"github.com/jetsetilly/gopher2600/debugger/rammap"
+)
+
+// start recording the usage of RIOT RAM and cartridge RAM. usage information
+// from any previous recording is discarded
+func (dbg *Debugger) startRAMMap() {
+	dbg.stopRAMMap()
+	dbg.ramMap = rammap.NewMap(dbg.dbgmem)
+	dbg.vcs.TV.AddFrameTrigger(dbg.ramMap)
+}
+
+// stop recording the usage of RAM if recording is active
+func (dbg *Debugger) stopRAMMap() {
+	if dbg.ramMap == nil {
+		return
+	}
+	dbg.vcs.TV.RemoveFrameTrigger(dbg.ramMap)
+	dbg.ramMap = nil
+}
+
+// write the RAM map to the named file in CSV format
+func (dbg *Debugger) writeRAMMap(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return dbg.ramMap.WriteCSV(f)
+}
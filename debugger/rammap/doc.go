@@ -0,0 +1,45 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package rammap records how the 6507 program uses RIOT RAM and cartridge RAM.
+//
+// For every byte of RAM the number of reads and writes are recorded, along
+// with the routines responsible for those reads and writes. A routine is
+// entered with a JSR instruction and the call stack is maintained by watching
+// the stack pointer, in the same way as the profiler package. Code that is
+// outside of any routine is accounted to a pseudo-routine named "(main)".
+//
+// The frame in which each byte is first written is also recorded, as is
+// whether a byte is ever read before it has been written. Reading a byte
+// before it has been written is usually a bug because the contents of RAM are
+// undefined when the console is switched on. Note that this information is
+// only meaningful if recording started when the console was reset.
+//
+// Accesses are recorded at the instruction level. The accesses made by an
+// instruction are derived from the instruction's definition, meaning that the
+// dummy reads made by some addressing modes are not recorded. The pointer
+// bytes used by the indirect addressing modes are recorded as reads, as are
+// the accesses made by stack operations. Bytes that are accessed by stack
+// operations are marked as such.
+//
+// Cartridge RAM is located using the CartRAMbus interface of the cartridge
+// mapper. The write port of the cartridge RAM is assumed to be immediately
+// below the origin reported by the mapper or, if there is no room, immediately
+// above it.
+//
+// The Map should be added to the television as a FrameTrigger. The Begin()
+// and End() functions should be called either side of every call to
+// CPU.ExecuteInstruction().
+package rammap
@@ -0,0 +1,413 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package rammap
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+)
+
+// the name of the pseudo-routine for code that is not inside any other routine
+const mainRoutine = "(main)"
+
+// Byte records how a single byte of RAM has been used.
+type Byte struct {
+	// the address of the byte. for cartridge RAM this is the address as
+	// reported by the cartridge mapper
+	Address uint16
+
+	// the label of the cartridge RAM segment. empty for RIOT RAM
+	Segment string
+
+	Reads  int
+	Writes int
+
+	// the routines that have read or written the byte along with the number
+	// of accesses
+	Readers map[string]int
+	Writers map[string]int
+
+	// the frame number in which the byte was first written. the value is
+	// meaningless if Writes is zero
+	FirstWrite int
+
+	// the byte was read before it had ever been written
+	ReadBeforeWrite bool
+
+	// the byte has been accessed by a stack operation
+	Stack bool
+}
+
+func newByte(address uint16, segment string) *Byte {
+	return &Byte{
+		Address: address,
+		Segment: segment,
+		Readers: make(map[string]int),
+		Writers: make(map[string]int),
+	}
+}
+
+// Free returns true if the byte has never been accessed.
+func (b *Byte) Free() bool {
+	return b.Reads == 0 && b.Writes == 0
+}
+
+// Shared returns true if the byte has been written by more than one routine.
+// Bytes that are used by the stack are never considered to be shared.
+func (b *Byte) Shared() bool {
+	return !b.Stack && len(b.Writers) > 1
+}
+
+func (b *Byte) read(routine string) {
+	if b.Writes == 0 {
+		b.ReadBeforeWrite = true
+	}
+	b.Reads++
+	b.Readers[routine]++
+}
+
+func (b *Byte) write(routine string, frame int) {
+	if b.Writes == 0 {
+		b.FirstWrite = frame
+	}
+	b.Writes++
+	b.Writers[routine]++
+}
+
+// the state of the emulation noted by Begin()
+type beginState struct {
+	frame int
+	bank  mapper.BankInfo
+	sp    uint8
+	x     uint8
+}
+
+// an entry in the call stack
+type frame struct {
+	routine string
+
+	// the value of the stack pointer before the routine was called. the
+	// routine has returned once the stack pointer is back to this value
+	sp uint8
+}
+
+// Map records how the 6507 program uses RIOT RAM and cartridge RAM.
+type Map struct {
+	mem *dbgmem.DbgMem
+
+	// RIOT RAM. indexed by the address of the byte less the RAM origin
+	RAM []*Byte
+
+	// cartridge RAM. one entry for each segment reported by the cartridge
+	// mapper. the slice will be empty if the cartridge has no RAM
+	Cart [][]*Byte
+
+	// the layout of the cartridge RAM segments. one entry for each entry in
+	// the Cart field. the layout is refreshed whenever the banks mapped by the
+	// cartridge change, which is noted by the mappedBanks field
+	segments    []segment
+	mappedBanks string
+
+	// the call stack. the first entry is always the main routine
+	stack []frame
+
+	// state noted by Begin(). pending is true if the CPU is about to execute
+	// an instruction
+	state   beginState
+	pending bool
+
+	// the number of frames that have been recorded
+	numFrames int
+}
+
+// NewMap is the preferred method of initialisation for the Map type.
+func NewMap(mem *dbgmem.DbgMem) *Map {
+	m := &Map{
+		mem: mem,
+	}
+	m.Reset()
+	return m
+}
+
+// Reset all RAM usage information.
+func (m *Map) Reset() {
+	m.RAM = make([]*Byte, memorymap.MemtopRAM-memorymap.OriginRAM+1)
+	for i := range m.RAM {
+		m.RAM[i] = newByte(memorymap.OriginRAM+uint16(i), "")
+	}
+
+	m.Cart = m.Cart[:0]
+	if m.mem != nil && m.mem.VCS != nil {
+		if bus := m.mem.VCS.Mem.Cart.GetRAMbus(); bus != nil {
+			for _, seg := range bus.GetRAM() {
+				b := make([]*Byte, len(seg.Data))
+				for i := range b {
+					b[i] = newByte(seg.Origin+uint16(i), seg.Label)
+				}
+				m.Cart = append(m.Cart, b)
+			}
+		}
+	}
+	m.refreshSegments()
+
+	m.stack = append(m.stack[:0], frame{routine: mainRoutine, sp: 0xff})
+	m.pending = false
+	m.numFrames = 0
+}
+
+// NumFrames returns the number of frames that have been recorded.
+func (m *Map) NumFrames() int {
+	return m.numFrames
+}
+
+// Begin notes the state of the CPU that is required to attribute the RAM
+// accesses of the next instruction. The X register is needed to find the zero
+// page pointer used by the (zp,X) addressing mode.
+func (m *Map) Begin() {
+	mc := m.mem.VCS.CPU
+
+	m.pending = !mc.Killed && mc.RdyFlg && (mc.LastResult.Final || mc.Interrupted)
+	if !m.pending {
+		return
+	}
+
+	m.state = beginState{
+		frame: m.mem.VCS.TV.GetCoords().Frame,
+		bank:  m.mem.VCS.Mem.Cart.GetBank(mc.PC.Address()),
+		sp:    mc.SP.Value(),
+		x:     mc.X.Value(),
+	}
+}
+
+// End records the RAM accesses made by the instruction started with Begin().
+// The cartridge RAM segments are refreshed first if a bank switch has changed
+// which RAM is mapped in.
+func (m *Map) End() {
+	if !m.pending {
+		return
+	}
+	m.pending = false
+
+	mc := m.mem.VCS.CPU
+	if !mc.LastResult.Final {
+		return
+	}
+
+	// the cartridge RAM that is mapped in may have changed as a result of a
+	// bank switch
+	if len(m.Cart) > 0 && m.mem.VCS.Mem.Cart.MappedBanks() != m.mappedBanks {
+		m.refreshSegments()
+	}
+
+	pc := mc.PC.Address()
+	m.instruction(m.state, mc.LastResult, m.mem.VCS.Mem.LastCPUAddressMapped, mc.SP.Value(), pc, m.mem.VCS.Mem.Cart.GetBank(pc))
+}
+
+// the routine at the top of the call stack
+func (m *Map) routine() string {
+	return m.stack[len(m.stack)-1].routine
+}
+
+// instruction records the RAM accesses made by an instruction. the instruction
+// began with the state noted by Begin() and the remaining arguments describe
+// the state of the CPU after the instruction. the address argument is the
+// most recent address accessed by the instruction
+func (m *Map) instruction(st beginState, res execution.Result, address uint16, sp uint8, pc uint16, bank mapper.BankInfo) {
+	if res.Defn == nil {
+		return
+	}
+
+	routine := m.routine()
+
+	// the pointer used by indirect addressing modes is always in the zero page
+	switch res.Defn.AddressingMode {
+	case instructions.IndirectIndexed:
+		ptr := uint8(res.InstructionData)
+		m.access(uint16(ptr), false, false, routine, st.frame)
+		m.access(uint16(ptr+1), false, false, routine, st.frame)
+	case instructions.IndexedIndirect:
+		ptr := uint8(res.InstructionData) + st.x
+		m.access(uint16(ptr), false, false, routine, st.frame)
+		m.access(uint16(ptr+1), false, false, routine, st.frame)
+	}
+
+	switch res.Defn.AddressingMode {
+	case instructions.Implied, instructions.Immediate, instructions.Relative:
+	default:
+		switch res.Defn.Effect {
+		case instructions.Read:
+			m.access(address, false, false, routine, st.frame)
+		case instructions.Write:
+			m.access(address, true, false, routine, st.frame)
+		case instructions.RMW:
+			m.access(address, false, false, routine, st.frame)
+			m.access(address, true, false, routine, st.frame)
+		}
+	}
+
+	// stack operations. pushes are made to the address indicated by the stack
+	// pointer before it is decremented and pulls are made from the address
+	// after the stack pointer is incremented
+	var pushes, pulls int
+	switch res.Defn.Operator {
+	case instructions.Pha, instructions.Php:
+		pushes = 1
+	case instructions.Jsr:
+		pushes = 2
+	case instructions.Brk:
+		pushes = 3
+	case instructions.Pla, instructions.Plp:
+		pulls = 1
+	case instructions.Rts:
+		pulls = 2
+	case instructions.Rti:
+		pulls = 3
+	}
+	for i := 0; i < pushes; i++ {
+		m.access(0x0100|uint16(st.sp-uint8(i)), true, true, routine, st.frame)
+	}
+	for i := 1; i <= pulls; i++ {
+		m.access(0x0100|uint16(st.sp+uint8(i)), false, true, routine, st.frame)
+	}
+
+	// pop any routines that have returned
+	for len(m.stack) > 1 && sp >= m.stack[len(m.stack)-1].sp {
+		m.stack = m.stack[:len(m.stack)-1]
+	}
+
+	// push a new routine on to the call stack
+	switch res.Defn.Operator {
+	case instructions.Jsr, instructions.Brk:
+		m.stack = append(m.stack, frame{routine: m.routineName(bank, pc), sp: st.sp})
+	}
+}
+
+// the name of the routine at the address
+func (m *Map) routineName(bank mapper.BankInfo, addr uint16) string {
+	if m.mem != nil && !bank.NonCart {
+		if e, ok := m.mem.Sym.GetLabel(bank.Number, addr); ok {
+			return e.Symbol
+		}
+	}
+	return fmt.Sprintf("$%04x", addr)
+}
+
+// record an access to the address if it is a RAM address
+func (m *Map) access(address uint16, write bool, stack bool, routine string, frame int) {
+	b := m.lookup(address)
+	if b == nil {
+		return
+	}
+	if stack {
+		b.Stack = true
+	}
+	if write {
+		b.write(routine, frame)
+	} else {
+		b.read(routine)
+	}
+}
+
+// find the RAM byte for the address. returns nil if the address is not a RAM
+// address
+func (m *Map) lookup(address uint16) *Byte {
+	ma, area := memorymap.MapAddress(address, true)
+	switch area {
+	case memorymap.RAM:
+		return m.RAM[ma-memorymap.OriginRAM]
+	case memorymap.Cartridge:
+		for s, seg := range m.segments {
+			if !seg.mapped {
+				continue // for loop
+			}
+			if idx, ok := seg.index(ma); ok && idx < len(m.Cart[s]) {
+				return m.Cart[s][idx]
+			}
+		}
+	}
+	return nil
+}
+
+// the layout of a cartridge RAM segment
+type segment struct {
+	// the origin of the read port in the primary cartridge mirror
+	origin uint16
+	size   int
+	mapped bool
+}
+
+func newSegment(seg mapper.CartRAM) segment {
+	origin, _ := memorymap.MapAddress(seg.Origin, true)
+	return segment{
+		origin: origin,
+		size:   len(seg.Data),
+		mapped: seg.Mapped,
+	}
+}
+
+// the index into the cartridge RAM segment for the address. the address
+// should be mapped
+func (seg segment) index(address uint16) (int, bool) {
+	// the read port
+	if int(address) >= int(seg.origin) && int(address) < int(seg.origin)+seg.size {
+		return int(address - seg.origin), true
+	}
+
+	// the write port. immediately below the read port if there is room,
+	// otherwise immediately above
+	write := int(seg.origin) - seg.size
+	if write < int(memorymap.OriginCart) {
+		write = int(seg.origin) + seg.size
+	}
+	if int(address) >= write && int(address) < write+seg.size {
+		return int(address) - write, true
+	}
+
+	return 0, false
+}
+
+// refreshSegments takes a new copy of the cartridge RAM layout. the layout is
+// cached because the cartridge RAM bus returns a copy of the RAM data, which
+// is too expensive to do on every access
+func (m *Map) refreshSegments() {
+	m.segments = m.segments[:0]
+	m.mappedBanks = ""
+	if len(m.Cart) == 0 {
+		return
+	}
+
+	m.mappedBanks = m.mem.VCS.Mem.Cart.MappedBanks()
+	if bus := m.mem.VCS.Mem.Cart.GetRAMbus(); bus != nil {
+		for _, seg := range bus.GetRAM() {
+			if len(m.segments) >= len(m.Cart) {
+				break // for loop
+			}
+			m.segments = append(m.segments, newSegment(seg))
+		}
+	}
+}
+
+// NewFrame implements the television.FrameTrigger interface.
+func (m *Map) NewFrame(_ television.FrameInfo) error {
+	m.numFrames++
+	return nil
+}
@@ -0,0 +1,163 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package rammap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/dbgmem"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// execute a simulated instruction. the address argument is the final address
+// accessed by the instruction
+func execute(m *Map, frame int, operator instructions.Operator, mode instructions.AddressingMode, effect instructions.EffectCategory,
+	data uint16, address uint16, spBefore uint8, spAfter uint8, pcAfter uint16) {
+	st := beginState{frame: frame, sp: spBefore}
+	res := execution.Result{
+		Defn: &instructions.Definition{
+			Operator:       operator,
+			AddressingMode: mode,
+			Effect:         effect,
+		},
+		InstructionData: data,
+		Final:           true,
+	}
+	m.instruction(st, res, address, spAfter, pcAfter, mapper.BankInfo{NonCart: true})
+}
+
+func TestAccess(t *testing.T) {
+	m := NewMap(nil)
+
+	execute(m, 1, instructions.Lda, instructions.ZeroPage, instructions.Read, 0x80, 0x80, 0xff, 0xff, 0xf002)
+	execute(m, 1, instructions.Sta, instructions.ZeroPage, instructions.Write, 0x80, 0x80, 0xff, 0xff, 0xf004)
+	execute(m, 2, instructions.Sta, instructions.ZeroPage, instructions.Write, 0x81, 0x81, 0xff, 0xff, 0xf006)
+	execute(m, 2, instructions.Jsr, instructions.Absolute, instructions.Subroutine, 0xf100, 0xf100, 0xff, 0xfd, 0xf100)
+	execute(m, 2, instructions.Inc, instructions.ZeroPage, instructions.RMW, 0x81, 0x81, 0xfd, 0xfd, 0xf102)
+	execute(m, 2, instructions.Lda, instructions.IndirectIndexed, instructions.Read, 0x82, 0xf800, 0xfd, 0xfd, 0xf104)
+	execute(m, 2, instructions.Rts, instructions.Implied, instructions.Subroutine, 0x00, 0x00, 0xfd, 0xff, 0xf009)
+	execute(m, 3, instructions.Sta, instructions.Absolute, instructions.Write, 0x0281, 0x0281, 0xff, 0xff, 0xf00c)
+
+	b := m.RAM[0x00]
+	test.ExpectEquality(t, b.Reads, 1)
+	test.ExpectEquality(t, b.Writes, 1)
+	test.ExpectEquality(t, b.FirstWrite, 1)
+	test.ExpectSuccess(t, b.ReadBeforeWrite)
+
+	b = m.RAM[0x01]
+	test.ExpectEquality(t, b.Reads, 1)
+	test.ExpectEquality(t, b.Writes, 2)
+	test.ExpectEquality(t, b.FirstWrite, 2)
+	test.ExpectFailure(t, b.ReadBeforeWrite)
+	test.ExpectEquality(t, b.Writers["(main)"], 1)
+	test.ExpectEquality(t, b.Writers["$f100"], 1)
+	test.ExpectSuccess(t, b.Shared())
+
+	// the pointer used by the indirect addressing mode
+	test.ExpectEquality(t, m.RAM[0x02].Reads, 1)
+	test.ExpectEquality(t, m.RAM[0x03].Reads, 1)
+	test.ExpectEquality(t, m.RAM[0x03].Readers["$f100"], 1)
+
+	// the return address pushed by JSR
+	test.ExpectSuccess(t, m.RAM[0x7f].Stack)
+	test.ExpectEquality(t, m.RAM[0x7f].Writes, 1)
+	test.ExpectEquality(t, m.RAM[0x7e].Reads, 1)
+	test.ExpectFailure(t, m.RAM[0x7e].Shared())
+
+	// RIOT registers are not RAM
+	test.ExpectSuccess(t, m.RAM[0x04].Free())
+
+	// call stack should be back to just the main routine
+	test.ExpectEquality(t, len(m.stack), 1)
+}
+
+func TestSegmentIndex(t *testing.T) {
+	// superchip. the write port is below the read port
+	seg := newSegment(mapper.CartRAM{Origin: 0x1080, Data: make([]uint8, 128)})
+	idx, ok := seg.index(0x1085)
+	test.ExpectSuccess(t, ok)
+	test.ExpectEquality(t, idx, 5)
+	idx, ok = seg.index(0x1005)
+	test.ExpectSuccess(t, ok)
+	test.ExpectEquality(t, idx, 5)
+	_, ok = seg.index(0x1100)
+	test.ExpectFailure(t, ok)
+
+	// the write port is above the read port
+	seg = newSegment(mapper.CartRAM{Origin: 0xf000, Data: make([]uint8, 64)})
+	idx, ok = seg.index(0x1045)
+	test.ExpectSuccess(t, ok)
+	test.ExpectEquality(t, idx, 5)
+}
+
+func TestBankedRAM(t *testing.T) {
+	// E7 cartridge. the program in the fixed bank maps in the second of the
+	// 256 byte RAM banks and then writes to it
+	data := make([]uint8, 16384)
+	copy(data[0x3a00:], []uint8{
+		0xad, 0xe9, 0x1f, // LDA $1fe9
+		0x8d, 0x00, 0x18, // STA $1800
+		0x4c, 0x06, 0x1a, // JMP $1a06
+	})
+	data[0x3ffc] = 0x00
+	data[0x3ffd] = 0x1a
+
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	cartload, err := cartridgeloader.NewLoaderFromData("rammap", data, "E7", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	m := NewMap(&dbgmem.DbgMem{VCS: vcs})
+
+	// the 1k segment and the four 256 byte segments
+	test.ExpectEquality(t, len(m.Cart), 5)
+
+	for range 3 {
+		m.Begin()
+		test.ExpectSuccess(t, vcs.Step(nil))
+		m.End()
+	}
+
+	// the write should have been recorded against the second 256 byte bank
+	test.ExpectEquality(t, m.Cart[1][0].Writes, 0)
+	test.ExpectEquality(t, m.Cart[2][0].Writes, 1)
+}
+
+func TestCSV(t *testing.T) {
+	m := NewMap(nil)
+	execute(m, 1, instructions.Sta, instructions.ZeroPage, instructions.Write, 0x80, 0x80, 0xff, 0xff, 0xf002)
+
+	var s strings.Builder
+	err := m.WriteCSV(&s)
+	test.ExpectSuccess(t, err)
+
+	l := strings.Split(s.String(), "\n")
+	test.ExpectEquality(t, l[0], "address,segment,symbol,reads,writes,first write,read before write,stack,shared,readers,writers")
+	test.ExpectEquality(t, l[1], "$0080,,,0,1,1,false,false,false,,(main)")
+	test.ExpectEquality(t, l[2], "$0081,,,0,0,,false,false,false,,")
+}
@@ -0,0 +1,196 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package rammap
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Filter selects the bytes included in a report.
+type Filter int
+
+// List of valid Filter values.
+const (
+	FilterAll Filter = iota
+	FilterUsed
+	FilterFree
+	FilterShared
+)
+
+func (f Filter) include(b *Byte) bool {
+	switch f {
+	case FilterUsed:
+		return !b.Free()
+	case FilterFree:
+		return b.Free()
+	case FilterShared:
+		return b.Shared()
+	}
+	return true
+}
+
+// Bytes returns every byte of RAM. RIOT RAM is listed first, followed by each
+// segment of cartridge RAM.
+func (m *Map) Bytes() []*Byte {
+	l := make([]*Byte, 0, len(m.RAM))
+	l = append(l, m.RAM...)
+	for _, seg := range m.Cart {
+		l = append(l, seg...)
+	}
+	return l
+}
+
+// the symbol for the byte, taken from either the read or the write symbols
+func (m *Map) symbol(b *Byte) string {
+	if m.mem == nil {
+		return ""
+	}
+	if ai := m.mem.GetAddressInfo(b.Address, true); ai != nil && ai.Symbol != "" {
+		return ai.Symbol
+	}
+	if ai := m.mem.GetAddressInfo(b.Address, false); ai != nil {
+		return ai.Symbol
+	}
+	return ""
+}
+
+// sorted list of routine names. the routine with the most accesses is first
+func routines(r map[string]int) []string {
+	l := make([]string, 0, len(r))
+	for n := range r {
+		l = append(l, n)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if r[l[i]] == r[l[j]] {
+			return l[i] < l[j]
+		}
+		return r[l[i]] > r[l[j]]
+	})
+	return l
+}
+
+// WriteReport writes a summary of RAM usage for the bytes selected by the
+// filter.
+func (m *Map) WriteReport(w io.Writer, filter Filter) error {
+	var s strings.Builder
+
+	var free, used, shared, uninitialised int
+	for _, b := range m.Bytes() {
+		if b.Free() {
+			free++
+		} else {
+			used++
+		}
+		if b.Shared() {
+			shared++
+		}
+		if b.ReadBeforeWrite {
+			uninitialised++
+		}
+
+		if !filter.include(b) {
+			continue // for loop
+		}
+
+		addr := fmt.Sprintf("$%04x", b.Address)
+		if b.Segment != "" {
+			addr = fmt.Sprintf("%s [%s]", addr, b.Segment)
+		}
+		s.WriteString(fmt.Sprintf("%-14s %-16s", addr, m.symbol(b)))
+
+		if b.Free() {
+			s.WriteString("free\n")
+			continue // for loop
+		}
+
+		s.WriteString(fmt.Sprintf("reads %-6d writes %-6d", b.Reads, b.Writes))
+		if b.Writes > 0 {
+			s.WriteString(fmt.Sprintf(" first write frame %d", b.FirstWrite))
+		}
+		if b.ReadBeforeWrite {
+			s.WriteString(" [read before write]")
+		}
+		if b.Stack {
+			s.WriteString(" [stack]")
+		}
+		if b.Shared() {
+			s.WriteString(" [shared]")
+		}
+		s.WriteString("\n")
+
+		if len(b.Readers) > 0 {
+			s.WriteString(fmt.Sprintf("%31s read by %s\n", "", strings.Join(routines(b.Readers), ", ")))
+		}
+		if len(b.Writers) > 0 {
+			s.WriteString(fmt.Sprintf("%31s written by %s\n", "", strings.Join(routines(b.Writers), ", ")))
+		}
+	}
+
+	s.WriteString(fmt.Sprintf("%d bytes used, %d bytes free, %d shared, %d read before write (%d frames)\n",
+		used, free, shared, uninitialised, m.numFrames))
+
+	_, err := io.WriteString(w, s.String())
+	if err != nil {
+		return fmt.Errorf("rammap: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes the RAM usage of every byte in CSV format. The readers and
+// writers fields are lists of routines separated by semi-colons.
+func (m *Map) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+
+	err := c.Write([]string{"address", "segment", "symbol", "reads", "writes", "first write",
+		"read before write", "stack", "shared", "readers", "writers"})
+	if err != nil {
+		return fmt.Errorf("rammap: %w", err)
+	}
+
+	for _, b := range m.Bytes() {
+		var firstWrite string
+		if b.Writes > 0 {
+			firstWrite = fmt.Sprintf("%d", b.FirstWrite)
+		}
+
+		err := c.Write([]string{
+			fmt.Sprintf("$%04x", b.Address),
+			b.Segment,
+			m.symbol(b),
+			fmt.Sprintf("%d", b.Reads),
+			fmt.Sprintf("%d", b.Writes),
+			firstWrite,
+			fmt.Sprintf("%v", b.ReadBeforeWrite),
+			fmt.Sprintf("%v", b.Stack),
+			fmt.Sprintf("%v", b.Shared()),
+			strings.Join(routines(b.Readers), ";"),
+			strings.Join(routines(b.Writers), ";"),
+		})
+		if err != nil {
+			return fmt.Errorf("rammap: %w", err)
+		}
+	}
+
+	c.Flush()
+	if err := c.Error(); err != nil {
+		return fmt.Errorf("rammap: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/gui"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/logger"
+	"github.com/jetsetilly/gopher2600/notifications"
+	"github.com/jetsetilly/gopher2600/rewind"
+	"github.com/jetsetilly/gopher2600/savestate"
+)
+
+// saveState writes the current state of the emulation to the named file. if
+// the filename is empty then a unique filename is generated. returns the
+// filename of the state file
+func (dbg *Debugger) saveState(filename string) (string, error) {
+	// a state file must be created on an instruction boundary. there is no
+	// way of resuming execution from the middle of an instruction
+	if !dbg.vcs.CPU.LastResult.Final {
+		return "", fmt.Errorf("cannot save state in the middle of a CPU instruction")
+	}
+
+	if filename == "" {
+		var err error
+		filename, err = savestate.Filename(dbg.vcs.Mem.Cart.ShortName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := savestate.Save(dbg.vcs, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// loadState replaces the state of the emulation with the contents of the
+// named file. the state file must have been created with the same cartridge
+// as is currently inserted
+func (dbg *Debugger) loadState(filename string) error {
+	state, err := savestate.Load(dbg.vcs, filename)
+	if err != nil {
+		return err
+	}
+
+	rewind.Plumb(dbg.vcs, state, false)
+
+	// rewind history from before the state was loaded is not meaningful
+	dbg.Rewind.ResetBoundary()
+	dbg.Tracker.Reset()
+
+	dbg.liveBankInfo = dbg.vcs.Mem.Cart.GetBank(dbg.vcs.CPU.PC.Address())
+	dbg.liveDisasmEntry = &disassembly.Entry{Result: execution.Result{Final: true}}
+
+	return nil
+}
+
+// PushSaveState saves the current state of the emulation to the quick save
+// file for the inserted cartridge. Only effective in playmode.
+func (dbg *Debugger) PushSaveState() {
+	if dbg.Mode() != govern.ModePlay {
+		return
+	}
+
+	dbg.PushFunction(func() {
+		filename, err := savestate.QuickFilename(dbg.vcs.Mem.Cart.Hash)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+			return
+		}
+
+		_, err = dbg.saveState(filename)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+			return
+		}
+
+		err = dbg.gui.SetFeature(gui.ReqNotification, notifications.NotifySaveState)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+		}
+	})
+}
+
+// PushLoadState loads the quick save file for the inserted cartridge. Only
+// effective in playmode.
+func (dbg *Debugger) PushLoadState() {
+	if dbg.Mode() != govern.ModePlay {
+		return
+	}
+
+	dbg.PushFunction(func() {
+		filename, err := savestate.QuickFilename(dbg.vcs.Mem.Cart.Hash)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+			return
+		}
+
+		err = dbg.loadState(filename)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+			return
+		}
+
+		err = dbg.gui.SetFeature(gui.ReqNotification, notifications.NotifyLoadState)
+		if err != nil {
+			logger.Logf(logger.Allow, "debugger", err.Error())
+		}
+	})
+}
@@ -0,0 +1,85 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"github.com/jetsetilly/gopher2600/debugger/terminal"
+)
+
+// print the lines of the source listing surrounding the current instruction.
+// the context argument is the number of lines to show before and after the
+// current line. if the current instruction is part of a macro expansion then
+// the expansion is shown after the line that invoked the macro
+func (dbg *Debugger) printSource(context int) {
+	lst := dbg.Disasm.Listing()
+	if lst == nil {
+		dbg.printLine(terminal.StyleError, "no source listing for cartridge")
+		return
+	}
+
+	pc := dbg.vcs.CPU.PC.Address()
+	bank := dbg.vcs.Mem.Cart.GetBank(pc)
+	if bank.ExecutingCoprocessor || bank.NonCart {
+		dbg.printLine(terminal.StyleError, "not executing cartridge code")
+		return
+	}
+
+	e := lst.Entry(bank.Number, pc)
+	if e == nil {
+		dbg.printLine(terminal.StyleError, "no source for address %#04x in bank %d", pc, bank.Number)
+		return
+	}
+
+	f := e.Caller.File
+	start := max(1, e.Caller.Number-context)
+	end := min(len(f.Lines), e.Caller.Number+context)
+
+	dbg.printLine(terminal.StyleFeedbackSecondary, f.Name)
+	for n := start; n <= end; n++ {
+		ln := f.Lines[n-1]
+		if ln != e.Caller {
+			dbg.printLine(terminal.StyleFeedbackSecondary, "  %5d  %s", n, ln.Text)
+			continue // for loop
+		}
+
+		dbg.printLine(terminal.StyleFeedback, "> %5d  %s", n, ln.Text)
+		for _, x := range lst.Expansion(e) {
+			loc := ""
+			if x.Line != nil {
+				loc = x.Line.String()
+			}
+			if x == e {
+				dbg.printLine(terminal.StyleFeedback, ">        + %-32s %s", x.Text, loc)
+			} else {
+				dbg.printLine(terminal.StyleFeedbackSecondary, "         + %-32s %s", x.Text, loc)
+			}
+		}
+	}
+}
+
+// print the source files in the source listing
+func (dbg *Debugger) printSourceFiles() {
+	lst := dbg.Disasm.Listing()
+	if lst == nil {
+		dbg.printLine(terminal.StyleError, "no source listing for cartridge")
+		return
+	}
+
+	dbg.printLine(terminal.StyleFeedbackSecondary, lst.Filename)
+	for _, f := range lst.Files {
+		dbg.printLine(terminal.StyleFeedback, "%s (%d lines)", f.Name, len(f.Lines))
+	}
+}
@@ -0,0 +1,93 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// resolve address argument to a cartridge address and the bank it is
+// currently in
+func (dbg *Debugger) cartridgeAddress(address string) (uint16, int, error) {
+	ai := dbg.dbgmem.GetAddressInfo(address, true)
+	if ai == nil {
+		return 0, 0, fmt.Errorf("%s is not a valid address", address)
+	}
+	if ai.Area != memorymap.Cartridge {
+		return 0, 0, fmt.Errorf("%s is not a cartridge address", address)
+	}
+	return ai.MappedAddress, dbg.vcs.Mem.Cart.GetBank(ai.MappedAddress).Number, nil
+}
+
+// add or change the label for the address in the current bank
+func (dbg *Debugger) setLabel(address string, label string) error {
+	addr, bank, err := dbg.cartridgeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if e, ok := dbg.Disasm.Sym.GetLabel(bank, addr); ok {
+		if !dbg.Disasm.Sym.UpdateLabel(symbols.SourceCustom, bank, addr, e.Symbol, label) {
+			return fmt.Errorf("cannot change label for %#04x", addr)
+		}
+		return nil
+	}
+
+	if !dbg.Disasm.Sym.AddLabel(symbols.SourceCustom, bank, addr, label) {
+		return fmt.Errorf("cannot add label for %#04x", addr)
+	}
+
+	return nil
+}
+
+// remove the label for the address in the current bank
+func (dbg *Debugger) removeLabel(address string) error {
+	addr, bank, err := dbg.cartridgeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	e, ok := dbg.Disasm.Sym.GetLabel(bank, addr)
+	if !ok {
+		return fmt.Errorf("no label for %#04x", addr)
+	}
+
+	if !dbg.Disasm.Sym.RemoveLabel(e.Source, bank, addr) {
+		return fmt.Errorf("cannot remove label for %#04x", addr)
+	}
+
+	return nil
+}
+
+// write the user defined symbols to the named file in the specified format
+func (dbg *Debugger) saveSymbols(format string, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "VICE":
+		return dbg.Disasm.Sym.WriteVICE(f)
+	default:
+		return dbg.Disasm.Sym.WriteDASM(f)
+	}
+}
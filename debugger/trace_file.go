@@ -0,0 +1,234 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/registers"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/television/coords"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// the state of the emulation immediately before a CPU instruction is executed
+type traceFileState struct {
+	coords coords.TelevisionCoords
+	bank   mapper.BankInfo
+	pc     uint16
+	a      uint8
+	x      uint8
+	y      uint8
+	sp     uint8
+	status registers.StatusRegister
+}
+
+// traceFile writes every instruction executed by the CPU to a file. this is
+// different to the traces type, which reports access to specific addresses.
+//
+// each line of the file shows the state of the CPU before the instruction is
+// executed, followed by the instruction itself. the layout of the line is
+// fixed width so that the file can be compared with the trace output of other
+// emulators, Stella in particular, with the standard diff tools
+type traceFile struct {
+	dbg *Debugger
+
+	filename string
+	f        *os.File
+	w        *bufio.Writer
+
+	// the state is captured by begin() and written by end(). the pending flag
+	// is false if a new instruction is not about to be executed
+	state   traceFileState
+	pending bool
+
+	// number of CPU cycles since the start of the trace
+	cycles int
+}
+
+// the column header written at the start of the trace file. the columns are:
+// frame, scanline, clock, bank, program counter, registers, flags,
+// instruction cycles, cumulative cycles, bytecode and disassembly
+const traceFileHeader = "; frame  sl clk bank    pc  a  x  y sp NV-BDIZC cyc    total bytecode  disassembly"
+
+// newTraceFile creates (or truncates) the named file and prepares it for
+// tracing.
+func newTraceFile(dbg *Debugger, filename string) (*traceFile, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("trace file: %w", err)
+	}
+
+	trc := &traceFile{
+		dbg:      dbg,
+		filename: filename,
+		f:        f,
+		w:        bufio.NewWriter(f),
+	}
+
+	_, err = trc.w.WriteString(traceFileHeader + "\n")
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("trace file: %w", err)
+	}
+
+	return trc, nil
+}
+
+func (trc *traceFile) String() string {
+	return trc.filename
+}
+
+// close flushes and closes the trace file.
+func (trc *traceFile) close() error {
+	err := trc.w.Flush()
+	if err != nil {
+		_ = trc.f.Close()
+		return fmt.Errorf("trace file: %w", err)
+	}
+	err = trc.f.Close()
+	if err != nil {
+		return fmt.Errorf("trace file: %w", err)
+	}
+	return nil
+}
+
+// begin should be called before the CPU executes an instruction. the state of
+// the emulation is noted if the CPU is ready to begin a new instruction.
+func (trc *traceFile) begin() {
+	mc := trc.dbg.vcs.CPU
+
+	trc.pending = mc.RdyFlg && !mc.Killed && (mc.LastResult.Final || mc.Interrupted)
+	if !trc.pending {
+		return
+	}
+
+	trc.state = traceFileState{
+		coords: trc.dbg.vcs.TV.GetCoords(),
+		bank:   trc.dbg.vcs.Mem.Cart.GetBank(mc.PC.Address()),
+		pc:     mc.PC.Address(),
+		a:      mc.A.Value(),
+		x:      mc.X.Value(),
+		y:      mc.Y.Value(),
+		sp:     mc.SP.Value(),
+		status: mc.Status,
+	}
+}
+
+// end should be called after the CPU has executed an instruction. the
+// instruction is written to the file if begin() noted a new instruction and
+// if the instruction has completed.
+//
+// returns false if the trace file can no longer be written to.
+func (trc *traceFile) end() bool {
+	if !trc.pending {
+		return true
+	}
+	trc.pending = false
+
+	res := trc.dbg.vcs.CPU.LastResult
+	if !res.Final {
+		return true
+	}
+	trc.cycles += res.Cycles
+
+	st := trc.state
+
+	var bank string
+	if st.bank.NonCart {
+		bank = "-"
+	} else {
+		bank = fmt.Sprintf("%d", st.bank.Number)
+	}
+
+	e := trc.dbg.Disasm.FormatResult(st.bank, res, disassembly.EntryLevelExecuted)
+	disasm := strings.TrimSpace(fmt.Sprintf("%s %s", e.Operator, e.Operand.Resolve()))
+
+	_, err := fmt.Fprintf(trc.w, "%7d %3d %3d %4s  %04x %02x %02x %02x %02x %s %3d %8d %-9s %s\n",
+		st.coords.Frame, st.coords.Scanline, st.coords.Clock, bank, st.pc,
+		st.a, st.x, st.y, st.sp, traceFileFlags(st.status),
+		res.Cycles, trc.cycles, e.Bytecode, disasm)
+	if err != nil {
+		logger.Log(logger.Allow, "trace file", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// the status register in the form used by Stella. the flag is shown in upper
+// case if it is set and in lower case if it is not
+func traceFileFlags(sr registers.StatusRegister) string {
+	flags := []struct {
+		set bool
+		c   byte
+	}{
+		{set: sr.Sign, c: 'N'},
+		{set: sr.Overflow, c: 'V'},
+		{set: true, c: '-'},
+		{set: sr.Break, c: 'B'},
+		{set: sr.DecimalMode, c: 'D'},
+		{set: sr.InterruptDisable, c: 'I'},
+		{set: sr.Zero, c: 'Z'},
+		{set: sr.Carry, c: 'C'},
+	}
+
+	var s [8]byte
+	for i, f := range flags {
+		if f.set {
+			s[i] = f.c
+		} else {
+			s[i] = f.c | 0x20
+		}
+	}
+
+	return string(s[:])
+}
+
+// start writing the CPU trace to the named file. any existing trace file will
+// be closed first
+func (dbg *Debugger) startTraceFile(filename string) error {
+	dbg.stopTraceFile()
+
+	var err error
+	dbg.traceFile, err = newTraceFile(dbg, filename)
+	if err != nil {
+		return fmt.Errorf("debugger: %w", err)
+	}
+
+	// note the current state in case the emulation is already between two
+	// instructions
+	dbg.traceFile.begin()
+
+	return nil
+}
+
+// stop writing the CPU trace if it is active
+func (dbg *Debugger) stopTraceFile() {
+	if dbg.traceFile == nil {
+		return
+	}
+
+	err := dbg.traceFile.close()
+	if err != nil {
+		logger.Log(logger.Allow, "debugger", err.Error())
+	}
+	dbg.traceFile = nil
+}
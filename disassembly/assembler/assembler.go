@@ -0,0 +1,369 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+)
+
+// Symbols is the interface used by the assembler to resolve symbols in an
+// operand. It is satisfied by the symbols.Symbols type.
+type Symbols interface {
+	SearchBySymbol(symbol string, table symbols.SearchTable) *symbols.SearchResults
+}
+
+// alternative names for the undocumented instructions. the names on the right
+// are the names used by the instructions package
+var aliases = map[string]string{
+	"ALR": "ASR",
+	"SBX": "AXS",
+	"ISB": "ISC",
+	"INS": "ISC",
+	"DCM": "DCP",
+	"SHA": "AHX",
+	"AXA": "AHX",
+	"ANE": "XAA",
+	"LXA": "LAX",
+	"JAM": "KIL",
+	"HLT": "KIL",
+	"LAR": "LAS",
+	"ASO": "SLO",
+	"LSE": "SRE",
+}
+
+// instruction definitions indexed by mnemonic. the mnemonic is always upper case
+var definitions map[string][]*instructions.Definition
+
+func init() {
+	definitions = make(map[string][]*instructions.Definition)
+	for _, defn := range instructions.GetDefinitions() {
+		if defn == nil {
+			continue
+		}
+		m := strings.ToUpper(defn.Operator.String())
+		definitions[m] = append(definitions[m], defn)
+	}
+}
+
+// find the definition for the mnemonic with the addressing mode. documented
+// instructions are preferred if there is more than one candidate
+func find(defns []*instructions.Definition, mode instructions.AddressingMode) *instructions.Definition {
+	var undocumented *instructions.Definition
+	for _, defn := range defns {
+		if defn.AddressingMode == mode {
+			if !defn.Undocumented {
+				return defn
+			}
+			if undocumented == nil {
+				undocumented = defn
+			}
+		}
+	}
+	return undocumented
+}
+
+// Assemble a single instruction for the specified address. The address is
+// used for branch instructions, for the * symbol and for deciding the origin
+// of any labels used in the operand.
+//
+// The sym argument can be nil, in which case any symbol in the operand will
+// cause an error.
+func Assemble(address uint16, instruction string, sym Symbols) ([]uint8, error) {
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return nil, fmt.Errorf("assembler: no instruction")
+	}
+
+	mnemonic, operand, _ := strings.Cut(instruction, " ")
+	mnemonic = strings.ToUpper(mnemonic)
+
+	// the .w extension forces absolute addressing in the same way as DASM
+	mnemonic, word := strings.CutSuffix(mnemonic, ".W")
+
+	if a, ok := aliases[mnemonic]; ok {
+		mnemonic = a
+	}
+
+	defns, ok := definitions[mnemonic]
+	if !ok {
+		return nil, fmt.Errorf("assembler: unknown instruction (%s)", mnemonic)
+	}
+
+	// whitespace has no meaning in an operand
+	operand = strings.Join(strings.Fields(operand), "")
+
+	asm := assembly{
+		address: address,
+		sym:     sym,
+		effect:  defns[0].Effect,
+	}
+
+	var defn *instructions.Definition
+	var value int
+	var err error
+
+	operandUpper := strings.ToUpper(operand)
+
+	switch {
+	case operand == "" || operandUpper == "A":
+		defn = find(defns, instructions.Implied)
+
+	case operand[0] == '#':
+		value, err = asm.immediate(operand[1:])
+		if err != nil {
+			return nil, err
+		}
+		defn = find(defns, instructions.Immediate)
+
+	case operand[0] == '(':
+		var mode instructions.AddressingMode
+		switch {
+		case strings.HasSuffix(operandUpper, ",X)"):
+			mode = instructions.IndexedIndirect
+			operand = operand[1 : len(operand)-3]
+		case strings.HasSuffix(operandUpper, "),Y"):
+			mode = instructions.IndirectIndexed
+			operand = operand[1 : len(operand)-3]
+		case strings.HasSuffix(operand, ")"):
+			mode = instructions.Indirect
+			operand = operand[1 : len(operand)-1]
+		default:
+			return nil, fmt.Errorf("assembler: badly formed operand (%s)", operand)
+		}
+
+		value, err = asm.expression(operand)
+		if err != nil {
+			return nil, err
+		}
+		if mode != instructions.Indirect && value > 0xff {
+			return nil, fmt.Errorf("assembler: indirect zero page address is too large ($%04x)", value)
+		}
+		defn = find(defns, mode)
+
+	default:
+		zp := instructions.ZeroPage
+		abs := instructions.Absolute
+		switch {
+		case strings.HasSuffix(operandUpper, ",X"):
+			zp = instructions.ZeroPageIndexedX
+			abs = instructions.AbsoluteIndexedX
+			operand = operand[:len(operand)-2]
+		case strings.HasSuffix(operandUpper, ",Y"):
+			zp = instructions.ZeroPageIndexedY
+			abs = instructions.AbsoluteIndexedY
+			operand = operand[:len(operand)-2]
+		}
+
+		value, err = asm.expression(operand)
+		if err != nil {
+			return nil, err
+		}
+
+		if defn = find(defns, instructions.Relative); defn != nil {
+			value, err = asm.branch(value)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if value <= 0xff && !word {
+				defn = find(defns, zp)
+			}
+			if defn == nil {
+				defn = find(defns, abs)
+			}
+		}
+	}
+
+	if defn == nil {
+		return nil, fmt.Errorf("assembler: addressing mode not supported by %s (%s)", mnemonic, operand)
+	}
+
+	if value < 0 || value > 0xffff {
+		return nil, fmt.Errorf("assembler: operand out of range (%d)", value)
+	}
+
+	b := []uint8{defn.OpCode}
+	switch defn.Bytes {
+	case 2:
+		b = append(b, uint8(value))
+	case 3:
+		b = append(b, uint8(value), uint8(value>>8))
+	}
+
+	return b, nil
+}
+
+// the context of a single call to Assemble()
+type assembly struct {
+	address uint16
+	sym     Symbols
+	effect  instructions.EffectCategory
+}
+
+// an immediate value can be preceded by < or > to select the low or high byte
+func (asm assembly) immediate(operand string) (int, error) {
+	var shift int
+	if strings.HasPrefix(operand, "<") {
+		operand = operand[1:]
+	} else if strings.HasPrefix(operand, ">") {
+		operand = operand[1:]
+		shift = 8
+	} else {
+		v, err := asm.expression(operand)
+		if err != nil {
+			return 0, err
+		}
+
+		// negative numbers are allowed for immediate values
+		if v < -128 || v > 0xff {
+			return 0, fmt.Errorf("assembler: immediate value out of range (%d)", v)
+		}
+		return v & 0xff, nil
+	}
+
+	v, err := asm.expression(operand)
+	if err != nil {
+		return 0, err
+	}
+	return (v >> shift) & 0xff, nil
+}
+
+// convert a branch target address into the relative offset. the difference is
+// taken in the 13 bit address space of the 6507 so that the origin of the
+// target address is not important and so that a branch can wrap around the
+// end of the address space
+func (asm assembly) branch(target int) (int, error) {
+	offset := (target - int(asm.address+2)) & 0x1fff
+	if offset >= 0x1000 {
+		offset -= 0x2000
+	}
+	if offset < -128 || offset > 127 {
+		return 0, fmt.Errorf("assembler: branch target out of range ($%04x)", target)
+	}
+	return offset & 0xff, nil
+}
+
+// evaluate an expression made up of numbers and symbols combined with + and -
+func (asm assembly) expression(expr string) (int, error) {
+	if expr == "" {
+		return 0, fmt.Errorf("assembler: missing operand")
+	}
+
+	var result int
+	var negative bool
+
+	for len(expr) > 0 {
+		if expr[0] == '-' {
+			negative = true
+			expr = expr[1:]
+		} else if expr[0] == '+' {
+			expr = expr[1:]
+		}
+
+		// the term ends at the next operator
+		n := strings.IndexAny(expr, "+-")
+		if n == -1 {
+			n = len(expr)
+		}
+
+		v, err := asm.term(expr[:n])
+		if err != nil {
+			return 0, err
+		}
+
+		if negative {
+			result -= v
+		} else {
+			result += v
+		}
+
+		negative = false
+		expr = expr[n:]
+	}
+
+	return result, nil
+}
+
+// evaluate a single number or symbol
+func (asm assembly) term(term string) (int, error) {
+	var v uint64
+	var err error
+
+	switch {
+	case term == "":
+		return 0, fmt.Errorf("assembler: badly formed expression")
+	case term == "*":
+		return int(asm.address), nil
+	case term[0] == '$':
+		v, err = strconv.ParseUint(term[1:], 16, 16)
+	case strings.HasPrefix(term, "0x") || strings.HasPrefix(term, "0X"):
+		v, err = strconv.ParseUint(term[2:], 16, 16)
+	case term[0] == '%':
+		v, err = strconv.ParseUint(term[1:], 2, 16)
+	case term[0] >= '0' && term[0] <= '9':
+		v, err = strconv.ParseUint(term, 10, 16)
+	default:
+		return asm.symbol(term)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("assembler: invalid number (%s)", term)
+	}
+
+	return int(v), nil
+}
+
+// look up symbol. the order in which the symbol tables are searched depends on
+// the effect of the instruction
+func (asm assembly) symbol(symbol string) (int, error) {
+	if asm.sym == nil {
+		return 0, fmt.Errorf("assembler: unknown symbol (%s)", symbol)
+	}
+
+	var tables []symbols.SearchTable
+	switch asm.effect {
+	case instructions.Flow, instructions.Subroutine:
+		tables = []symbols.SearchTable{symbols.SearchLabel, symbols.SearchRead, symbols.SearchWrite}
+	case instructions.Write:
+		tables = []symbols.SearchTable{symbols.SearchWrite, symbols.SearchRead, symbols.SearchLabel}
+	default:
+		tables = []symbols.SearchTable{symbols.SearchRead, symbols.SearchWrite, symbols.SearchLabel}
+	}
+
+	for _, t := range tables {
+		res := asm.sym.SearchBySymbol(symbol, t)
+		if res == nil {
+			continue
+		}
+
+		addr := res.Address
+
+		// labels are stored with normalised addresses. use the same origin
+		// as the address being assembled
+		if t == symbols.SearchLabel && addr&0x1000 == 0x1000 && asm.address&0x1000 == 0x1000 {
+			addr = (addr & 0x0fff) | (asm.address & 0xf000)
+		}
+
+		return int(addr), nil
+	}
+
+	return 0, fmt.Errorf("assembler: unknown symbol (%s)", symbol)
+}
@@ -0,0 +1,125 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package assembler_test
+
+import (
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/disassembly/assembler"
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func expect(t *testing.T, sym assembler.Symbols, address uint16, instruction string, expected ...uint8) {
+	t.Helper()
+	b, err := assembler.Assemble(address, instruction, sym)
+	if err != nil {
+		t.Errorf("%s: unexpected error (%v)", instruction, err)
+		return
+	}
+	if len(b) != len(expected) {
+		t.Errorf("%s: expected % 02x but got % 02x", instruction, expected, b)
+		return
+	}
+	for i := range b {
+		if b[i] != expected[i] {
+			t.Errorf("%s: expected % 02x but got % 02x", instruction, expected, b)
+			return
+		}
+	}
+}
+
+func TestAddressingModes(t *testing.T) {
+	expect(t, nil, 0xf000, "nop", 0xea)
+	expect(t, nil, 0xf000, "ASL A", 0x0a)
+	expect(t, nil, 0xf000, "lda #$10", 0xa9, 0x10)
+	expect(t, nil, 0xf000, "LDA #-1", 0xa9, 0xff)
+	expect(t, nil, 0xf000, "LDA #<$f123", 0xa9, 0x23)
+	expect(t, nil, 0xf000, "LDA #>$f123", 0xa9, 0xf1)
+	expect(t, nil, 0xf000, "LDA $80", 0xa5, 0x80)
+	expect(t, nil, 0xf000, "LDA $0080", 0xa5, 0x80)
+	expect(t, nil, 0xf000, "LDA $1080", 0xad, 0x80, 0x10)
+	expect(t, nil, 0xf000, "LDA $80,X", 0xb5, 0x80)
+	expect(t, nil, 0xf000, "LDA $80,Y", 0xb9, 0x80, 0x00)
+	expect(t, nil, 0xf000, "LDX $80,Y", 0xb6, 0x80)
+	expect(t, nil, 0xf000, "LDA $f000,x", 0xbd, 0x00, 0xf0)
+	expect(t, nil, 0xf000, "LDA ($80,X)", 0xa1, 0x80)
+	expect(t, nil, 0xf000, "LDA ($80),Y", 0xb1, 0x80)
+	expect(t, nil, 0xf000, "JMP ($fffc)", 0x6c, 0xfc, 0xff)
+	expect(t, nil, 0xf000, "JMP $f000", 0x4c, 0x00, 0xf0)
+	expect(t, nil, 0xf000, "LDA %1010+10", 0xa5, 0x14)
+	expect(t, nil, 0xf000, "LDA 0x90 - 1", 0xa5, 0x8f)
+	expect(t, nil, 0xf000, "LDA.w $80", 0xad, 0x80, 0x00)
+	expect(t, nil, 0xf000, "lda.w $80,x", 0xbd, 0x80, 0x00)
+	expect(t, nil, 0xf000, "LDX.W $80,Y", 0xbe, 0x80, 0x00)
+}
+
+func TestBranches(t *testing.T) {
+	expect(t, nil, 0xf010, "BNE *", 0xd0, 0xfe)
+	expect(t, nil, 0xf010, "BNE $f000", 0xd0, 0xee)
+	expect(t, nil, 0xf010, "BEQ $f020", 0xf0, 0x0e)
+
+	// the origin of the target address is not important
+	expect(t, nil, 0xf010, "BEQ $1020", 0xf0, 0x0e)
+
+	// branches past the end of a 4k block and past the end of the address space
+	expect(t, nil, 0x9ffc, "BNE $a036", 0xd0, 0x38)
+	expect(t, nil, 0xfffa, "BEQ $0009", 0xf0, 0x0d)
+
+	_, err := assembler.Assemble(0xf000, "BNE $f100", nil)
+	test.ExpectFailure(t, err)
+}
+
+func TestUndocumented(t *testing.T) {
+	expect(t, nil, 0xf000, "LAX $80", 0xa7, 0x80)
+	expect(t, nil, 0xf000, "DCP $80", 0xc7, 0x80)
+	expect(t, nil, 0xf000, "DCM $80", 0xc7, 0x80)
+	expect(t, nil, 0xf000, "ISB $80", 0xe7, 0x80)
+	expect(t, nil, 0xf000, "KIL", 0x02)
+
+	// documented instructions are preferred over undocumented instructions
+	// with the same mnemonic
+	expect(t, nil, 0xf000, "SBC #$01", 0xe9, 0x01)
+	expect(t, nil, 0xf000, "NOP", 0xea)
+	expect(t, nil, 0xf000, "NOP $80", 0x04, 0x80)
+}
+
+func TestErrors(t *testing.T) {
+	for _, s := range []string{"", "FOO", "LDA", "STA #$10", "LDA #$100", "JMP ($80),Y", "LDA ($100,X)", "LDA unknown"} {
+		_, err := assembler.Assemble(0xf000, s, nil)
+		test.ExpectFailure(t, err)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	var sym symbols.Symbols
+
+	cart := cartridge.NewCartridge(nil)
+	err := sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	sym.AddLabel(symbols.SourceCustom, 0, 0x1010, "loop")
+	sym.AddSymbol(symbols.SourceCustom, 0x80, "ptr", true)
+
+	expect(t, &sym, 0xf000, "STA COLUBK", 0x85, 0x09)
+	expect(t, &sym, 0xf000, "BIT INPT4", 0x24, 0x0c)
+	expect(t, &sym, 0xf000, "LDA ptr+1", 0xa5, 0x81)
+	expect(t, &sym, 0xf000, "LDA (ptr),Y", 0xb1, 0x80)
+	expect(t, &sym, 0xf000, "JMP loop", 0x4c, 0x10, 0xf0)
+	expect(t, &sym, 0xf000, "BNE loop", 0xd0, 0x0e)
+	expect(t, &sym, 0xf000, "LDA #>loop", 0xa9, 0xf0)
+}
@@ -0,0 +1,44 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package assembler converts a single line of 6502 assembly language into the
+// bytes of the equivalent machine code. It is intended for small patches made
+// in the debugger and is not a replacement for a full assembler such as DASM.
+//
+// All instructions in the hardware/cpu/instructions package, including the
+// undocumented instructions, can be assembled. Undocumented instructions can
+// be referred to by the names used in that package or by any of the common
+// alternative names (for example, ISB for ISC or ALR for ASR).
+//
+// Operands are written in the usual way:
+//
+//	LDA #$10
+//	LDA (ptr),Y
+//	STA COLUBK
+//	JMP ($fffc)
+//	BNE loop
+//
+// Numbers can be written in hexadecimal with a $ or 0x prefix, in binary with
+// a % prefix, or in decimal. Symbols are looked up in the supplied symbol
+// table. Numbers and symbols can be combined with + and - and immediate
+// values can select the low or high byte of an address with the < and >
+// operators.
+//
+// The zero page addressing modes are used whenever the operand is less than
+// 256 and the instruction supports zero page addressing. As with DASM, a .w
+// extension on the mnemonic forces the absolute addressing mode:
+//
+//	LDA.w $80
+package assembler
@@ -0,0 +1,261 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// the directives in a configuration file and the class they are converted to.
+// directives that are not directly supported are converted to the nearest
+// equivalent
+var configDirectives = map[string]Class{
+	"CODE":  ClassCode,
+	"TCODE": ClassCode,
+	"GFX":   ClassGfx,
+	"PGFX":  ClassGfx,
+	"DATA":  ClassData,
+	"COL":   ClassData,
+	"PCOL":  ClassData,
+	"BCOL":  ClassData,
+	"AUD":   ClassData,
+	"ROW":   ClassRow,
+}
+
+// a single directive from a configuration file
+type configDirective struct {
+	bank  int
+	class Class
+	start uint16
+	end   uint16
+}
+
+// ReadConfig reads a Distella or Stella configuration file and applies the
+// directives to the disassembly. The directives override the classification
+// made by the static analysis of the cartridge.
+//
+// Addresses in CODE directives are disassembled linearly from the start of the
+// directive and the instructions are blessed. Blessed instructions in any
+// other type of directive are demoted.
+//
+// Stella configuration files separate the directives for each bank with a
+// bank number in square brackets. Distella configuration files have no bank
+// numbers and the directives are applied to the first bank.
+func (dsm *Disassembly) ReadConfig(r io.Reader) error {
+	var directives []configDirective
+	var bank int
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			b, err := strconv.Atoi(strings.Trim(line, "[]"))
+			if err != nil {
+				return fmt.Errorf("disassembly: config: line %d: invalid bank (%s)", n, line)
+			}
+			bank = b
+			continue
+		}
+
+		f := strings.Fields(line)
+		directive := strings.ToUpper(f[0])
+
+		// the ORG directive gives the origin of the bank. we don't need it
+		// because addresses are masked before being applied
+		if directive == "ORG" {
+			continue
+		}
+
+		class, ok := configDirectives[directive]
+		if !ok {
+			return fmt.Errorf("disassembly: config: line %d: unknown directive (%s)", n, f[0])
+		}
+		if len(f) != 3 {
+			return fmt.Errorf("disassembly: config: line %d: %s directive requires start and end addresses", n, directive)
+		}
+
+		start, err := configAddress(f[1])
+		if err != nil {
+			return fmt.Errorf("disassembly: config: line %d: %w", n, err)
+		}
+		end, err := configAddress(f[2])
+		if err != nil {
+			return fmt.Errorf("disassembly: config: line %d: %w", n, err)
+		}
+		if end < start {
+			return fmt.Errorf("disassembly: config: line %d: end address is before start address", n)
+		}
+
+		directives = append(directives, configDirective{
+			bank:  bank,
+			class: class,
+			start: start,
+			end:   end,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("disassembly: config: %w", err)
+	}
+
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+
+	for _, d := range directives {
+		if d.bank < 0 || d.bank >= len(dsm.classes) {
+			return fmt.Errorf("disassembly: config: no bank %d in cartridge", d.bank)
+		}
+		dsm.applyDirective(d)
+	}
+
+	return nil
+}
+
+func configAddress(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "$"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address (%s)", s)
+	}
+	return uint16(v), nil
+}
+
+// apply the directive to the classes and to the disassembly entries. the
+// critical section should be locked before calling this function
+func (dsm *Disassembly) applyDirective(d configDirective) {
+	start := int(d.start & memorymap.CartridgeBits)
+	end := start + int(d.end-d.start)
+	if end > int(memorymap.CartridgeBits) {
+		end = int(memorymap.CartridgeBits)
+	}
+
+	classes := dsm.classes[d.bank]
+	entries := dsm.disasmEntries.Entries[d.bank]
+
+	for a := start; a <= end; a++ {
+		classes[a] = d.class
+	}
+
+	if d.class != ClassCode {
+		for a := start; a <= end; a++ {
+			if e := entries[a]; e != nil && e.Level == EntryLevelBlessed {
+				e.Level = EntryLevelDecoded
+			}
+		}
+		return
+	}
+
+	for a := start; a <= end; {
+		e := entries[a]
+		if e == nil || e.Level == EntryLevelUnmappable {
+			a++
+			continue
+		}
+		if e.Level == EntryLevelDecoded {
+			e.Level = EntryLevelBlessed
+		}
+		a += max(e.Result.ByteCount, 1)
+	}
+}
+
+// WriteConfig writes the classification of the cartridge as a Stella
+// configuration file. The file can be edited and read back with ReadConfig().
+func (dsm *Disassembly) WriteConfig(w io.Writer) error {
+	banks, err := dsm.vcs.Mem.Cart.CopyBanks()
+	if err != nil {
+		return fmt.Errorf("disassembly: config: %w", err)
+	}
+
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("// %s (%s)\n", dsm.vcs.Mem.Cart.ShortName, dsm.vcs.Mem.Cart.ID()))
+	b.WriteString(fmt.Sprintf("// SHA1: %s\n", dsm.vcs.Mem.Cart.Hash))
+
+	for _, bank := range banks {
+		if bank.Number >= len(dsm.classes) || len(bank.Origins) == 0 || len(bank.Data) == 0 {
+			continue
+		}
+
+		classes := dsm.classes[bank.Number]
+		origin := bank.Origins[0] & memorymap.CartridgeBits
+		end := min(int(origin)+len(bank.Data), len(classes))
+
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("[%d]\n", bank.Number))
+		b.WriteString(fmt.Sprintf("ORG %04X\n", origin|dsm.Prefs.mirrorOrigin))
+
+		start := int(origin)
+		for a := start + 1; a <= end; a++ {
+			if a < end && classes[a] == classes[start] {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%s %04X %04X\n", classes[start],
+				uint16(start)|dsm.Prefs.mirrorOrigin, uint16(a-1)|dsm.Prefs.mirrorOrigin))
+			start = a
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	if err != nil {
+		return fmt.Errorf("disassembly: config: %w", err)
+	}
+
+	return nil
+}
+
+// read the configuration file for the cartridge if it exists. the
+// configuration file has the same name as the cartridge but with the .cfg
+// extension
+func (dsm *Disassembly) readConfigFile() {
+	filename := dsm.vcs.Mem.Cart.Filename
+	if filename == "" {
+		return
+	}
+
+	ext := filepath.Ext(filename)
+	if ext == strings.ToUpper(ext) && ext != "" {
+		filename = fmt.Sprintf("%s.CFG", strings.TrimSuffix(filename, ext))
+	} else {
+		filename = fmt.Sprintf("%s.cfg", strings.TrimSuffix(filename, ext))
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	err = dsm.ReadConfig(f)
+	if err != nil {
+		logger.Log(logger.Allow, "disassembly", err.Error())
+		return
+	}
+
+	logger.Logf(logger.Allow, "disassembly", "applied configuration file (%s)", filename)
+}
@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/disassembly/listing"
 	"github.com/jetsetilly/gopher2600/disassembly/symbols"
 	"github.com/jetsetilly/gopher2600/environment"
 	"github.com/jetsetilly/gopher2600/hardware"
@@ -48,7 +49,14 @@ type Disassembly struct {
 	// emulation goroutine
 	disasmEntries DisasmEntries
 
-	// critical sectioning to protect disasmEntries
+	// classification of every address in the cartridge. indexed in the same
+	// way as disasmEntries
+	classes [][]Class
+
+	// the source listing for the cartridge. nil if there is no listing
+	listing *listing.Listing
+
+	// critical sectioning to protect disasmEntries, classes and listing
 	crit sync.Mutex
 }
 
@@ -108,7 +116,7 @@ func FromCartridge(cartload cartridgeloader.Loader) (*Disassembly, error) {
 
 	// ignore errors caused by loading of symbols table - we always get a
 	// standard symbols table even in the event of an error
-	err = dsm.Sym.ReadDASMSymbolsFile(vcs.Mem.Cart)
+	err = dsm.Sym.ReadSymbolsFile(vcs.Mem.Cart)
 	if err != nil {
 		return nil, fmt.Errorf("disassembly: %w", err)
 	}
@@ -148,7 +156,7 @@ func (dsm *Disassembly) FromMemory() error {
 	}
 
 	// read symbols file
-	err = dsm.Sym.ReadDASMSymbolsFile(dsm.vcs.Mem.Cart)
+	err = dsm.Sym.ReadSymbolsFile(dsm.vcs.Mem.Cart)
 	if err != nil {
 		dsm.crit.Unlock()
 		return err
@@ -161,6 +169,10 @@ func (dsm *Disassembly) FromMemory() error {
 		dsm.disasmEntries.Entries[b] = make([]*Entry, memorymap.CartridgeBits+1)
 	}
 
+	// classes and listing are recreated after the disassembly
+	dsm.classes = nil
+	dsm.listing = nil
+
 	// exit early if cartridge memory self reports as being ejected
 	if dsm.vcs.Mem.Cart.IsEjected() {
 		dsm.crit.Unlock()
@@ -175,7 +187,20 @@ func (dsm *Disassembly) FromMemory() error {
 	// end of critical section
 
 	// disassemble cartridge binary
-	return dsm.disassemble(mc, mem)
+	err = dsm.disassemble(mc, mem)
+	if err != nil {
+		return err
+	}
+
+	// static analysis of the cartridge. the analysis can be corrected by a
+	// configuration file if one is available
+	dsm.analyse(mem)
+	dsm.readConfigFile()
+
+	// source listing for source level debugging
+	dsm.readListingFile(copiedBanks)
+
+	return nil
 }
 
 // GetEntryByAddress returns the disassembly entry at the specified
@@ -36,6 +36,33 @@
 // EntryTypeBlessed only. Useful for printing static disassemblies of a
 // cartridge but probably not much else.
 //
+// The WriteSource() function on the other hand, writes the disassembly as DASM
+// source that can be reassembled to recreate the original cartridge data
+// exactly. Entries that are not blessed are written as data.
+//
+// # Static Analysis
+//
+// After the linear disassembly of each bank, the execution flow of the
+// program is traced from the reset and interrupt vectors. The trace follows
+// branches, JSR and JMP instructions, and continues past accesses to bank
+// switching hotspots. Every byte in the cartridge is then classified as CODE,
+// GFX, DATA or ROW (unclassified) and the classification is available with the
+// Class() function.
+//
+// The classification can be corrected by hand with a Distella/Stella style
+// configuration file. The ReadConfig() function applies a configuration file
+// to the disassembly and WriteConfig() writes the current classification in
+// the same format. A configuration file with the same name as the cartridge
+// file but with the .cfg extension is applied automatically.
+//
+// # Source Listings
+//
+// A DASM listing file or ca65/ld65 debug file for the cartridge is read
+// automatically if it has the same name as the cartridge file but with the
+// .lst or .dbg extension. The Listing() function returns the listing, which
+// maps the lines of the original source to cartridge banks and addresses. See
+// the listing package for details.
+//
 // The iteration types provides a convenient way of iterating of the disassembly
 // entries. It takes care of empty entries and entries not of the correct entry
 // type. IterateAll() in particular is useful and flexible enough for many
@@ -0,0 +1,448 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly
+
+import (
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// Class is the classification of a byte in the cartridge. The names of the
+// classes are the same as the directives used in Distella and Stella
+// configuration files.
+type Class int
+
+// List of valid Class values.
+const (
+	// bytes that have not been classified
+	ClassRow Class = iota
+
+	// data that has been referenced by the program
+	ClassData
+
+	// data that has been referenced by the program and then written to one of
+	// the TIA graphics registers
+	ClassGfx
+
+	// instructions reachable by the program
+	ClassCode
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassRow:
+		return "ROW"
+	case ClassData:
+		return "DATA"
+	case ClassGfx:
+		return "GFX"
+	case ClassCode:
+		return "CODE"
+	}
+	return "unknown class"
+}
+
+// Class returns the classification of the address in the bank.
+func (dsm *Disassembly) Class(bank int, addr uint16) Class {
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+
+	if bank < 0 || bank >= len(dsm.classes) {
+		return ClassRow
+	}
+	return dsm.classes[bank][addr&memorymap.CartridgeBits]
+}
+
+// the maximum size of a data table referenced by an indexed instruction
+const flowMaxTable = 256
+
+// the TIA registers that are considered to be graphics registers when
+// classifying data
+var flowGraphicsRegisters = map[cpubus.Register]bool{
+	cpubus.PF0:  true,
+	cpubus.PF1:  true,
+	cpubus.PF2:  true,
+	cpubus.GRP0: true,
+	cpubus.GRP1: true,
+}
+
+// a bank mapped into the cartridge address space at a particular origin
+type flowContext struct {
+	bank   int
+	origin uint16
+	data   []uint8
+}
+
+// a position in the program
+type flowPoint struct {
+	ctx  *flowContext
+	addr uint16
+}
+
+// a reference to data made by an instruction
+type flowReference struct {
+	ctx     *flowContext
+	addr    uint16
+	indexed bool
+}
+
+// flow is the state of the static flow analysis
+type flow struct {
+	contexts []*flowContext
+	defns    []*instructions.Definition
+
+	// bank switching hotspots. addresses are in the primary cartridge mirror
+	readHotspots  map[uint16]bool
+	writeHotspots map[uint16]bool
+
+	// classifications indexed by bank and address, in the same way as the
+	// disassembly entries
+	classes [][]Class
+
+	// instructions that have been visited. indexed in the same way as classes
+	visited [][]bool
+
+	// the queue of program points that have yet to be traced
+	queue []flowPoint
+
+	// all references to data and the subset of references where the data is
+	// written to a graphics register
+	references []flowReference
+	graphics   []flowReference
+}
+
+// index into the data for the address. returns false if the address is not
+// mapped by the context
+func (ctx *flowContext) index(addr uint16) (int, bool) {
+	idx := int((addr - ctx.origin) & memorymap.CartridgeBits)
+	return idx, idx < len(ctx.data)
+}
+
+// the contexts that can map the address. if the context argument can map the
+// address then it is preferred over all other contexts
+func (f *flow) mapping(ctx *flowContext, addr uint16) []*flowContext {
+	if _, area := memorymap.MapAddress(addr, true); area != memorymap.Cartridge {
+		return nil
+	}
+	if _, ok := ctx.index(addr); ok {
+		return []*flowContext{ctx}
+	}
+	var l []*flowContext
+	for _, c := range f.contexts {
+		if _, ok := c.index(addr); ok {
+			l = append(l, c)
+		}
+	}
+	return l
+}
+
+func (f *flow) push(ctx *flowContext, addr uint16) {
+	for _, c := range f.mapping(ctx, addr) {
+		f.queue = append(f.queue, flowPoint{ctx: c, addr: addr})
+	}
+}
+
+// analyse the cartridge banks by tracing the flow of the program from the
+// reset and interrupt vectors. the classification of every byte in the
+// cartridge is stored in the classes field and the instructions found by the
+// analysis are blessed
+//
+// the analysis is static so the targets of indirect jumps and RTS tricks will
+// not be found. bank switching through the hotspots reported by the cartridge
+// is followed by continuing the trace in every bank that can be mapped at the
+// address following the switching instruction
+func (dsm *Disassembly) analyse(mem *disasmMemory) {
+	f := &flow{
+		defns:         instructions.GetDefinitions(),
+		readHotspots:  make(map[uint16]bool),
+		writeHotspots: make(map[uint16]bool),
+	}
+
+	if hb := dsm.vcs.Mem.Cart.GetCartHotspotsBus(); hb != nil {
+		for a, h := range hb.ReadHotspots() {
+			if h.Action == mapper.HotspotBankSwitch {
+				f.readHotspots[a] = true
+			}
+		}
+		for a, h := range hb.WriteHotspots() {
+			if h.Action == mapper.HotspotBankSwitch {
+				f.writeHotspots[a] = true
+			}
+		}
+	}
+
+	f.classes = make([][]Class, len(mem.banks))
+	f.visited = make([][]bool, len(mem.banks))
+	for b := range f.classes {
+		f.classes[b] = make([]Class, memorymap.CartridgeBits+1)
+		f.visited[b] = make([]bool, memorymap.CartridgeBits+1)
+	}
+
+	for _, bank := range mem.banks {
+		for _, origin := range bank.Origins {
+			f.contexts = append(f.contexts, &flowContext{
+				bank:   bank.Number,
+				origin: (origin & memorymap.CartridgeBits) | memorymap.OriginCart,
+				data:   bank.Data,
+			})
+		}
+	}
+
+	// start from the reset and interrupt vectors of every context that maps
+	// the top of the cartridge address space. the vectors themselves are data
+	for _, ctx := range f.contexts {
+		for _, v := range []uint16{cpubus.NMI, cpubus.Reset, cpubus.BRK} {
+			idx, ok := ctx.index(v)
+			if !ok || idx+1 >= len(ctx.data) {
+				continue
+			}
+			f.references = append(f.references,
+				flowReference{ctx: ctx, addr: v},
+				flowReference{ctx: ctx, addr: v + 1})
+
+			if v == cpubus.NMI {
+				continue
+			}
+			addr := uint16(ctx.data[idx]) | uint16(ctx.data[idx+1])<<8
+			if _, ok := ctx.index(addr); ok {
+				f.queue = append(f.queue, flowPoint{ctx: ctx, addr: addr})
+			}
+		}
+	}
+
+	for len(f.queue) > 0 {
+		p := f.queue[0]
+		f.queue = f.queue[1:]
+		f.trace(p)
+	}
+
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+
+	// bless the instructions found by the analysis. instructions that have
+	// been blessed by other means are also classified as code
+	for b := range dsm.disasmEntries.Entries {
+		if b >= len(f.classes) {
+			break
+		}
+		for a, e := range dsm.disasmEntries.Entries[b] {
+			if e == nil {
+				continue
+			}
+			if f.visited[b][a] && e.Level == EntryLevelDecoded {
+				e.Level = EntryLevelBlessed
+			}
+			if e.Level >= EntryLevelBlessed {
+				for i := 0; i < e.Result.ByteCount && a+i < len(f.classes[b]); i++ {
+					f.classes[b][a+i] = ClassCode
+				}
+			}
+		}
+	}
+
+	f.classify(f.references, ClassData)
+	f.classify(f.graphics, ClassGfx)
+
+	dsm.classes = f.classes
+}
+
+// trace the program from the program point until the flow of the program
+// stops or reaches an instruction that has already been traced
+func (f *flow) trace(p flowPoint) {
+	ctx := p.ctx
+	addr := p.addr
+
+	// the most recent data reference loaded into each register
+	var loaded [3]*flowReference
+	const (
+		regA = iota
+		regX
+		regY
+	)
+
+	for {
+		idx, ok := ctx.index(addr)
+		if !ok {
+			return
+		}
+
+		a := addr & memorymap.CartridgeBits
+		if f.visited[ctx.bank][a] {
+			return
+		}
+
+		defn := f.defns[ctx.data[idx]]
+		if defn == nil || defn.Operator == instructions.KIL || idx+defn.Bytes > len(ctx.data) {
+			return
+		}
+
+		f.visited[ctx.bank][a] = true
+		for i := 0; i < defn.Bytes; i++ {
+			f.classes[ctx.bank][(a+uint16(i))&memorymap.CartridgeBits] = ClassCode
+		}
+
+		var operand uint16
+		switch defn.Bytes {
+		case 2:
+			operand = uint16(ctx.data[idx+1])
+		case 3:
+			operand = uint16(ctx.data[idx+1]) | uint16(ctx.data[idx+2])<<8
+		}
+
+		next := addr + uint16(defn.Bytes)
+
+		// the data referenced by the instruction. only absolute addresses in
+		// the cartridge are considered
+		var ref *flowReference
+		switch defn.AddressingMode {
+		case instructions.Absolute, instructions.AbsoluteIndexedX, instructions.AbsoluteIndexedY:
+			if defn.Effect == instructions.Flow || defn.Effect == instructions.Subroutine {
+				break
+			}
+
+			ma, area := memorymap.MapAddress(operand, defn.Effect == instructions.Read)
+			if area != memorymap.Cartridge {
+				break
+			}
+
+			// bank switching. the trace is continued in every bank that can be
+			// mapped at the next address
+			read := defn.Effect == instructions.Read || defn.Effect == instructions.RMW
+			write := defn.Effect == instructions.Write || defn.Effect == instructions.RMW
+			if (read && f.readHotspots[ma]) || (write && f.writeHotspots[ma]) {
+				if defn.AddressingMode == instructions.Absolute {
+					for _, c := range f.contexts {
+						if c != ctx {
+							if _, ok := c.index(next); ok {
+								f.queue = append(f.queue, flowPoint{ctx: c, addr: next})
+							}
+						}
+					}
+				}
+				break
+			}
+
+			if defn.Effect == instructions.Read {
+				for _, c := range f.mapping(ctx, operand) {
+					ref = &flowReference{
+						ctx:     c,
+						addr:    operand,
+						indexed: defn.AddressingMode != instructions.Absolute,
+					}
+					f.references = append(f.references, *ref)
+				}
+			}
+		}
+
+		// keep track of data loaded into registers and stored to the graphics
+		// registers
+		switch defn.Operator {
+		case instructions.Lda:
+			loaded[regA] = ref
+		case instructions.Ldx:
+			loaded[regX] = ref
+		case instructions.Ldy:
+			loaded[regY] = ref
+		case instructions.LAX:
+			loaded[regA] = ref
+			loaded[regX] = ref
+		case instructions.Sta, instructions.Stx, instructions.Sty:
+			if defn.AddressingMode == instructions.ZeroPage || defn.AddressingMode == instructions.Absolute {
+				reg, _ := memorymap.MapAddress(operand, false)
+				if flowGraphicsRegisters[cpubus.TIAWriteSymbols[reg]] {
+					r := loaded[regA]
+					switch defn.Operator {
+					case instructions.Stx:
+						r = loaded[regX]
+					case instructions.Sty:
+						r = loaded[regY]
+					}
+					if r != nil {
+						f.graphics = append(f.graphics, *r)
+					}
+				}
+			}
+		}
+
+		// flow of the program
+		switch defn.Effect {
+		case instructions.Flow:
+			if defn.IsBranch() {
+				f.push(ctx, next+uint16(int8(operand)))
+				break
+			}
+
+			// JMP. the target of an indirect JMP is not known
+			if defn.AddressingMode == instructions.Absolute {
+				f.push(ctx, operand)
+			}
+			return
+
+		case instructions.Subroutine:
+			if defn.Operator == instructions.Jsr {
+				f.push(ctx, operand)
+				break
+			}
+
+			// RTS and RTI
+			return
+
+		case instructions.Interrupt:
+			return
+		}
+
+		addr = next
+	}
+}
+
+// classify the referenced data. data referenced by an indexed instruction is
+// assumed to be a table that extends to the next reference or to the next
+// instruction
+func (f *flow) classify(refs []flowReference, class Class) {
+	starts := make(map[flowPoint]bool)
+	for _, r := range f.references {
+		starts[flowPoint{ctx: r.ctx, addr: r.addr & memorymap.CartridgeBits}] = true
+	}
+
+	for _, r := range refs {
+		n := 1
+		if r.indexed {
+			n = flowMaxTable
+		}
+
+		for i := 0; i < n; i++ {
+			addr := r.addr + uint16(i)
+			if _, ok := r.ctx.index(addr); !ok {
+				break
+			}
+
+			a := addr & memorymap.CartridgeBits
+			if i > 0 && starts[flowPoint{ctx: r.ctx, addr: a}] {
+				break
+			}
+
+			c := &f.classes[r.ctx.bank][a]
+			if *c == ClassCode {
+				break
+			}
+			if *c < class {
+				*c = class
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// a 4k program with a graphics table, a colour table and a subroutine that is
+// only reachable through a branch
+const flowProgram = `
+	ORG $0000
+	RORG $f000
+Start
+	sei
+	ldx #$00
+Loop
+	ldy #$07
+Line
+	sta $02
+	lda Gfx,y
+	sta $1b
+	lda Colors,y
+	sta $06
+	dey
+	bpl Line
+	bit $80
+	bmi Skip
+	jsr Sub
+Skip
+	jmp Loop
+Sub
+	inx
+	rts
+Unused
+	.byte $ea,$ea,$ea,$ea
+Gfx
+	.byte $18,$3c,$7e,$ff,$ff,$7e,$3c,$18
+Colors
+	.byte $0e,$1e,$2e,$3e,$4e,$5e,$6e,$7e
+	ORG $0ffc
+	RORG $fffc
+	.byte $00,$f0,$00,$f0
+`
+
+func flowDisassembly(t *testing.T) (*disassembly.Disassembly, map[string]uint16) {
+	t.Helper()
+
+	data, symbols, err := assemble(flowProgram)
+	test.ExpectSuccess(t, err)
+
+	cartload, err := cartridgeloader.NewLoaderFromData("flow", data, "4k", nil)
+	test.ExpectSuccess(t, err)
+
+	dsm, err := disassembly.FromCartridge(cartload)
+	test.ExpectSuccess(t, err)
+
+	return dsm, symbols
+}
+
+func TestFlow(t *testing.T) {
+	dsm, sym := flowDisassembly(t)
+
+	test.ExpectEquality(t, dsm.Class(0, sym["Start"]), disassembly.ClassCode)
+	test.ExpectEquality(t, dsm.Class(0, sym["Sub"]), disassembly.ClassCode)
+	test.ExpectEquality(t, dsm.Class(0, sym["Sub"]+1), disassembly.ClassCode)
+	test.ExpectEquality(t, dsm.Class(0, sym["Gfx"]), disassembly.ClassGfx)
+	test.ExpectEquality(t, dsm.Class(0, sym["Gfx"]+7), disassembly.ClassGfx)
+	test.ExpectEquality(t, dsm.Class(0, sym["Colors"]), disassembly.ClassData)
+	test.ExpectEquality(t, dsm.Class(0, sym["Colors"]+7), disassembly.ClassData)
+	test.ExpectEquality(t, dsm.Class(0, sym["Unused"]), disassembly.ClassRow)
+}
+
+func TestConfig(t *testing.T) {
+	dsm, sym := flowDisassembly(t)
+
+	// the written configuration should read back without changing anything
+	var cfg bytes.Buffer
+	test.ExpectSuccess(t, dsm.WriteConfig(&cfg))
+
+	var before bytes.Buffer
+	test.ExpectSuccess(t, dsm.WriteSource(&before, make([]uint8, 4096)))
+
+	test.ExpectSuccess(t, dsm.ReadConfig(bytes.NewReader(cfg.Bytes())))
+
+	var after bytes.Buffer
+	test.ExpectSuccess(t, dsm.WriteSource(&after, make([]uint8, 4096)))
+	test.ExpectEquality(t, after.String(), before.String())
+
+	// correct the classification of the unused bytes by hand. a Distella
+	// style file has no bank numbers
+	test.ExpectSuccess(t, dsm.ReadConfig(bytes.NewBufferString(fmt.Sprintf(`
+ORG F000
+CODE %04X %04X
+GFX %04X %04X
+`, sym["Unused"], sym["Unused"]+3, sym["Sub"], sym["Sub"]+1))))
+	test.ExpectEquality(t, dsm.Class(0, sym["Unused"]), disassembly.ClassCode)
+	test.ExpectEquality(t, dsm.Class(0, sym["Sub"]), disassembly.ClassGfx)
+
+	// errors
+	test.ExpectFailure(t, dsm.ReadConfig(bytes.NewBufferString("[1]\nCODE F000 F0FF\n")))
+	test.ExpectFailure(t, dsm.ReadConfig(bytes.NewBufferString("CODE F0FF F000\n")))
+	test.ExpectFailure(t, dsm.ReadConfig(bytes.NewBufferString("BOGUS F000 F0FF\n")))
+}
@@ -0,0 +1,84 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly
+
+import (
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/disassembly/listing"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// Listing returns the source listing for the cartridge. Returns nil if there
+// is no listing.
+//
+// The Listing is not changed once it has been created and so is safe to use
+// from any goroutine.
+func (dsm *Disassembly) Listing() *listing.Listing {
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+	return dsm.listing
+}
+
+// LoadListing reads the DASM listing file or ca65 debug file and associates
+// it with the cartridge.
+func (dsm *Disassembly) LoadListing(filename string) error {
+	banks, err := dsm.vcs.Mem.Cart.CopyBanks()
+	if err != nil {
+		return fmt.Errorf("disassembly: %w", err)
+	}
+
+	lst, err := listing.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("disassembly: %w", err)
+	}
+	lst.Resolve(banks)
+
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+	dsm.listing = lst
+
+	return nil
+}
+
+// UnloadListing removes the listing associated with the cartridge.
+func (dsm *Disassembly) UnloadListing() {
+	dsm.crit.Lock()
+	defer dsm.crit.Unlock()
+	dsm.listing = nil
+}
+
+// read the listing file for the cartridge if one exists
+func (dsm *Disassembly) readListingFile(banks []mapper.BankContent) {
+	filename := listing.FindFile(dsm.vcs.Mem.Cart.Filename)
+	if filename == "" {
+		return
+	}
+
+	lst, err := listing.ReadFile(filename)
+	if err != nil {
+		logger.Log(logger.Allow, "disassembly", err.Error())
+		return
+	}
+	lst.Resolve(banks)
+
+	dsm.crit.Lock()
+	dsm.listing = lst
+	dsm.crit.Unlock()
+
+	logger.Logf(logger.Allow, "disassembly", "using listing file (%s)", filename)
+}
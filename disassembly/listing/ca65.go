@@ -0,0 +1,290 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package listing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the type of a line record in a ca65 debug file
+const (
+	ca65LineAssembler = 0
+	ca65LineExternal  = 1
+	ca65LineMacro     = 2
+)
+
+// a record in a ca65 debug file is a list of key/value pairs
+type ca65Record map[string]string
+
+func (r ca65Record) int(key string) (int, bool) {
+	v, ok := r[key]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// split the attributes of a record. values may be quoted strings that contain
+// commas
+func parseCA65Record(s string) ca65Record {
+	r := make(ca65Record)
+
+	var quoted bool
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			if s[i] == '"' {
+				quoted = !quoted
+			}
+			if quoted || s[i] != ',' {
+				continue
+			}
+		}
+		k, v, ok := strings.Cut(s[start:i], "=")
+		if ok {
+			r[k] = strings.Trim(v, `"`)
+		}
+		start = i + 1
+	}
+
+	return r
+}
+
+type ca65Line struct {
+	file  int
+	line  int
+	typ   int
+	count int
+	spans []int
+}
+
+type ca65Span struct {
+	seg   int
+	start int
+	size  int
+}
+
+type ca65Segment struct {
+	start  int
+	offset int
+}
+
+// ReadCA65 reads a debug information file produced by ld65 with the
+// --dbgfile option. The filename argument is used to locate the source files
+// referred to by the debug information. The source files are looked for
+// relative to the directory containing the debug information file.
+func ReadCA65(r io.Reader, filename string) (*Listing, error) {
+	lst := newListing(filename)
+
+	files := make(map[int]*File)
+	var lines []ca65Line
+	spans := make(map[int]ca65Span)
+	segs := make(map[int]ca65Segment)
+
+	var version bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		typ, attr, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue // for loop
+		}
+		rec := parseCA65Record(attr)
+		id, _ := rec.int("id")
+
+		switch typ {
+		case "version":
+			version = true
+		case "file":
+			files[id] = lst.file(rec["name"])
+		case "line":
+			var ln ca65Line
+			ln.file, _ = rec.int("file")
+			ln.line, _ = rec.int("line")
+			ln.typ, _ = rec.int("type")
+			ln.count, _ = rec.int("count")
+			if s, ok := rec["span"]; ok {
+				for _, v := range strings.Split(s, "+") {
+					n, err := strconv.Atoi(v)
+					if err == nil {
+						ln.spans = append(ln.spans, n)
+					}
+				}
+			}
+			lines = append(lines, ln)
+		case "span":
+			var sp ca65Span
+			sp.seg, _ = rec.int("seg")
+			sp.start, _ = rec.int("start")
+			sp.size, _ = rec.int("size")
+			spans[id] = sp
+		case "seg":
+			var sg ca65Segment
+			sg.start, _ = rec.int("start")
+
+			// segments that are not written to the output file have no
+			// offset
+			if o, ok := rec.int("ooffs"); ok {
+				sg.offset = o
+			} else {
+				sg.offset = -1
+			}
+			segs[id] = sg
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("listing: %w", err)
+	}
+
+	if !version {
+		return nil, fmt.Errorf("listing: %s is not a ca65 debug file", filename)
+	}
+
+	// source text for each file
+	dir := filepath.Dir(filename)
+	for _, f := range lst.Files {
+		readCA65Source(f, dir)
+	}
+
+	// the lines that refer to each span. the caller is the assembler (or
+	// external) line and the line is the most deeply nested macro line
+	type spanLines struct {
+		line   *Line
+		caller *Line
+		count  int
+	}
+	refs := make(map[int]*spanLines)
+
+	for _, ln := range lines {
+		f, ok := files[ln.file]
+		if !ok {
+			continue // for loop
+		}
+		l := f.line(ln.line)
+
+		for _, s := range ln.spans {
+			sl, ok := refs[s]
+			if !ok {
+				sl = &spanLines{}
+				refs[s] = sl
+			}
+			switch ln.typ {
+			case ca65LineAssembler, ca65LineExternal:
+				// prefer assembler lines over external lines
+				if sl.caller == nil || ln.typ == ca65LineAssembler {
+					sl.caller = l
+				}
+			case ca65LineMacro:
+				if sl.line == nil || ln.count > sl.count {
+					sl.line = l
+					sl.count = ln.count
+				}
+			}
+		}
+	}
+
+	// create entries for every span that is in the output file
+	ids := make([]int, 0, len(refs))
+	for id := range refs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		sl := refs[id]
+		sp, ok := spans[id]
+		if !ok || sp.size == 0 {
+			continue // for loop
+		}
+		sg, ok := segs[sp.seg]
+		if !ok || sg.offset < 0 {
+			continue // for loop
+		}
+
+		e := &Entry{
+			Line:    sl.line,
+			Caller:  sl.caller,
+			Address: uint16(sg.start + sp.start),
+			Offset:  sg.offset + sp.start,
+			Bank:    -1,
+			size:    sp.size,
+		}
+		if e.Caller == nil {
+			e.Caller = e.Line
+		}
+		if e.Line == nil {
+			e.Line = e.Caller
+		}
+		if e.Caller == nil {
+			continue // for loop
+		}
+		e.Text = strings.TrimSpace(e.Line.Text)
+
+		lst.Entries = append(lst.Entries, e)
+	}
+
+	// the order of entries should be the same as the order of the output file
+	sort.SliceStable(lst.Entries, func(i, j int) bool {
+		return lst.Entries[i].Offset < lst.Entries[j].Offset
+	})
+	entries := lst.Entries
+	lst.Entries = nil
+	for _, e := range entries {
+		lst.add(e)
+	}
+
+	return lst, nil
+}
+
+// read the source text of the file. the lines of the file are not changed if
+// the source can't be found
+func readCA65Source(f *File, dir string) {
+	candidates := []string{
+		filepath.Join(dir, f.Name),
+		filepath.Join(dir, filepath.Base(f.Name)),
+	}
+	if filepath.IsAbs(f.Name) {
+		candidates = append([]string{f.Name}, candidates...)
+	}
+
+	for _, c := range candidates {
+		sf, err := os.Open(c)
+		if err != nil {
+			continue // for loop
+		}
+		defer sf.Close()
+
+		scanner := bufio.NewScanner(sf)
+		n := 1
+		for scanner.Scan() {
+			f.line(n).Text = strings.TrimRight(scanner.Text(), " \t\r")
+			n++
+		}
+		return
+	}
+}
@@ -0,0 +1,427 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package listing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// a macro defined in a DASM source file
+type dasmMacro struct {
+	file *File
+
+	// line number of the MAC directive
+	line int
+
+	// the number of lines in the macro definition including the ENDM
+	// directive. a value of -1 means the end of the macro has not been seen
+	length int
+}
+
+// the file or macro currently being listed
+type dasmContext struct {
+	// the file being listed. nil if the context is a macro expansion
+	file *File
+
+	// the macro being expanded and the line that (ultimately) invoked it. the
+	// macro field may be nil if the macro definition was not seen
+	macro  *dasmMacro
+	caller *Line
+
+	// the most recent line number seen in the context
+	last int
+}
+
+// the line for the line number in the context. returns nil if the line can't
+// be found
+func (ctx *dasmContext) line(number int) *Line {
+	if ctx.file != nil {
+		return ctx.file.line(number)
+	}
+	if ctx.macro != nil {
+		return ctx.macro.file.line(ctx.macro.line + number)
+	}
+	return nil
+}
+
+// the caller for entries in the context
+func (ctx *dasmContext) callerLine(line *Line) *Line {
+	if ctx.file != nil {
+		return line
+	}
+	return ctx.caller
+}
+
+// the tab width used by DASM when writing listing files
+const dasmTabWidth = 8
+
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			n := dasmTabWidth - col%dasmTabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		} else {
+			b.WriteRune(r)
+			col++
+		}
+	}
+	return b.String()
+}
+
+func isHexByte(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parsed line from a DASM listing file
+type dasmLine struct {
+	number  int
+	address uint16
+	bytes   []uint8
+	text    string
+}
+
+// parse a single line from a DASM listing file. returns false if the line is
+// not a listing line
+func parseDASMLine(s string) (dasmLine, bool) {
+	var ln dasmLine
+
+	s = strings.TrimRight(expandTabs(s), " \r")
+	p := strings.TrimLeft(s, " ")
+
+	// line number
+	i := strings.IndexByte(p, ' ')
+	if i <= 0 {
+		return ln, false
+	}
+	n, err := strconv.Atoi(p[:i])
+	if err != nil || n < 0 {
+		return ln, false
+	}
+	ln.number = n
+	p = strings.TrimLeft(p[i:], " ")
+
+	// address. the address is prefixed with U if it is in an uninitialised
+	// segment. addresses in an uninitialised segment never have bytes
+	i = strings.IndexByte(p, ' ')
+	if i < 0 {
+		i = len(p)
+	}
+	addr := p[:i]
+	uninitialised := strings.HasPrefix(addr, "U")
+	addr = strings.TrimPrefix(addr, "U")
+	a, err := strconv.ParseUint(addr, 16, 32)
+	if err != nil {
+		return ln, false
+	}
+	p = p[i:]
+
+	// flags that follow the address. the value of the address is not known if
+	// the ???? flag is present
+	unknown := false
+	for {
+		q := strings.TrimLeft(p, " ")
+		f, _, _ := strings.Cut(q, " ")
+		switch f {
+		case "????":
+			unknown = true
+		case "str", "eqm", "(R", "R", "(S", "S":
+		default:
+			f = ""
+		}
+		if f == "" {
+			break
+		}
+		p = q[len(f):]
+	}
+	p = strings.TrimLeft(p, " ")
+
+	// a maximum of four bytes are shown in the listing. if there are more
+	// bytes then the last byte is followed by an asterisk
+	var bytes []uint8
+	for len(bytes) < 4 && len(p) >= 2 && isHexByte(p[:2]) && (len(p) == 2 || p[2] == ' ' || p[2] == '*') {
+		v, _ := strconv.ParseUint(p[:2], 16, 8)
+		bytes = append(bytes, uint8(v))
+		p = p[2:]
+		if len(p) > 0 && p[0] == '*' {
+			p = p[1:]
+		}
+		if len(p) > 0 && p[0] == ' ' {
+			p = p[1:]
+		}
+	}
+
+	if !uninitialised && !unknown && a <= 0xffff {
+		ln.address = uint16(a)
+		ln.bytes = bytes
+	}
+	ln.text = dasmText(p)
+
+	return ln, true
+}
+
+// the text of a line in the listing is reconstructed by DASM with the fields
+// aligned by padding. the padding is removed but any comment is preserved as
+// it is
+func dasmText(s string) string {
+	code, comment, ok := strings.Cut(s, ";")
+	code = strings.Join(strings.Fields(code), " ")
+	if !ok {
+		return code
+	}
+	if code == "" {
+		return ";" + comment
+	}
+	return code + " ;" + comment
+}
+
+// the fields of the text with any comment removed
+func dasmFields(text string) []string {
+	text, _, _ = strings.Cut(text, ";")
+	return strings.Fields(text)
+}
+
+// the name of the macro being defined by the line
+func dasmMacroDefinition(text string) string {
+	f := dasmFields(text)
+	for i := 0; i < len(f)-1 && i < 2; i++ {
+		switch strings.ToUpper(f[i]) {
+		case "MAC", "MACRO":
+			return strings.ToLower(f[i+1])
+		}
+	}
+	return ""
+}
+
+// whether the line ends a macro definition
+func dasmMacroEnd(text string) bool {
+	f := dasmFields(text)
+	for i := 0; i < len(f) && i < 2; i++ {
+		switch strings.ToUpper(f[i]) {
+		case "ENDM", "ENDMAC", "ENDMACRO":
+			return true
+		}
+	}
+	return false
+}
+
+// the macro invoked by the line. returns the empty string if the line does
+// not invoke a known macro
+func dasmMacroInvocation(text string, macros map[string]*dasmMacro) string {
+	f := dasmFields(text)
+	for i := 0; i < len(f) && i < 2; i++ {
+		if _, ok := macros[strings.ToLower(f[i])]; ok {
+			return strings.ToLower(f[i])
+		}
+	}
+	return ""
+}
+
+// ReadDASM reads a listing file produced by DASM with the -l option. The
+// filename argument is used to identify the listing and is not opened.
+func ReadDASM(r io.Reader, filename string) (*Listing, error) {
+	lst := newListing(filename)
+
+	var stack []*dasmContext
+	var macros map[string]*dasmMacro
+	var defining *dasmMacro
+	var pass int
+
+	// the next line with a line number of zero is the include directive in
+	// the parent file
+	var include bool
+
+	// the previous entry in the listing
+	var prev *Entry
+
+	reset := func() {
+		lst = newListing(filename)
+		stack = stack[:0]
+		macros = make(map[string]*dasmMacro)
+		defining = nil
+		include = false
+		prev = nil
+	}
+	reset()
+
+	const fileHeader = "------- FILE "
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s := scanner.Text()
+
+		if strings.HasPrefix(s, fileHeader) {
+			f := strings.Fields(s[len(fileHeader):])
+			if len(f) == 0 {
+				continue // for loop
+			}
+			name := f[0]
+
+			if len(f) >= 5 && f[1] == "LEVEL" && f[3] == "PASS" {
+				level, _ := strconv.Atoi(f[2])
+				p, _ := strconv.Atoi(f[4])
+
+				// only the most recent pass is of interest
+				if p > pass {
+					reset()
+					pass = p
+				}
+
+				if level <= 1 || len(stack) == 0 {
+					stack = append(stack[:0], &dasmContext{file: lst.file(name)})
+				} else {
+					stack = append(stack, &dasmContext{file: lst.file(name)})
+					include = true
+				}
+			} else {
+				// returning to a file that was being listed previously
+				for len(stack) > 1 && (stack[len(stack)-1].file == nil || stack[len(stack)-1].file.Name != name) {
+					stack = stack[:len(stack)-1]
+				}
+			}
+			continue // for loop
+		}
+
+		if len(stack) == 0 {
+			continue // for loop
+		}
+
+		dl, ok := parseDASMLine(s)
+		if !ok {
+			continue // for loop
+		}
+
+		e := &Entry{
+			Text:    dl.text,
+			Address: dl.address,
+			Bytes:   dl.bytes,
+			Offset:  -1,
+			Bank:    -1,
+		}
+
+		top := stack[len(stack)-1]
+
+		if dl.number == 0 {
+			if include && len(stack) > 1 {
+				// the include directive belongs to the parent file
+				parent := stack[len(stack)-2]
+				parent.last++
+				e.Line = parent.line(parent.last)
+				e.Caller = parent.callerLine(e.Line)
+				include = false
+			} else {
+				// a macro expansion that has reached the end of the macro
+				// definition has ended
+				for top.file == nil && len(stack) > 1 && top.macro != nil && top.macro.length >= 0 && top.last >= top.macro.length-1 {
+					stack = stack[:len(stack)-1]
+					top = stack[len(stack)-1]
+				}
+
+				// the start of a macro expansion
+				top.last++
+				e.Line = top.line(top.last)
+				e.Caller = top.callerLine(e.Line)
+				if e.Caller == nil {
+					e.Caller = e.Line
+				}
+				stack = append(stack, &dasmContext{
+					macro:  macros[dasmMacroInvocation(dl.text, macros)],
+					caller: e.Caller,
+				})
+			}
+		} else {
+			// the end of a macro expansion is detected by a break in the
+			// sequence of line numbers
+			for top.file == nil && len(stack) > 1 && (dl.number != top.last+1 ||
+				(top.macro != nil && top.macro.length >= 0 && dl.number > top.macro.length)) {
+				stack = stack[:len(stack)-1]
+				top = stack[len(stack)-1]
+			}
+
+			// some versions of DASM do not list the invocation of a macro with
+			// a line number of zero. in which case the start of the expansion
+			// is detected by the line number returning to one
+			if dl.number == 1 && top.last+1 != 1 && prev != nil {
+				if m := dasmMacroInvocation(prev.Text, macros); m != "" {
+					stack = append(stack, &dasmContext{
+						macro:  macros[m],
+						caller: prev.Caller,
+					})
+					top = stack[len(stack)-1]
+				}
+			}
+
+			top.last = dl.number
+			e.Line = top.line(dl.number)
+			e.Caller = top.callerLine(e.Line)
+			if e.Caller == nil {
+				e.Caller = e.Line
+			}
+		}
+
+		// source text for lines in a file
+		if top.file != nil && e.Line != nil && e.Line.Text == "" && e.Line == e.Caller {
+			e.Line.Text = dl.text
+		}
+
+		// keep track of macro definitions in files
+		if e.Line != nil && e.Line == e.Caller {
+			if name := dasmMacroDefinition(dl.text); name != "" {
+				defining = &dasmMacro{
+					file:   e.Line.File,
+					line:   e.Line.Number,
+					length: -1,
+				}
+				macros[name] = defining
+			} else if defining != nil && dasmMacroEnd(dl.text) {
+				defining.length = e.Line.Number - defining.line
+				defining = nil
+			}
+		}
+
+		if e.Caller != nil {
+			lst.add(e)
+			prev = e
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("listing: %w", err)
+	}
+
+	if len(lst.Entries) == 0 {
+		return nil, fmt.Errorf("listing: %s is not a DASM listing file", filename)
+	}
+
+	return lst, nil
+}
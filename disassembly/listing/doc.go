@@ -0,0 +1,36 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package listing maps the source of a 6507 program to the cartridge. Listing
+// files produced by DASM and the debug information files produced by the
+// ca65/ld65 toolchain are supported.
+//
+// A Listing is read with ReadDASM() or ReadCA65(). The ReadFile() function
+// will decide which reader to use from the file extension. Once read, the
+// Resolve() function should be called with the banks of the cartridge. This
+// associates every line that assembled to bytes in the cartridge with a bank
+// and address.
+//
+// Each line in the listing is represented by an Entry. The Entry records the
+// text of the line, with macros expanded, and the source Line that produced
+// it. For lines that are the result of a macro expansion, the Caller field
+// refers to the line that invoked the macro. This is the line that is most
+// useful when stepping through the program.
+//
+// DASM listing files do not record the bank of each line. Banks are resolved by
+// matching the bytes recorded in the listing against the data in the cartridge.
+// Where the bytes can be found in more than one bank, the bank of the previous
+// line is preferred.
+package listing
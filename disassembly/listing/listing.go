@@ -0,0 +1,390 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package listing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// File is a source file referred to by a Listing.
+type File struct {
+	Name  string
+	Lines []*Line
+}
+
+// line returns the Line with the line number, creating it if necessary.
+// numbering of lines starts at one.
+func (f *File) line(number int) *Line {
+	if number < 1 {
+		number = 1
+	}
+	for len(f.Lines) < number {
+		f.Lines = append(f.Lines, &Line{File: f, Number: len(f.Lines) + 1})
+	}
+	return f.Lines[number-1]
+}
+
+// Line is a single line in a source File.
+type Line struct {
+	File   *File
+	Number int
+
+	// the text of the line as it appears in the source file. not all lines
+	// in a file will have text
+	Text string
+
+	// the entries in the listing that refer to this line. entries that are
+	// the result of a macro expansion refer to the line in the macro
+	// definition. see the Callers field of Line
+	Entries []*Entry
+
+	// entries in the listing that are the result of a macro invoked by the
+	// line
+	Callers []*Entry
+}
+
+func (ln *Line) String() string {
+	return fmt.Sprintf("%s:%d", ln.File.Name, ln.Number)
+}
+
+// Entry is a line in the listing.
+type Entry struct {
+	// the source line for the entry. for macro expansions this is the line in
+	// the macro definition. can be nil if the line in the macro definition is
+	// not known
+	Line *Line
+
+	// the line in the source that caused the entry. this is the same as the
+	// Line field unless the entry is the result of a macro expansion
+	Caller *Line
+
+	// the text of the entry as it appears in the listing. for macro
+	// expansions, the text will have the macro arguments substituted
+	Text string
+
+	// the address and bytes produced by the assembler for the entry. the
+	// Address field is not meaningful if there are no bytes
+	Address uint16
+	Bytes   []uint8
+
+	// the offset of the entry in the cartridge file. a value of -1 means that
+	// the offset is not known
+	Offset int
+
+	// the number of bytes produced for the entry when the offset is known but
+	// the Bytes field has not been filled in yet. the Bytes field is filled
+	// by the Resolve() function from the cartridge data
+	size int
+
+	// the cartridge bank containing the entry. a value of -1 means that the
+	// bank could not be resolved. see Resolve() function
+	Bank int
+
+	// index of the entry in the Entries field of the Listing
+	index int
+}
+
+// Macro returns true if the entry is the result of a macro expansion.
+func (e *Entry) Macro() bool {
+	return e.Line != e.Caller
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s: %s", e.Caller, e.Text)
+}
+
+// Listing is the result of reading a listing file.
+type Listing struct {
+	Filename string
+
+	// the source files in the order they were first encountered
+	Files []*File
+
+	// entries in the order they appear in the listing
+	Entries []*Entry
+
+	// entries that have been resolved to the cartridge. keyed by bank and
+	// address. see key() function
+	addresses map[int]*Entry
+}
+
+func newListing(filename string) *Listing {
+	return &Listing{
+		Filename:  filename,
+		addresses: make(map[int]*Entry),
+	}
+}
+
+// file returns the File with the name, adding it to the listing if necessary
+func (lst *Listing) file(name string) *File {
+	for _, f := range lst.Files {
+		if f.Name == name {
+			return f
+		}
+	}
+	f := &File{Name: name}
+	lst.Files = append(lst.Files, f)
+	return f
+}
+
+// add entry to the listing. the entry is added to the line and caller
+func (lst *Listing) add(e *Entry) {
+	e.index = len(lst.Entries)
+	lst.Entries = append(lst.Entries, e)
+	if e.Line != nil {
+		e.Line.Entries = append(e.Line.Entries, e)
+	}
+	if e.Caller != nil && e.Caller != e.Line {
+		e.Caller.Callers = append(e.Caller.Callers, e)
+	}
+}
+
+// the key into the addresses map
+func key(bank int, address uint16) int {
+	return bank<<16 | int(address&memorymap.CartridgeBits)
+}
+
+// ReadFile reads the listing file. Files with the .dbg extension are read with
+// ReadCA65(). All other files are read with ReadDASM().
+func ReadFile(filename string) (*Listing, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("listing: %w", err)
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(filename)) == ".dbg" {
+		return ReadCA65(f, filename)
+	}
+	return ReadDASM(f, filename)
+}
+
+// FindFile looks for a listing file for the cartridge. The listing file will
+// have the same name as the cartridge but with a .lst or .dbg extension.
+// Returns the empty string if no file can be found.
+func FindFile(cartFilename string) string {
+	if cartFilename == "" {
+		return ""
+	}
+
+	base := strings.TrimSuffix(cartFilename, filepath.Ext(cartFilename))
+	for _, ext := range []string{".lst", ".LST", ".dbg", ".DBG"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+
+	return ""
+}
+
+// Resolve the bank and address of each entry in the listing that has produced
+// bytes. Entries that can not be found in the cartridge data are not resolved.
+func (lst *Listing) Resolve(banks []mapper.BankContent) {
+	clear(lst.addresses)
+
+	// the offset of each bank in the cartridge file
+	offsets := make([]int, len(banks)+1)
+	for i, b := range banks {
+		offsets[i+1] = offsets[i] + len(b.Data)
+	}
+
+	// whether the bytes of the entry can be found in the bank at the address
+	match := func(e *Entry, bank mapper.BankContent) bool {
+		for _, origin := range bank.Origins {
+			idx := int(e.Address&memorymap.CartridgeBits) - int(origin&memorymap.CartridgeBits)
+			if idx < 0 || idx+len(e.Bytes) > len(bank.Data) {
+				continue
+			}
+			ok := true
+			for i, v := range e.Bytes {
+				if bank.Data[idx+i] != v {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	prev := -1
+	for _, e := range lst.Entries {
+		e.Bank = -1
+
+		// the offset in the file identifies the bank exactly
+		if e.Offset >= 0 {
+			b := sort.SearchInts(offsets, e.Offset+1) - 1
+			if b < 0 || b >= len(banks) {
+				continue
+			}
+			if e.size > 0 {
+				idx := e.Offset - offsets[b]
+				if idx+e.size > len(banks[b].Data) {
+					continue
+				}
+				e.Bytes = append([]uint8(nil), banks[b].Data[idx:idx+e.size]...)
+			}
+			if len(e.Bytes) > 0 && match(e, banks[b]) {
+				e.Bank = banks[b].Number
+			}
+		} else if len(e.Bytes) > 0 {
+			// prefer the bank of the previous entry
+			if prev >= 0 && prev < len(banks) && match(e, banks[prev]) {
+				e.Bank = banks[prev].Number
+			} else {
+				for i := range banks {
+					if match(e, banks[i]) {
+						e.Bank = banks[i].Number
+						break
+					}
+				}
+			}
+		}
+
+		if e.Bank == -1 {
+			continue
+		}
+
+		for i := range banks {
+			if banks[i].Number == e.Bank {
+				prev = i
+				break
+			}
+		}
+
+		// the first entry for an address takes priority
+		k := key(e.Bank, e.Address)
+		if _, ok := lst.addresses[k]; !ok {
+			lst.addresses[k] = e
+		}
+	}
+}
+
+// Entry returns the resolved entry for the bank and address. Returns nil if
+// there is no entry that begins at that address.
+func (lst *Listing) Entry(bank int, address uint16) *Entry {
+	if lst == nil {
+		return nil
+	}
+	return lst.addresses[key(bank, address)]
+}
+
+// FindFile returns the file in the listing with the name. The name can be a
+// path or just the base name of the file. Returns nil if the file can not be
+// found.
+func (lst *Listing) FindFile(name string) *File {
+	for _, f := range lst.Files {
+		if f.Name == name {
+			return f
+		}
+	}
+	for _, f := range lst.Files {
+		if filepath.Base(f.Name) == filepath.Base(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Breakpoints returns the entries that should be used as breakpoints for the
+// line specified in the form "file:line". If the line did not produce any
+// code, the first line following it that did produce code is used.
+func (lst *Listing) Breakpoints(fileLine string) ([]*Entry, error) {
+	name, number, ok := strings.Cut(fileLine, ":")
+	if !ok {
+		return nil, fmt.Errorf("listing: line should be specified as file:line")
+	}
+
+	var n int
+	_, err := fmt.Sscanf(number, "%d", &n)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("listing: invalid line number (%s)", number)
+	}
+
+	f := lst.FindFile(name)
+	if f == nil {
+		return nil, fmt.Errorf("listing: no file named %s", name)
+	}
+
+	for ; n <= len(f.Lines); n++ {
+		ln := f.Lines[n-1]
+
+		var entries []*Entry
+
+		// entries produced directly by the line or by a macro definition line
+		for _, e := range ln.Entries {
+			if e.Bank >= 0 {
+				entries = append(entries, e)
+			}
+		}
+
+		// the first entry of each macro invocation. the entries from the
+		// same invocation follow one another in the listing
+		var last *Entry
+		for _, e := range ln.Callers {
+			if e.Bank >= 0 && (last == nil || !sameInvocation(lst, last, e)) {
+				entries = append(entries, e)
+				last = e
+			}
+		}
+
+		if len(entries) > 0 {
+			return entries, nil
+		}
+	}
+
+	return nil, fmt.Errorf("listing: no code at or after %s:%s", f.Name, number)
+}
+
+// sameInvocation returns true if the two entries are the result of the same
+// macro invocation. the entries must be in listing order
+func sameInvocation(lst *Listing, a *Entry, b *Entry) bool {
+	for _, e := range lst.Entries[a.index:b.index] {
+		if e.Caller != a.Caller {
+			return false
+		}
+	}
+	return true
+}
+
+// Expansion returns the entries that are part of the same macro expansion as
+// the entry, in listing order. Returns nil if the entry is not part of a
+// macro expansion.
+func (lst *Listing) Expansion(e *Entry) []*Entry {
+	if !e.Macro() {
+		return nil
+	}
+
+	start := e.index
+	for start > 0 && lst.Entries[start-1].Caller == e.Caller && lst.Entries[start-1].Macro() {
+		start--
+	}
+	end := e.index + 1
+	for end < len(lst.Entries) && lst.Entries[end].Caller == e.Caller && lst.Entries[end].Macro() {
+		end++
+	}
+
+	return lst.Entries[start:end]
+}
@@ -0,0 +1,195 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package listing_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/disassembly/listing"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// create banks of cartridge data from the entries in the listing. the bytes
+// are placed in the bank indicated by the offset or in bank zero if the offset
+// is not known
+func banksFromListing(lst *listing.Listing, num int, code map[uint16][]uint8) []mapper.BankContent {
+	banks := make([]mapper.BankContent, num)
+	for i := range banks {
+		banks[i] = mapper.BankContent{
+			Number:  i,
+			Data:    make([]uint8, 4096),
+			Origins: []uint16{0x1000},
+		}
+	}
+	for _, e := range lst.Entries {
+		if len(e.Bytes) > 0 {
+			copy(banks[0].Data[e.Address&0x0fff:], e.Bytes)
+		}
+	}
+	for addr, b := range code {
+		copy(banks[num-1].Data[addr&0x0fff:], b)
+	}
+	return banks
+}
+
+func TestDASM(t *testing.T) {
+	lst, err := listing.ReadFile("testdata/test.lst")
+	if !test.ExpectSuccess(t, err) {
+		return
+	}
+	lst.Resolve(banksFromListing(lst, 1, nil))
+
+	test.ExpectEquality(t, len(lst.Files), 2)
+	test.ExpectEquality(t, lst.Files[0].Name, "main.asm")
+	test.ExpectEquality(t, lst.Files[1].Name, "macro.h")
+
+	// instruction in the main file
+	e := lst.Entry(0, 0xf000)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.asm:6")
+	test.ExpectEquality(t, e.Macro(), false)
+	test.ExpectEquality(t, e.Text, "sei")
+
+	// the include directive belongs to the main file
+	test.ExpectEquality(t, lst.Files[0].Lines[1].Text, `include "macro.h"`)
+
+	// macro expansion listed without a zero line
+	e = lst.Entry(0, 0xf003)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.asm:7")
+	test.ExpectEquality(t, e.Line.String(), "macro.h:4")
+	test.ExpectEquality(t, e.Macro(), true)
+	test.ExpectEquality(t, e.Text, "sta $80")
+	test.ExpectEquality(t, e.Line.Text, "sta {2}")
+
+	x := lst.Expansion(e)
+	test.ExpectEquality(t, len(x), 2)
+	test.ExpectEquality(t, x[0].Text, "lda #0")
+	test.ExpectEquality(t, x[1], e)
+
+	// macro expansion listed with a zero line
+	e = lst.Entry(0, 0xf005)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.asm:8")
+	test.ExpectEquality(t, e.Line.String(), "macro.h:3")
+
+	// the line after the macro expansions
+	e = lst.Entry(0, 0xf009)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.asm:9")
+	test.ExpectEquality(t, e.Macro(), false)
+
+	// addresses that are not the start of an instruction
+	test.ExpectEquality(t, lst.Entry(0, 0xf002), nil)
+	test.ExpectEquality(t, lst.Entry(1, 0xf000), nil)
+
+	addresses := func(fileLine string) []uint16 {
+		t.Helper()
+		bps, err := lst.Breakpoints(fileLine)
+		if !test.ExpectSuccess(t, err) {
+			return nil
+		}
+		var a []uint16
+		for _, e := range bps {
+			a = append(a, e.Address)
+		}
+		return a
+	}
+
+	test.ExpectEquality(t, len(addresses("main.asm:6")), 1)
+	test.ExpectEquality(t, addresses("main.asm:6")[0], 0xf000)
+
+	// label line does not produce code so the breakpoint is on the next line
+	test.ExpectEquality(t, addresses("main.asm:5")[0], 0xf000)
+
+	// macro invocation
+	test.ExpectEquality(t, len(addresses("main.asm:7")), 1)
+	test.ExpectEquality(t, addresses("main.asm:7")[0], 0xf001)
+
+	// line in macro definition is a breakpoint in every expansion
+	a := addresses("macro.h:4")
+	test.ExpectEquality(t, len(a), 2)
+	test.ExpectEquality(t, a[0], 0xf003)
+	test.ExpectEquality(t, a[1], 0xf007)
+
+	_, err = lst.Breakpoints("main.asm:100")
+	test.ExpectFailure(t, err)
+	_, err = lst.Breakpoints("other.asm:1")
+	test.ExpectFailure(t, err)
+	_, err = lst.Breakpoints("main.asm")
+	test.ExpectFailure(t, err)
+}
+
+func TestDASMBanks(t *testing.T) {
+	lst, err := listing.ReadFile("testdata/test.lst")
+	if !test.ExpectSuccess(t, err) {
+		return
+	}
+
+	// the jmp instruction is not found in the first bank
+	banks := banksFromListing(lst, 2, map[uint16][]uint8{
+		0xf009: {0x4c, 0x00, 0xf0},
+	})
+	banks[0].Data[0x0009] = 0x00
+	lst.Resolve(banks)
+
+	test.ExpectInequality(t, lst.Entry(0, 0xf000), nil)
+	test.ExpectEquality(t, lst.Entry(1, 0xf000), nil)
+	test.ExpectEquality(t, lst.Entry(0, 0xf009), nil)
+	test.ExpectInequality(t, lst.Entry(1, 0xf009), nil)
+}
+
+func TestCA65(t *testing.T) {
+	lst, err := listing.ReadFile("testdata/test.dbg")
+	if !test.ExpectSuccess(t, err) {
+		return
+	}
+
+	// the code is in the second bank according to the debug file
+	banks := banksFromListing(lst, 2, map[uint16][]uint8{
+		0xf000: {0x78, 0xa9, 0x00, 0x85, 0x80, 0x4c, 0x00, 0xf0},
+	})
+	lst.Resolve(banks)
+
+	test.ExpectEquality(t, len(lst.Entries), 4)
+	test.ExpectEquality(t, lst.Entry(0, 0xf000), nil)
+
+	e := lst.Entry(1, 0xf000)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.s:7")
+	test.ExpectEquality(t, e.Text, "sei")
+
+	e = lst.Entry(1, 0xf001)
+	test.ExpectInequality(t, e, nil)
+	test.ExpectEquality(t, e.Caller.String(), "main.s:8")
+	test.ExpectEquality(t, e.Line.String(), "main.s:2")
+	test.ExpectEquality(t, e.Macro(), true)
+	test.ExpectEquality(t, e.Text, "lda #value")
+	test.ExpectEquality(t, len(e.Bytes), 2)
+
+	bps, err := lst.Breakpoints("main.s:6")
+	if test.ExpectSuccess(t, err) {
+		test.ExpectEquality(t, len(bps), 1)
+		test.ExpectEquality(t, bps[0].Address, 0xf000)
+		test.ExpectEquality(t, bps[0].Bank, 1)
+	}
+
+	// not a debug file
+	_, err = listing.ReadCA65(strings.NewReader("not a debug file"), "testdata/test.lst")
+	test.ExpectFailure(t, err)
+}
@@ -0,0 +1,9 @@
+.macro clear value, addr
+    lda #value
+    sta addr
+.endmacro
+.segment "CODE"
+Reset:
+    sei
+    clear 0, $80
+    jmp Reset
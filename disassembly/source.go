@@ -0,0 +1,589 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// the number of bytes in each .byte line of a data region
+const sourceBytesPerLine = 8
+
+// symbols must be valid DASM identifiers to be used in the source. symbols
+// that do not qualify are replaced with generated labels or with numbers
+var sourceSymbol = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// a line of source in a section. a line is either a single instruction or a
+// run of data bytes
+type sourceLine struct {
+	offset int
+	length int
+
+	// the instruction definition. nil if the line is data
+	defn *instructions.Definition
+
+	// label for the line. empty if the line has no label
+	label string
+}
+
+// a section of the source. a section is either a located cartridge bank or a
+// region of the cartridge data that does not belong to a bank
+type sourceSection struct {
+	// offset into the cartridge data
+	offset int
+	data   []uint8
+
+	// the bank for this section. nil if the section is not a bank
+	bank *mapper.BankContent
+
+	// the address the bank is assembled for (the DASM RORG value)
+	origin uint16
+
+	lines []sourceLine
+
+	// index into the lines array for every offset that starts a line.
+	// offsets that do not start a line have a value of -1
+	lineStart []int
+}
+
+// a number in the source that has been given a name by an equate
+type sourceEquate struct {
+	name  string
+	value uint16
+}
+
+type sourceWriter struct {
+	dsm  *Disassembly
+	data []uint8
+
+	sections []*sourceSection
+
+	// every name used in the source so far. the value is the value of the
+	// name, used to make sure equates are consistent
+	names   map[string]uint16
+	equates []sourceEquate
+
+	// the lookup table for instruction definitions
+	defns []*instructions.Definition
+}
+
+// WriteSource writes the disassembly as DASM source. The data argument should
+// be the cartridge data that was disassembled. Assembling the source with DASM,
+// using the raw output format (-f3), will recreate the data exactly.
+//
+// Each cartridge bank is written as a separate section with its own ORG and
+// RORG values. Branch and jump targets are given labels, using the labels in
+// the symbols table where possible. Data regions, including any part of the
+// cartridge data that does not belong to a bank, are written as .byte
+// directives.
+//
+// Undocumented opcodes and the BRK instruction are always written as data to
+// make sure the original bytes are recreated. Data classified as graphics is
+// written in binary.
+func (dsm *Disassembly) WriteSource(output io.Writer, data []uint8) error {
+	if len(data) == 0 {
+		return fmt.Errorf("disassembly: no cartridge data to write as source")
+	}
+
+	banks, err := dsm.vcs.Mem.Cart.CopyBanks()
+	if err != nil {
+		return fmt.Errorf("disassembly: %w", err)
+	}
+
+	src := &sourceWriter{
+		dsm:   dsm,
+		data:  data,
+		names: make(map[string]uint16),
+		defns: instructions.GetDefinitions(),
+	}
+
+	dsm.crit.Lock()
+	src.locate(banks)
+	for _, s := range src.sections {
+		src.decode(s)
+	}
+	src.label()
+	body := src.body()
+	dsm.crit.Unlock()
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("; %s (%s)\n", dsm.vcs.Mem.Cart.ShortName, dsm.vcs.Mem.Cart.ID()))
+	b.WriteString(";\n")
+	b.WriteString("; assemble with DASM using the raw output format. for example:\n")
+	b.WriteString(";\n")
+	b.WriteString(fmt.Sprintf(";\tdasm %s.asm -f3 -o%s.bin\n", dsm.vcs.Mem.Cart.ShortName, dsm.vcs.Mem.Cart.ShortName))
+	b.WriteString("\n")
+	b.WriteString("\tprocessor 6502\n")
+
+	if len(src.equates) > 0 {
+		b.WriteString("\n")
+		for _, e := range src.equates {
+			b.WriteString(fmt.Sprintf("%-16s = %s\n", e.name, sourceNumber(e.value)))
+		}
+	}
+
+	b.WriteString(body)
+
+	_, err = io.WriteString(output, b.String())
+	if err != nil {
+		return fmt.Errorf("disassembly: %w", err)
+	}
+
+	return nil
+}
+
+// locate the cartridge banks in the cartridge data. banks are expected to
+// appear in the data in order. a bank that can not be found, because the
+// mapper has transformed the data in some way for example, is not written as
+// a bank and the data is written as a data region instead
+func (src *sourceWriter) locate(banks []mapper.BankContent) {
+	var offset int
+
+	data := func(end int) {
+		if end > offset {
+			src.sections = append(src.sections, &sourceSection{
+				offset: offset,
+				data:   src.data[offset:end],
+			})
+		}
+	}
+
+	for i := range banks {
+		bank := &banks[i]
+		if len(bank.Data) == 0 || len(bank.Origins) == 0 {
+			continue
+		}
+
+		idx := bytes.Index(src.data[offset:], bank.Data)
+		if idx < 0 {
+			continue
+		}
+		idx += offset
+
+		data(idx)
+		src.sections = append(src.sections, &sourceSection{
+			offset: idx,
+			data:   src.data[idx : idx+len(bank.Data)],
+			bank:   bank,
+		})
+		offset = idx + len(bank.Data)
+	}
+
+	data(len(src.data))
+}
+
+// the index into the disassembly entries for the offset in the section
+func (s *sourceSection) index(offset int) int {
+	return (int(s.bank.Origins[0]&memorymap.CartridgeBits) + offset) & int(memorymap.CartridgeBits)
+}
+
+// the entry in the disassembly for the offset in the section
+func (src *sourceWriter) entry(s *sourceSection, offset int) *Entry {
+	if s.bank == nil || s.bank.Number >= len(src.dsm.disasmEntries.Entries) {
+		return nil
+	}
+	return src.dsm.disasmEntries.Entries[s.bank.Number][s.index(offset)]
+}
+
+// the classification of the offset in the section
+func (src *sourceWriter) class(s *sourceSection, offset int) Class {
+	if s.bank == nil || s.bank.Number >= len(src.dsm.classes) {
+		return ClassRow
+	}
+	return src.dsm.classes[s.bank.Number][s.index(offset)]
+}
+
+// decode the section into lines. only blessed entries in the disassembly are
+// written as instructions
+func (src *sourceWriter) decode(s *sourceSection) {
+	s.lineStart = make([]int, len(s.data))
+	for i := range s.lineStart {
+		s.lineStart[i] = -1
+	}
+
+	if s.bank != nil {
+		s.origin = src.origin(s)
+	} else {
+		s.origin = uint16(s.offset)
+	}
+
+	for offset := 0; offset < len(s.data); {
+		l := sourceLine{
+			offset: offset,
+			length: 1,
+		}
+
+		if e := src.entry(s, offset); e != nil && e.Level >= EntryLevelBlessed {
+			defn := src.defns[s.data[offset]]
+			if defn != nil && sourceOperator(defn) && offset+defn.Bytes <= len(s.data) {
+				l.defn = defn
+				l.length = defn.Bytes
+			}
+		}
+
+		s.lineStart[offset] = len(s.lines)
+		s.lines = append(s.lines, l)
+		offset += l.length
+	}
+}
+
+// whether the instruction can be written as an instruction. undocumented
+// opcodes can not be relied upon to assemble to the same opcode and DASM
+// assembles BRK as a single byte
+func sourceOperator(defn *instructions.Definition) bool {
+	op := defn.Operator.String()
+	return op == strings.ToLower(op) && defn.Operator != instructions.Brk
+}
+
+// whether the instruction's operand should be looked up in the read symbols
+// table rather than the write symbols table
+func sourceRead(defn *instructions.Definition) bool {
+	return defn.Effect != instructions.Write && defn.Effect != instructions.RMW
+}
+
+// the operand of the instruction at the line
+func (s *sourceSection) operand(l sourceLine) uint16 {
+	switch l.defn.Bytes {
+	case 2:
+		return uint16(s.data[l.offset+1])
+	case 3:
+		return uint16(s.data[l.offset+1]) | uint16(s.data[l.offset+2])<<8
+	}
+	return 0
+}
+
+// the target address of a branch instruction
+func (s *sourceSection) branchTarget(l sourceLine) uint16 {
+	return s.origin + uint16(l.offset) + 2 + uint16(int8(s.data[l.offset+1]))
+}
+
+// the offset of the address in the section. returns false if the address
+// is not the start of a line in the section
+func (s *sourceSection) lineAt(addr uint16) (int, bool) {
+	if s.bank == nil || addr < s.origin {
+		return 0, false
+	}
+	offset := int(addr - s.origin)
+	if offset >= len(s.data) || s.lineStart[offset] < 0 {
+		return 0, false
+	}
+	return s.lineStart[offset], true
+}
+
+// decide on the address the bank is assembled for. the cartridge address space
+// is mirrored and the choice of mirror affects the value of the bank's
+// labels. the mirror most commonly used by the bank's JMP and JSR instructions
+// is chosen, falling back to the mirror used by the reset vector
+func (src *sourceWriter) origin(s *sourceSection) uint16 {
+	base := s.bank.Origins[0] & memorymap.CartridgeBits
+
+	votes := make(map[uint16]int)
+	for offset := 0; offset < len(s.data); {
+		e := src.entry(s, offset)
+		if e == nil || e.Level < EntryLevelBlessed {
+			offset++
+			continue
+		}
+
+		defn := src.defns[s.data[offset]]
+		if defn == nil || offset+defn.Bytes > len(s.data) {
+			offset++
+			continue
+		}
+
+		if defn.AddressingMode == instructions.Absolute && (defn.Operator == instructions.Jmp || defn.Operator == instructions.Jsr) {
+			addr := uint16(s.data[offset+1]) | uint16(s.data[offset+2])<<8
+			if _, area := memorymap.MapAddress(addr, true); area == memorymap.Cartridge {
+				a := addr & memorymap.CartridgeBits
+				if a >= base && int(a-base) < len(s.data) {
+					votes[addr&^memorymap.CartridgeBits]++
+				}
+			}
+		}
+
+		offset += defn.Bytes
+	}
+
+	// the reset vector is only present if the bank occupies the end of the
+	// cartridge address space
+	mirror := uint16(0xf000)
+	if len(s.data) >= 4 && int(base)+len(s.data) == int(memorymap.CartridgeBits)+1 {
+		reset := uint16(s.data[len(s.data)-4]) | uint16(s.data[len(s.data)-3])<<8
+		if _, area := memorymap.MapAddress(reset, true); area == memorymap.Cartridge {
+			mirror = reset &^ memorymap.CartridgeBits
+		}
+	}
+
+	most := 0
+	for m, v := range votes {
+		if v > most || (v == most && m > mirror) {
+			mirror = m
+			most = v
+		}
+	}
+
+	return mirror | base
+}
+
+// add labels to every line that is the target of a branch, jump or absolute
+// operand. lines with a label in the symbols table are also labelled
+func (src *sourceWriter) label() {
+	// labels from the symbols table take priority over generated labels
+	for _, s := range src.sections {
+		if s.bank == nil {
+			continue
+		}
+		for i := range s.lines {
+			addr := s.origin + uint16(s.lines[i].offset)
+			e, ok := src.dsm.Sym.GetLabel(s.bank.Number, addr)
+			if ok && e.Source != symbols.SourceAuto {
+				src.nameLine(s, i, e.Symbol)
+			}
+		}
+	}
+
+	for _, s := range src.sections {
+		for _, l := range s.lines {
+			if l.defn == nil {
+				continue
+			}
+
+			var addr uint16
+			switch l.defn.AddressingMode {
+			case instructions.Relative:
+				addr = s.branchTarget(l)
+			case instructions.Absolute, instructions.AbsoluteIndexedX, instructions.AbsoluteIndexedY, instructions.Indirect:
+				addr = s.operand(l)
+				if src.hotspot(addr, sourceRead(l.defn)) {
+					continue
+				}
+			default:
+				continue
+			}
+
+			if i, ok := s.lineAt(addr); ok && s.lines[i].label == "" {
+				src.nameLine(s, i, "")
+			}
+		}
+	}
+}
+
+// give the line a name. if the preferred name is empty, invalid or already in
+// use then a name is generated
+func (src *sourceWriter) nameLine(s *sourceSection, i int, name string) {
+	addr := s.origin + uint16(s.lines[i].offset)
+
+	if !src.validName(name) {
+		if len(src.dsm.disasmEntries.Entries) > 1 {
+			name = fmt.Sprintf("L%04X_%d", addr, s.bank.Number)
+		} else {
+			name = fmt.Sprintf("L%04X", addr)
+		}
+	}
+
+	src.names[name] = addr
+	s.lines[i].label = name
+}
+
+func (src *sourceWriter) validName(name string) bool {
+	if !sourceSymbol.MatchString(name) {
+		return false
+	}
+	switch strings.ToLower(name) {
+	case "a", "x", "y":
+		return false
+	}
+	_, used := src.names[name]
+	return !used
+}
+
+// whether the address is a cartridge hotspot
+func (src *sourceWriter) hotspot(addr uint16, read bool) bool {
+	ma, area := memorymap.MapAddress(addr, read)
+	if area != memorymap.Cartridge {
+		return false
+	}
+	e, ok := src.dsm.Sym.GetSymbol(ma, read)
+	return ok && e.Source == symbols.SourceCartridge
+}
+
+// the name for the value taken from the read or write symbols table. returns
+// the empty string if there is no suitable name
+func (src *sourceWriter) symbol(value uint16, read bool) string {
+	ma, _ := memorymap.MapAddress(value, read)
+
+	// system and cartridge symbols are defined for the primary mirror of an
+	// address. system symbols are only used if the value is the primary
+	// mirror. cartridge hotspots are commonly referred to by another mirror
+	// so they are used for any mirror, so long as the name keeps the same
+	// value throughout the source
+	e, ok := src.dsm.Sym.GetSymbol(ma, read)
+	if ok && e.Source == symbols.SourceSystem && ma != value {
+		return ""
+	}
+	if !ok || (e.Source != symbols.SourceSystem && e.Source != symbols.SourceCartridge) {
+		e, ok = src.dsm.Sym.GetSymbol(value, read)
+	}
+	if !ok || !sourceSymbol.MatchString(e.Symbol) {
+		return ""
+	}
+
+	// a name can only have one value
+	if v, used := src.names[e.Symbol]; used {
+		if v != value {
+			return ""
+		}
+		return e.Symbol
+	}
+
+	switch strings.ToLower(e.Symbol) {
+	case "a", "x", "y":
+		return ""
+	}
+
+	src.names[e.Symbol] = value
+	src.equates = append(src.equates, sourceEquate{name: e.Symbol, value: value})
+
+	return e.Symbol
+}
+
+// the text for an address used as an operand. cartridge hotspots are
+// preferred to labels
+func (src *sourceWriter) address(s *sourceSection, addr uint16, read bool) string {
+	if src.hotspot(addr, read) {
+		if sym := src.symbol(addr, read); sym != "" {
+			return sym
+		}
+	}
+	if i, ok := s.lineAt(addr); ok && s.lines[i].label != "" {
+		return s.lines[i].label
+	}
+	if sym := src.symbol(addr, read); sym != "" {
+		return sym
+	}
+	return sourceNumber(addr)
+}
+
+func sourceNumber(v uint16) string {
+	if v < 0x100 {
+		return fmt.Sprintf("$%02x", v)
+	}
+	return fmt.Sprintf("$%04x", v)
+}
+
+// the source for every section. equates are collected as a side effect
+func (src *sourceWriter) body() string {
+	var b strings.Builder
+
+	for _, s := range src.sections {
+		b.WriteString("\n")
+		if s.bank != nil {
+			b.WriteString(fmt.Sprintf("; bank %d\n", s.bank.Number))
+		} else {
+			b.WriteString("; data\n")
+		}
+		b.WriteString(fmt.Sprintf("\tORG $%04x\n", s.offset))
+		b.WriteString(fmt.Sprintf("\tRORG $%04x\n", s.origin))
+
+		var data []string
+		flush := func() {
+			if len(data) > 0 {
+				b.WriteString(fmt.Sprintf("\t.byte %s\n", strings.Join(data, ",")))
+				data = data[:0]
+			}
+		}
+
+		for _, l := range s.lines {
+			if l.label != "" {
+				flush()
+				b.WriteString(fmt.Sprintf("%s\n", l.label))
+			}
+
+			// graphics data is written in binary, one byte per line
+			if l.defn == nil && src.class(s, l.offset) == ClassGfx {
+				flush()
+				b.WriteString(fmt.Sprintf("\t.byte %%%08b\n", s.data[l.offset]))
+				continue
+			}
+
+			if l.defn == nil {
+				data = append(data, fmt.Sprintf("$%02x", s.data[l.offset]))
+				if len(data) >= sourceBytesPerLine {
+					flush()
+				}
+				continue
+			}
+
+			flush()
+			b.WriteString(fmt.Sprintf("\t%s\n", src.instruction(s, l)))
+		}
+		flush()
+	}
+
+	return b.String()
+}
+
+// the source for the instruction
+func (src *sourceWriter) instruction(s *sourceSection, l sourceLine) string {
+	op := l.defn.Operator.String()
+	read := sourceRead(l.defn)
+	v := s.operand(l)
+
+	switch l.defn.AddressingMode {
+	case instructions.Implied:
+		return op
+	case instructions.Immediate:
+		return fmt.Sprintf("%s #$%02x", op, v)
+	case instructions.Relative:
+		return fmt.Sprintf("%s %s", op, src.address(s, s.branchTarget(l), read))
+	case instructions.ZeroPage:
+		return fmt.Sprintf("%s %s", op, src.address(s, v, read))
+	case instructions.ZeroPageIndexedX:
+		return fmt.Sprintf("%s %s,x", op, src.address(s, v, read))
+	case instructions.ZeroPageIndexedY:
+		return fmt.Sprintf("%s %s,y", op, src.address(s, v, read))
+	case instructions.IndexedIndirect:
+		return fmt.Sprintf("%s (%s,x)", op, src.address(s, v, read))
+	case instructions.IndirectIndexed:
+		return fmt.Sprintf("%s (%s),y", op, src.address(s, v, read))
+	case instructions.Indirect:
+		return fmt.Sprintf("%s (%s)", op, src.address(s, v, read))
+	}
+
+	// DASM will use zero page addressing if it can. the .w extension forces
+	// absolute addressing
+	if v < 0x100 {
+		op = fmt.Sprintf("%s.w", op)
+	}
+
+	switch l.defn.AddressingMode {
+	case instructions.AbsoluteIndexedX:
+		return fmt.Sprintf("%s %s,x", op, src.address(s, v, read))
+	case instructions.AbsoluteIndexedY:
+		return fmt.Sprintf("%s %s,y", op, src.address(s, v, read))
+	}
+
+	return fmt.Sprintf("%s %s", op, src.address(s, v, read))
+}
@@ -0,0 +1,241 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package disassembly_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/disassembly/assembler"
+	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// sourceSymbols implements the assembler.Symbols interface for the labels and
+// equates in the source. the symbols are always found in the read table so
+// that the assembler uses the value as it is
+type sourceSymbols struct {
+	values map[string]uint16
+
+	// on the first pass, labels that have not yet been seen are given the
+	// value of the address being assembled. this is enough to decide the
+	// length of the instruction because labels are never in zero page
+	provisional bool
+	pc          uint16
+}
+
+func (sym *sourceSymbols) SearchBySymbol(symbol string, table symbols.SearchTable) *symbols.SearchResults {
+	if table != symbols.SearchRead {
+		return nil
+	}
+	v, ok := sym.values[symbol]
+	if !ok {
+		if !sym.provisional {
+			return nil
+		}
+		v = sym.pc
+	}
+	return &symbols.SearchResults{Table: table, Address: v}
+}
+
+// numbers in the source are either hex or binary. ORG values can be larger
+// than 16 bits for large cartridges
+func parseNumber(s string) (int, error) {
+	var v uint64
+	var err error
+	switch {
+	case strings.HasPrefix(s, "$"):
+		v, err = strconv.ParseUint(s[1:], 16, 32)
+	case strings.HasPrefix(s, "%"):
+		v, err = strconv.ParseUint(s[1:], 2, 32)
+	default:
+		err = fmt.Errorf("not a number: %s", s)
+	}
+	return int(v), err
+}
+
+// assemble the output of WriteSource(). instructions are assembled by the
+// assembler package and this function deals with the directives, labels and
+// equates used by WriteSource(). the value of every label and equate is
+// returned along with the assembled data
+func assemble(source string) ([]uint8, map[string]uint16, error) {
+	sym := &sourceSymbols{
+		values: make(map[string]uint16),
+	}
+
+	var out []uint8
+
+	for pass := 0; pass < 2; pass++ {
+		sym.provisional = pass == 0
+		out = out[:0]
+
+		var offset int
+		var pc uint16
+
+		emit := func(b ...uint8) {
+			for len(out) < offset+len(b) {
+				out = append(out, 0)
+			}
+			copy(out[offset:], b)
+			offset += len(b)
+			pc += uint16(len(b))
+		}
+
+		for n, line := range strings.Split(source, "\n") {
+			line, _, _ = strings.Cut(line, ";")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			// labels and equates start in the first column
+			if line[0] != '\t' {
+				name, value, ok := strings.Cut(line, "=")
+				if ok {
+					v, err := parseNumber(strings.TrimSpace(value))
+					if err != nil {
+						return nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+					}
+					sym.values[strings.TrimSpace(name)] = uint16(v)
+				} else {
+					sym.values[line] = pc
+				}
+				continue
+			}
+
+			directive, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+			switch directive {
+			case "processor":
+			case "ORG":
+				v, err := parseNumber(arg)
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				offset = v
+			case "RORG":
+				v, err := parseNumber(arg)
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				pc = uint16(v)
+			case ".byte":
+				for _, b := range strings.Split(arg, ",") {
+					v, err := parseNumber(b)
+					if err != nil {
+						return nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+					}
+					emit(uint8(v))
+				}
+			default:
+				sym.pc = pc
+				b, err := assembler.Assemble(pc, line, sym)
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				emit(b...)
+			}
+		}
+	}
+
+	return out, sym.values, nil
+}
+
+func TestSourceRoundTrip(t *testing.T) {
+	// random data will be disassembled to a mixture of blessed instructions
+	// and data. the reset vector of each 4k block points to the start of the
+	// block
+	rnd := rand.New(rand.NewSource(2600))
+
+	// every mapper that can be created from plain cartridge data. mappers that
+	// require an ARM program (DPC+, CDF, ACE and ELF), Supercharger tapes and
+	// MovieCart streams are not included
+	for _, c := range []struct {
+		mapping string
+		size    int
+	}{
+		{mapping: "2K", size: 2048},
+		{mapping: "4K", size: 4096},
+		{mapping: "F8", size: 8192},
+		{mapping: "WF8", size: 8192},
+		{mapping: "F6", size: 16384},
+		{mapping: "F4", size: 32768},
+		{mapping: "2KSC", size: 2048},
+		{mapping: "4KSC", size: 4096},
+		{mapping: "F8SC", size: 8192},
+		{mapping: "F6SC", size: 16384},
+		{mapping: "F4SC", size: 32768},
+		{mapping: "CV", size: 2048},
+		{mapping: "FA", size: 12288},
+		{mapping: "FA2", size: 28672},
+		{mapping: "4A50", size: 131072},
+		{mapping: "CTY", size: 32768},
+		{mapping: "FE", size: 8192},
+		{mapping: "E0", size: 8192},
+		{mapping: "E7", size: 16384},
+		{mapping: "3F", size: 8192},
+		{mapping: "UA", size: 8192},
+		{mapping: "0840", size: 8192},
+		{mapping: "X07", size: 65536},
+		{mapping: "DF", size: 131072},
+		{mapping: "DFSC", size: 131072},
+		{mapping: "BF", size: 262144},
+		{mapping: "BFSC", size: 262144},
+		{mapping: "3E", size: 32768},
+		{mapping: "E3P", size: 32768},
+		{mapping: "E3+", size: 32768},
+		{mapping: "3E+", size: 32768},
+		{mapping: "EF", size: 65536},
+		{mapping: "EFSC", size: 65536},
+		{mapping: "SB", size: 131072},
+		{mapping: "WD", size: 8192},
+		{mapping: "DPC", size: 10240},
+	} {
+		data := make([]uint8, c.size)
+		rnd.Read(data)
+		for b := 0; b < c.size; b += 4096 {
+			end := min(b+4096, c.size)
+			data[end-4] = 0x00
+			data[end-3] = 0xf0
+		}
+
+		cartload, err := cartridgeloader.NewLoaderFromData("source", data, c.mapping, nil)
+		test.ExpectSuccess(t, err)
+
+		dsm, err := disassembly.FromCartridge(cartload)
+		if !test.ExpectSuccess(t, err) {
+			continue
+		}
+
+		var src bytes.Buffer
+		test.ExpectSuccess(t, dsm.WriteSource(&src, data))
+
+		out, _, err := assemble(src.String())
+		if !test.ExpectSuccess(t, err) {
+			t.Logf("%s: %v", c.mapping, err)
+			continue
+		}
+
+		if !bytes.Equal(out, data) {
+			t.Errorf("%s: assembled source does not match cartridge data", c.mapping)
+		}
+	}
+}
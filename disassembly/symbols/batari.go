@@ -0,0 +1,64 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package symbols
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the names generated by the batari Basic compiler. when there is more than
+// one symbol for an address, these names are less useful than the names
+// chosen by the programmer. for example, a variable declared with
+//
+//	dim score = a
+//
+// will result in two symbols for the same address: "a" and "score"
+var batariGenerated = regexp.MustCompile(`^([a-z]|var[0-9]+|temp[0-9]+|\.L[0-9]+|\.skipL[0-9]+)$`)
+
+// whether the symbols are from a batari Basic program. batari Basic programs
+// are assembled with DASM so the symbols file is a DASM symbols file
+func isBatari(filename string, symbols []fileSymbol) bool {
+	if strings.Contains(strings.ToLower(filename), ".bas.") {
+		return true
+	}
+
+	// the standard batari Basic variables
+	var var0, temp1 bool
+	for _, s := range symbols {
+		var0 = var0 || s.symbol == "var0"
+		temp1 = temp1 || s.symbol == "temp1"
+	}
+	return var0 && temp1
+}
+
+// prioritise the symbols chosen by the programmer of a batari Basic program
+// over the symbols generated by the compiler. because only the first symbol
+// for an address is used, generated symbols are moved to the end of the list
+func prioritiseBatari(symbols []fileSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return !batariGenerated.MatchString(symbols[i].symbol) && batariGenerated.MatchString(symbols[j].symbol)
+	})
+
+	// labels in batari Basic programs are prefixed with a period. this is
+	// removed for clarity
+	for i := range symbols {
+		if !batariGenerated.MatchString(symbols[i].symbol) {
+			symbols[i].symbol = strings.TrimPrefix(symbols[i].symbol, ".")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parse the debug information file produced by ld65 with the --dbgfile
+// option. only the sym records are of interest
+func parseCA65(r io.Reader) ([]fileSymbol, error) {
+	var symbols []fileSymbol
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		typ, attr, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok || typ != "sym" {
+			continue // for loop
+		}
+
+		var name, val, kind string
+		for _, a := range strings.Split(attr, ",") {
+			k, v, _ := strings.Cut(a, "=")
+			switch k {
+			case "name":
+				name = strings.Trim(v, `"`)
+			case "val":
+				val = v
+			case "type":
+				kind = v
+			}
+		}
+
+		// imported symbols have no value. the value is recorded by the
+		// symbol record for the export
+		if name == "" || val == "" || kind == "imp" {
+			continue // for loop
+		}
+
+		address, err := strconv.ParseUint(val, 0, 32)
+		if err != nil || address > 0xffff {
+			continue // for loop
+		}
+
+		symbols = append(symbols, fileSymbol{
+			symbol:  name,
+			address: uint16(address),
+			equate:  kind == "equ",
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ca65: processing error: %w", err)
+	}
+
+	return symbols, nil
+}
+
+// parse a label file in the format used by the VICE monitor. ld65 writes
+// files in this format with the -Ln option
+//
+//	al C:f000 .reset
+func parseVICE(r io.Reader) ([]fileSymbol, error) {
+	var symbols []fileSymbol
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p := strings.Fields(scanner.Text())
+		if len(p) < 3 || p[0] != "al" {
+			continue // for loop
+		}
+
+		// the address may be prefixed by a memory space
+		_, addr, ok := strings.Cut(p[1], ":")
+		if !ok {
+			addr = p[1]
+		}
+
+		address, err := strconv.ParseUint(addr, 16, 32)
+		if err != nil || address > 0xffff {
+			continue // for loop
+		}
+
+		symbols = append(symbols, fileSymbol{
+			symbol:  strings.TrimPrefix(p[2], "."),
+			address: uint16(address),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("vice: processing error: %w", err)
+	}
+
+	return symbols, nil
+}
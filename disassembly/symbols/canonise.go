@@ -16,10 +16,12 @@
 package symbols
 
 import (
+	"sort"
+
 	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
 	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
 	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
-	"github.com/jetsetilly/gopher2600/logger"
 )
 
 // put canonical symbols into table. prefer flag should be true if canonical
@@ -65,20 +67,39 @@ func (sym *Symbols) canonise(cart *cartridge.Cartridge) {
 
 	hb := cart.GetCartHotspotsBus()
 	if hb != nil {
-		for k, v := range hb.ReadHotspots() {
-			ma, area := memorymap.MapAddress(k, true)
-			if area != memorymap.Cartridge {
-				logger.Logf(logger.Allow, "symbols", "%s reporting hotspot (%s) outside of cartridge address space", cart.ID(), v.Symbol)
-			}
-			sym.read.add(SourceCartridge, ma, v.Symbol)
+		// hotspots are added in address order so that symbols made unique by
+		// the table are numbered the same way every time
+		h := hb.ReadHotspots()
+		for _, k := range sortedHotspots(h) {
+			sym.read.add(SourceCartridge, hotspotAddress(k, true), h[k].Symbol)
 		}
 
-		for k, v := range hb.WriteHotspots() {
-			ma, area := memorymap.MapAddress(k, false)
-			if area != memorymap.Cartridge {
-				logger.Logf(logger.Allow, "symbols", "%s reporting hotspot (%s) outside of cartridge address space", cart.ID(), v.Symbol)
-			}
-			sym.write.add(SourceCartridge, ma, v.Symbol)
+		h = hb.WriteHotspots()
+		for _, k := range sortedHotspots(h) {
+			sym.write.add(SourceCartridge, hotspotAddress(k, false), h[k].Symbol)
 		}
 	}
 }
+
+func sortedHotspots(h map[uint16]mapper.CartHotspotInfo) []uint16 {
+	keys := make([]uint16, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// some mappers (eg. 0840, X07, 4A50) bankswitch on accesses to addresses
+// outside of the cartridge area. the mapped address for these hotspots is the
+// address of a TIA or RIOT register and so they are indexed by the unmapped
+// address instead. see GetSymbol() for how these addresses are looked up
+func hotspotAddress(addr uint16, read bool) uint16 {
+	ma, area := memorymap.MapAddress(addr, read)
+	if area != memorymap.Cartridge {
+		return addr & memorymap.Memtop
+	}
+	return ma
+}
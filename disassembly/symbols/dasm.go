@@ -16,78 +16,22 @@
 package symbols
 
 import (
+	"bufio"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
-
-	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
-	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
-	"github.com/jetsetilly/gopher2600/logger"
 )
 
-// ReadDASMSymbolsFile initialises a symbols table from the symbols file for the
-// specified cartridge. Even in the event of an error the Symbols table will
-// still be usable and will contain the standard 2600 symbols.
-//
-// Currently, only symbols files generated by DASM are supported.
-func (sym *Symbols) ReadDASMSymbolsFile(cart *cartridge.Cartridge) error {
-	sym.initialise(cart.NumBanks())
-
-	sym.crit.Lock()
-	defer sym.crit.Unlock()
-
-	// prefer default symbol for an address over any symbol that has been
-	// specified in the symbols file. we always do this even in the event of
-	// there being no symbols file.
-	defer sym.canonise(cart)
-
-	err := sym.fromDasm(cart)
-	if err != nil {
-		return fmt.Errorf("symbols: %w", err)
-	}
-
-	return nil
-}
-
-func (sym *Symbols) fromDasm(cart *cartridge.Cartridge) error {
-	// if this is the empty cartridge then this error is expected. return
-	// the empty symbol table
-	if cart.Filename == "" {
-		return nil
-	}
-
-	// try to open symbols file
-	symFilename := cart.Filename
-	ext := filepath.Ext(symFilename)
+// parse a symbols file produced by DASM with the -s option. symbol files
+// written by Stella are in the same format
+func parseDASM(r io.Reader) ([]fileSymbol, error) {
+	var symbols []fileSymbol
 
-	// try to figure out the case of the file extension
-	if ext == ".BIN" {
-		symFilename = fmt.Sprintf("%s.SYM", symFilename[:len(symFilename)-len(ext)])
-	} else {
-		symFilename = fmt.Sprintf("%s.sym", symFilename[:len(symFilename)-len(ext)])
-	}
-
-	sf, err := os.Open(symFilename)
-	if err != nil {
-		logger.Logf(logger.Allow, "symbols", "dasm .sym file not available (%s)", cart.Filename)
-		return nil
-	}
-	defer sf.Close()
-
-	data, err := io.ReadAll(sf)
-	if err != nil {
-		return fmt.Errorf("dasm: processing error: %w", err)
-	}
-	lines := strings.Split(string(data), "\n")
-
-	// find interesting lines in the symbols file and add to the Symbols
-	// instance.
-	for _, ln := range lines {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
 		// ignore uninteresting lines
-		p := strings.Fields(ln)
+		p := strings.Fields(scanner.Text())
 		if len(p) < 2 || p[0] == "---" {
 			continue // for loop
 		}
@@ -98,11 +42,7 @@ func (sym *Symbols) fromDasm(cart *cartridge.Cartridge) error {
 			continue // for loop
 		}
 
-		// add symbol to label list or read/write list
-
-		// get symbol and mapped address and memory area
 		symbol := p[0]
-		ma, area := memorymap.MapAddress(uint16(address), true)
 
 		// remove leading digits if they are present. these digits have
 		// been added by DASM for the symbols file
@@ -111,20 +51,15 @@ func (sym *Symbols) fromDasm(cart *cartridge.Cartridge) error {
 			symbol = symbol[len(sp[0]):]
 		}
 
-		if area == memorymap.Cartridge {
-			// adding label for address in every bank for now
-			// !!TODO: more selecting adding of label from symbols file
-			for b := range sym.label {
-				sym.label[b].add(SourceDASM, ma, symbol)
-			}
-		} else {
-			// (non-label) symbols are both a read and write symbol. compare to
-			// canonical vcs symbols which are specific to a read or write
-			// context
-			sym.read.add(SourceDASM, uint16(address), symbol)
-			sym.write.add(SourceDASM, uint16(address), symbol)
-		}
+		symbols = append(symbols, fileSymbol{
+			symbol:  symbol,
+			address: uint16(address),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("dasm: processing error: %w", err)
 	}
 
-	return nil
+	return symbols, nil
 }
@@ -14,7 +14,7 @@
 // along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
 
 // Package symbols helps keep track of address symbols for the currently loaded
-// cartridge. It will load symbols from a DASM symbol file if one can be found.
+// cartridge. It will load symbols from a symbols file if one can be found.
 // It also handles the allocation of standard (or canonical) symbol names.
 //
 // In the context of the Gopher2600 project, it works best if the Symbol type
@@ -0,0 +1,294 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/logger"
+)
+
+// a symbol as read from a symbols file. the address is unmapped
+type fileSymbol struct {
+	symbol  string
+	address uint16
+
+	// the symbol is a constant value rather than an address. not all file
+	// formats make the distinction
+	equate bool
+}
+
+// ReadSymbolsFile initialises a symbols table from the symbols file for the
+// specified cartridge. Even in the event of an error the Symbols table will
+// still be usable and will contain the standard 2600 symbols.
+//
+// The symbols file is found by replacing the extension of the cartridge
+// filename. In order of preference:
+//
+//	.sym      DASM or Stella symbols file (including batari Basic programs)
+//	.bas.sym  symbols file generated by batari Basic
+//	.dbg      ld65 debug information file
+//	.lbl      VICE label file (as generated by ld65)
+func (sym *Symbols) ReadSymbolsFile(cart *cartridge.Cartridge) error {
+	sym.initialise(cart.NumBanks())
+
+	sym.crit.Lock()
+	defer sym.crit.Unlock()
+
+	// prefer default symbol for an address over any symbol that has been
+	// specified in the symbols file. we always do this even in the event of
+	// there being no symbols file.
+	defer sym.canonise(cart)
+
+	// if this is the empty cartridge then this error is expected. return
+	// the empty symbol table
+	if cart.Filename == "" {
+		return nil
+	}
+
+	base := strings.TrimSuffix(cart.Filename, filepath.Ext(cart.Filename))
+
+	// try to figure out the case of the file extension
+	candidates := []string{".sym", ".bas.sym", ".dbg", ".lbl", ".lab"}
+	if filepath.Ext(cart.Filename) == ".BIN" {
+		candidates = []string{".SYM", ".BAS.SYM", ".DBG", ".LBL", ".LAB"}
+	}
+
+	for _, ext := range candidates {
+		filename := fmt.Sprintf("%s%s", base, ext)
+		if _, err := os.Stat(filename); err != nil {
+			continue // for loop
+		}
+
+		err := sym.fromFile(filename)
+		if err != nil {
+			return fmt.Errorf("symbols: %w", err)
+		}
+		return nil
+	}
+
+	logger.Logf(logger.Allow, "symbols", "symbols file not available (%s)", cart.Filename)
+
+	return nil
+}
+
+// ReadFile adds the symbols in the named file to the existing symbols table.
+// The format of the file is detected automatically.
+//
+// Symbols in the file are added alongside any symbols that already exist.
+// Addresses that already have a symbol are not changed.
+func (sym *Symbols) ReadFile(filename string) error {
+	sym.crit.Lock()
+	defer sym.crit.Unlock()
+
+	err := sym.fromFile(filename)
+	if err != nil {
+		return fmt.Errorf("symbols: %w", err)
+	}
+
+	sym.resort()
+
+	return nil
+}
+
+// the different symbol file formats
+type fileFormat int
+
+const (
+	formatDASM fileFormat = iota
+	formatCA65
+	formatVICE
+)
+
+// detect the format of a symbols file by looking at the first meaningful line
+func detectFormat(data []byte) fileFormat {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		ln := strings.TrimSpace(scanner.Text())
+		if ln == "" {
+			continue // for loop
+		}
+		switch {
+		case strings.HasPrefix(ln, "version\t"):
+			return formatCA65
+		case strings.HasPrefix(ln, "al "):
+			return formatVICE
+		}
+		return formatDASM
+	}
+	return formatDASM
+}
+
+// should be called in critical section
+func (sym *Symbols) fromFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var symbols []fileSymbol
+	var source SymbolSource
+
+	switch detectFormat(data) {
+	case formatCA65:
+		source = SourceCA65
+		symbols, err = parseCA65(bytes.NewReader(data))
+	case formatVICE:
+		source = SourceVICE
+		symbols, err = parseVICE(bytes.NewReader(data))
+	default:
+		// batari Basic programs are assembled with DASM so the source is
+		// still DASM
+		source = SourceDASM
+		symbols, err = parseDASM(bytes.NewReader(data))
+		if err == nil && isBatari(filename, symbols) {
+			prioritiseBatari(symbols)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	sym.addFileSymbols(source, symbols)
+
+	return nil
+}
+
+// add symbols read from a symbols file to the label or read/write tables.
+// should be called in critical section
+func (sym *Symbols) addFileSymbols(source SymbolSource, symbols []fileSymbol) {
+	for _, s := range symbols {
+		// get mapped address and memory area
+		ma, area := memorymap.MapAddress(s.address, true)
+
+		if area == memorymap.Cartridge {
+			// an equate with a value in the cartridge area is unlikely to be
+			// the address of anything
+			if s.equate {
+				continue // for loop
+			}
+
+			// adding label for address in every bank for now
+			// !!TODO: more selecting adding of label from symbols file
+			for b := range sym.label {
+				sym.label[b].add(source, ma, s.symbol)
+			}
+		} else {
+			// (non-label) symbols are both a read and write symbol. compare to
+			// canonical vcs symbols which are specific to a read or write
+			// context
+			sym.read.add(source, s.address, s.symbol)
+			sym.write.add(source, s.address, s.symbol)
+		}
+	}
+}
+
+// WriteDASM writes the user defined symbols in the format of a DASM symbols
+// file. Files written in this format can be loaded by Stella.
+//
+// Symbols that are defined by the emulator (canonical symbols and
+// automatically generated labels) are not written.
+func (sym *Symbols) WriteDASM(w io.Writer) error {
+	symbols := sym.exportable()
+
+	// DASM sorts the symbols file by symbol
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return strings.ToLower(symbols[i].symbol) < strings.ToLower(symbols[j].symbol)
+	})
+
+	var b strings.Builder
+	b.WriteString("--- Symbol List (sorted by symbol)\n")
+	for _, s := range symbols {
+		b.WriteString(fmt.Sprintf("%-24s %04x\n", s.symbol, s.address))
+	}
+	b.WriteString("--- End of Symbol List.\n")
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return fmt.Errorf("symbols: %w", err)
+	}
+	return nil
+}
+
+// WriteVICE writes the user defined symbols in the format of a VICE label
+// file.
+//
+// Symbols that are defined by the emulator (canonical symbols and
+// automatically generated labels) are not written.
+func (sym *Symbols) WriteVICE(w io.Writer) error {
+	symbols := sym.exportable()
+
+	var b strings.Builder
+	for _, s := range symbols {
+		b.WriteString(fmt.Sprintf("al C:%04x .%s\n", s.address, s.symbol))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return fmt.Errorf("symbols: %w", err)
+	}
+	return nil
+}
+
+// returns the list of symbols suitable for exporting to a file, sorted by
+// address. a label that appears in more than one bank is listed once
+func (sym *Symbols) exportable() []fileSymbol {
+	sym.crit.Lock()
+	defer sym.crit.Unlock()
+
+	var symbols []fileSymbol
+	seen := make(map[fileSymbol]bool)
+
+	add := func(t *table, labels bool) {
+		for _, addr := range t.sortedIdx {
+			e := t.byAddr[addr]
+			switch e.Source {
+			case SourceSystem, SourceCartridge:
+				continue // for loop
+			case SourceAuto:
+				if labels {
+					continue // for loop
+				}
+			}
+			s := fileSymbol{symbol: e.Symbol, address: addr}
+			if !seen[s] {
+				seen[s] = true
+				symbols = append(symbols, s)
+			}
+		}
+	}
+
+	for _, l := range sym.label {
+		add(l, true)
+	}
+	add(sym.read, false)
+	add(sym.write, false)
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].address < symbols[j].address
+	})
+
+	return symbols
+}
@@ -118,10 +118,23 @@ func (sym *Symbols) GetSymbol(addr uint16, read bool) (Entry, bool) {
 	sym.crit.Lock()
 	defer sym.crit.Unlock()
 
+	// cartridge hotspots outside of the cartridge area are indexed by the
+	// unmapped address and take precedence over the system symbol for the
+	// mapped address. see hotspotAddress() in canonise.go
+	ma, area := memorymap.MapAddress(addr, read)
+	if area != memorymap.Cartridge {
+		t := sym.write
+		if read {
+			t = sym.read
+		}
+		if e, ok := t.get(addr & memorymap.Memtop); ok && e.Source == SourceCartridge {
+			return e, ok
+		}
+	}
+
 	// we first try to get the symbol with a mapped address. if the resulting
 	// symbol is of SourceSystem then the result is fine, otherwise we try
 	// again with the unmapped address
-	ma, _ := memorymap.MapAddress(addr, read)
 
 	if read {
 		if e, ok := sym.read.get(ma); !ok || e.Source == SourceSystem {
@@ -142,6 +155,8 @@ type SymbolSource string
 // List of valid SymbolSource values.
 const (
 	SourceDASM      SymbolSource = "DASM"
+	SourceCA65      SymbolSource = "ca65"
+	SourceVICE      SymbolSource = "VICE"
 	SourceAuto      SymbolSource = "Auto"
 	SourceSystem    SymbolSource = "System"
 	SourceCartridge SymbolSource = "Cartridge"
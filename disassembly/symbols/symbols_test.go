@@ -16,11 +16,17 @@
 package symbols_test
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
 	"github.com/jetsetilly/gopher2600/disassembly/symbols"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
 	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/hardware/television"
 	"github.com/jetsetilly/gopher2600/test"
 )
 
@@ -28,7 +34,7 @@ func TestDefaultSymbols(t *testing.T) {
 	var sym symbols.Symbols
 
 	cart := cartridge.NewCartridge(nil)
-	err := sym.ReadDASMSymbolsFile(cart)
+	err := sym.ReadSymbolsFile(cart)
 	if err != nil {
 		t.Errorf("unexpected error (%s)", err)
 	}
@@ -50,7 +56,7 @@ func TestFlappySymbols(t *testing.T) {
 	cart := cartridge.NewCartridge(nil)
 	cart.Filename = "testdata/flappy.bin"
 
-	err := sym.ReadDASMSymbolsFile(cart)
+	err := sym.ReadSymbolsFile(cart)
 	if err != nil {
 		t.Errorf("unexpected error (%s)", err)
 	}
@@ -65,6 +71,126 @@ func TestFlappySymbols(t *testing.T) {
 	}
 }
 
+// list the labels and read symbols that are not system symbols
+func listNonSystem(sym *symbols.Symbols) string {
+	var s strings.Builder
+	for addr := uint16(0); addr < 0x2000; addr++ {
+		if e, ok := sym.GetLabel(0, addr); ok && e.Source != symbols.SourceSystem {
+			s.WriteString(fmt.Sprintf("%#04x -> %s [%s]\n", addr, e.Symbol, e.Source))
+		}
+	}
+	for addr := uint16(0); addr < 0x1000; addr++ {
+		if e, ok := sym.GetSymbol(addr, true); ok && e.Source != symbols.SourceSystem {
+			s.WriteString(fmt.Sprintf("%#04x -> %s [%s]\n", addr, e.Symbol, e.Source))
+		}
+	}
+	return s.String()
+}
+
+func TestOtherFormats(t *testing.T) {
+	var sym symbols.Symbols
+
+	cart := cartridge.NewCartridge(nil)
+	err := sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	err = sym.ReadFile("testdata/vice.lbl")
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, listNonSystem(&sym), `0x1000 -> reset [VICE]
+0x100a -> kernel [VICE]
+0x1ffc -> vectors [VICE]
+0x0080 -> score [VICE]
+`)
+
+	err = sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	// imported symbols and equates in the cartridge area are ignored
+	err = sym.ReadFile("testdata/ca65.dbg")
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, listNonSystem(&sym), `0x1000 -> reset [ca65]
+0x1004 -> loop [ca65]
+0x0081 -> lives [ca65]
+`)
+
+	err = sym.ReadFile("testdata/missing.sym")
+	test.ExpectFailure(t, err)
+}
+
+func TestBatariSymbols(t *testing.T) {
+	var sym symbols.Symbols
+
+	// the symbols file for a batari Basic program is found with the .bas.sym
+	// extension
+	cart := cartridge.NewCartridge(nil)
+	cart.Filename = "testdata/game.bin"
+
+	err := sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	// names chosen by the programmer are preferred over generated names
+	test.ExpectEquality(t, listNonSystem(&sym), `0x1010 -> mainloop [DASM]
+0x1018 -> .skipL01 [DASM]
+0x009c -> temp1 [DASM]
+0x00a4 -> var0 [DASM]
+0x00d6 -> score [DASM]
+0x00d7 -> b [DASM]
+`)
+}
+
+func TestWriteSymbols(t *testing.T) {
+	var sym symbols.Symbols
+
+	cart := cartridge.NewCartridge(nil)
+	err := sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	err = sym.ReadFile("testdata/ca65.dbg")
+	test.ExpectSuccess(t, err)
+
+	// custom labels are exported. automatic labels are not
+	test.ExpectSuccess(t, sym.AddLabel(symbols.SourceCustom, 0, 0xf020, "custom"))
+	test.ExpectSuccess(t, sym.AddLabelAuto(0, 0xf030))
+
+	var dasm strings.Builder
+	err = sym.WriteDASM(&dasm)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, dasm.String(), `--- Symbol List (sorted by symbol)
+custom                   1020
+lives                    0081
+loop                     1004
+reset                    1000
+--- End of Symbol List.
+`)
+
+	var vice strings.Builder
+	err = sym.WriteVICE(&vice)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, vice.String(), `al C:0081 .lives
+al C:1000 .reset
+al C:1004 .loop
+al C:1020 .custom
+`)
+
+	// the exported DASM file can be read back in
+	err = sym.ReadSymbolsFile(cart)
+	test.ExpectSuccess(t, err)
+
+	f, err := os.CreateTemp(t.TempDir(), "*.sym")
+	test.ExpectSuccess(t, err)
+	_, err = f.WriteString(dasm.String())
+	test.ExpectSuccess(t, err)
+	f.Close()
+
+	err = sym.ReadFile(f.Name())
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, listNonSystem(&sym), `0x1000 -> reset [DASM]
+0x1004 -> loop [DASM]
+0x1020 -> custom [DASM]
+0x0081 -> lives [DASM]
+`)
+}
+
 const expectedDefaultSymbols = `Labels
 ------
 
@@ -586,3 +712,41 @@ Write Symbols
 0x0297 -> T1024T [System]
 0x2e20 -> _MSG_MARKER [DASM]
 `
+
+// hotspots outside of the cartridge area do not replace the system symbols
+func TestHotspotSymbols(t *testing.T) {
+	for _, c := range []struct {
+		mapping string
+		size    int
+		addr    uint16
+		symbol  string
+		source  symbols.SymbolSource
+	}{
+		{mapping: "4A50", size: 131072, addr: 0x0c00, symbol: "HIGHROM", source: symbols.SourceCartridge},
+		{mapping: "4A50", size: 131072, addr: 0x6c00, symbol: "HIGHROM", source: symbols.SourceCartridge},
+		{mapping: "4A50", size: 131072, addr: 0x0c01, symbol: "CXM1P", source: symbols.SourceSystem},
+		{mapping: "4A50", size: 131072, addr: 0x0000, symbol: "CXM0P", source: symbols.SourceSystem},
+		{mapping: "0840", size: 8192, addr: 0x0800, symbol: "BANK0", source: symbols.SourceCartridge},
+		{mapping: "0840", size: 8192, addr: 0x0840, symbol: "BANK1", source: symbols.SourceCartridge},
+		{mapping: "0840", size: 8192, addr: 0x0940, symbol: "BANK1_1", source: symbols.SourceCartridge},
+		{mapping: "0840", size: 8192, addr: 0x0040, symbol: "CXM0P", source: symbols.SourceSystem},
+		{mapping: "X07", size: 65536, addr: 0x08ad, symbol: "BANK10", source: symbols.SourceCartridge},
+	} {
+		tv, err := television.NewTelevision("NTSC")
+		test.ExpectSuccess(t, err)
+		vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+		test.ExpectSuccess(t, err)
+
+		cartload, err := cartridgeloader.NewLoaderFromData("hotspots", make([]uint8, c.size), c.mapping, nil)
+		test.ExpectSuccess(t, err)
+		test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+		var sym symbols.Symbols
+		test.ExpectSuccess(t, sym.ReadSymbolsFile(vcs.Mem.Cart))
+
+		e, ok := sym.GetSymbol(c.addr, true)
+		test.ExpectSuccess(t, ok)
+		test.ExpectEquality(t, e.Symbol, c.symbol)
+		test.ExpectEquality(t, e.Source, c.source)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/coverage"
 	"github.com/jetsetilly/gopher2600/debugger"
 	"github.com/jetsetilly/gopher2600/debugger/govern"
 	"github.com/jetsetilly/gopher2600/debugger/terminal"
@@ -36,12 +37,18 @@ import (
 	"github.com/jetsetilly/gopher2600/gui"
 	"github.com/jetsetilly/gopher2600/gui/sdlimgui"
 	"github.com/jetsetilly/gopher2600/hardware/riot/ports"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
 	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+	"github.com/jetsetilly/gopher2600/library"
+	"github.com/jetsetilly/gopher2600/lint"
 	"github.com/jetsetilly/gopher2600/logger"
 	"github.com/jetsetilly/gopher2600/performance"
+	"github.com/jetsetilly/gopher2600/properties"
 	"github.com/jetsetilly/gopher2600/recorder"
 	"github.com/jetsetilly/gopher2600/regression"
+	"github.com/jetsetilly/gopher2600/render"
 	"github.com/jetsetilly/gopher2600/resources"
+	"github.com/jetsetilly/gopher2600/robustness"
 	"github.com/jetsetilly/gopher2600/version"
 )
 
@@ -227,7 +234,7 @@ func launch(sync *mainSync, args []string) {
 	err := flgs.Parse(args)
 	if err != nil {
 		if err == flag.ErrHelp {
-			fmt.Println("Execution Modes: RUN, DEBUG, DISASM, PERFORMANCE, REGRESS, VERSION")
+			fmt.Println("Execution Modes: RUN, DEBUG, DISASM, PERFORMANCE, REGRESS, RENDER, COVERAGE, LINT, ROBUSTNESS, LIBRARY, VERSION")
 			sync.state <- stateRequest{req: reqQuit, args: 20}
 			return
 		}
@@ -260,6 +267,16 @@ func launch(sync *mainSync, args []string) {
 		err = perform(mode, sync, args[1:])
 	case "REGRESS":
 		err = regress(mode, args[1:])
+	case "RENDER":
+		err = renderFrames(mode, args[1:])
+	case "COVERAGE":
+		err = codeCoverage(mode, args[1:])
+	case "LINT":
+		err = lintROM(mode, args[1:])
+	case "ROBUSTNESS":
+		err = robust(mode, args[1:])
+	case "LIBRARY":
+		err = romLibrary(mode, args[1:])
 	case "VERSION":
 		err = showVersion(mode, args[1:])
 	}
@@ -309,6 +326,10 @@ func emulate(mode string, sync *mainSync, args []string) error {
 	flgs.BoolVar(&opts.Swap, "swap", false, "swap player ports")
 	flgs.StringVar(&opts.Profile, "profile", "none", "run performance check with profiling: CPU, MEM, TRACE, ALL (comma sep)")
 	flgs.StringVar(&opts.ELF, "elf", "", "path to ELF file. only valid for some coproc supporting ROMs")
+	flgs.StringVar(&opts.GDB, "gdb", "", "listen for GDB connections on address or port. only valid for coproc supporting ROMs")
+	flgs.StringVar(&opts.DAP, "dap", "", "listen for Debug Adapter Protocol connections on address or port")
+	flgs.StringVar(&opts.TraceFile, "tracefile", "", "write every executed CPU instruction to file")
+	flgs.StringVar(&opts.PatchFile, "patch", "", "patch file to apply to cartridge: IPS, BPS, cmp or neo format (not playback files)")
 
 	// playmode specific arguments
 	if emulationMode == govern.ModePlay {
@@ -316,7 +337,6 @@ func emulate(mode string, sync *mainSync, args []string) error {
 		flgs.StringVar(&opts.ComparisonPrefs, "comparisonPrefs", "", "preferences for comparison emulation")
 		flgs.BoolVar(&opts.Record, "record", false, "record user input to playback file")
 		flgs.BoolVar(&opts.PlaybackCheckROM, "playbackCheckROM", true, "check ROM hashes on playback")
-		flgs.StringVar(&opts.PatchFile, "patch", "", "path to apply to emulation (not playback files")
 		flgs.BoolVar(&opts.Wav, "wav", false, "record audio to wav file")
 		flgs.BoolVar(&opts.NoEject, "noeject", false, "emulator will not quit is noeject is true")
 		flgs.StringVar(&opts.Macro, "macro", "", "macro file to be run on trigger")
@@ -485,11 +505,17 @@ func disasm(mode string, args []string) error {
 	var mapping string
 	var bytecode bool
 	var bank int
+	var source bool
+	var cfg string
+	var writecfg string
 
 	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
 	flgs.StringVar(&mapping, "mapping", "AUTO", "force cartridge mapper selection")
 	flgs.BoolVar(&bytecode, "bytecode", false, "including bytecode in disassembly")
 	flgs.IntVar(&bank, "bank", -1, "show disassembly for a specific bank")
+	flgs.BoolVar(&source, "source", false, "output DASM source that reassembles to the original cartridge")
+	flgs.StringVar(&cfg, "cfg", "", "apply Distella/Stella configuration file to the disassembly")
+	flgs.StringVar(&writecfg, "writecfg", "", "write Stella configuration file for the disassembly")
 
 	// parse args and get copy of remaining arguments
 	err := flgs.Parse(args)
@@ -524,6 +550,50 @@ func disasm(mode string, args []string) error {
 			return err
 		}
 
+		// configuration files are applied after the static analysis so that
+		// the directives in the file take precedence
+		if cfg != "" {
+			f, err := os.Open(cfg)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			err = dsm.ReadConfig(f)
+			if err != nil {
+				return err
+			}
+		}
+
+		if writecfg != "" {
+			f, err := os.Create(writecfg)
+			if err != nil {
+				return err
+			}
+			err = dsm.WriteConfig(f)
+			if err != nil {
+				_ = f.Close()
+				return err
+			}
+			err = f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		// output source for the entire cartridge. the bank and bytecode
+		// flags are ignored
+		if source {
+			err = cartload.Reset()
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(cartload)
+			if err != nil {
+				return err
+			}
+			return dsm.WriteSource(os.Stdout, data)
+		}
+
 		// output entire disassembly or just a specific bank
 		if bank < 0 {
 			err = dsm.Write(os.Stdout, attr)
@@ -879,6 +949,507 @@ func regressCleanup(mode string, args []string) error {
 	return regression.RegressCleanup(os.Stdout, confirmation)
 }
 
+func renderFrames(mode string, args []string) error {
+	var mapping string
+	var spec string
+	var format string
+	var output string
+	var from int
+	var to int
+	var scale int
+	var playback string
+	var macro string
+	var log bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.StringVar(&mapping, "mapping", "AUTO", "force cartridge mapper selection")
+	flgs.StringVar(&spec, "tv", "AUTO",
+		fmt.Sprintf("television specification: %s", strings.Join(specification.ReqSpecList, ", ")))
+	flgs.StringVar(&format, "format", "PNG",
+		fmt.Sprintf("output format: %s", strings.Join(render.FormatList, ", ")))
+	flgs.StringVar(&output, "o", "", "prefix for output files (unique prefix created if not specified)")
+	flgs.IntVar(&from, "from", 60, "first frame to render")
+	flgs.IntVar(&to, "to", -1, "last frame to render (defaults to the first frame)")
+	flgs.IntVar(&scale, "scale", 1, "scaling of output image")
+	flgs.StringVar(&playback, "playback", "", "playback file to drive emulation input")
+	flgs.StringVar(&macro, "macro", "", "macro file to drive emulation input")
+	flgs.BoolVar(&log, "log", false, "echo debugging log to stdout")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// set debugging log echo
+	if log {
+		logger.SetEcho(os.Stdout, true)
+	} else {
+		logger.SetEcho(nil, false)
+	}
+
+	if to < 0 {
+		to = from
+	}
+
+	opts := render.Options{
+		Mapping:  mapping,
+		Spec:     spec,
+		Output:   output,
+		From:     from,
+		To:       to,
+		Scale:    scale,
+		Playback: playback,
+		Macro:    macro,
+	}
+
+	opts.Format, err = render.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	switch len(args) {
+	case 0:
+		// cartridge is not required if a playback file has been specified
+		if playback == "" {
+			return fmt.Errorf("2600 cartridge required")
+		}
+		err = render.Render(os.Stdout, "", opts)
+	case 1:
+		err = render.Render(os.Stdout, args[0], opts)
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func codeCoverage(mode string, args []string) error {
+	var jsonFile string
+	var lcovFile string
+	var listingFile string
+	var baseline string
+	var minimum float64
+	var log bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.StringVar(&jsonFile, "json", "", "write coverage summary to JSON file")
+	flgs.StringVar(&lcovFile, "lcov", "", "write lcov tracefile (annotated listing written alongside with .lst extension)")
+	flgs.StringVar(&listingFile, "listing", "", "write annotated listing")
+	flgs.StringVar(&baseline, "baseline", "", "fail if coverage is lower than in the JSON summary file")
+	flgs.Float64Var(&minimum, "min", 0, "fail if coverage percentage is lower than value")
+	flgs.BoolVar(&log, "log", false, "echo debugging log to stdout")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// set debugging log echo
+	if log {
+		logger.SetEcho(os.Stdout, true)
+	} else {
+		logger.SetEcho(nil, false)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("playback file required")
+	}
+
+	cov := coverage.NewCoverage()
+
+	var dsm *disassembly.Disassembly
+	for _, playback := range args {
+		dsm, err = cov.Playback(os.Stdout, playback)
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := cov.Summary(dsm)
+	fmt.Println(summary)
+
+	write := func(filename string, f func(io.Writer) error) error {
+		if filename == "" {
+			return nil
+		}
+		w, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		return f(w)
+	}
+
+	err = write(jsonFile, summary.WriteJSON)
+	if err != nil {
+		return err
+	}
+
+	err = write(listingFile, func(w io.Writer) error {
+		return cov.WriteListing(w, dsm)
+	})
+	if err != nil {
+		return err
+	}
+
+	if lcovFile != "" {
+		listing := coverage.ListingFilename(lcovFile)
+		err = write(listing, func(w io.Writer) error {
+			return cov.WriteListing(w, dsm)
+		})
+		if err != nil {
+			return err
+		}
+		err = write(lcovFile, func(w io.Writer) error {
+			return cov.WriteLCOV(w, dsm, listing)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if baseline != "" {
+		base, err := coverage.ReadSummary(baseline)
+		if err != nil {
+			return err
+		}
+		err = summary.Compare(base)
+		if err != nil {
+			return err
+		}
+	}
+
+	if summary.Coverage < minimum {
+		return fmt.Errorf("coverage is below minimum: %.2f%% < %.2f%%", summary.Coverage, minimum)
+	}
+
+	return nil
+}
+
+func lintROM(mode string, args []string) error {
+	var mapping string
+	var spec string
+	var frames int
+	var playback string
+	var ignore string
+	var log bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.StringVar(&mapping, "mapping", "AUTO", "force cartridge mapper selection")
+	flgs.StringVar(&spec, "tv", "AUTO",
+		fmt.Sprintf("television specification: %s", strings.Join(specification.ReqSpecList, ", ")))
+	flgs.IntVar(&frames, "frames", 0,
+		fmt.Sprintf("number of frames to run (defaults to %d frames or the length of the playback)", lint.DefaultFrames))
+	flgs.StringVar(&playback, "playback", "", "playback file to drive emulation input")
+	flgs.StringVar(&ignore, "ignore", "",
+		fmt.Sprintf("comma separated list of problems to ignore: %s", strings.Join(lint.KindList, ", ")))
+	flgs.BoolVar(&log, "log", false, "echo debugging log to stdout")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// set debugging log echo
+	if log {
+		logger.SetEcho(os.Stdout, true)
+	} else {
+		logger.SetEcho(nil, false)
+	}
+
+	opts := lint.Options{
+		Mapping:  mapping,
+		Spec:     spec,
+		Frames:   frames,
+		Playback: playback,
+	}
+
+	if ignore != "" {
+		for _, s := range strings.Split(ignore, ",") {
+			k, err := lint.ParseKind(s)
+			if err != nil {
+				return err
+			}
+			opts.Ignore = append(opts.Ignore, k)
+		}
+	}
+
+	var l *lint.Lint
+
+	switch len(args) {
+	case 0:
+		// cartridge is not required if a playback file has been specified
+		if playback == "" {
+			return fmt.Errorf("2600 cartridge required")
+		}
+		l, err = lint.Run(os.Stdout, "", opts)
+	case 1:
+		l, err = lint.Run(os.Stdout, args[0], opts)
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+	if err != nil {
+		return err
+	}
+
+	err = l.WriteReport(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if n := len(l.Problems()); n > 0 {
+		return fmt.Errorf("%d problems found", n)
+	}
+
+	return nil
+}
+
+func robust(mode string, args []string) error {
+	var mapping string
+	var spec string
+	var frames int
+	var playback string
+	var seeds int
+	var seed int64
+	var log bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.StringVar(&mapping, "mapping", "AUTO", "force cartridge mapper selection")
+	flgs.StringVar(&spec, "tv", "AUTO",
+		fmt.Sprintf("television specification: %s", strings.Join(specification.ReqSpecList, ", ")))
+	flgs.IntVar(&frames, "frames", 0,
+		fmt.Sprintf("number of frames to run (defaults to %d frames or the length of the playback)", robustness.DefaultFrames))
+	flgs.StringVar(&playback, "playback", "", "playback file to drive emulation input")
+	flgs.IntVar(&seeds, "seeds", robustness.DefaultSeeds, "number of random seeds to test")
+	flgs.Int64Var(&seed, "seed", 1, "first random seed. the first seed is the reference")
+	flgs.BoolVar(&log, "log", false, "echo debugging log to stdout")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// set debugging log echo
+	if log {
+		logger.SetEcho(os.Stdout, true)
+	} else {
+		logger.SetEcho(nil, false)
+	}
+
+	opts := robustness.Options{
+		Mapping:   mapping,
+		Spec:      spec,
+		Frames:    frames,
+		Playback:  playback,
+		Seeds:     seeds,
+		FirstSeed: seed,
+	}
+
+	var res *robustness.Result
+
+	switch len(args) {
+	case 0:
+		// cartridge is not required if a playback file has been specified
+		if playback == "" {
+			return fmt.Errorf("2600 cartridge required")
+		}
+		res, err = robustness.Run(os.Stdout, "", opts)
+	case 1:
+		res, err = robustness.Run(os.Stdout, args[0], opts)
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+	if err != nil {
+		return err
+	}
+
+	err = res.WriteReport(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if len(res.Divergences) > 0 {
+		return fmt.Errorf("%d seeds diverge", len(res.Divergences))
+	}
+
+	return nil
+}
+
+func romLibrary(mode string, args []string) error {
+	var subMode string
+
+	// use flag set to provide the --help flag
+	flgs := flag.NewFlagSet(mode, flag.ContinueOnError)
+	err := flgs.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			fmt.Println("Sub modes: LIST, SCAN, CLEAN")
+		}
+		return nil
+	}
+	args = flgs.Args()
+
+	if len(args) > 0 {
+		subMode = strings.ToUpper(args[0])
+	}
+
+	lib, err := library.Load()
+	if err != nil {
+		return err
+	}
+
+	switch subMode {
+	default:
+		err = libraryList(fmt.Sprintf("%s %s", mode, "LIST"), lib, args)
+	case "LIST":
+		err = libraryList(fmt.Sprintf("%s %s", mode, subMode), lib, args[1:])
+	case "SCAN":
+		err = libraryScan(fmt.Sprintf("%s %s", mode, subMode), lib, args[1:])
+	case "CLEAN":
+		err = libraryClean(fmt.Sprintf("%s %s", mode, subMode), lib, args[1:])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func libraryList(mode string, lib *library.Library, args []string) error {
+	var filter library.Filter
+	var controller string
+	var verbose bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.StringVar(&filter.Manufacturer, "manufacturer", "", "list only cartridges from the manufacturer")
+	flgs.StringVar(&filter.Mapping, "mapping", "", "list only cartridges with the mapping")
+	flgs.StringVar(&controller, "controller", "", "list only cartridges that use the controller")
+	flgs.BoolVar(&verbose, "v", false, "output more detail")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// remaining arguments are the search terms
+	filter.Search = strings.Join(args, " ")
+
+	// controller names are case insensitive
+	if controller != "" {
+		for _, c := range lib.Controllers() {
+			if strings.EqualFold(string(c), controller) {
+				filter.Controller = c
+				break
+			}
+		}
+		if filter.Controller == "" {
+			filter.Controller = plugging.PeripheralID(controller)
+		}
+	}
+
+	for _, e := range lib.Select(filter) {
+		if verbose {
+			var lastPlayed string
+			if e.LastPlayed.IsZero() {
+				lastPlayed = "never played"
+			} else {
+				lastPlayed = fmt.Sprintf("played %d times, last on %s", e.PlayCount, e.LastPlayed.Format(time.DateTime))
+			}
+			fmt.Printf("%s\n\t%s\n\t%s [%s/%s]\n\t%s\n", e, e.Filename, e.Mapping, e.LeftPlayer, e.RightPlayer, lastPlayed)
+		} else {
+			fmt.Println(e)
+		}
+	}
+
+	return nil
+}
+
+func libraryScan(mode string, lib *library.Library, args []string) error {
+	var thumbnails bool
+	var log bool
+
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+	flgs.BoolVar(&thumbnails, "thumbnails", false, "create thumbnail images (this will make the scan slower)")
+	flgs.BoolVar(&log, "log", false, "echo debugging log to stdout")
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	// set debugging log echo
+	if log {
+		logger.SetEcho(os.Stdout, true)
+	} else {
+		logger.SetEcho(nil, false)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("at least one directory required")
+	}
+
+	opts := library.ScanOptions{
+		Thumbnails: thumbnails,
+	}
+
+	props, err := properties.Load()
+	if err != nil {
+		fmt.Printf("* %s\n", err)
+	} else {
+		opts.Properties = props
+	}
+
+	for _, path := range args {
+		res, err := lib.Scan(os.Stdout, path, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", path, res)
+	}
+
+	return nil
+}
+
+func libraryClean(mode string, lib *library.Library, args []string) error {
+	flgs := flag.NewFlagSet(mode, flag.ExitOnError)
+
+	// parse args and get copy of remaining arguments
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = flgs.Args()
+
+	if len(args) > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	n, err := lib.Clean()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d entries removed\n", n)
+
+	return nil
+}
+
 func showVersion(mode string, args []string) error {
 	var revision bool
 
@@ -135,6 +135,13 @@ func (oly *playscrOverlay) set(v any, args ...any) {
 			oly.event = n
 			oly.eventLatch = overlayLatchShort
 
+		case notifications.NotifySaveState:
+			oly.event = n
+			oly.eventLatch = overlayLatchShort
+		case notifications.NotifyLoadState:
+			oly.event = n
+			oly.eventLatch = overlayLatchShort
+
 		default:
 			return
 		}
@@ -369,6 +376,10 @@ func (oly *playscrOverlay) drawTopLeft() {
 		switch oly.event {
 		case notifications.NotifyScreenshot:
 			oly.iconQueue = append(oly.iconQueue, fonts.Camera)
+		case notifications.NotifySaveState:
+			oly.iconQueue = append(oly.iconQueue, fonts.Disk)
+		case notifications.NotifyLoadState:
+			oly.iconQueue = append(oly.iconQueue, fonts.Persist)
 		}
 	}
 
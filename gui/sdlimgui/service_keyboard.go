@@ -138,6 +138,18 @@ func (img *SdlImgui) serviceKeyboard(ev *sdl.KeyboardEvent) {
 					img.dbg.PushSetMode(govern.ModePlay)
 				}
 
+			case sdl.SCANCODE_F5:
+				// F5 without a modifier is the player 1 difficulty switch so
+				// the save state key requires the control key
+				if img.isPlaymode() && ctrl {
+					img.dbg.PushSaveState()
+				}
+
+			case sdl.SCANCODE_F6:
+				if img.isPlaymode() && ctrl {
+					img.dbg.PushLoadState()
+				}
+
 			case sdl.SCANCODE_F7:
 				if img.isPlaymode() {
 					fps := img.prefs.fpsDetail.Get().(bool)
@@ -0,0 +1,99 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package sdlimgui
+
+import (
+	"fmt"
+
+	"github.com/inkyblackness/imgui-go/v4"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+)
+
+const win4A50registersID = "4A50 Slices"
+
+type win4A50registers struct {
+	debuggerWin
+
+	img *SdlImgui
+}
+
+func newWin4A50registers(img *SdlImgui) (window, error) {
+	win := &win4A50registers{
+		img: img,
+	}
+
+	return win, nil
+}
+
+func (win *win4A50registers) init() {
+}
+
+func (win *win4A50registers) id() string {
+	return win4A50registersID
+}
+
+func (win *win4A50registers) debuggerDraw() bool {
+	if !win.debuggerOpen {
+		return false
+	}
+
+	// do not open window if there is no cartridge registers bus available
+	bus := win.img.cache.VCS.Mem.Cart.GetRegistersBus()
+	if bus == nil {
+		return false
+	}
+	regs, ok := bus.GetRegisters().(cartridge.Registers4A50)
+	if !ok {
+		return false
+	}
+
+	imgui.SetNextWindowPosV(imgui.Vec2{255, 153}, imgui.ConditionFirstUseEver, imgui.Vec2{0, 0})
+	if imgui.BeginV(win.debuggerID(win.id()), &win.debuggerOpen, imgui.WindowFlagsAlwaysAutoResize) {
+		win.draw(regs)
+	}
+
+	win.debuggerGeom.update()
+	imgui.End()
+
+	return true
+}
+
+func (win *win4A50registers) draw(regs cartridge.Registers4A50) {
+	win.drawSlice("Low", "low", regs.LowSlice, regs.LowROM)
+	win.drawSlice("Middle", "middle", regs.MiddleSlice, regs.MiddleROM)
+	win.drawSlice("High", "high", regs.HighSlice, regs.HighROM)
+}
+
+func (win *win4A50registers) drawSlice(label string, register string, slice uint16, isROM bool) {
+	imguiLabel(fmt.Sprintf("%-6s", label))
+
+	s := fmt.Sprintf("%04x", slice)
+	if imguiHexInput(fmt.Sprintf("##%sslice", register), 4, &s) {
+		win.img.dbg.PushFunction(func() {
+			b := win.img.dbg.VCS().Mem.Cart.GetRegistersBus()
+			b.PutRegister(fmt.Sprintf("%s::slice", register), s)
+		})
+	}
+
+	imgui.SameLine()
+	rom := isROM
+	if imgui.Checkbox(fmt.Sprintf("ROM##%srom", register), &rom) {
+		win.img.dbg.PushFunction(func() {
+			b := win.img.dbg.VCS().Mem.Cart.GetRegistersBus()
+			b.PutRegister(fmt.Sprintf("%s::rom", register), fmt.Sprintf("%v", rom))
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/jetsetilly/gopher2600/debugger/govern"
 	"github.com/jetsetilly/gopher2600/disassembly"
+	"github.com/jetsetilly/gopher2600/disassembly/listing"
 	"github.com/jetsetilly/gopher2600/gui/fonts"
 	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
 	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
@@ -52,6 +53,11 @@ type winDisasm struct {
 	// options
 	followCPU  bool
 	usingColor bool
+	showSource bool
+
+	// source listing for the cartridge. updated every frame and will be nil
+	// if there is no listing
+	listing *listing.Listing
 
 	// selected bank to display
 	filter       disasmFilter
@@ -80,8 +86,9 @@ type winDisasm struct {
 
 func newWinDisasm(img *SdlImgui) (window, error) {
 	win := &winDisasm{
-		img:       img,
-		followCPU: true,
+		img:        img,
+		followCPU:  true,
+		showSource: true,
 	}
 	return win, nil
 }
@@ -124,6 +131,9 @@ func (win *winDisasm) draw() {
 		return
 	}
 
+	// the listing must be retrieved before the disassembly is borrowed
+	win.listing = win.img.dbg.Disasm.Listing()
+
 	// the currBank that is currently selected
 	addr := win.img.cache.VCS.CPU.PC.Address()
 	currBank := win.img.cache.VCS.Mem.Cart.GetBank(addr)
@@ -294,6 +304,10 @@ func (win *winDisasm) drawOptions(currBank mapper.BankInfo) {
 		if imgui.Checkbox("Use Colour", &win.usingColor) {
 			win.img.prefs.colorDisasm.Set(win.usingColor)
 		}
+		if win.listing != nil {
+			imgui.SameLineV(0, 15)
+			imgui.Checkbox("Show Source", &win.showSource)
+		}
 
 		// special execution icons
 		if currBank.ExecutingCoprocessor {
@@ -697,6 +711,21 @@ func (win *winDisasm) drawEntry(currBank mapper.BankInfo, e *disassembly.Entry,
 				imgui.PopStyleColor()
 			}
 
+			if le := win.listing.Entry(bank, e.Result.Address); le != nil {
+				imgui.Spacing()
+				imgui.Separator()
+				imgui.Spacing()
+				imgui.Text(le.Caller.String())
+				imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.DisasmOperand)
+				imgui.Text(le.Caller.Text)
+				imgui.PopStyleColor()
+				if le.Macro() {
+					imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.DisasmOperand)
+					imgui.Text(fmt.Sprintf("+ %s", le.Text))
+					imgui.PopStyleColor()
+				}
+			}
+
 			if e.Level == disassembly.EntryLevelExecuted {
 				notes := e.Notes()
 				if notes != "" {
@@ -729,13 +758,24 @@ func (win *winDisasm) drawEntry(currBank mapper.BankInfo, e *disassembly.Entry,
 	}
 	imgui.Text(e.Address)
 
+	// the source text replaces the operator and operand columns if it is
+	// available. macro expansions are shown with the arguments substituted
+	var source *listing.Entry
+	if win.showSource {
+		source = win.listing.Entry(bank, e.Result.Address)
+	}
+
 	// operator column
 	imgui.TableNextColumn()
 	if win.usingColor {
 		imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.DisasmOperator)
 		defer imgui.PopStyleColor()
 	}
-	imgui.Text(e.Operator)
+	if source == nil {
+		imgui.Text(e.Operator)
+	} else if source.Macro() {
+		imgui.Text("+")
+	}
 
 	// operand column
 	imgui.TableNextColumn()
@@ -743,7 +783,11 @@ func (win *winDisasm) drawEntry(currBank mapper.BankInfo, e *disassembly.Entry,
 		imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.DisasmOperand)
 		defer imgui.PopStyleColor()
 	}
-	imgui.Text(e.Operand.Resolve())
+	if source == nil {
+		imgui.Text(e.Operand.Resolve())
+	} else {
+		imgui.Text(source.Text)
+	}
 
 	// cycles column
 	imgui.TableNextColumn()
@@ -32,6 +32,7 @@ import (
 	"github.com/jetsetilly/gopher2600/gui/fonts"
 	"github.com/jetsetilly/gopher2600/hardware/peripherals"
 	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+	"github.com/jetsetilly/gopher2600/library"
 	"github.com/jetsetilly/gopher2600/logger"
 	"github.com/jetsetilly/gopher2600/properties"
 	"github.com/jetsetilly/gopher2600/resources"
@@ -76,6 +77,20 @@ type winSelectROM struct {
 	boxartTexture    texture
 	boxartDimensions image.Point
 	boxartUse        bool
+
+	// the filter used in the library tab and the entries that match the
+	// filter. the entries are only selected from the library when the filter
+	// changes or when libraryRefresh is true
+	libraryFilter  library.Filter
+	libraryPrev    library.Filter
+	libraryEntries []library.Entry
+	libraryRefresh bool
+
+	// the thumbnail from the library for the entry being hovered over
+	libraryThmbTexture    texture
+	libraryThmbHash       string
+	libraryThmbUse        bool
+	libraryThmbDimensions image.Point
 }
 
 // boxart from libretro project
@@ -126,6 +141,9 @@ func newSelectROM(img *SdlImgui) (window, error) {
 	// prepare boxart texture
 	win.boxartTexture = img.rnd.addTexture(textureColor, false, false)
 
+	// prepare library thumbnail texture
+	win.libraryThmbTexture = img.rnd.addTexture(textureColor, false, false)
+
 	return win, nil
 }
 
@@ -148,6 +166,9 @@ func (win *winSelectROM) setOpen(open bool) {
 
 	// open at the most recently selected ROM
 	win.path.Set <- win.img.dbg.Prefs.RecentROM.String()
+
+	// the library may have changed since the window was last open
+	win.libraryRefresh = true
 }
 
 func (win *winSelectROM) playmodeSetOpen(open bool) {
@@ -253,13 +274,34 @@ func (win *winSelectROM) draw() {
 
 	imgui.BeginGroup()
 
-	if imgui.Button("Parent") {
-		win.path.Set <- filepath.Dir(win.path.Results.Dir)
-		win.scrollToTop = true
+	// the ROM library is shown in a separate tab. the library is loaded the
+	// first time the tab is selected
+	var libraryTab bool
+	var lib *library.Library
+	imgui.BeginTabBar("##romSelectTabs")
+	if imgui.BeginTabItem("Files") {
+		imgui.EndTabItem()
+	}
+	if imgui.BeginTabItem("Library") {
+		libraryTab = true
+		lib = win.img.dbg.Library()
+		imgui.EndTabItem()
 	}
+	imgui.EndTabBar()
 
-	imgui.SameLine()
-	imgui.Text(archivefs.RemoveArchiveExt(win.path.Results.Dir))
+	if libraryTab {
+		if lib != nil {
+			win.drawLibraryFilter(lib)
+		}
+	} else {
+		if imgui.Button("Parent") {
+			win.path.Set <- filepath.Dir(win.path.Results.Dir)
+			win.scrollToTop = true
+		}
+
+		imgui.SameLine()
+		imgui.Text(archivefs.RemoveArchiveExt(win.path.Results.Dir))
+	}
 
 	if imgui.BeginTable("romSelector", 2) {
 		imgui.TableSetupColumnV("filelist", imgui.TableColumnFlagsWidthStretch, -1, 0)
@@ -276,82 +318,11 @@ func (win *winSelectROM) draw() {
 			win.scrollToTop = false
 		}
 
-		// list directories
-		imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.ROMSelectDir)
-		for _, e := range win.path.Results.Entries {
-			// ignore dot files
-			if !win.showHidden && e.Name[0] == '.' {
-				continue
-			}
-
-			if e.IsDir {
-				s := strings.Builder{}
-				if e.IsArchive {
-					s.WriteString(string(fonts.Paperclip))
-					s.WriteString(" ")
-					s.WriteString(archivefs.TrimArchiveExt(e.Name))
-				} else {
-					s.WriteString(string(fonts.Directory))
-					s.WriteString(" ")
-					s.WriteString(e.Name)
-				}
-
-				if imgui.Selectable(s.String()) {
-					win.path.Set <- filepath.Join(win.path.Results.Dir, e.Name)
-					win.scrollToTop = true
-				}
-			}
-		}
-		imgui.PopStyleColor()
-
-		// list files
-		imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.ROMSelectFile)
-		for _, e := range win.path.Results.Entries {
-			// ignore dot files
-			if !win.showHidden && e.Name[0] == '.' {
-				continue
-			}
-
-			// ignore invalid file extensions unless showAll flags is set
-			ext := strings.ToUpper(filepath.Ext(e.Name))
-			if !win.showAll {
-				hasExt := false
-				for _, e := range cartridgeloader.FileExtensions {
-					if e == ext {
-						hasExt = true
-						break
-					}
-				}
-				if !hasExt {
-					for _, e := range archivefs.ArchiveExtensions {
-						if e == ext {
-							hasExt = true
-							break
-						}
-					}
-				}
-				if !hasExt {
-					continue // to next file
-				}
-			}
-
-			if !e.IsDir {
-				selected := e.Name == win.path.Results.Base
-
-				if selected && win.centreOnFile {
-					imgui.SetScrollHereY(0.0)
-					win.centreOnFile = false
-				}
-
-				if imgui.SelectableV(e.Name, selected, 0, imgui.Vec2{0, 0}) {
-					win.path.Set <- filepath.Join(win.path.Results.Dir, e.Name)
-				}
-				if imgui.IsItemHovered() && imgui.IsMouseDoubleClicked(0) {
-					win.insertCartridge()
-				}
-			}
+		if libraryTab {
+			win.drawLibrary(lib)
+		} else {
+			win.drawFiles()
 		}
-		imgui.PopStyleColor()
 
 		imgui.EndChild()
 
@@ -580,6 +551,80 @@ func (win *winSelectROM) draw() {
 	}
 }
 
+func (win *winSelectROM) drawFiles() {
+	// list directories
+	imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.ROMSelectDir)
+	for _, e := range win.path.Results.Entries {
+		// ignore dot files
+		if !win.showHidden && e.Name[0] == '.' {
+			continue
+		}
+
+		if e.IsDir {
+			s := strings.Builder{}
+			if e.IsArchive {
+				s.WriteString(string(fonts.Paperclip))
+				s.WriteString(" ")
+				s.WriteString(archivefs.TrimArchiveExt(e.Name))
+			} else {
+				s.WriteString(string(fonts.Directory))
+				s.WriteString(" ")
+				s.WriteString(e.Name)
+			}
+
+			if imgui.Selectable(s.String()) {
+				win.path.Set <- filepath.Join(win.path.Results.Dir, e.Name)
+				win.scrollToTop = true
+			}
+		}
+	}
+	imgui.PopStyleColor()
+
+	// list files
+	imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.ROMSelectFile)
+	for _, e := range win.path.Results.Entries {
+		// ignore dot files
+		if !win.showHidden && e.Name[0] == '.' {
+			continue
+		}
+
+		// ignore invalid file extensions unless showAll flags is set
+		ext := strings.ToUpper(filepath.Ext(e.Name))
+		if !win.showAll {
+			hasExt := false
+			for _, e := range cartridgeloader.FileExtensions {
+				if e == ext {
+					hasExt = true
+					break
+				}
+			}
+			if !hasExt {
+				hasExt = archivefs.HasArchiveExt(e.Name)
+			}
+			if !hasExt {
+				continue // to next file
+			}
+		}
+
+		if !e.IsDir {
+			selected := e.Name == win.path.Results.Base
+
+			if selected && win.centreOnFile {
+				imgui.SetScrollHereY(0.0)
+				win.centreOnFile = false
+			}
+
+			if imgui.SelectableV(e.Name, selected, 0, imgui.Vec2{0, 0}) {
+				win.path.Set <- filepath.Join(win.path.Results.Dir, e.Name)
+			}
+			if imgui.IsItemHovered() && imgui.IsMouseDoubleClicked(0) {
+				win.insertCartridge()
+			}
+		}
+	}
+	imgui.PopStyleColor()
+}
+
 func (win *winSelectROM) insertCartridge() {
 	// do not try to load cartridge if the file is not being emulated by the
 	// thumbnailer. if it's not then that's a good sign that the file isn't
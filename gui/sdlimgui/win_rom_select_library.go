@@ -0,0 +1,183 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package sdlimgui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/inkyblackness/imgui-go/v4"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/library"
+	"github.com/jetsetilly/gopher2600/logger"
+	"golang.org/x/image/draw"
+)
+
+// the label used in the library filter combos to indicate that the filter is
+// not being used
+const libraryFilterAll = "All"
+
+func (win *winSelectROM) drawLibraryFilter(lib *library.Library) {
+
+	imgui.AlignTextToFramePadding()
+	imgui.Text("Search")
+	imgui.SameLine()
+	imgui.SetNextItemWidth(250)
+	imgui.InputText("##librarySearch", &win.libraryFilter.Search)
+
+	// filter combos use the same layout and the same "All" option
+	combo := func(label string, width float32, current string, options []string) (string, bool) {
+		preview := current
+		if preview == "" {
+			preview = libraryFilterAll
+		}
+
+		var selected bool
+		imgui.SameLine()
+		imgui.SetNextItemWidth(width)
+		if imgui.BeginCombo(label, preview) {
+			if imgui.Selectable(libraryFilterAll) {
+				current = ""
+				selected = true
+			}
+			for _, o := range options {
+				if imgui.Selectable(o) {
+					current = o
+					selected = true
+				}
+			}
+			imgui.EndCombo()
+		}
+		return current, selected
+	}
+
+	if v, ok := combo("##libraryManufacturer", 150, win.libraryFilter.Manufacturer, lib.Manufacturers()); ok {
+		win.libraryFilter.Manufacturer = v
+	}
+	win.img.imguiTooltipSimple("Manufacturer")
+
+	if v, ok := combo("##libraryMapping", 80, win.libraryFilter.Mapping, lib.Mappings()); ok {
+		win.libraryFilter.Mapping = v
+	}
+	win.img.imguiTooltipSimple("Mapper")
+
+	var controllers []string
+	for _, c := range lib.Controllers() {
+		controllers = append(controllers, string(c))
+	}
+	if v, ok := combo("##libraryController", 100, string(win.libraryFilter.Controller), controllers); ok {
+		win.libraryFilter.Controller = plugging.PeripheralID(v)
+	}
+	win.img.imguiTooltipSimple("Controller")
+
+	// select entries from library if the filter has changed
+	if win.libraryRefresh || win.libraryFilter != win.libraryPrev {
+		win.libraryEntries = lib.Select(win.libraryFilter)
+		win.libraryPrev = win.libraryFilter
+		win.libraryRefresh = false
+		win.scrollToTop = true
+	}
+}
+
+func (win *winSelectROM) drawLibrary(lib *library.Library) {
+	if lib == nil {
+		imgui.Text("The library could not be loaded")
+		return
+	}
+
+	if len(win.libraryEntries) == 0 {
+		if lib.Len() == 0 {
+			imgui.Text("The library is empty. Use the")
+			imgui.Text("LIBRARY SCAN mode to add ROMs")
+		} else {
+			imgui.Text("No matching ROMs")
+		}
+		return
+	}
+
+	imgui.PushStyleColor(imgui.StyleColorText, win.img.cols.ROMSelectFile)
+	defer imgui.PopStyleColor()
+
+	for _, e := range win.libraryEntries {
+		selected := e.Filename == win.path.Results.Selected
+
+		if selected && win.centreOnFile {
+			imgui.SetScrollHereY(0.0)
+			win.centreOnFile = false
+		}
+
+		// the hash is used as the ID because there may be more than one entry
+		// with the same name
+		if imgui.SelectableV(fmt.Sprintf("%s##%s", e.Name, e.Hash), selected, 0, imgui.Vec2{0, 0}) {
+			win.path.Set <- e.Filename
+		}
+		if imgui.IsItemHovered() && imgui.IsMouseDoubleClicked(0) {
+			win.insertCartridge()
+		}
+
+		win.img.imguiTooltip(func() {
+			if win.libraryThumbnail(e) {
+				imgui.Image(imgui.TextureID(win.libraryThmbTexture.getID()),
+					imgui.Vec2{float32(win.libraryThmbDimensions.X), float32(win.libraryThmbDimensions.Y) / 2})
+			}
+			imgui.Text(e.Name)
+			if e.Manufacturer != "" {
+				imgui.Text(e.Manufacturer)
+			}
+			imgui.Text(fmt.Sprintf("%s cartridge (%s & %s)", e.Mapping, e.LeftPlayer, e.RightPlayer))
+			if e.LastPlayed.IsZero() {
+				imgui.Text("Never played")
+			} else {
+				imgui.Text(fmt.Sprintf("Played %d times", e.PlayCount))
+			}
+		}, true)
+	}
+}
+
+// load the thumbnail for the library entry into the library thumbnail texture.
+// returns false if there is no thumbnail for the entry
+func (win *winSelectROM) libraryThumbnail(e library.Entry) bool {
+	if e.Hash == win.libraryThmbHash {
+		return win.libraryThmbUse
+	}
+
+	win.libraryThmbHash = e.Hash
+	win.libraryThmbUse = false
+
+	src, err := e.Thumbnail()
+	if err != nil {
+		// not every entry will have a thumbnail
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Logf(logger.Allow, "sdlimgui", err.Error())
+		}
+		return false
+	}
+
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Copy(dst, image.Point{}, src, b, draw.Src, nil)
+	win.libraryThmbDimensions = dst.Bounds().Max
+
+	// see comment in winSelectROM.findBoxart() for why the texture is marked
+	// for creation before rendering
+	win.libraryThmbTexture.markForCreation()
+	win.libraryThmbTexture.render(dst)
+	win.libraryThmbUse = true
+
+	return true
+}
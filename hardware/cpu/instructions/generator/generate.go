@@ -191,7 +191,7 @@ func parseCSV() (string, error) {
 		}
 
 		// field: undocumented
-		newDef.Undocumented = unicode.IsLower(rune(rec[1][0]))
+		newDef.Undocumented = unicode.IsUpper(rune(rec[1][0]))
 
 		// field: cycles
 		newDef.Cycles.Value, err = strconv.Atoi(rec[2])
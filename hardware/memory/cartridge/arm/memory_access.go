@@ -354,3 +354,25 @@ func (arm *ARM) Peek(addr uint32) (uint32, bool) {
 	}
 	return arm.byteOrder.Uint32((*mem)[addr:]), true
 }
+
+// Poke implements the coprocessor.CoProc interface
+func (arm *ARM) Poke(addr uint32, value uint32) bool {
+	mem, origin := arm.mem.MapAddress(addr, false)
+	addr -= origin
+	if mem == nil || addr >= uint32(len(*mem)-3) {
+		return false
+	}
+	arm.byteOrder.PutUint32((*mem)[addr:], value)
+
+	// forget any decoded instructions that overlap the poked address. a 32bit
+	// instruction can begin two bytes before the address
+	if cache, ok := arm.executionCache[origin]; ok {
+		for i := int(addr) - 2; i < int(addr)+4 && i < len(cache); i++ {
+			if i >= 0 {
+				cache[i] = nil
+			}
+		}
+	}
+
+	return true
+}
@@ -264,6 +264,12 @@ func (cart *Cartridge) Attach(cartload cartridgeloader.Loader) error {
 		cart.mapper, err = newCommaVid(cart.env, cartload)
 	case "FA":
 		cart.mapper, err = newCBS(cart.env, cartload)
+	case "FA2":
+		cart.mapper, err = newFA2(cart.env, cartload)
+	case "4A50":
+		cart.mapper, err = new4A50(cart.env, cartload)
+	case "CTY":
+		cart.mapper, err = newCTY(cart.env, cartload)
 	case "FE":
 		cart.mapper, err = newSCABS(cart.env, cartload)
 	case "E0":
@@ -274,10 +280,21 @@ func (cart *Cartridge) Attach(cartload cartridgeloader.Loader) error {
 		cart.mapper, err = newTigervision(cart.env, cartload)
 	case "UA":
 		cart.mapper, err = newUA(cart.env, cartload)
+	case "0840":
+		cart.mapper, err = new0840(cart.env, cartload)
+	case "X07":
+		cart.mapper, err = newX07(cart.env, cartload)
 	case "AR":
 		cart.mapper, err = supercharger.NewSupercharger(cart.env, cartload)
 	case "DF":
-		cart.mapper, err = newDF(cart.env, cartload)
+		cart.mapper, err = newDF(cart.env, cartload, "DF")
+	case "DFSC":
+		cart.mapper, err = newDF(cart.env, cartload, "DFSC")
+	case "BF":
+		cart.mapper, err = newBF(cart.env, cartload)
+	case "BFSC":
+		cart.mapper, err = newBF(cart.env, cartload)
+		forceSuperchip = true
 	case "3E":
 		cart.mapper, err = new3e(cart.env, cartload)
 	case "E3P":
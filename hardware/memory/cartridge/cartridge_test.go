@@ -0,0 +1,381 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// the origin of a RAM segment returned by GetRAM() is the address of the read
+// port of the RAM
+func TestRAMOrigin(t *testing.T) {
+	for _, c := range []struct {
+		mapping string
+		size    int
+		write   uint16
+	}{
+		{mapping: "F8SC", size: 8192, write: 0x1000},
+		{mapping: "FA", size: 12288, write: 0x1000},
+		{mapping: "CV", size: 2048, write: 0x1400},
+	} {
+		tv, err := television.NewTelevision("NTSC")
+		test.ExpectSuccess(t, err)
+		vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+		test.ExpectSuccess(t, err)
+
+		data := make([]uint8, c.size)
+		for i := range data {
+			data[i] = 0xaa
+		}
+
+		cartload, err := cartridgeloader.NewLoaderFromData("ram origin", data, c.mapping, nil)
+		test.ExpectSuccess(t, err)
+		test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+		test.ExpectSuccess(t, vcs.Mem.Write(c.write+5, 0x55))
+
+		ram := vcs.Mem.Cart.GetRAMbus().GetRAM()
+		test.ExpectEquality(t, len(ram), 1)
+		test.ExpectEquality(t, ram[0].Data[5], 0x55)
+
+		// read from ROM so that the value written to RAM is no longer on the
+		// data bus
+		_, err = vcs.Mem.Read(0x1800)
+		test.ExpectSuccess(t, err)
+
+		v, err := vcs.Mem.Read(ram[0].Origin + 5)
+		test.ExpectSuccess(t, err)
+		if v != 0x55 {
+			t.Errorf("%s: RAM is not readable at the origin reported by GetRAM()", c.mapping)
+		}
+	}
+}
+
+// the output of the CTY tune player is the operand of an LDA #$F2 instruction.
+// the program writes the value to the TIA audio volume register
+func TestCTYAudio(t *testing.T) {
+	// the tune data is appended to the 32K cartridge data
+	data := make([]uint8, 65536)
+	for i := 32768; i < len(data); i++ {
+		data[i] = 40
+	}
+
+	// the program in bank 1 plays the tune
+	copy(data[0x1100:], []uint8{
+		0x8d, 0x03, 0x10, // STA $1003
+		0xa9, 0xf2, //       LDA #$F2
+		0x85, 0x19, //       STA AUDV0
+		0x85, 0x80, //       STA $80
+		0x4c, 0x00, 0x11, // JMP $1100
+	})
+	data[0x1ffc] = 0x00
+	data[0x1ffd] = 0x11
+
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	cartload, err := cartridgeloader.NewLoaderFromData("cty audio", data, "CTY", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	var vol uint8
+	for range 10000 {
+		test.ExpectSuccess(t, vcs.Step(nil))
+		vol = max(vol, vcs.TIA.Audio.Vol0)
+
+		// the operand is always replaced by the output of the tune player
+		v, err := vcs.Mem.Peek(0x80)
+		test.ExpectSuccess(t, err)
+		if v == 0xf2 {
+			t.Fatalf("CTY: LDA #$F2 operand was not replaced")
+		}
+	}
+	if vol == 0 {
+		t.Errorf("CTY: tune player output is silent")
+	}
+}
+
+// RAM in the FA2 cartridge is saved to and loaded from the flash file by
+// accessing the flash hotspot
+func TestFA2Flash(t *testing.T) {
+	// the flash file is written to the resources path, which is relative to
+	// the working directory in development builds
+	wd, err := os.Getwd()
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	data := make([]uint8, 24576)
+	cartload, err := cartridgeloader.NewLoaderFromData("fa2 flash", data, "FA2", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	// GetRAM() returns a copy of cartridge RAM
+	ram := func() []uint8 {
+		return vcs.Mem.Cart.GetRAMbus().GetRAM()[0].Data
+	}
+
+	// wait until the flash operation has completed. the operation should be
+	// busy for the first access of the hotspot and finish before the limit
+	waitFlash := func() {
+		t.Helper()
+		v, err := vcs.Mem.Read(0x1ff4)
+		test.ExpectSuccess(t, err)
+		test.ExpectSuccess(t, v&0x40 == 0x40)
+		for range 200000 {
+			vcs.Mem.Cart.Step(1.19)
+			v, err = vcs.Mem.Read(0x1ff4)
+			test.ExpectSuccess(t, err)
+			if v&0x40 == 0x00 {
+				return
+			}
+		}
+		t.Fatalf("FA2: flash operation did not complete")
+	}
+
+	// an access of the hotspot with no operation selected completes
+	// immediately but is busy on the first access
+	waitFlash()
+
+	// save RAM to flash
+	for i := range 255 {
+		test.ExpectSuccess(t, vcs.Mem.Write(0x1000+uint16(i), uint8(i)))
+	}
+	test.ExpectSuccess(t, vcs.Mem.Write(0x10ff, 2))
+	waitFlash()
+	test.ExpectEquality(t, ram()[255], 0)
+
+	fn := filepath.Join(".gopher2600", "fa2flash", cartload.HashSHA1)
+	d, err := os.ReadFile(fn)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(d), 256)
+	test.ExpectSuccess(t, bytes.Equal(d[:255], ram()[:255]))
+
+	// load RAM from flash
+	for i := range 255 {
+		test.ExpectSuccess(t, vcs.Mem.Write(0x1000+uint16(i), 0))
+	}
+	test.ExpectSuccess(t, vcs.Mem.Write(0x10ff, 1))
+	waitFlash()
+	test.ExpectEquality(t, ram()[255], 0)
+	r := ram()
+	for i := range 255 {
+		if r[i] != uint8(i) {
+			t.Fatalf("FA2: RAM not restored from flash file")
+		}
+	}
+}
+
+// the FA2 read port is RAM and poking it should change RAM and not ROM
+func TestFA2Poke(t *testing.T) {
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	data := make([]uint8, 28672)
+	cartload, err := cartridgeloader.NewLoaderFromData("fa2 poke", data, "FA2", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	test.ExpectSuccess(t, vcs.Mem.Cart.Poke(0x1105, 0x55))
+	test.ExpectEquality(t, vcs.Mem.Cart.GetRAMbus().GetRAM()[0].Data[5], 0x55)
+	banks, err := vcs.Mem.Cart.CopyBanks()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, banks[0].Data[0x105], 0x00)
+
+	v, err := vcs.Mem.Cart.Peek(0x1105)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, v, 0x55)
+
+	// bankswitching is unaffected by the state of the data bus
+	_, err = vcs.Mem.Read(0x1ffb)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, vcs.Mem.Cart.GetBank(0x1000).Number, 6)
+}
+
+// slices in the 4A50 cartridge are selected by accessing zero page hotspots and
+// by accessing non-cartridge addresses after the mechanism has been primed
+func Test4A50Bankswitch(t *testing.T) {
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	// each byte of the cartridge data is the high byte of its offset
+	data := make([]uint8, 131072)
+	for i := range data {
+		data[i] = uint8(i >> 8)
+	}
+
+	// the value read from 1F80 primes the bankswitching mechanism
+	data[0x1ff80] = 0x6c
+
+	cartload, err := cartridgeloader.NewLoaderFromData("4a50 bankswitch", data, "4A50", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	read := func(addr uint16) uint8 {
+		t.Helper()
+		v, err := vcs.Mem.Read(addr)
+		test.ExpectSuccess(t, err)
+		return v
+	}
+
+	// write to zero page hotspot selects ROM for the lower slice
+	test.ExpectSuccess(t, vcs.Mem.Write(0x00f8, 0x03))
+	test.ExpectEquality(t, read(0x1000), 0x18)
+	test.ExpectEquality(t, vcs.Mem.Cart.GetBank(0x1000).Number, 3)
+
+	// write to zero page hotspot selects ROM for the middle slice
+	test.ExpectSuccess(t, vcs.Mem.Write(0x00f9, 0x92))
+	test.ExpectEquality(t, read(0x1800), 0x90)
+
+	// a read of a zero page hotspot uses the value that was read and not the
+	// value that was on the data bus before the read
+	test.ExpectSuccess(t, vcs.Mem.Poke(0x00f4, 0x05))
+	test.ExpectEquality(t, read(0x1f00), 0xff)
+	test.ExpectEquality(t, read(0x00f4), 0x05)
+	test.ExpectEquality(t, read(0x1e00), 0x05)
+
+	// primed access selects ROM for the high slice
+	test.ExpectEquality(t, read(0x1f80), 0x6c)
+	read(0x0c07)
+	test.ExpectEquality(t, read(0x1e00), 0x07)
+
+	// an access that is not primed does not change the slice
+	read(0x0c09)
+	test.ExpectEquality(t, read(0x1e00), 0x07)
+
+	// primed access selects RAM for the lower slice
+	test.ExpectEquality(t, read(0x1f80), 0x6c)
+	read(0x0e41)
+	test.ExpectSuccess(t, vcs.Mem.Write(0x1005, 0xaa))
+	test.ExpectEquality(t, vcs.Mem.Cart.GetBank(0x1000).IsRAM, true)
+
+	// writes to cartridge RAM are committed on the next step
+	vcs.Mem.Cart.Step(1.19)
+	test.ExpectEquality(t, read(0x1005), 0xaa)
+	test.ExpectEquality(t, vcs.Mem.Cart.GetRAMbus().GetRAM()[1].Data[5], 0xaa)
+}
+
+// the 0840 and X07 cartridges bankswitch on accesses to addresses outside of
+// the cartridge area. every reported hotspot should select the bank named by
+// the hotspot symbol
+func TestTIAHotspots(t *testing.T) {
+	for _, c := range []struct {
+		mapping  string
+		numBanks int
+		hotspots int
+	}{
+		{mapping: "0840", numBanks: 2, hotspots: 16},
+		{mapping: "X07", numBanks: 16, hotspots: 16},
+	} {
+		tv, err := television.NewTelevision("NTSC")
+		test.ExpectSuccess(t, err)
+		vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+		test.ExpectSuccess(t, err)
+
+		// the first byte of each bank is the bank number
+		data := make([]uint8, c.numBanks*4096)
+		for b := range c.numBanks {
+			data[b*4096] = uint8(b)
+		}
+
+		cartload, err := cartridgeloader.NewLoaderFromData("tia hotspots", data, c.mapping, nil)
+		test.ExpectSuccess(t, err)
+		test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+		hotspots := vcs.Mem.Cart.GetCartHotspotsBus().ReadHotspots()
+		test.ExpectEquality(t, len(hotspots), c.hotspots)
+
+		for addr, h := range hotspots {
+			var bank int
+			_, err := fmt.Sscanf(h.Symbol, "BANK%d", &bank)
+			test.ExpectSuccess(t, err)
+
+			// select a different bank before accessing the hotspot
+			other := (bank + 1) % c.numBanks
+			for k, o := range hotspots {
+				if o.Symbol == fmt.Sprintf("BANK%d", other) {
+					_, err = vcs.Mem.Read(k)
+					test.ExpectSuccess(t, err)
+					break
+				}
+			}
+			test.ExpectEquality(t, vcs.Mem.Cart.GetBank(0x1000).Number, other)
+
+			_, err = vcs.Mem.Read(addr)
+			test.ExpectSuccess(t, err)
+			if vcs.Mem.Cart.GetBank(0x1000).Number != bank {
+				t.Errorf("%s: hotspot %04x (%s) does not select bank %d", c.mapping, addr, h.Symbol, bank)
+			}
+
+			v, err := vcs.Mem.Read(0x1000)
+			test.ExpectSuccess(t, err)
+			test.ExpectEquality(t, v, uint8(bank))
+		}
+	}
+}
+
+// when bank 14 or 15 of the X07 cartridge is selected, access to the TIA
+// selects bank 14 or 15 depending on the state of A6
+func TestX07TIASwitching(t *testing.T) {
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	cartload, err := cartridgeloader.NewLoaderFromData("x07", make([]uint8, 65536), "X07", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	bank := func() int {
+		return vcs.Mem.Cart.GetBank(0x1000).Number
+	}
+
+	// TIA access has no effect for other banks
+	_, err = vcs.Mem.Read(0x083d)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, bank(), 3)
+	test.ExpectSuccess(t, vcs.Mem.Write(0x0040, 0))
+	test.ExpectEquality(t, bank(), 3)
+
+	_, err = vcs.Mem.Read(0x08ed)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, bank(), 14)
+	test.ExpectSuccess(t, vcs.Mem.Write(0x0040, 0))
+	test.ExpectEquality(t, bank(), 15)
+	test.ExpectSuccess(t, vcs.Mem.Write(0x0002, 0))
+	test.ExpectEquality(t, bank(), 14)
+}
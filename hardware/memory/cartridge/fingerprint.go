@@ -214,13 +214,104 @@ func fingerprintParkerBros(loader cartridgeloader.Loader) bool {
 	return false
 }
 
-func fingerprintDF(loader cartridgeloader.Loader) bool {
-	b := make([]byte, 4)
-	loader.Seek(0x0ff8, io.SeekStart)
+// DF, DFSC, BF and BFSC cartridges have a four byte signature at address 0xff8
+// of a bank. the signature is usually found in the first bank but Stella looks
+// in the last bank so we'll look in both
+func fingerprintBankSignature(loader cartridgeloader.Loader, signature string) bool {
+	b := make([]byte, len(signature))
+	for _, offset := range []int64{0x0ff8, int64(loader.Size()) - 0x1000 + 0x0ff8} {
+		loader.Seek(offset, io.SeekStart)
+		if n, err := loader.Read(b); n != len(b) || err != nil {
+			return false
+		}
+		if bytes.Equal(b, []byte(signature)) {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprintFA2(loader cartridgeloader.Loader) bool {
+	// the 32K version of FA2 is the 28K version with the 1K Harmony driver at
+	// the start of the file. the remaining 3K is padding. fingerprint taken
+	// from Stella
+	b := make([]byte, 3072)
+	loader.Seek(29696, io.SeekStart)
 	if n, err := loader.Read(b); n != len(b) || err != nil {
 		return false
 	}
-	return bytes.Equal(b, []byte{'D', 'F', 'S', 'C'})
+	for _, v := range b {
+		if v != 0x00 {
+			return false
+		}
+	}
+	return true
+}
+
+func fingerprint4A50(loader cartridgeloader.Loader) bool {
+	// fingerprint taken from Stella
+	loader.Seek(0, io.SeekStart)
+	data, err := io.ReadAll(loader)
+	if err != nil || len(data) < 0x10000 {
+		return false
+	}
+
+	// the NMI vector in the last page of the ROM is set to $4A50
+	if data[len(data)-6] == 0x50 && data[len(data)-5] == 0x4a {
+		return true
+	}
+
+	// program starts in the fixed page with a NOP $6Exx or NOP $6Fxx
+	// instruction
+	if data[0xfffd]&0x1f == 0x1f {
+		reset := int(data[0xfffd])<<8 | int(data[0xfffc])
+		if reset+2 < len(data) && data[reset] == 0x0c && data[reset+2]&0xfe == 0x6e {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fingerprint0840(loader cartridgeloader.Loader) bool {
+	// fingerprint taken from Stella. the hotspots must be accessed at least
+	// twice
+	fingerprint := [][]byte{
+		{0xad, 0x00, 0x08},       // LDA $0800
+		{0xad, 0x40, 0x08},       // LDA $0840
+		{0x2c, 0x00, 0x08},       // BIT $0800
+		{0x0c, 0x00, 0x08, 0x4c}, // NOP $0800; JMP
+		{0x0c, 0xff, 0x0f, 0x4c}, // NOP $0FFF; JMP
+	}
+	for _, f := range fingerprint {
+		if loader.Count(f) >= 2 {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprintX07(loader cartridgeloader.Loader) bool {
+	// fingerprint taken from Stella
+	fingerprint := [][]byte{
+		{0xad, 0x0d, 0x08}, // LDA $080D
+		{0xad, 0x1d, 0x08}, // LDA $081D
+		{0xad, 0x2d, 0x08}, // LDA $082D
+		{0x0c, 0x0d, 0x08}, // NOP $080D
+		{0x0c, 0x1d, 0x08}, // NOP $081D
+		{0x0c, 0x2d, 0x08}, // NOP $082D
+	}
+	for _, f := range fingerprint {
+		if loader.Contains(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprintCTY(loader cartridgeloader.Loader) bool {
+	// fingerprint taken from Stella
+	return loader.Contains([]byte{'L', 'E', 'N', 'I', 'N'})
 }
 
 func fingerprintWickstead(loader cartridgeloader.Loader) bool {
@@ -356,6 +447,10 @@ func fingerprint8k(loader cartridgeloader.Loader) string {
 		return "E0"
 	}
 
+	if fingerprint0840(loader) {
+		return "0840"
+	}
+
 	// mnetwork has the lowest threshold so place it at the end
 	if fingerprintMnetwork(loader) {
 		return "E7"
@@ -389,24 +484,52 @@ func fingerprint16k(loader cartridgeloader.Loader) string {
 }
 
 func fingerprint32k(loader cartridgeloader.Loader) string {
+	if fingerprintCTY(loader) {
+		return "CTY"
+	}
 	if fingerprintTigervision(loader) {
 		return "3F"
 	}
+	if fingerprintFA2(loader) {
+		return "FA2"
+	}
 	return "F4"
 }
 
 func fingerprint64k(loader cartridgeloader.Loader) string {
+	// CTY cartridge data with the tune data appended
+	if fingerprintCTY(loader) {
+		return "CTY"
+	}
+	if fingerprint4A50(loader) {
+		return "4A50"
+	}
+	if fingerprintX07(loader) {
+		return "X07"
+	}
 	return "EF"
 }
 
 func fingerprint128k(loader cartridgeloader.Loader) string {
-	if fingerprintDF(loader) {
+	if fingerprintBankSignature(loader, "DFSC") {
+		return "DFSC"
+	}
+	if fingerprintBankSignature(loader, "DFDF") {
 		return "DF"
 	}
+	if fingerprint4A50(loader) {
+		return "4A50"
+	}
 	return "SB"
 }
 
 func fingerprint256k(loader cartridgeloader.Loader) string {
+	if fingerprintBankSignature(loader, "BFSC") {
+		return "BFSC"
+	}
+	if fingerprintBankSignature(loader, "BFBF") {
+		return "BF"
+	}
 	return "SB"
 }
 
@@ -469,12 +592,27 @@ func (cart *Cartridge) fingerprint(cartload cartridgeloader.Loader) (string, err
 	case 12288:
 		return "FA", nil
 
+	case 24576:
+		fallthrough
+
+	case 28672:
+		fallthrough
+
+	case 29696:
+		return "FA2", nil
+
 	case 16384:
 		return fingerprint16k(cartload), nil
 
 	case 32768:
 		return fingerprint32k(cartload), nil
 
+	case 61440:
+		// CTY cartridge data with the tune data appended
+		if fingerprintCTY(cartload) {
+			return "CTY", nil
+		}
+
 	case 65536:
 		return fingerprint64k(cartload), nil
 
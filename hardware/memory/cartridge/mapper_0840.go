@@ -0,0 +1,127 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+)
+
+// the 0840 mapper (also known as "EconoBanking") is an 8k cartridge with two
+// 4k banks. unlike the standard F8 format the hotspots are outside of the
+// cartridge address space. an access to an address matching 0800 selects the
+// first bank and an access to an address matching 0840 selects the second
+// bank. only address lines A12, A11 and A6 are decoded
+type m0840 struct {
+	atari
+}
+
+// new0840 is the preferred method of initialisation for the m0840 type
+func new0840(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+	data, err := io.ReadAll(loader)
+	if err != nil {
+		return nil, fmt.Errorf("0840: %w", err)
+	}
+
+	cart := &m0840{
+		atari: atari{
+			env:       env,
+			bankSize:  4096,
+			mappingID: "0840",
+			state:     newAtariState(),
+		},
+	}
+
+	if len(data) != cart.bankSize*cart.NumBanks() {
+		return nil, fmt.Errorf("0840: wrong number of bytes in the cartridge data")
+	}
+
+	cart.banks = make([][]uint8, cart.NumBanks())
+	for k := 0; k < cart.NumBanks(); k++ {
+		cart.banks[k] = make([]uint8, cart.bankSize)
+		offset := k * cart.bankSize
+		copy(cart.banks[k], data[offset:offset+cart.bankSize])
+	}
+
+	return cart, nil
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (cart *m0840) Snapshot() mapper.CartMapper {
+	n := *cart
+	n.state = cart.state.Snapshot()
+	return &n
+}
+
+// Plumb implements the mapper.CartMapper interface.
+func (cart *m0840) Plumb(env *environment.Environment) {
+	cart.env = env
+}
+
+// Access implements the mapper.CartMapper interface.
+func (cart *m0840) Access(addr uint16, _ bool) (uint8, uint8, error) {
+	if data, mask, ok := cart.atari.access(addr); ok {
+		return data, mask, nil
+	}
+	return cart.banks[cart.state.bank][addr], mapper.CartDrivenPins, nil
+}
+
+// AccessVolatile implements the mapper.CartMapper interface.
+func (cart *m0840) AccessVolatile(addr uint16, data uint8, poke bool) error {
+	return cart.accessVolatile(addr, data, poke)
+}
+
+// AccessPassive implements the mapper.CartMapper interface.
+func (cart *m0840) AccessPassive(addr uint16, _ uint8) error {
+	switch addr & 0x1840 {
+	case 0x0800:
+		cart.state.bank = 0
+	case 0x0840:
+		cart.state.bank = 1
+	}
+	return nil
+}
+
+// Reset implements the mapper.CartMapper interface.
+func (cart *m0840) Reset() {
+	cart.reset(cart.NumBanks())
+}
+
+// NumBanks implements the mapper.CartMapper interface.
+func (cart *m0840) NumBanks() int {
+	return 2
+}
+
+// ReadHotspots implements the mapper.CartHotspotsBus interface.
+//
+// The hotspots are 0800 and 0840 and their mirrors in the 0800 to 0FFF range.
+func (cart *m0840) ReadHotspots() map[uint16]mapper.CartHotspotInfo {
+	h := make(map[uint16]mapper.CartHotspotInfo)
+	for m := uint16(0x0800); m <= 0x0f00; m += 0x0100 {
+		h[m] = mapper.CartHotspotInfo{Symbol: "BANK0", Action: mapper.HotspotBankSwitch}
+		h[m|0x0040] = mapper.CartHotspotInfo{Symbol: "BANK1", Action: mapper.HotspotBankSwitch}
+	}
+	return h
+}
+
+// WriteHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *m0840) WriteHotspots() map[uint16]mapper.CartHotspotInfo {
+	return cart.ReadHotspots()
+}
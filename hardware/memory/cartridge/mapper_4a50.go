@@ -0,0 +1,644 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+)
+
+// 4A50 is the format designed by John Payson. The cartridge address space is
+// divided into four regions:
+//
+//	1000-17FF   2K slice of ROM (from the first 64K) or RAM
+//	1800-1DFF   1.5K slice of ROM (from the second 64K) or RAM
+//	1E00-1EFF   256 byte slice of ROM (from the second 64K) or RAM
+//	1F00-1FFF   fixed to the last 256 bytes of ROM
+//
+// There is 32K of RAM. Unlike most other cartridge formats with RAM, there are
+// no separate read and write ports. RAM is read and written at the same
+// address.
+//
+// Slices are selected by accessing addresses outside of the cartridge address
+// space. There are two methods:
+//
+// Firstly, by accessing an address in the 0C00-0FFF range (or the 0400-05FF and
+// 0800-09FF ranges, which toggle address lines of the lower and middle slices)
+// immediately after an access that put a value in the range 60-6F on the data
+// bus. The usual way of doing this is with a NOP instruction in the cartridge
+// with an operand in the 6C00-6FFF range. The 6507 sees the 6C00-6FFF range as
+// a mirror of the 0C00-0FFF range.
+//
+// In addition, under the same condition, accessing an address in the 1F00-1FFF
+// range will change the high slice by modifying bits 8 to 11 of its address.
+//
+// Secondly, by writing to the zero page hotspots. F4, F6, FC, FE select a ROM
+// page for the high slice and F5, F7, FD, FF select a RAM page. F8 to FB select
+// a slice for the lower or middle region depending on the value written. The
+// hotspots are also active in the 74-7F mirror.
+//
+// The emulation is based on the implementation in Stella.
+type m4A50 struct {
+	env *environment.Environment
+
+	mappingID string
+
+	// the size of the original cartridge data. the image is always 128K but
+	// smaller cartridges are duplicated to fill the image
+	size  int
+	image []uint8
+
+	// the image is presented to the disassembly as banks of 2K. the first 32
+	// banks are used by the lower slice and the remaining 32 banks are used by
+	// the middle and high slices
+	bankSize int
+
+	// rewindable state
+	state *m4A50State
+}
+
+const (
+	m4A50ImageSize = 131072
+	m4A50RAMSize   = 32768
+)
+
+func new4A50(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+	data, err := io.ReadAll(loader)
+	if err != nil {
+		return nil, fmt.Errorf("4A50: %w", err)
+	}
+
+	cart := &m4A50{
+		env:       env,
+		mappingID: "4A50",
+		size:      len(data),
+		image:     make([]uint8, m4A50ImageSize),
+		bankSize:  2048,
+		state:     newM4A50State(),
+	}
+
+	switch len(data) {
+	case 32768, 65536, 131072:
+	default:
+		return nil, fmt.Errorf("4A50: wrong number of bytes in the cartridge data")
+	}
+
+	for i := 0; i < len(cart.image); i += len(data) {
+		copy(cart.image[i:], data)
+	}
+
+	return cart, nil
+}
+
+// MappedBanks implements the mapper.CartMapper interface.
+func (cart *m4A50) MappedBanks() string {
+	r := cart.state.registers
+	slice := func(isROM bool, offset uint16, size uint16) string {
+		if isROM {
+			return fmt.Sprintf("%d", offset/size)
+		}
+		return fmt.Sprintf("R%d", offset/size)
+	}
+	return fmt.Sprintf("L: %s M: %s H: %s",
+		slice(r.LowROM, r.LowSlice, 0x800),
+		slice(r.MiddleROM, r.MiddleSlice, 0x800),
+		slice(r.HighROM, r.HighSlice, 0x100))
+}
+
+// ID implements the mapper.CartMapper interface.
+func (cart *m4A50) ID() string {
+	return cart.mappingID
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (cart *m4A50) Snapshot() mapper.CartMapper {
+	n := *cart
+	n.state = cart.state.Snapshot()
+	return &n
+}
+
+// Plumb implements the mapper.CartMapper interface.
+func (cart *m4A50) Plumb(env *environment.Environment) {
+	cart.env = env
+}
+
+// Reset implements the mapper.CartMapper interface.
+func (cart *m4A50) Reset() {
+	for i := range cart.state.ram {
+		if cart.env.Prefs.RandomState.Get().(bool) {
+			cart.state.ram[i] = uint8(cart.env.Random.NoRewind(0xff))
+		} else {
+			cart.state.ram[i] = 0
+		}
+	}
+
+	cart.state.registers = Registers4A50{
+		LowROM:    true,
+		MiddleROM: true,
+		HighROM:   true,
+	}
+	cart.state.lastAddress = 0xffff
+	cart.state.lastData = 0xff
+	cart.state.pendingWrite = false
+	cart.state.passivePending = false
+}
+
+// read the value at the cartridge address without side effects.
+func (cart *m4A50) read(addr uint16) uint8 {
+	r := cart.state.registers
+	switch {
+	case addr <= 0x07ff:
+		if r.LowROM {
+			return cart.image[int(addr&0x7ff)+int(r.LowSlice)]
+		}
+		return cart.state.ram[int(addr&0x7ff)+int(r.LowSlice)]
+	case addr <= 0x0dff:
+		if r.MiddleROM {
+			return cart.image[int(addr&0x7ff)+int(r.MiddleSlice)+0x10000]
+		}
+		return cart.state.ram[int(addr&0x7ff)+int(r.MiddleSlice)]
+	case addr <= 0x0eff:
+		if r.HighROM {
+			return cart.image[int(addr&0xff)+int(r.HighSlice)+0x10000]
+		}
+		return cart.state.ram[int(addr&0xff)+int(r.HighSlice)]
+	}
+	return cart.image[int(addr&0xff)+0x1ff00]
+}
+
+// write the value to the cartridge address. returns false if the address is
+// not currently mapped to RAM.
+func (cart *m4A50) write(addr uint16, data uint8) bool {
+	r := cart.state.registers
+	switch {
+	case addr <= 0x07ff:
+		if !r.LowROM {
+			cart.state.ram[int(addr&0x7ff)+int(r.LowSlice)] = data
+			return true
+		}
+	case addr <= 0x0dff:
+		if !r.MiddleROM {
+			cart.state.ram[int(addr&0x7ff)+int(r.MiddleSlice)] = data
+			return true
+		}
+	case addr <= 0x0eff:
+		if !r.HighROM {
+			cart.state.ram[int(addr&0xff)+int(r.HighSlice)] = data
+			return true
+		}
+	}
+	return false
+}
+
+// Access implements the mapper.CartMapper interface.
+func (cart *m4A50) Access(addr uint16, peek bool) (uint8, uint8, error) {
+	data := cart.read(addr)
+	if peek {
+		return data, mapper.CartDrivenPins, nil
+	}
+
+	// the cartridge is being read and not written to. the pending write was
+	// the result of the memory system treating the read as a volatile access
+	if cart.state.pendingWrite && cart.state.pendingAddress == addr {
+		cart.state.pendingWrite = false
+	}
+
+	if addr >= 0x0f00 {
+		cart.fixedAccess(addr)
+	}
+
+	cart.state.lastAddress = addr | memorymap.OriginCart
+	cart.state.lastData = data
+
+	return data, mapper.CartDrivenPins, nil
+}
+
+// AccessVolatile implements the mapper.CartMapper interface.
+func (cart *m4A50) AccessVolatile(addr uint16, data uint8, poke bool) error {
+	if poke {
+		if !cart.write(addr, data) {
+			if addr <= 0x07ff {
+				cart.image[int(addr&0x7ff)+int(cart.state.registers.LowSlice)] = data
+			} else if addr <= 0x0dff {
+				cart.image[int(addr&0x7ff)+int(cart.state.registers.MiddleSlice)+0x10000] = data
+			} else if addr <= 0x0eff {
+				cart.image[int(addr&0xff)+int(cart.state.registers.HighSlice)+0x10000] = data
+			} else {
+				cart.image[int(addr&0xff)+0x1ff00] = data
+			}
+		}
+		return nil
+	}
+
+	// AccessVolatile() is called for every cartridge access, including reads.
+	// because RAM is read and written at the same address we can't commit the
+	// write until we know that it wasn't a read. a read will be followed
+	// immediately by a call to Access() at the same address, which will cancel
+	// the write
+	//
+	// pending writes are committed in the Step() function
+	cart.state.pendingWrite = true
+	cart.state.pendingAddress = addr
+	cart.state.pendingData = data
+
+	return nil
+}
+
+// commit pending write to RAM.
+func (cart *m4A50) commitWrite() {
+	if !cart.state.pendingWrite {
+		return
+	}
+	cart.state.pendingWrite = false
+
+	addr := cart.state.pendingAddress
+	data := cart.state.pendingData
+
+	if addr >= 0x0f00 {
+		cart.fixedAccess(addr)
+	} else {
+		cart.write(addr, data)
+	}
+
+	cart.state.lastAddress = addr | memorymap.OriginCart
+	cart.state.lastData = data
+}
+
+// the previous access to the bus primed the bankswitching mechanism.
+func (cart *m4A50) primed() bool {
+	return cart.state.lastData&0xe0 == 0x60 &&
+		(cart.state.lastAddress >= 0x1000 || cart.state.lastAddress < 0x200)
+}
+
+// access to the fixed region of the cartridge can change the high slice.
+func (cart *m4A50) fixedAccess(addr uint16) {
+	if cart.primed() {
+		r := &cart.state.registers
+		r.HighSlice = (r.HighSlice & 0xf0ff) | ((addr & 0x08) << 8) | ((addr & 0x70) << 4)
+	}
+}
+
+// bankswitch on access to non-cartridge addresses.
+func (cart *m4A50) bankswitch(addr uint16, data uint8) {
+	r := &cart.state.registers
+
+	if cart.primed() {
+		switch {
+		case addr&0x0f00 == 0x0c00:
+			r.HighROM = true
+			r.HighSlice = (addr & 0xff) << 8
+		case addr&0x0f00 == 0x0d00:
+			r.HighROM = false
+			r.HighSlice = (addr & 0x7f) << 8
+		case addr&0x0f40 == 0x0e00:
+			r.LowROM = true
+			r.LowSlice = (addr & 0x1f) << 11
+		case addr&0x0f40 == 0x0e40:
+			r.LowROM = false
+			r.LowSlice = (addr & 0x0f) << 11
+		case addr&0x0f40 == 0x0f00:
+			r.MiddleROM = true
+			r.MiddleSlice = (addr & 0x1f) << 11
+		case addr&0x0f50 == 0x0f40:
+			r.MiddleROM = false
+			r.MiddleSlice = (addr & 0x0f) << 11
+
+		// toggle address lines A11 and A12 of the lower and middle slices
+		case addr&0x0f00 == 0x0400:
+			r.LowSlice ^= 0x0800
+		case addr&0x0f00 == 0x0500:
+			r.LowSlice ^= 0x1000
+		case addr&0x0f00 == 0x0800:
+			r.MiddleSlice ^= 0x0800
+		case addr&0x0f00 == 0x0900:
+			r.MiddleSlice ^= 0x1000
+		}
+	}
+
+	// zero page hotspots
+	switch {
+	case addr&0x0f75 == 0x74:
+		r.HighROM = true
+		r.HighSlice = uint16(data) << 8
+	case addr&0x0f75 == 0x75:
+		r.HighROM = false
+		r.HighSlice = uint16(data&0x7f) << 8
+	case addr&0x0f7c == 0x78:
+		switch data & 0xf0 {
+		case 0x00:
+			r.LowROM = true
+			r.LowSlice = uint16(data&0x0f) << 11
+		case 0x40:
+			r.LowROM = false
+			r.LowSlice = uint16(data&0x0f) << 11
+		case 0x90:
+			r.MiddleROM = true
+			r.MiddleSlice = uint16((data&0x0f)|0x10) << 11
+		case 0xc0:
+			r.MiddleROM = false
+			r.MiddleSlice = uint16(data&0x0f) << 11
+		}
+	}
+}
+
+// NumBanks implements the mapper.CartMapper interface.
+func (cart *m4A50) NumBanks() int {
+	return len(cart.image) / cart.bankSize
+}
+
+// GetBank implements the mapper.CartMapper interface.
+func (cart *m4A50) GetBank(addr uint16) mapper.BankInfo {
+	r := cart.state.registers
+	switch {
+	case addr <= 0x07ff:
+		if r.LowROM {
+			return mapper.BankInfo{Number: int(r.LowSlice) / cart.bankSize, IsSegmented: true, Segment: 0}
+		}
+		return mapper.BankInfo{Number: int(r.LowSlice) / cart.bankSize, IsRAM: true, IsSegmented: true, Segment: 0}
+	case addr <= 0x0dff:
+		if r.MiddleROM {
+			return mapper.BankInfo{Number: 32 + int(r.MiddleSlice)/cart.bankSize, IsSegmented: true, Segment: 1}
+		}
+		return mapper.BankInfo{Number: int(r.MiddleSlice) / cart.bankSize, IsRAM: true, IsSegmented: true, Segment: 1}
+	case addr <= 0x0eff:
+		if r.HighROM {
+			return mapper.BankInfo{Number: 32 + int(r.HighSlice)/cart.bankSize, IsSegmented: true, Segment: 2}
+		}
+		return mapper.BankInfo{Number: int(r.HighSlice) / cart.bankSize, IsRAM: true, IsSegmented: true, Segment: 2}
+	}
+	return mapper.BankInfo{Number: cart.NumBanks() - 1, IsSegmented: true, Segment: 3}
+}
+
+// Patch implements the mapper.CartPatchable interface
+func (cart *m4A50) Patch(offset int, data uint8) error {
+	if offset >= cart.size {
+		return fmt.Errorf("4A50: patch offset too high (%d)", offset)
+	}
+
+	// patch every copy of the cartridge data in the image
+	for i := offset; i < len(cart.image); i += cart.size {
+		cart.image[i] = data
+	}
+	return nil
+}
+
+// AccessPassive implements the mapper.CartMapper interface.
+func (cart *m4A50) AccessPassive(addr uint16, data uint8) error {
+	// any pending write will have been committed by Step() but we check anyway
+	cart.commitWrite()
+
+	// AccessPassive() is called before the data bus has been updated by a read
+	// access. the bankswitching for an access to a non-cartridge address must
+	// therefore wait until the next call to AccessPassive(), at which point
+	// the data value is the value that was read (or written) by that access.
+	// this is the value that Stella uses to decide on the bankswitch
+	if cart.state.passivePending {
+		cart.state.passivePending = false
+		cart.bankswitch(cart.state.passiveAddress, data)
+		cart.state.lastAddress = cart.state.passiveAddress
+		cart.state.lastData = data
+	}
+
+	// cartridge addresses are dealt with by Access() and AccessVolatile()
+	if addr&memorymap.OriginCart == memorymap.OriginCart {
+		return nil
+	}
+
+	cart.state.passivePending = true
+	cart.state.passiveAddress = addr & memorymap.Memtop
+
+	return nil
+}
+
+// Step implements the mapper.CartMapper interface.
+func (cart *m4A50) Step(_ float32) {
+	cart.commitWrite()
+}
+
+// GetRAM implements the mapper.CartRAMBus interface.
+func (cart *m4A50) GetRAM() []mapper.CartRAM {
+	r := make([]mapper.CartRAM, len(cart.state.ram)/cart.bankSize)
+	for i := range r {
+		mapped := func(isROM bool, slice uint16) bool {
+			return !isROM && int(slice)/cart.bankSize == i
+		}
+
+		regs := cart.state.registers
+		r[i] = mapper.CartRAM{
+			Label:  fmt.Sprintf("2K [%d]", i),
+			Origin: 0x1000,
+			Data:   make([]uint8, cart.bankSize),
+			Mapped: mapped(regs.LowROM, regs.LowSlice) ||
+				mapped(regs.MiddleROM, regs.MiddleSlice) ||
+				mapped(regs.HighROM, regs.HighSlice),
+		}
+		copy(r[i].Data, cart.state.ram[i*cart.bankSize:])
+	}
+	return r
+}
+
+// PutRAM implements the mapper.CartRAMBus interface.
+func (cart *m4A50) PutRAM(bank int, idx int, data uint8) {
+	cart.state.ram[bank*cart.bankSize+idx] = data
+}
+
+// CopyBanks implements the mapper.CartMapper interface.
+func (cart *m4A50) CopyBanks() []mapper.BankContent {
+	c := make([]mapper.BankContent, cart.NumBanks())
+	for b := range c {
+		origin := memorymap.OriginCart
+		if b >= cart.NumBanks()/2 {
+			origin += 0x0800
+		}
+		c[b] = mapper.BankContent{Number: b,
+			Data:    cart.image[b*cart.bankSize : (b+1)*cart.bankSize],
+			Origins: []uint16{origin},
+		}
+	}
+	return c
+}
+
+// GetRegisters implements the mapper.CartRegistersBus interface.
+func (cart *m4A50) GetRegisters() mapper.CartRegisters {
+	return cart.state.registers
+}
+
+// PutRegister implements the mapper.CartRegistersBus interface.
+//
+// Register specification is divided with the "::" string. The following table
+// describes what the valid register strings and, after the = sign, the type to
+// which the data argument will be converted.
+//
+//	low::slice = uint16
+//	low::rom = bool
+//	middle::slice = uint16
+//	middle::rom = bool
+//	high::slice = uint16
+//	high::rom = bool
+//
+// note that PutRegister() will panic() if the register or data string is invalid.
+func (cart *m4A50) PutRegister(register string, data string) {
+	r := strings.Split(register, "::")
+	if len(r) != 2 {
+		panic(fmt.Sprintf("unrecognised register [%s]", register))
+	}
+
+	var slice *uint16
+	var rom *bool
+
+	regs := &cart.state.registers
+	switch r[0] {
+	case "low":
+		slice = &regs.LowSlice
+		rom = &regs.LowROM
+	case "middle":
+		slice = &regs.MiddleSlice
+		rom = &regs.MiddleROM
+	case "high":
+		slice = &regs.HighSlice
+		rom = &regs.HighROM
+	default:
+		panic(fmt.Sprintf("unrecognised register [%s]", register))
+	}
+
+	switch r[1] {
+	case "slice":
+		v, err := strconv.ParseUint(data, 16, 16)
+		if err != nil {
+			panic(fmt.Sprintf("unrecognised slice value [%s]", data))
+		}
+		*slice = uint16(v)
+	case "rom":
+		switch data {
+		case "true":
+			*rom = true
+		case "false":
+			*rom = false
+		default:
+			panic(fmt.Sprintf("unrecognised boolean state [%s]", data))
+		}
+	default:
+		panic(fmt.Sprintf("unrecognised variable [%s]", register))
+	}
+
+	// make sure slices point to valid memory
+	if !regs.LowROM {
+		regs.LowSlice &= 0x7800
+	}
+	if !regs.MiddleROM {
+		regs.MiddleSlice &= 0x7800
+	}
+	if !regs.HighROM {
+		regs.HighSlice &= 0x7f00
+	}
+}
+
+// ReadHotspots implements the mapper.CartHotspotsBus interface.
+//
+// The hotspots are the first address of each of the ranges that select a
+// slice when the bankswitching mechanism has been primed. The slice is
+// selected by the lower bits of the address.
+func (cart *m4A50) ReadHotspots() map[uint16]mapper.CartHotspotInfo {
+	return map[uint16]mapper.CartHotspotInfo{
+		0x0400: {Symbol: "LOWA11", Action: mapper.HotspotBankSwitch},
+		0x0500: {Symbol: "LOWA12", Action: mapper.HotspotBankSwitch},
+		0x0800: {Symbol: "MIDA11", Action: mapper.HotspotBankSwitch},
+		0x0900: {Symbol: "MIDA12", Action: mapper.HotspotBankSwitch},
+		0x0c00: {Symbol: "HIGHROM", Action: mapper.HotspotBankSwitch},
+		0x0d00: {Symbol: "HIGHRAM", Action: mapper.HotspotBankSwitch},
+		0x0e00: {Symbol: "LOWROM", Action: mapper.HotspotBankSwitch},
+		0x0e40: {Symbol: "LOWRAM", Action: mapper.HotspotBankSwitch},
+		0x0f00: {Symbol: "MIDROM", Action: mapper.HotspotBankSwitch},
+		0x0f40: {Symbol: "MIDRAM", Action: mapper.HotspotBankSwitch},
+	}
+}
+
+// WriteHotspots implements the mapper.CartHotspotsBus interface.
+//
+// The zero page hotspots are not reported because they are RIOT RAM addresses
+// as far as the rest of the system is concerned.
+func (cart *m4A50) WriteHotspots() map[uint16]mapper.CartHotspotInfo {
+	return map[uint16]mapper.CartHotspotInfo{}
+}
+
+// Registers4A50 implements the mapper.CartRegisters interface. The slice values
+// are offsets into ROM or RAM, depending on the corresponding ROM field.
+type Registers4A50 struct {
+	LowSlice    uint16
+	LowROM      bool
+	MiddleSlice uint16
+	MiddleROM   bool
+	HighSlice   uint16
+	HighROM     bool
+}
+
+func (r Registers4A50) String() string {
+	s := strings.Builder{}
+	region := func(label string, slice uint16, isROM bool) {
+		if isROM {
+			s.WriteString(fmt.Sprintf("%s: ROM %04x\n", label, slice))
+		} else {
+			s.WriteString(fmt.Sprintf("%s: RAM %04x\n", label, slice))
+		}
+	}
+	region("Low", r.LowSlice, r.LowROM)
+	region("Middle", r.MiddleSlice, r.MiddleROM)
+	region("High", r.HighSlice, r.HighROM)
+	return s.String()
+}
+
+// rewindable state for the 4A50 cartridge.
+type m4A50State struct {
+	registers Registers4A50
+
+	ram []uint8
+
+	// the most recent access to the address and data bus
+	lastAddress uint16
+	lastData    uint8
+
+	// write to the cartridge that has not yet been committed. see commentary
+	// in AccessVolatile()
+	pendingWrite   bool
+	pendingAddress uint16
+	pendingData    uint8
+
+	// access to a non-cartridge address that has not yet been resolved. see
+	// commentary in AccessPassive()
+	passivePending bool
+	passiveAddress uint16
+}
+
+func newM4A50State() *m4A50State {
+	return &m4A50State{
+		ram: make([]uint8, m4A50RAMSize),
+	}
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (s *m4A50State) Snapshot() *m4A50State {
+	n := *s
+	n.ram = make([]uint8, len(s.ram))
+	copy(n.ram, s.ram)
+	return &n
+}
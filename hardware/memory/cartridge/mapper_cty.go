@@ -0,0 +1,676 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/logger"
+	"github.com/jetsetilly/gopher2600/resources"
+)
+
+// the directory in the resources path in which CTY EEPROM data is stored. each
+// cartridge has its own file named after the SHA1 hash of the cartridge data
+const ctyEEPROMPath = "ctyeeprom"
+
+// the EEPROM holds four score tables of 64 bytes each. the first four bytes of
+// each table are not used
+const (
+	ctyScoreTableSize = 64
+	ctyScoreTables    = 4
+	ctyScoreTableSkip = 4
+)
+
+// the tune data is appended to the 32K cartridge data. there are seven tunes
+// of 4K each
+const (
+	ctyTuneSize = 4096
+	ctyTunes    = 7
+)
+
+// the lower nibble of the operation register selects the EEPROM operation. the
+// upper nibble is the index of the tune or score table
+const (
+	ctyLoadTune   = 1
+	ctyLoadScore  = 2
+	ctySaveScore  = 3
+	ctyWipeScores = 4
+)
+
+// the amount of time, in microseconds, that an EEPROM operation takes. these
+// values are taken from Stella
+const (
+	ctyReadTime  = 500000
+	ctyWriteTime = 1000000
+)
+
+// the rate in Hz at which the tune player's oscillators are clocked
+const ctyMusicClock = 20000
+
+// the initial value of the random number generator. the value is the ASCII
+// string "+CPD"
+const ctyRandomSeed = 0x2b435044
+
+// CTY is the mapper used by Chris Walton's Chetiry. On the real cartridge the
+// scheme is implemented by ARM code on the Harmony cartridge, which is stored in
+// bank 0. The ARM code is not emulated and bank 0 is never mapped into the
+// cartridge address space.
+//
+// There are eight banks of 4K. Banks 1 to 7 are selected by accessing 1FF5 to
+// 1FFB. The start bank is bank 1.
+//
+// There are 64 bytes of RAM. 1000-103F is the write port and 1040-107F is the
+// read port. The first four bytes of each port are registers:
+//
+//	Write
+//	1000	operation register. see eeprom() function
+//	1001	reset the random number generator
+//	1002	reset the tune player to the beginning of the tune
+//	1003	advance the tune player to the next note
+//
+//	Read
+//	1040	result of the last operation. reading clears the value
+//	1041	next random number
+//	1042	tune position (low byte)
+//	1043	tune position (high byte)
+//
+// Note that reading from the write port causes an unintentional write.
+//
+// The tune player has three square wave channels. The mixed output of the
+// three channels is returned by the operand of any LDA #$F2 instruction. The
+// 6507 program is expected to write the value to an AUDV register of the TIA.
+type cty struct {
+	env *environment.Environment
+
+	mappingID string
+
+	// the hash of the cartridge data. used to name the EEPROM file
+	hash string
+
+	// cty cartridges have 8 banks of 4096 bytes
+	bankSize int
+	banks    [][]uint8
+
+	// tune data appended to the cartridge data. if there is no tune data then
+	// the tune player will be silent
+	tunes []uint8
+
+	// rewindable state
+	state *ctyState
+}
+
+func newCTY(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+	data, err := io.ReadAll(loader)
+	if err != nil {
+		return nil, fmt.Errorf("CTY: %w", err)
+	}
+
+	cart := &cty{
+		env:       env,
+		mappingID: "CTY",
+		hash:      loader.HashSHA1,
+		bankSize:  4096,
+		tunes:     make([]uint8, ctyTuneSize*ctyTunes),
+		state:     newCTYState(),
+	}
+
+	// the tune data may be padded to make a 64K file
+	const romSize = 32768
+
+	if len(data) < romSize || len(data) > romSize*2 {
+		return nil, fmt.Errorf("CTY: wrong number of bytes in the cartridge data")
+	}
+
+	cart.banks = make([][]uint8, romSize/cart.bankSize)
+
+	for k := 0; k < len(cart.banks); k++ {
+		cart.banks[k] = make([]uint8, cart.bankSize)
+		offset := k * cart.bankSize
+		copy(cart.banks[k], data[offset:offset+cart.bankSize])
+	}
+
+	copy(cart.tunes, data[romSize:])
+
+	return cart, nil
+}
+
+// MappedBanks implements the mapper.CartMapper interface.
+func (cart *cty) MappedBanks() string {
+	return fmt.Sprintf("Bank: %d", cart.state.bank)
+}
+
+// ID implements the mapper.CartMapper interface.
+func (cart *cty) ID() string {
+	return cart.mappingID
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (cart *cty) Snapshot() mapper.CartMapper {
+	n := *cart
+	n.state = cart.state.Snapshot()
+	return &n
+}
+
+// Plumb implements the mapper.CartMapper interface.
+func (cart *cty) Plumb(env *environment.Environment) {
+	cart.env = env
+}
+
+// Reset implements the mapper.CartMapper interface.
+func (cart *cty) Reset() {
+	for i := range cart.state.ram {
+		if cart.env.Prefs.RandomState.Get().(bool) {
+			cart.state.ram[i] = uint8(cart.env.Random.NoRewind(0xff))
+		} else {
+			cart.state.ram[i] = 0
+		}
+	}
+
+	cart.state.bank = 1
+	cart.state.operation = 0
+	cart.state.random = ctyRandomSeed
+	cart.state.ldaImmediate = false
+	cart.state.eepromBusy = false
+	cart.state.eepromTime = 0
+	cart.state.tune = 0
+	cart.resetTune()
+}
+
+// Access implements the mapper.CartMapper interface.
+func (cart *cty) Access(addr uint16, peek bool) (uint8, uint8, error) {
+	data := cart.banks[cart.state.bank][addr]
+
+	if peek {
+		if addr <= 0x003f {
+			return cart.state.ram[addr], mapper.CartDrivenPins, nil
+		}
+		if addr <= 0x007f {
+			return cart.state.ram[addr-0x40], mapper.CartDrivenPins, nil
+		}
+		return data, mapper.CartDrivenPins, nil
+	}
+
+	// the operand of an LDA immediate instruction with the value F2 is
+	// replaced with the output of the tune player
+	if cart.state.ldaImmediate && data == 0xf2 {
+		cart.state.ldaImmediate = false
+		return cart.music(), mapper.CartDrivenPins, nil
+	}
+
+	if addr <= 0x003f {
+		return cart.state.ram[addr], mapper.CartDrivenPins, nil
+	}
+
+	if addr <= 0x007f {
+		return cart.readRegister(addr - 0x40), mapper.CartDrivenPins, nil
+	}
+
+	if addr == 0x0ff4 {
+		cart.eeprom()
+
+		// bit 6 of the hotspot address indicates whether the EEPROM operation
+		// is still in progress
+		if cart.state.eepromBusy {
+			data |= 0x40
+		} else {
+			data &^= 0x40
+		}
+	} else {
+		cart.bankswitch(addr)
+	}
+
+	cart.state.ldaImmediate = data == 0xa9
+
+	return data, mapper.CartDrivenPins, nil
+}
+
+// AccessVolatile implements the mapper.CartMapper interface.
+func (cart *cty) AccessVolatile(addr uint16, data uint8, poke bool) error {
+	if poke {
+		if addr <= 0x003f {
+			cart.state.ram[addr] = data
+		} else if addr <= 0x007f {
+			cart.state.ram[addr-0x40] = data
+		} else {
+			cart.banks[cart.state.bank][addr] = data
+		}
+		return nil
+	}
+
+	if addr <= 0x003f {
+		cart.writeRegister(addr, data)
+		return nil
+	}
+
+	if addr == 0x0ff4 {
+		cart.eeprom()
+		return nil
+	}
+
+	cart.bankswitch(addr)
+
+	return nil
+}
+
+// bankswitch on hotspot access. bank 0 contains the ARM code and can not be
+// selected.
+func (cart *cty) bankswitch(addr uint16) bool {
+	if addr >= 0x0ff5 && addr <= 0x0ffb {
+		cart.state.bank = int(addr - 0x0ff4)
+		return true
+	}
+	return false
+}
+
+// readRegister returns the value from the RAM read port, with the exception of
+// the first four bytes which are registers.
+func (cart *cty) readRegister(idx uint16) uint8 {
+	switch idx {
+	case 0x00:
+		v := cart.state.ram[0]
+		cart.state.ram[0] = 0
+		return v
+	case 0x01:
+		// galois LFSR as implemented by the ARM driver
+		if cart.state.random&(1<<10) != 0 {
+			cart.state.random = 0x10adab1e ^ ((cart.state.random >> 11) | (cart.state.random << 21))
+		} else {
+			cart.state.random = (cart.state.random >> 11) | (cart.state.random << 21)
+		}
+		return uint8(cart.state.random)
+	case 0x02:
+		return uint8(cart.state.tunePosition)
+	case 0x03:
+		return uint8(cart.state.tunePosition >> 8)
+	}
+	return cart.state.ram[idx]
+}
+
+// writeRegister writes the value to the RAM write port, with the exception of
+// the first four bytes which are registers.
+func (cart *cty) writeRegister(idx uint16, data uint8) {
+	switch idx {
+	case 0x00:
+		cart.state.operation = data
+	case 0x01:
+		cart.state.random = ctyRandomSeed
+	case 0x02:
+		cart.resetTune()
+	case 0x03:
+		cart.advanceTune()
+	default:
+		cart.state.ram[idx] = data
+	}
+}
+
+// eeprom is called on every access of the EEPROM hotspot. the protocol is:
+//
+//  1. the program writes the operation to the operation register (1000). the
+//     lower nibble is the operation and the upper nibble is the index of the
+//     tune or score table
+//  2. the first access of the hotspot starts the operation
+//  3. bit 6 of the value read from the hotspot is set while the operation is
+//     busy. the program should poll the hotspot until bit 6 is clear
+//  4. the result register (1040) is set to zero to indicate that the operation
+//     has completed successfully
+//
+// the operations are:
+//
+//	1	load tune (index 0 to 6)
+//	2	load score table into RAM (index 0 to 3)
+//	3	save score table from RAM (index 0 to 3)
+//	4	wipe all score tables
+//
+// score tables are stored in bytes 4 to 63 of RAM.
+//
+// the operation itself happens immediately. the delay exists only to mimic the
+// time taken by the Harmony cartridge to access the EEPROM.
+func (cart *cty) eeprom() {
+	if cart.state.eepromBusy {
+		if cart.state.eepromTime <= 0 {
+			cart.state.eepromBusy = false
+			cart.state.ram[0] = 0
+		}
+		return
+	}
+
+	cart.state.eepromBusy = true
+	cart.state.eepromTime = 0
+
+	idx := int(cart.state.operation >> 4)
+
+	switch cart.state.operation & 0x0f {
+	case ctyLoadTune:
+		if idx < ctyTunes {
+			cart.state.tune = idx
+			cart.resetTune()
+			cart.state.eepromTime = ctyReadTime
+		}
+	case ctyLoadScore:
+		if idx < ctyScoreTables {
+			d := cart.readEEPROM()
+			offset := idx*ctyScoreTableSize + ctyScoreTableSkip
+			copy(cart.state.ram[ctyScoreTableSkip:], d[offset:offset+ctyScoreTableSize-ctyScoreTableSkip])
+			cart.state.eepromTime = ctyReadTime
+		}
+	case ctySaveScore:
+		if idx < ctyScoreTables {
+			d := cart.readEEPROM()
+			offset := idx*ctyScoreTableSize + ctyScoreTableSkip
+			copy(d[offset:offset+ctyScoreTableSize-ctyScoreTableSkip], cart.state.ram[ctyScoreTableSkip:])
+			cart.writeEEPROM(d)
+			cart.state.eepromTime = ctyWriteTime
+		}
+	case ctyWipeScores:
+		cart.writeEEPROM(make([]uint8, ctyScoreTableSize*ctyScoreTables))
+		cart.state.eepromTime = ctyWriteTime
+	}
+}
+
+// readEEPROM returns the contents of the EEPROM file. if there is no EEPROM
+// file then the returned data is all zeroes.
+func (cart *cty) readEEPROM() []uint8 {
+	d := make([]uint8, ctyScoreTableSize*ctyScoreTables)
+
+	fn, err := resources.JoinPath(ctyEEPROMPath, cart.hash)
+	if err != nil {
+		logger.Logf(cart.env, "CTY", "could not load eeprom file (%s)", err)
+		return d
+	}
+
+	f, err := os.ReadFile(fn)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Logf(cart.env, "CTY", "could not load eeprom file (%s)", err)
+		}
+		return d
+	}
+
+	if len(f) != len(d) {
+		logger.Logf(cart.env, "CTY", "eeprom file is of incorrect length. %d should be %d", len(f), len(d))
+	}
+
+	copy(d, f)
+	logger.Logf(cart.env, "CTY", "eeprom file loaded from %s", fn)
+
+	return d
+}
+
+// writeEEPROM saves data to the EEPROM file. only the main emulation is allowed
+// to write to the EEPROM file.
+func (cart *cty) writeEEPROM(d []uint8) {
+	if !cart.env.IsEmulation(environment.MainEmulation) {
+		return
+	}
+
+	fn, err := resources.JoinPath(ctyEEPROMPath, cart.hash)
+	if err != nil {
+		logger.Logf(cart.env, "CTY", "could not write eeprom file (%s)", err)
+		return
+	}
+
+	err = os.WriteFile(fn, d, 0600)
+	if err != nil {
+		logger.Logf(cart.env, "CTY", "could not write eeprom file (%s)", err)
+		return
+	}
+
+	logger.Logf(cart.env, "CTY", "eeprom file saved to %s", fn)
+}
+
+// resetTune returns the tune player to the beginning of the current tune and
+// silences all channels.
+func (cart *cty) resetTune() {
+	cart.state.tunePosition = 0
+	cart.state.musicClocks = 0
+	for i := range cart.state.musicCounters {
+		cart.state.musicCounters[i] = 0
+		cart.state.musicFrequencies[i] = 0
+	}
+}
+
+// advanceTune moves the tune player to the next position in the tune. each
+// position in the tune is three bytes, one note for each channel.
+//
+// a note value of zero in the first two channels means that the channel
+// continues to play the previous note. a note value of zero in the third
+// channel silences the channel and a value of one indicates the end of the
+// tune, causing the tune to loop.
+func (cart *cty) advanceTune() {
+	cart.state.tunePosition++
+
+	idx := int(cart.state.tunePosition) * 3
+	if idx+3 > ctyTuneSize {
+		cart.state.tunePosition = 0
+		idx = 0
+	}
+
+	tune := cart.tunes[cart.state.tune*ctyTuneSize:]
+
+	if n := tune[idx]; n != 0 {
+		cart.state.musicFrequencies[0] = ctyNoteFrequency(n)
+	}
+	if n := tune[idx+1]; n != 0 {
+		cart.state.musicFrequencies[1] = ctyNoteFrequency(n)
+	}
+	if n := tune[idx+2]; n == 1 {
+		cart.state.tunePosition = 0
+	} else {
+		cart.state.musicFrequencies[2] = ctyNoteFrequency(n)
+	}
+}
+
+// ctyNoteFrequency returns the value to be added to a channel's counter on
+// every clock of the tune player's oscillator.
+//
+// the frequency table used by the ARM driver is not yet part of the emulation
+// so we use an equal tempered scale with note value one being C2. note value
+// zero is a rest.
+func ctyNoteFrequency(n uint8) uint32 {
+	if n == 0 {
+		return 0
+	}
+	const c2 = 65.406
+	f := c2 * math.Pow(2, float64(n-1)/12)
+	return uint32(f * (1 << 32) / ctyMusicClock)
+}
+
+// music returns the mixed output of the three tune player channels. each
+// channel is a square wave and the output is scaled to the range of the TIA
+// volume registers.
+func (cart *cty) music() uint8 {
+	var v uint8
+	for _, c := range cart.state.musicCounters {
+		v += uint8(c >> 31)
+	}
+	return v * 5
+}
+
+// NumBanks implements the mapper.CartMapper interface.
+func (cart *cty) NumBanks() int {
+	return len(cart.banks)
+}
+
+// GetBank implements the mapper.CartMapper interface.
+func (cart *cty) GetBank(addr uint16) mapper.BankInfo {
+	// cty cartridges are like atari cartridges in that the entire address
+	// space points to the selected bank
+	return mapper.BankInfo{Number: cart.state.bank, IsRAM: addr <= 0x007f}
+}
+
+// Patch implements the mapper.CartPatchable interface
+func (cart *cty) Patch(offset int, data uint8) error {
+	if offset >= cart.bankSize*len(cart.banks) {
+		return fmt.Errorf("CTY: patch offset too high (%d)", offset)
+	}
+
+	bank := offset / cart.bankSize
+	offset %= cart.bankSize
+	cart.banks[bank][offset] = data
+	return nil
+}
+
+// AccessPassive implements the mapper.CartMapper interface.
+func (cart *cty) AccessPassive(addr uint16, data uint8) error {
+	return nil
+}
+
+// Step implements the mapper.CartMapper interface.
+func (cart *cty) Step(clock float32) {
+	// clock is in MHz so the reciprocal is the number of microseconds in one
+	// CPU cycle
+	if cart.state.eepromBusy && cart.state.eepromTime > 0 {
+		cart.state.eepromTime -= 1 / clock
+	}
+
+	// clock the tune player's oscillators
+	cart.state.musicClocks += ctyMusicClock / (clock * 1000000)
+	for cart.state.musicClocks >= 1 {
+		cart.state.musicClocks--
+		for i := range cart.state.musicCounters {
+			cart.state.musicCounters[i] += cart.state.musicFrequencies[i]
+		}
+	}
+}
+
+// GetRAM implements the mapper.CartRAMBus interface.
+func (cart *cty) GetRAM() []mapper.CartRAM {
+	r := make([]mapper.CartRAM, 1)
+	r[0] = mapper.CartRAM{
+		Label:  "CTY RAM",
+		Origin: 0x1040,
+		Data:   make([]uint8, len(cart.state.ram)),
+		Mapped: true,
+	}
+	copy(r[0].Data, cart.state.ram)
+	return r
+}
+
+// PutRAM implements the mapper.CartRAMBus interface.
+func (cart *cty) PutRAM(_ int, idx int, data uint8) {
+	cart.state.ram[idx] = data
+}
+
+// CopyBanks implements the mapper.CartMapper interface.
+func (cart *cty) CopyBanks() []mapper.BankContent {
+	c := make([]mapper.BankContent, len(cart.banks))
+	for b := 0; b < len(cart.banks); b++ {
+		c[b] = mapper.BankContent{Number: b,
+			Data:    cart.banks[b],
+			Origins: []uint16{memorymap.OriginCart},
+		}
+	}
+	return c
+}
+
+// ReadHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *cty) ReadHotspots() map[uint16]mapper.CartHotspotInfo {
+	h := map[uint16]mapper.CartHotspotInfo{
+		0x1040: {Symbol: "RESULT", Action: mapper.HotspotRegister},
+		0x1041: {Symbol: "RANDOM", Action: mapper.HotspotRegister},
+		0x1042: {Symbol: "TUNELO", Action: mapper.HotspotRegister},
+		0x1043: {Symbol: "TUNEHI", Action: mapper.HotspotRegister},
+		0x1ff4: {Symbol: "EEPROM", Action: mapper.HotspotFunction},
+	}
+	for b := 1; b < len(cart.banks); b++ {
+		h[0x1ff4+uint16(b)] = mapper.CartHotspotInfo{
+			Symbol: fmt.Sprintf("BANK%d", b),
+			Action: mapper.HotspotBankSwitch,
+		}
+	}
+	return h
+}
+
+// WriteHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *cty) WriteHotspots() map[uint16]mapper.CartHotspotInfo {
+	h := map[uint16]mapper.CartHotspotInfo{
+		0x1000: {Symbol: "OPERATION", Action: mapper.HotspotRegister},
+		0x1001: {Symbol: "SEED", Action: mapper.HotspotFunction},
+		0x1002: {Symbol: "TUNERESET", Action: mapper.HotspotFunction},
+		0x1003: {Symbol: "TUNENEXT", Action: mapper.HotspotFunction},
+		0x1ff4: {Symbol: "EEPROM", Action: mapper.HotspotFunction},
+	}
+	for b := 1; b < len(cart.banks); b++ {
+		h[0x1ff4+uint16(b)] = mapper.CartHotspotInfo{
+			Symbol: fmt.Sprintf("BANK%d", b),
+			Action: mapper.HotspotBankSwitch,
+		}
+	}
+	return h
+}
+
+// rewindable state for the CTY cartridge.
+type ctyState struct {
+	// identifies the currently selected bank
+	bank int
+
+	// the first four bytes of the RAM are shadowed by registers. the first
+	// byte is the result of the last EEPROM operation
+	ram []uint8
+
+	// the value of the operation register
+	operation uint8
+
+	// state of the random number generator
+	random uint32
+
+	// the previous value read from the cartridge was the LDA immediate opcode
+	ldaImmediate bool
+
+	// an EEPROM operation has been started and has not yet been completed
+	eepromBusy bool
+
+	// the number of microseconds remaining before the EEPROM operation is
+	// complete
+	eepromTime float32
+
+	// the selected tune and the current position in the tune
+	tune         int
+	tunePosition uint16
+
+	// the tune player's channels. the frequency is added to the counter on
+	// every clock of the oscillator. the most significant bit of the counter
+	// is the output of the channel
+	musicCounters    [3]uint32
+	musicFrequencies [3]uint32
+
+	// the number of oscillator clocks that have not yet been applied to the
+	// channel counters
+	musicClocks float32
+}
+
+func newCTYState() *ctyState {
+	const ctyRAMsize = 64
+
+	return &ctyState{
+		ram: make([]uint8, ctyRAMsize),
+	}
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (s *ctyState) Snapshot() *ctyState {
+	n := *s
+	n.ram = make([]uint8, len(s.ram))
+	copy(n.ram, s.ram)
+	return &n
+}
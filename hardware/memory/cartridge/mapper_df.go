@@ -39,7 +39,10 @@ type df struct {
 }
 
 // example ROM: penult RPG.
-func newDF(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+//
+// DF cartridges always have RAM so the DFSC mapping is the same mapper with a
+// different ID.
+func newDF(env *environment.Environment, loader cartridgeloader.Loader, mappingID string) (mapper.CartMapper, error) {
 	data, err := io.ReadAll(loader)
 	if err != nil {
 		return nil, fmt.Errorf("DF: %w", err)
@@ -47,7 +50,7 @@ func newDF(env *environment.Environment, loader cartridgeloader.Loader) (mapper.
 
 	cart := &df{
 		env:       env,
-		mappingID: "DF",
+		mappingID: mappingID,
 		bankSize:  4096,
 		state:     newDfState(),
 	}
@@ -0,0 +1,422 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/logger"
+	"github.com/jetsetilly/gopher2600/resources"
+)
+
+// the directory in the resources path in which FA2 flash data is stored. each
+// cartridge has its own file named after the SHA1 hash of the cartridge data
+const fa2FlashPath = "fa2flash"
+
+// flash operations are selected by the value of the last byte of RAM
+const (
+	fa2FlashRead  = 1
+	fa2FlashWrite = 2
+)
+
+// the amount of time, in microseconds, that a flash operation takes. these
+// values are taken from Stella
+const (
+	fa2FlashReadTime  = 500
+	fa2FlashWriteTime = 101000
+)
+
+// FA2 is an extension of the CBS (FA) format supported by the Harmony
+// cartridge. There are six (24K) or seven (28K) banks of 4K, selected by
+// accessing 1FF5 to 1FFA (or 1FFB). Unlike the CBS format, the bankswitch
+// happens regardless of the state of the data bus.
+//
+// As with the CBS format there are 256 bytes of RAM. 1000-10FF is the write
+// port and 1100-11FF is the read port.
+//
+// The RAM can be loaded from and saved to the Harmony's flash memory by
+// accessing 1FF4. In the emulation the flash memory is a file in the resources
+// directory. See the flash() function for details.
+//
+// Cartridge data of 29K or 32K in size is the 28K format with the Harmony ARM
+// driver in the first 1K of the file. The driver is discarded.
+type fa2 struct {
+	env *environment.Environment
+
+	mappingID string
+
+	// the hash of the cartridge data. used to name the flash file
+	hash string
+
+	// fa2 cartridges have 6 or 7 banks of 4096 bytes
+	bankSize int
+	banks    [][]uint8
+
+	// rewindable state
+	state *fa2State
+}
+
+func newFA2(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+	data, err := io.ReadAll(loader)
+	if err != nil {
+		return nil, fmt.Errorf("FA2: %w", err)
+	}
+
+	cart := &fa2{
+		env:       env,
+		mappingID: "FA2",
+		hash:      loader.HashSHA1,
+		bankSize:  4096,
+		state:     newFA2State(),
+	}
+
+	switch len(data) {
+	case 24576:
+	case 28672:
+	case 29696, 32768:
+		// discard ARM driver
+		data = data[1024:29696]
+	default:
+		return nil, fmt.Errorf("FA2: wrong number of bytes in the cartridge data")
+	}
+
+	cart.banks = make([][]uint8, len(data)/cart.bankSize)
+
+	for k := 0; k < len(cart.banks); k++ {
+		cart.banks[k] = make([]uint8, cart.bankSize)
+		offset := k * cart.bankSize
+		copy(cart.banks[k], data[offset:offset+cart.bankSize])
+	}
+
+	return cart, nil
+}
+
+// MappedBanks implements the mapper.CartMapper interface.
+func (cart *fa2) MappedBanks() string {
+	return fmt.Sprintf("Bank: %d", cart.state.bank)
+}
+
+// ID implements the mapper.CartMapper interface.
+func (cart *fa2) ID() string {
+	return cart.mappingID
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (cart *fa2) Snapshot() mapper.CartMapper {
+	n := *cart
+	n.state = cart.state.Snapshot()
+	return &n
+}
+
+// Plumb implements the mapper.CartMapper interface.
+func (cart *fa2) Plumb(env *environment.Environment) {
+	cart.env = env
+}
+
+// Reset implements the mapper.CartMapper interface.
+func (cart *fa2) Reset() {
+	for i := range cart.state.ram {
+		if cart.env.Prefs.RandomState.Get().(bool) {
+			cart.state.ram[i] = uint8(cart.env.Random.NoRewind(0xff))
+		} else {
+			cart.state.ram[i] = 0
+		}
+	}
+
+	cart.state.bank = 0
+	cart.state.flashBusy = false
+	cart.state.flashTime = 0
+}
+
+// Access implements the mapper.CartMapper interface.
+func (cart *fa2) Access(addr uint16, peek bool) (uint8, uint8, error) {
+	if addr <= 0x00ff {
+		return 0, 0, nil
+	}
+	if addr >= 0x0100 && addr <= 0x01ff {
+		return cart.state.ram[addr-0x100], mapper.CartDrivenPins, nil
+	}
+
+	if addr == 0x0ff4 {
+		// the flash operation is started or advanced by AccessVolatile(),
+		// which is called for every access of the hotspot. bit 6 of the
+		// hotspot address indicates whether the flash operation is still in
+		// progress
+		data := cart.banks[cart.state.bank][addr]
+		if cart.state.flashBusy {
+			return data | 0x40, mapper.CartDrivenPins, nil
+		}
+		return data &^ 0x40, mapper.CartDrivenPins, nil
+	}
+
+	data := cart.banks[cart.state.bank][addr]
+
+	if !peek {
+		cart.bankswitch(addr)
+	}
+
+	return data, mapper.CartDrivenPins, nil
+}
+
+// AccessVolatile implements the mapper.CartMapper interface.
+func (cart *fa2) AccessVolatile(addr uint16, data uint8, poke bool) error {
+	if addr <= 0x00ff {
+		cart.state.ram[addr] = data
+		return nil
+	}
+
+	if poke {
+		// poking the read port changes the RAM that is read from that address
+		if addr <= 0x01ff {
+			cart.state.ram[addr-0x100] = data
+		} else {
+			cart.banks[cart.state.bank][addr] = data
+		}
+		return nil
+	}
+
+	// the memory bus calls AccessVolatile() before Access() for a read of a
+	// cartridge address. the flash operation is only handled here so that a
+	// single access of the hotspot calls flash() once
+	if addr == 0x0ff4 {
+		cart.flash()
+		return nil
+	}
+
+	cart.bankswitch(addr)
+
+	return nil
+}
+
+// bankswitch on hotspot access. unlike the CBS format, the state of the data
+// bus is not considered.
+func (cart *fa2) bankswitch(addr uint16) bool {
+	if addr >= 0x0ff5 && int(addr-0x0ff5) < len(cart.banks) {
+		cart.state.bank = int(addr - 0x0ff5)
+		return true
+	}
+	return false
+}
+
+// flash is called on every access of the flash hotspot. the protocol is:
+//
+//  1. the program sets the last byte of RAM to 1 (read) or 2 (write)
+//  2. the first access of the hotspot starts the operation. all 256 bytes of
+//     RAM are read from or written to flash
+//  3. bit 6 of the value read from the hotspot is set while the operation is
+//     busy. the program should poll the hotspot until bit 6 is clear
+//  4. the last byte of RAM is set to zero to indicate that the operation has
+//     completed
+//
+// the operation itself happens immediately. the delay exists only to mimic the
+// time taken by the Harmony cartridge to access flash memory.
+func (cart *fa2) flash() {
+	if cart.state.flashBusy {
+		if cart.state.flashTime <= 0 {
+			cart.state.flashBusy = false
+			cart.state.ram[len(cart.state.ram)-1] = 0
+		}
+		return
+	}
+
+	cart.state.flashBusy = true
+
+	switch cart.state.ram[len(cart.state.ram)-1] {
+	case fa2FlashRead:
+		cart.readFlash()
+		cart.state.flashTime = fa2FlashReadTime
+	case fa2FlashWrite:
+		cart.writeFlash()
+		cart.state.flashTime = fa2FlashWriteTime
+	default:
+		cart.state.flashTime = 0
+	}
+}
+
+// readFlash loads the flash file into RAM. if there is no flash file then RAM
+// is cleared.
+func (cart *fa2) readFlash() {
+	clear(cart.state.ram)
+
+	fn, err := resources.JoinPath(fa2FlashPath, cart.hash)
+	if err != nil {
+		logger.Logf(cart.env, "FA2", "could not load flash file (%s)", err)
+		return
+	}
+
+	d, err := os.ReadFile(fn)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Logf(cart.env, "FA2", "could not load flash file (%s)", err)
+		}
+		return
+	}
+
+	if len(d) != len(cart.state.ram) {
+		logger.Logf(cart.env, "FA2", "flash file is of incorrect length. %d should be %d", len(d), len(cart.state.ram))
+	}
+
+	copy(cart.state.ram, d)
+	logger.Logf(cart.env, "FA2", "flash file loaded from %s", fn)
+}
+
+// writeFlash saves RAM to the flash file. only the main emulation is allowed to
+// write to the flash file.
+func (cart *fa2) writeFlash() {
+	if !cart.env.IsEmulation(environment.MainEmulation) {
+		return
+	}
+
+	fn, err := resources.JoinPath(fa2FlashPath, cart.hash)
+	if err != nil {
+		logger.Logf(cart.env, "FA2", "could not write flash file (%s)", err)
+		return
+	}
+
+	err = os.WriteFile(fn, cart.state.ram, 0600)
+	if err != nil {
+		logger.Logf(cart.env, "FA2", "could not write flash file (%s)", err)
+		return
+	}
+
+	logger.Logf(cart.env, "FA2", "flash file saved to %s", fn)
+}
+
+// NumBanks implements the mapper.CartMapper interface.
+func (cart *fa2) NumBanks() int {
+	return len(cart.banks)
+}
+
+// GetBank implements the mapper.CartMapper interface.
+func (cart *fa2) GetBank(addr uint16) mapper.BankInfo {
+	// fa2 cartridges are like atari cartridges in that the entire address
+	// space points to the selected bank
+	return mapper.BankInfo{Number: cart.state.bank, IsRAM: addr <= 0x00ff}
+}
+
+// Patch implements the mapper.CartPatchable interface
+func (cart *fa2) Patch(offset int, data uint8) error {
+	if offset >= cart.bankSize*len(cart.banks) {
+		return fmt.Errorf("FA2: patch offset too high (%d)", offset)
+	}
+
+	bank := offset / cart.bankSize
+	offset %= cart.bankSize
+	cart.banks[bank][offset] = data
+	return nil
+}
+
+// AccessPassive implements the mapper.CartMapper interface.
+func (cart *fa2) AccessPassive(addr uint16, data uint8) error {
+	return nil
+}
+
+// Step implements the mapper.CartMapper interface.
+func (cart *fa2) Step(clock float32) {
+	// clock is in MHz so the reciprocal is the number of microseconds in one
+	// CPU cycle
+	if cart.state.flashBusy && cart.state.flashTime > 0 {
+		cart.state.flashTime -= 1 / clock
+	}
+}
+
+// GetRAM implements the mapper.CartRAMBus interface.
+func (cart *fa2) GetRAM() []mapper.CartRAM {
+	r := make([]mapper.CartRAM, 1)
+	r[0] = mapper.CartRAM{
+		Label:  "FA2 RAM",
+		Origin: 0x1100,
+		Data:   make([]uint8, len(cart.state.ram)),
+		Mapped: true,
+	}
+	copy(r[0].Data, cart.state.ram)
+	return r
+}
+
+// PutRAM implements the mapper.CartRAMBus interface.
+func (cart *fa2) PutRAM(_ int, idx int, data uint8) {
+	cart.state.ram[idx] = data
+}
+
+// IterateBank implements the mapper.CartMapper interface.
+func (cart *fa2) CopyBanks() []mapper.BankContent {
+	c := make([]mapper.BankContent, len(cart.banks))
+	for b := 0; b < len(cart.banks); b++ {
+		c[b] = mapper.BankContent{Number: b,
+			Data:    cart.banks[b],
+			Origins: []uint16{memorymap.OriginCart},
+		}
+	}
+	return c
+}
+
+// ReadHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *fa2) ReadHotspots() map[uint16]mapper.CartHotspotInfo {
+	h := map[uint16]mapper.CartHotspotInfo{
+		0x1ff4: {Symbol: "FLASH", Action: mapper.HotspotFunction},
+	}
+	for b := range cart.banks {
+		h[0x1ff5+uint16(b)] = mapper.CartHotspotInfo{
+			Symbol: fmt.Sprintf("BANK%d", b),
+			Action: mapper.HotspotBankSwitch,
+		}
+	}
+	return h
+}
+
+// WriteHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *fa2) WriteHotspots() map[uint16]mapper.CartHotspotInfo {
+	return cart.ReadHotspots()
+}
+
+// rewindable state for the FA2 cartridge.
+type fa2State struct {
+	// identifies the currently selected bank
+	bank int
+
+	// same as the RAM in the CBS cartridge. the last byte is also used to
+	// select the flash operation
+	ram []uint8
+
+	// a flash operation has been started and has not yet been completed
+	flashBusy bool
+
+	// the number of microseconds remaining before the flash operation is
+	// complete
+	flashTime float32
+}
+
+func newFA2State() *fa2State {
+	const fa2RAMsize = 256
+
+	return &fa2State{
+		ram: make([]uint8, fa2RAMsize),
+	}
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (s *fa2State) Snapshot() *fa2State {
+	n := *s
+	n.ram = make([]uint8, len(s.ram))
+	copy(n.ram, s.ram)
+	return &n
+}
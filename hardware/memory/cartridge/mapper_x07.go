@@ -0,0 +1,135 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package cartridge
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+)
+
+// the X07 mapper was designed by Fred Quimby for AtariAge multicarts (eg.
+// Stella's Stocking). it is a 64k cartridge with sixteen 4k banks.
+//
+// the hotspots are outside of the cartridge address space. an access to an
+// address matching 080D selects the bank indicated by bits 4 to 7 of the
+// address. ie. 080D selects bank 0, 081D selects bank 1 and so on.
+//
+// in addition, when bank 14 or 15 is selected, an access to any address with
+// A12, A11 and A7 low (ie. the TIA) will select bank 14 or 15 depending on the
+// state of A6.
+type x07 struct {
+	atari
+}
+
+// newX07 is the preferred method of initialisation for the x07 type
+func newX07(env *environment.Environment, loader cartridgeloader.Loader) (mapper.CartMapper, error) {
+	data, err := io.ReadAll(loader)
+	if err != nil {
+		return nil, fmt.Errorf("X07: %w", err)
+	}
+
+	cart := &x07{
+		atari: atari{
+			env:       env,
+			bankSize:  4096,
+			mappingID: "X07",
+			state:     newAtariState(),
+		},
+	}
+
+	if len(data) != cart.bankSize*cart.NumBanks() {
+		return nil, fmt.Errorf("X07: wrong number of bytes in the cartridge data")
+	}
+
+	cart.banks = make([][]uint8, cart.NumBanks())
+	for k := 0; k < cart.NumBanks(); k++ {
+		cart.banks[k] = make([]uint8, cart.bankSize)
+		offset := k * cart.bankSize
+		copy(cart.banks[k], data[offset:offset+cart.bankSize])
+	}
+
+	return cart, nil
+}
+
+// Snapshot implements the mapper.CartMapper interface.
+func (cart *x07) Snapshot() mapper.CartMapper {
+	n := *cart
+	n.state = cart.state.Snapshot()
+	return &n
+}
+
+// Plumb implements the mapper.CartMapper interface.
+func (cart *x07) Plumb(env *environment.Environment) {
+	cart.env = env
+}
+
+// Access implements the mapper.CartMapper interface.
+func (cart *x07) Access(addr uint16, _ bool) (uint8, uint8, error) {
+	if data, mask, ok := cart.atari.access(addr); ok {
+		return data, mask, nil
+	}
+	return cart.banks[cart.state.bank][addr], mapper.CartDrivenPins, nil
+}
+
+// AccessVolatile implements the mapper.CartMapper interface.
+func (cart *x07) AccessVolatile(addr uint16, data uint8, poke bool) error {
+	return cart.accessVolatile(addr, data, poke)
+}
+
+// AccessPassive implements the mapper.CartMapper interface.
+func (cart *x07) AccessPassive(addr uint16, _ uint8) error {
+	if addr&0x180f == 0x080d {
+		cart.state.bank = int((addr & 0x00f0) >> 4)
+	} else if addr&0x1880 == 0x0000 {
+		if cart.state.bank&0x0e == 0x0e {
+			cart.state.bank = int((addr&0x0040)>>6) | 0x0e
+		}
+	}
+	return nil
+}
+
+// Reset implements the mapper.CartMapper interface.
+func (cart *x07) Reset() {
+	cart.reset(cart.NumBanks())
+}
+
+// NumBanks implements the mapper.CartMapper interface.
+func (cart *x07) NumBanks() int {
+	return 16
+}
+
+// ReadHotspots implements the mapper.CartHotspotsBus interface.
+//
+// The switching between banks 14 and 15 with TIA addresses is not reported.
+func (cart *x07) ReadHotspots() map[uint16]mapper.CartHotspotInfo {
+	h := make(map[uint16]mapper.CartHotspotInfo)
+	for b := 0; b < cart.NumBanks(); b++ {
+		h[0x080d|uint16(b<<4)] = mapper.CartHotspotInfo{
+			Symbol: fmt.Sprintf("BANK%d", b),
+			Action: mapper.HotspotBankSwitch,
+		}
+	}
+	return h
+}
+
+// WriteHotspots implements the mapper.CartHotspotsBus interface.
+func (cart *x07) WriteHotspots() map[uint16]mapper.CartHotspotInfo {
+	return cart.ReadHotspots()
+}
@@ -0,0 +1,25 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package headless prepares and runs an emulation without a GUI. It is used by
+// the packages that run a cartridge from the command line to produce a
+// report or an image file, rather than for interactive use.
+//
+// The cartridge can be driven by a playback file created by the recorder
+// package. In that case the cartridge, the cartridge mapping and the
+// television specification are all taken from the playback file. Without a
+// playback file the emulation is normalised and the cartridge is attached
+// using the setup package.
+package headless
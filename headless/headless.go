@@ -0,0 +1,201 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package headless
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/recorder"
+	"github.com/jetsetilly/gopher2600/setup"
+)
+
+// Options for the NewEmulation() function.
+type Options struct {
+	// cartridge mapping and television specification. both fields are
+	// ignored if a playback file is being used
+	Mapping string
+	Spec    string
+
+	// the name of a playback file to drive the input of the emulation
+	Playback string
+
+	// whether the emulation should be limited to the refresh rate of the
+	// television. most headless emulations should run as quickly as possible
+	FPSCap bool
+
+	// called after the VCS has been created but before the cartridge is
+	// attached. this is the last opportunity to change the preferences that
+	// affect how the VCS is reset
+	Prepare func(vcs *hardware.VCS) error
+}
+
+// Emulation is a VCS with a cartridge attached and ready to run.
+type Emulation struct {
+	TV  *television.Television
+	VCS *hardware.VCS
+
+	// the cartridge attached to the VCS
+	Cartridge cartridgeloader.Loader
+
+	// the playback driving the emulation. will be nil if no playback file
+	// was specified
+	Playback *recorder.Playback
+}
+
+// NewEmulation creates a new emulation for the named cartridge. The filename
+// argument can be empty if a playback file is specified in the options. In
+// that case the cartridge named in the playback file is used.
+//
+// The End() function should be called when the emulation is no longer
+// required.
+func NewEmulation(filename string, opts Options) (*Emulation, error) {
+	em := &Emulation{}
+
+	spec := opts.Spec
+	mapping := opts.Mapping
+
+	if opts.Playback != "" {
+		var err error
+		em.Playback, err = recorder.NewPlayback(opts.Playback)
+		if err != nil {
+			return nil, err
+		}
+
+		// take cartridge information from the playback file
+		spec = em.Playback.TVSpec
+		mapping = "AUTO"
+		if filename == "" {
+			filename = em.Playback.Cartridge
+		}
+	}
+
+	if filename == "" {
+		return nil, fmt.Errorf("2600 cartridge required")
+	}
+
+	var err error
+
+	em.TV, err = television.NewTelevision(spec)
+	if err != nil {
+		return nil, err
+	}
+	em.TV.SetFPSCap(opts.FPSCap)
+
+	em.VCS, err = hardware.NewVCS(environment.MainEmulation, em.TV, nil, nil)
+	if err != nil {
+		em.TV.End()
+		return nil, err
+	}
+
+	if em.Playback != nil {
+		// attaching playback to the VCS will normalise the VCS
+		err = em.Playback.AttachToVCSInput(em.VCS)
+		if err != nil {
+			em.TV.End()
+			return nil, err
+		}
+	} else {
+		// we want the machine in a known state. the easiest way to do this is
+		// to default the hardware preferences
+		em.VCS.Env.Normalise()
+	}
+
+	if opts.Prepare != nil {
+		err = opts.Prepare(em.VCS)
+		if err != nil {
+			em.TV.End()
+			return nil, err
+		}
+	}
+
+	em.Cartridge, err = cartridgeloader.NewLoaderFromFilename(filename, mapping, nil)
+	if err != nil {
+		em.TV.End()
+		return nil, err
+	}
+
+	if em.Playback != nil {
+		if em.Cartridge.HashSHA1 != em.Playback.Hash {
+			em.End()
+			return nil, fmt.Errorf("playback: unexpected hash")
+		}
+
+		// not using setup.AttachCartridge. see the comment in the regression
+		// package for the reason why
+		err = em.VCS.AttachCartridge(em.Cartridge, true)
+	} else {
+		err = setup.AttachCartridge(em.VCS, em.Cartridge, true)
+	}
+	if err != nil {
+		em.End()
+		return nil, err
+	}
+
+	return em, nil
+}
+
+// End the emulation and release any resources.
+func (em *Emulation) End() {
+	em.TV.End()
+	_ = em.Cartridge.Close()
+}
+
+// Run the emulation until the continueCheck function returns a state other
+// than govern.Running. The continueCheck function is called after every CPU
+// instruction. The emulation being powered off is not treated as an error.
+func (em *Emulation) Run(continueCheck func() (govern.State, error)) error {
+	err := em.VCS.Run(continueCheck)
+	if err != nil && !errors.Is(err, ports.PowerOff) {
+		return err
+	}
+	return nil
+}
+
+// Continue returns govern.Ending if the emulation has reached the number of
+// frames, if the CPU is in the KIL state, or if the playback file has ended.
+// Otherwise govern.Running is returned. If numFrames is zero then there is no
+// limit to the number of frames.
+//
+// The function is intended to be called from the continueCheck function
+// passed to Run().
+func (em *Emulation) Continue(numFrames int) (govern.State, error) {
+	if em.VCS.CPU.Killed {
+		return govern.Ending, nil
+	}
+
+	if numFrames > 0 && em.TV.GetCoords().Frame >= numFrames {
+		return govern.Ending, nil
+	}
+
+	if em.Playback != nil {
+		hasEnded, err := em.Playback.EndFrame()
+		if err != nil {
+			return govern.Ending, fmt.Errorf("playback: %w", err)
+		}
+		if hasEnded {
+			return govern.Ending, nil
+		}
+	}
+
+	return govern.Running, nil
+}
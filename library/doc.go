@@ -0,0 +1,38 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package library is a persistent database of the cartridges on the local
+// disk. Cartridges are added to the library with the Scan() function, which
+// searches directories (and archives, using the archivefs package) for files
+// with a recognised cartridge file extension.
+//
+// Each cartridge is identified by the MD5 hash of its data. The name and
+// manufacturer of the cartridge are found in the properties file (see the
+// properties package) if possible. The mapper and controllers that are
+// detected when the cartridge is attached to the emulation are also
+// recorded.
+//
+// Thumbnails can optionally be created during a scan. Thumbnails are created
+// by the thumbnailer package and are stored as PNG files in the resources
+// directory, alongside the library file.
+//
+// The number of times a cartridge has been played and the time it was last
+// played are updated with the Played() function. The emulation should call
+// this function whenever a cartridge is attached for playing.
+//
+// Entries can be selected from the library with the Select() function, using
+// a Filter to search by name and manufacturer, and to filter by mapper and
+// controller type.
+package library
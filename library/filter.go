@@ -0,0 +1,121 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package library
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+)
+
+// Filter is used to select entries from the library with the Select()
+// function. Fields with the zero value are ignored.
+type Filter struct {
+	// every word in the search string must be found in either the name, the
+	// manufacturer or the filename of the entry. the comparison is case
+	// insensitive
+	Search string
+
+	// the manufacturer must contain the string. the comparison is case
+	// insensitive
+	Manufacturer string
+
+	// the mapping must be the same. the comparison is case insensitive
+	Mapping string
+
+	// the controller must be plugged into one of the ports
+	Controller plugging.PeripheralID
+}
+
+func (f Filter) match(e Entry) bool {
+	if f.Manufacturer != "" && !strings.Contains(strings.ToLower(e.Manufacturer), strings.ToLower(f.Manufacturer)) {
+		return false
+	}
+
+	if f.Mapping != "" && !strings.EqualFold(e.Mapping, f.Mapping) {
+		return false
+	}
+
+	if f.Controller != "" && e.LeftPlayer != f.Controller && e.RightPlayer != f.Controller {
+		return false
+	}
+
+	if f.Search != "" {
+		s := strings.ToLower(strings.Join([]string{e.Name, e.Manufacturer, e.Filename}, " "))
+		for _, w := range strings.Fields(strings.ToLower(f.Search)) {
+			if !strings.Contains(s, w) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Select returns the entries in the library that match the filter. Entries
+// are sorted by name.
+func (lib *Library) Select(f Filter) []Entry {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+	return lib.sorted(f.match)
+}
+
+// Manufacturers returns a sorted list of every manufacturer in the library.
+// Useful for presenting the filtering options to the user.
+func (lib *Library) Manufacturers() []string {
+	return lib.values(func(e Entry) []string {
+		return []string{e.Manufacturer}
+	})
+}
+
+// Mappings returns a sorted list of every mapping in the library. Useful for
+// presenting the filtering options to the user.
+func (lib *Library) Mappings() []string {
+	return lib.values(func(e Entry) []string {
+		return []string{e.Mapping}
+	})
+}
+
+// Controllers returns a sorted list of every controller in the library.
+// Useful for presenting the filtering options to the user.
+func (lib *Library) Controllers() []plugging.PeripheralID {
+	var l []plugging.PeripheralID
+	for _, v := range lib.values(func(e Entry) []string {
+		return []string{string(e.LeftPlayer), string(e.RightPlayer)}
+	}) {
+		l = append(l, plugging.PeripheralID(v))
+	}
+	return l
+}
+
+// return the unique non-empty values of a field in sorted order
+func (lib *Library) values(field func(Entry) []string) []string {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+
+	var l []string
+	for _, e := range lib.entries {
+		for _, v := range field(e) {
+			if v != "" {
+				l = append(l, v)
+			}
+		}
+	}
+
+	slices.Sort(l)
+	return slices.Compact(l)
+}
@@ -0,0 +1,363 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package library
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jetsetilly/gopher2600/archivefs"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/resources"
+)
+
+// the directory in the resources folder in which the library is stored
+const libraryPath = "library"
+
+// the name of the library file in the library directory
+const libraryFile = "roms"
+
+// the directory in the library directory in which thumbnails are stored
+const thumbnailPath = "thumbnails"
+
+// the field separator used in the library file
+const fieldSep = "\t"
+
+// Entry is a single cartridge in the library.
+type Entry struct {
+	// the MD5 hash of the cartridge data. this is the same hash used to find
+	// the entry in the properties file
+	Hash string
+
+	// the filename of the cartridge. the filename may be inside an archive
+	Filename string
+
+	// the name of the cartridge. the name is taken from the properties file if
+	// possible and from the filename otherwise
+	Name string
+
+	// information from the properties file. the fields will be empty if the
+	// cartridge is not in the properties file
+	Manufacturer string
+	Rarity       string
+	Model        string
+
+	// the mapper and the controllers detected when the cartridge was scanned
+	Mapping     string
+	LeftPlayer  plugging.PeripheralID
+	RightPlayer plugging.PeripheralID
+
+	// the number of times the cartridge has been played and the time it was
+	// last played. LastPlayed is the zero time if the cartridge has never
+	// been played
+	PlayCount  int
+	LastPlayed time.Time
+}
+
+func (e Entry) String() string {
+	if e.Manufacturer == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s (%s)", e.Name, e.Manufacturer)
+}
+
+// ThumbnailFilename returns the filename of the thumbnail for the cartridge
+// with the specified hash. The path will be in the resources directory.
+func ThumbnailFilename(hash string) (string, error) {
+	pth, err := resources.JoinPath(libraryPath, thumbnailPath, fmt.Sprintf("%s.png", hash))
+	if err != nil {
+		return "", fmt.Errorf("library: %w", err)
+	}
+	return pth, nil
+}
+
+// Thumbnail returns the thumbnail image for the entry. The returned error
+// will wrap os.ErrNotExist if no thumbnail has been created for the entry.
+func (e Entry) Thumbnail() (image.Image, error) {
+	pth, err := ThumbnailFilename(e.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(pth)
+	if err != nil {
+		return nil, fmt.Errorf("library: %w", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("library: thumbnail: %w", err)
+	}
+
+	return img, nil
+}
+
+// Library is a collection of cartridges, keyed by hash.
+//
+// The Library type is safe to access in goroutines.
+type Library struct {
+	crit     sync.Mutex
+	filename string
+	entries  map[string]Entry
+}
+
+// Load the library from the resources directory. It is not an error for the
+// library file to not exist. In that case the library is empty.
+func Load() (*Library, error) {
+	pth, err := resources.JoinPath(libraryPath, libraryFile)
+	if err != nil {
+		return nil, fmt.Errorf("library: %w", err)
+	}
+	return load(pth)
+}
+
+// Exists returns true if the library file exists in the resources directory.
+// Unlike Load(), the library directory will not be created if it does not
+// exist.
+func Exists() bool {
+	pth, err := resources.JoinPath(libraryPath)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(pth, libraryFile))
+	return err == nil
+}
+
+// load the library from the named file
+func load(filename string) (*Library, error) {
+	lib := &Library{
+		filename: filename,
+		entries:  make(map[string]Entry),
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lib, nil
+		}
+		return nil, fmt.Errorf("library: %w", err)
+	}
+	defer f.Close()
+
+	l, err := read(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range l {
+		lib.entries[e.Hash] = e
+	}
+
+	return lib, nil
+}
+
+// the number of fields in each line of the library file
+const numFields = 11
+
+// read entries from the reader. each line is one entry with the fields
+// separated by a tab character. lines beginning with # are comments
+func read(r io.Reader) ([]Entry, error) {
+	var l []Entry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		ln := scanner.Text()
+		if strings.TrimSpace(ln) == "" || strings.HasPrefix(ln, "#") {
+			continue // for loop
+		}
+
+		p := strings.Split(ln, fieldSep)
+		if len(p) != numFields {
+			return nil, fmt.Errorf("library: malformed line (%s)", ln)
+		}
+
+		playCount, err := strconv.Atoi(p[9])
+		if err != nil {
+			return nil, fmt.Errorf("library: malformed play count (%s)", p[9])
+		}
+
+		lastPlayed, err := strconv.ParseInt(p[10], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("library: malformed last played time (%s)", p[10])
+		}
+
+		e := Entry{
+			Hash:         p[0],
+			Filename:     p[1],
+			Name:         p[2],
+			Manufacturer: p[3],
+			Rarity:       p[4],
+			Model:        p[5],
+			Mapping:      p[6],
+			LeftPlayer:   plugging.PeripheralID(p[7]),
+			RightPlayer:  plugging.PeripheralID(p[8]),
+			PlayCount:    playCount,
+		}
+		if lastPlayed != 0 {
+			e.LastPlayed = time.Unix(lastPlayed, 0)
+		}
+		l = append(l, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("library: %w", err)
+	}
+
+	return l, nil
+}
+
+// the field separator and line endings cannot appear in a field
+var fieldReplacer = strings.NewReplacer(fieldSep, " ", "\n", " ", "\r", " ")
+
+// write entries to the writer in the format expected by read()
+func write(w io.Writer, l []Entry) error {
+	var s strings.Builder
+	s.WriteString("# gopher2600 rom library\n")
+	for _, e := range l {
+		var lastPlayed int64
+		if !e.LastPlayed.IsZero() {
+			lastPlayed = e.LastPlayed.Unix()
+		}
+
+		f := []string{
+			e.Hash, e.Filename, e.Name, e.Manufacturer, e.Rarity, e.Model,
+			e.Mapping, string(e.LeftPlayer), string(e.RightPlayer),
+			strconv.Itoa(e.PlayCount), strconv.FormatInt(lastPlayed, 10),
+		}
+		for i := range f {
+			f[i] = fieldReplacer.Replace(f[i])
+		}
+
+		s.WriteString(strings.Join(f, fieldSep))
+		s.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, s.String())
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+	return nil
+}
+
+// save the library to the library file. the critical section must be held
+// by the caller
+func (lib *Library) save() error {
+	f, err := os.Create(lib.filename)
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+	defer f.Close()
+
+	return write(f, lib.sorted(nil))
+}
+
+// Save the library to the library file in the resources directory.
+func (lib *Library) Save() error {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+	return lib.save()
+}
+
+// Len returns the number of entries in the library.
+func (lib *Library) Len() int {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+	return len(lib.entries)
+}
+
+// Lookup the entry for the cartridge with the specified hash.
+func (lib *Library) Lookup(hash string) (Entry, bool) {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+	e, ok := lib.entries[hash]
+	return e, ok
+}
+
+// Played should be called when the cartridge with the specified hash is
+// played. The play count and last played time are updated and the library is
+// saved. Cartridges that are not in the library are ignored.
+func (lib *Library) Played(hash string) error {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+
+	e, ok := lib.entries[hash]
+	if !ok {
+		return nil
+	}
+
+	e.PlayCount++
+	e.LastPlayed = time.Now()
+	lib.entries[hash] = e
+
+	return lib.save()
+}
+
+// Clean removes entries for files that no longer exist and saves the library.
+// Returns the number of entries that were removed.
+func (lib *Library) Clean() (int, error) {
+	lib.crit.Lock()
+	defer lib.crit.Unlock()
+
+	var n int
+	for h, e := range lib.entries {
+		var afs archivefs.Path
+		err := afs.Set(e.Filename, false)
+		afs.Close()
+		if err != nil {
+			delete(lib.entries, h)
+			n++
+		}
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	return n, lib.save()
+}
+
+// return the entries that match the filter sorted by name. the critical
+// section must be held by the caller
+func (lib *Library) sorted(match func(Entry) bool) []Entry {
+	l := make([]Entry, 0, len(lib.entries))
+	for _, e := range lib.entries {
+		if match == nil || match(e) {
+			l = append(l, e)
+		}
+	}
+
+	sort.Slice(l, func(i, j int) bool {
+		ni := strings.ToLower(l[i].Name)
+		nj := strings.ToLower(l[j].Name)
+		if ni == nj {
+			return l[i].Filename < l[j].Filename
+		}
+		return ni < nj
+	})
+
+	return l
+}
@@ -0,0 +1,202 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package library
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/properties"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func TestReadWrite(t *testing.T) {
+	l := []Entry{
+		{
+			Hash: "0123456789abcdef0123456789abcdef", Filename: "/roms/combat.bin",
+			Name: "Combat", Manufacturer: "Atari", Rarity: "Common", Model: "CX2601",
+			Mapping: "2k", LeftPlayer: plugging.PeriphStick, RightPlayer: plugging.PeriphStick,
+			PlayCount: 3, LastPlayed: time.Unix(1700000000, 0),
+		},
+		{
+			Hash: "fedcba9876543210fedcba9876543210", Filename: "/roms/tab\tname.bin",
+			Name: "tab name", Mapping: "F8",
+			LeftPlayer: plugging.PeriphPaddles, RightPlayer: plugging.PeriphPaddles,
+		},
+	}
+
+	var s strings.Builder
+	test.ExpectSuccess(t, write(&s, l))
+
+	r, err := read(strings.NewReader(s.String()))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(r), 2)
+	test.ExpectEquality(t, r[0], l[0])
+	test.ExpectEquality(t, r[1].Filename, "/roms/tab name.bin")
+	test.ExpectEquality(t, r[1].LastPlayed.IsZero(), true)
+	test.ExpectEquality(t, r[1].RightPlayer, plugging.PeriphPaddles)
+
+	_, err = read(strings.NewReader("foo\tbar\n"))
+	test.ExpectFailure(t, err)
+}
+
+func TestSelect(t *testing.T) {
+	lib := &Library{entries: make(map[string]Entry)}
+	for _, e := range []Entry{
+		{Hash: "1", Name: "Pitfall!", Manufacturer: "Activision", Mapping: "4K", LeftPlayer: plugging.PeriphStick},
+		{Hash: "2", Name: "Kaboom!", Manufacturer: "Activision", Mapping: "2K", LeftPlayer: plugging.PeriphPaddles},
+		{Hash: "3", Name: "Combat", Manufacturer: "Atari", Mapping: "2K", LeftPlayer: plugging.PeriphStick},
+		{Hash: "4", Name: "combat", Filename: "/homebrew/combat_hack.bin", Mapping: "2K"},
+	} {
+		lib.entries[e.Hash] = e
+	}
+
+	names := func(l []Entry) string {
+		var s []string
+		for _, e := range l {
+			s = append(s, e.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	test.ExpectEquality(t, names(lib.Select(Filter{})), "Combat,combat,Kaboom!,Pitfall!")
+	test.ExpectEquality(t, names(lib.Select(Filter{Search: "activision"})), "Kaboom!,Pitfall!")
+	test.ExpectEquality(t, names(lib.Select(Filter{Search: "COMBAT hack"})), "combat")
+	test.ExpectEquality(t, names(lib.Select(Filter{Manufacturer: "atari"})), "Combat")
+	test.ExpectEquality(t, names(lib.Select(Filter{Mapping: "2k"})), "Combat,combat,Kaboom!")
+	test.ExpectEquality(t, names(lib.Select(Filter{Controller: plugging.PeriphPaddles})), "Kaboom!")
+	test.ExpectEquality(t, names(lib.Select(Filter{Manufacturer: "activision", Mapping: "4K"})), "Pitfall!")
+
+	test.ExpectEquality(t, strings.Join(lib.Manufacturers(), ","), "Activision,Atari")
+	test.ExpectEquality(t, strings.Join(lib.Mappings(), ","), "2K,4K")
+	test.ExpectEquality(t, len(lib.Controllers()), 2)
+}
+
+// a 4k cartridge that does nothing but loop
+func testROM() []byte {
+	d := make([]byte, 4096)
+	d[0] = 0x4c // JMP $f000
+	d[1] = 0x00
+	d[2] = 0xf0
+	d[0xffc] = 0x00
+	d[0xffd] = 0xf0
+	return d
+}
+
+type mockProperties map[string]properties.Entry
+
+func (p mockProperties) Lookup(md5Hash string) properties.Entry {
+	return p[md5Hash]
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+
+	// a cartridge file in the directory and a copy of the same cartridge,
+	// with a different name, inside a zip file in a sub-directory
+	test.ExpectSuccess(t, os.WriteFile(filepath.Join(dir, "loop.bin"), testROM(), 0600))
+	test.ExpectSuccess(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a rom"), 0600))
+	test.ExpectSuccess(t, os.Mkdir(filepath.Join(dir, "sub"), 0700))
+
+	f, err := os.Create(filepath.Join(dir, "sub", "roms.zip"))
+	test.ExpectSuccess(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("inner.bin")
+	test.ExpectSuccess(t, err)
+	d := testROM()
+	d[3] = 0xff
+	_, err = w.Write(d)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, zw.Close())
+	test.ExpectSuccess(t, f.Close())
+
+	lib, err := load(filepath.Join(dir, "library"))
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, lib.Len(), 0)
+
+	hash := fmt.Sprintf("%x", md5.Sum(testROM()))
+	props := mockProperties{
+		hash: properties.Entry{Hash: hash, Name: "Loop", Manufacturer: "Gopher"},
+	}
+
+	res, err := lib.Scan(io.Discard, dir, ScanOptions{Properties: props})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, res.Found, 2)
+	test.ExpectEquality(t, res.Added, 2)
+	test.ExpectEquality(t, res.Failed, 0)
+
+	l := lib.Select(Filter{})
+	test.ExpectEquality(t, len(l), 2)
+	test.ExpectEquality(t, l[0].Name, "inner")
+	test.ExpectEquality(t, l[0].Filename, filepath.Join(dir, "sub", "roms.zip", "inner.bin"))
+	test.ExpectEquality(t, l[0].Mapping, "4k")
+	test.ExpectEquality(t, l[0].LeftPlayer, plugging.PeriphStick)
+
+	// name and manufacturer from the properties
+	test.ExpectEquality(t, l[1].Hash, hash)
+	test.ExpectEquality(t, l[1].String(), "Loop (Gopher)")
+
+	// play count is preserved when the library is scanned again
+	test.ExpectSuccess(t, lib.Played(l[0].Hash))
+	res, err = lib.Scan(io.Discard, dir, ScanOptions{Properties: props})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, res.Added, 0)
+	test.ExpectEquality(t, res.Updated, 2)
+
+	// the library has been saved and can be loaded again
+	lib, err = load(filepath.Join(dir, "library"))
+	test.ExpectSuccess(t, err)
+	e, ok := lib.Lookup(l[0].Hash)
+	test.ExpectSuccess(t, ok)
+	test.ExpectEquality(t, e.PlayCount, 1)
+	test.ExpectSuccess(t, !e.LastPlayed.IsZero())
+
+	// removing the zip file and cleaning the library removes the entry
+	test.ExpectSuccess(t, os.Remove(filepath.Join(dir, "sub", "roms.zip")))
+	n, err := lib.Clean()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, n, 1)
+	test.ExpectEquality(t, lib.Len(), 1)
+}
+
+func TestExists(t *testing.T) {
+	// the resources directory is relative to the working directory in
+	// development builds
+	wd, err := os.Getwd()
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	// checking for the library must not create the library directory
+	test.ExpectEquality(t, Exists(), false)
+	_, err = os.Stat(filepath.Join(".gopher2600", libraryPath))
+	test.ExpectSuccess(t, os.IsNotExist(err))
+
+	lib, err := Load()
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, Exists(), false)
+
+	test.ExpectSuccess(t, lib.Save())
+	test.ExpectEquality(t, Exists(), true)
+}
@@ -0,0 +1,251 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package library
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/archivefs"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/thumbnailer"
+)
+
+// the number of frames to run the emulation for when creating a thumbnail.
+// many cartridges will be showing a title screen by this point
+const thumbnailFrames = 180
+
+// the maximum depth of directories and archives that will be scanned. this
+// prevents links from causing a scan to continue forever
+const maxDepth = 16
+
+var scanLabel = environment.Label("library")
+
+// ScanOptions for the Scan() function.
+type ScanOptions struct {
+	// properties used to find the name and manufacturer of each cartridge.
+	// can be nil
+	Properties cartridgeloader.Properties
+
+	// create a thumbnail for each cartridge that does not already have one.
+	// this will make the scan considerably slower
+	Thumbnails bool
+}
+
+// ScanResult is returned by the Scan() function.
+type ScanResult struct {
+	// the number of cartridge files found
+	Found int
+
+	// the number of entries added and updated
+	Added   int
+	Updated int
+
+	// the number of cartridge files that could not be loaded
+	Failed int
+}
+
+func (res ScanResult) String() string {
+	return fmt.Sprintf("%d found, %d added, %d updated, %d failed", res.Found, res.Added, res.Updated, res.Failed)
+}
+
+// scanner contains the emulations used to scan each cartridge
+type scanner struct {
+	lib    *Library
+	opts   ScanOptions
+	output io.Writer
+	vcs    *hardware.VCS
+	thmb   *thumbnailer.Image
+	res    ScanResult
+}
+
+// Scan the path for cartridge files and add them to the library. Directories
+// and archives are scanned recursively. Cartridge files are recognised by
+// their file extension. A cartridge that is already in the library is updated
+// but the play count and last played time are preserved.
+//
+// Progress and problems are written to the io.Writer. The library is saved
+// at the end of the scan.
+func (lib *Library) Scan(output io.Writer, path string, opts ScanOptions) (ScanResult, error) {
+	tv, err := television.NewTelevision("AUTO")
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("library: %w", err)
+	}
+	defer tv.End()
+
+	sc := &scanner{
+		lib:    lib,
+		opts:   opts,
+		output: output,
+	}
+
+	sc.vcs, err = hardware.NewVCS(scanLabel, tv, nil, nil)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("library: %w", err)
+	}
+
+	if opts.Thumbnails {
+		sc.thmb, err = thumbnailer.NewImage(sc.vcs.Env.Prefs, "AUTO")
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("library: %w", err)
+		}
+	}
+
+	err = sc.scanDir(path, 0)
+	if err != nil {
+		return sc.res, err
+	}
+
+	return sc.res, lib.Save()
+}
+
+func (sc *scanner) scanDir(path string, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+
+	var afs archivefs.Path
+	err := afs.Set(path, false)
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+	entries, err := afs.List()
+	afs.Close()
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+
+	for _, e := range entries {
+		// ignore dot files
+		if strings.HasPrefix(e.Name, ".") {
+			continue // for loop
+		}
+
+		p := filepath.Join(path, e.Name)
+
+		if e.IsDir {
+			err = sc.scanDir(p, depth+1)
+			if err != nil {
+				fmt.Fprintf(sc.output, "%s: %v\n", p, err)
+			}
+			continue // for loop
+		}
+
+		if !slices.Contains(cartridgeloader.FileExtensions, strings.ToUpper(filepath.Ext(e.Name))) {
+			continue // for loop
+		}
+
+		sc.res.Found++
+
+		err = sc.scanFile(p)
+		if err != nil {
+			sc.res.Failed++
+			fmt.Fprintf(sc.output, "%s: %v\n", p, err)
+		}
+	}
+
+	return nil
+}
+
+func (sc *scanner) scanFile(filename string) error {
+	cartload, err := cartridgeloader.NewLoaderFromFilename(filename, "AUTO", sc.opts.Properties)
+	if err != nil {
+		return err
+	}
+	defer cartload.Close()
+
+	// attaching the cartridge will fingerprint the mapper and the controllers
+	err = sc.vcs.AttachCartridge(cartload, true)
+	if err != nil {
+		return err
+	}
+
+	e := Entry{
+		Hash:        cartload.HashMD5,
+		Filename:    cartload.Filename,
+		Name:        cartload.Name,
+		Mapping:     sc.vcs.Mem.Cart.ID(),
+		LeftPlayer:  sc.vcs.RIOT.Ports.LeftPlayer.ID(),
+		RightPlayer: sc.vcs.RIOT.Ports.RightPlayer.ID(),
+	}
+
+	if cartload.Property.IsValid() {
+		e.Name = cartload.Property.Name
+		e.Manufacturer = cartload.Property.Manufacturer
+		e.Rarity = cartload.Property.Rarity
+		e.Model = cartload.Property.Model
+	}
+
+	sc.lib.crit.Lock()
+	if prev, ok := sc.lib.entries[e.Hash]; ok {
+		e.PlayCount = prev.PlayCount
+		e.LastPlayed = prev.LastPlayed
+		sc.res.Updated++
+	} else {
+		sc.res.Added++
+	}
+	sc.lib.entries[e.Hash] = e
+	sc.lib.crit.Unlock()
+
+	fmt.Fprintf(sc.output, "%s: %s [%s]\n", filename, e, e.Mapping)
+
+	if sc.thmb != nil {
+		err = sc.thumbnail(cartload)
+		if err != nil {
+			fmt.Fprintf(sc.output, "%s: %v\n", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// create thumbnail for the cartridge if one does not already exist
+func (sc *scanner) thumbnail(cartload cartridgeloader.Loader) error {
+	pth, err := ThumbnailFilename(cartload.HashMD5)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(pth); err == nil {
+		return nil
+	}
+
+	img, err := sc.thmb.CreateFromLoader(cartload, thumbnailFrames)
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+
+	f, err := os.Create(pth)
+	if err != nil {
+		return fmt.Errorf("library: %w", err)
+	}
+	defer f.Close()
+
+	err = png.Encode(f, img)
+	if err != nil {
+		return fmt.Errorf("library: thumbnail: %w", err)
+	}
+
+	return nil
+}
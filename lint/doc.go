@@ -0,0 +1,47 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package lint runs a cartridge headlessly and reports problems that are
+// likely to cause the ROM to behave differently on real hardware, or on
+// different television sets.
+//
+// The following problems are detected:
+//
+//   - frames with a different number of scanlines to the first stable frame
+//   - frames without a VSYNC or with a VSYNC that is not three scanlines long
+//   - writes to cartridge addresses that are neither hotspots nor cartridge RAM
+//   - reads of TIA registers where the value of the undriven bits is used
+//   - the stack pointer wrapping into TIA space
+//   - execution of the KIL opcode and of illegal opcodes
+//   - execution of opcodes that are unstable on real hardware
+//
+// Frames that occur before the television has stabilised are not checked.
+//
+// The check for undriven TIA bits is a heuristic. Only the instruction that
+// immediately follows a load from a TIA register is considered when deciding
+// whether the undriven bits are used. For example, a BIT instruction followed
+// by a BMI or BVS instruction is fine but an LDA instruction followed by a STA
+// instruction is not.
+//
+// Writes to cartridge addresses are only checked if the cartridge mapper
+// reports its hotspots, or if the cartridge has only one bank and no
+// coprocessor.
+//
+// The Run() function runs a cartridge for a number of frames, optionally with
+// a playback file, and returns the Lint instance containing the problems. The
+// Begin() and End() functions can be used to add lint checks to any other
+// emulation loop, in which case they should be called either side of every
+// call to CPU.ExecuteInstruction().
+package lint
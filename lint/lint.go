@@ -0,0 +1,441 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/execution"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+)
+
+// Kind is the category of a Problem.
+type Kind int
+
+// List of valid Kind values.
+const (
+	Scanlines Kind = iota
+	VSync
+	ROMWrite
+	Undriven
+	Stack
+	KIL
+	Illegal
+	Unstable
+	numKinds
+)
+
+var kindNames = [numKinds]string{
+	"scanlines",
+	"vsync",
+	"romwrite",
+	"undriven",
+	"stack",
+	"kil",
+	"illegal",
+	"unstable",
+}
+
+// KindList is the list of names accepted by ParseKind().
+var KindList = kindNames[:]
+
+func (k Kind) String() string {
+	if k < 0 || k >= numKinds {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// ParseKind converts a string to a Kind value. The string is not case
+// sensitive.
+func ParseKind(s string) (Kind, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for k, n := range kindNames {
+		if n == s {
+			return Kind(k), nil
+		}
+	}
+	return 0, fmt.Errorf("lint: unknown problem kind (%s)", s)
+}
+
+// whether the kind of problem is caused by an instruction rather than being a
+// problem with the television frame
+func (k Kind) instruction() bool {
+	return k != Scanlines && k != VSync
+}
+
+// Problem is a problem found by the linter. A problem of the same kind, for
+// the same instruction and with the same detail, is counted rather than being
+// repeated.
+type Problem struct {
+	Kind   Kind
+	Detail string
+
+	// the address and bank of the instruction that caused the problem. these
+	// fields are not meaningful for problems with the television frame
+	Address uint16
+	Bank    mapper.BankInfo
+
+	// the frame in which the problem was first found and the number of times
+	// it has been found
+	Frame int
+	Count int
+}
+
+func (p Problem) String() string {
+	if p.Kind.instruction() {
+		return fmt.Sprintf("%s: %#04x (bank %s): %s [frame %d, %d times]", p.Kind, p.Address, p.Bank, p.Detail, p.Frame, p.Count)
+	}
+	return fmt.Sprintf("%s: %s [frame %d, %d times]", p.Kind, p.Detail, p.Frame, p.Count)
+}
+
+// the fields that identify a problem
+type problemKey struct {
+	kind    Kind
+	detail  string
+	address uint16
+	bank    string
+}
+
+// a read of a TIA register that included undriven bits. whether the undriven
+// bits are used depends on the instruction that follows
+type undrivenRead struct {
+	address  uint16
+	bank     mapper.BankInfo
+	frame    int
+	operator instructions.Operator
+	register string
+	driven   uint8
+}
+
+// Lint checks the execution of a 6507 program for problems.
+type Lint struct {
+	problems map[problemKey]*Problem
+
+	// problems in the order they were first found
+	list []*Problem
+
+	// problems of these kinds will not be recorded
+	ignore [numKinds]bool
+
+	// the number of frames that have been checked and the number of scanlines
+	// in the first stable frame. the number of scanlines is zero until there
+	// has been a stable frame
+	numFrames int
+	scanlines int
+
+	// whether writes to the cartridge should be checked and the write
+	// hotspots reported by the cartridge mapper
+	checkROM bool
+	hotspots map[uint16]bool
+
+	// state noted by Begin(). pending is true if the CPU is about to execute
+	// an instruction
+	pending bool
+	bank    mapper.BankInfo
+	sp      uint8
+
+	// a TIA read waiting to be checked against the next instruction
+	undriven *undrivenRead
+}
+
+// NewLint is the preferred method of initialisation for the Lint type. The
+// Lint instance must be reset with a cartridge before it can be used.
+func NewLint() *Lint {
+	return &Lint{
+		problems: make(map[problemKey]*Problem),
+	}
+}
+
+// Ignore problems of the specified kind.
+func (l *Lint) Ignore(kind Kind) {
+	if kind >= 0 && kind < numKinds {
+		l.ignore[kind] = true
+	}
+}
+
+// Reset all problems and prepare for the specified cartridge.
+func (l *Lint) Reset(cart *cartridge.Cartridge) {
+	clear(l.problems)
+	l.list = l.list[:0]
+	l.numFrames = 0
+	l.scanlines = 0
+	l.pending = false
+	l.undriven = nil
+
+	l.hotspots = make(map[uint16]bool)
+	if hb := cart.GetCartHotspotsBus(); hb != nil {
+		for a := range hb.WriteHotspots() {
+			l.hotspots[a] = true
+		}
+		l.checkROM = true
+	} else {
+		l.checkROM = cart.NumBanks() == 1 && cart.GetCoProcBus() == nil
+	}
+}
+
+// Problems returns the problems found so far in the order they were found.
+func (l *Lint) Problems() []Problem {
+	p := make([]Problem, len(l.list))
+	for i := range l.list {
+		p[i] = *l.list[i]
+	}
+	return p
+}
+
+// NumFrames returns the number of frames that have been checked.
+func (l *Lint) NumFrames() int {
+	return l.numFrames
+}
+
+// add a problem or increase the count of an existing problem
+func (l *Lint) add(kind Kind, detail string, address uint16, bank mapper.BankInfo, frame int) {
+	if l.ignore[kind] {
+		return
+	}
+
+	k := problemKey{
+		kind:   kind,
+		detail: detail,
+	}
+	if kind.instruction() {
+		k.address = address
+		k.bank = bank.String()
+	}
+
+	if p, ok := l.problems[k]; ok {
+		p.Count++
+		return
+	}
+
+	p := &Problem{
+		Kind:    kind,
+		Detail:  detail,
+		Address: address,
+		Bank:    bank,
+		Frame:   frame,
+		Count:   1,
+	}
+	l.problems[k] = p
+	l.list = append(l.list, p)
+}
+
+// NewFrame implements the television.FrameTrigger interface.
+func (l *Lint) NewFrame(info television.FrameInfo) error {
+	// frames before the television has stabilised are often irregular for
+	// reasons that are not the fault of the ROM
+	if !info.Stable {
+		return nil
+	}
+	l.numFrames++
+
+	if !info.VSync {
+		l.add(VSync, "frame has no VSYNC", 0, mapper.BankInfo{}, info.FrameNum)
+	} else if info.VSyncScanlines != 3 {
+		l.add(VSync, fmt.Sprintf("VSYNC is %d scanlines", info.VSyncScanlines), 0, mapper.BankInfo{}, info.FrameNum)
+	}
+
+	if l.scanlines == 0 {
+		l.scanlines = info.TotalScanlines
+	} else if info.TotalScanlines != l.scanlines {
+		l.add(Scanlines, fmt.Sprintf("frame has %d scanlines (expected %d)", info.TotalScanlines, l.scanlines), 0, mapper.BankInfo{}, info.FrameNum)
+	}
+
+	return nil
+}
+
+// Begin notes the bank and the stack pointer before the CPU executes the next
+// instruction. The stack pointer is used by End() to detect the stack
+// wrapping into TIA space.
+func (l *Lint) Begin(vcs *hardware.VCS) {
+	mc := vcs.CPU
+	l.pending = mc.RdyFlg && !mc.Killed && (mc.LastResult.Final || mc.Interrupted)
+	if l.pending {
+		l.bank = vcs.Mem.Cart.GetBank(mc.PC.Address())
+		l.sp = mc.SP.Value()
+	}
+}
+
+// End checks the instruction started with Begin() for problems. Nothing is
+// checked until the instruction has completed.
+func (l *Lint) End(vcs *hardware.VCS) {
+	if !l.pending {
+		return
+	}
+	l.pending = false
+
+	if !vcs.CPU.LastResult.Final {
+		return
+	}
+
+	var ram []mapper.CartRAM
+	if bus := vcs.Mem.Cart.GetRAMbus(); bus != nil {
+		ram = bus.GetRAM()
+	}
+
+	l.instruction(vcs.CPU.LastResult, l.bank, vcs.TV.GetCoords().Frame, l.sp, vcs.CPU.SP.Value(),
+		vcs.Mem.LastCPUAddressMapped, vcs.Mem.LastCPUWrite, vcs.Mem.DataBusDriven, ram)
+}
+
+// whether the opcode behaves differently between individual consoles. the
+// LAX instruction is only unstable in the immediate addressing mode
+func unstable(defn *instructions.Definition) bool {
+	switch defn.Operator {
+	case instructions.XAA, instructions.AHX, instructions.SHX, instructions.SHY, instructions.TAS:
+		return true
+	case instructions.LAX:
+		return defn.AddressingMode == instructions.Immediate
+	}
+	return false
+}
+
+// instruction checks a single executed instruction. the instruction began in
+// the bank and with the stack pointer noted by Begin() and the remaining
+// arguments describe the most recent memory access made by the instruction
+func (l *Lint) instruction(res execution.Result, bank mapper.BankInfo, frame int, spBefore uint8, spAfter uint8,
+	address uint16, write bool, driven uint8, ram []mapper.CartRAM) {
+
+	defn := res.Defn
+	if defn == nil {
+		return
+	}
+
+	if l.undriven != nil {
+		l.checkUndriven(res)
+		l.undriven = nil
+	}
+
+	switch {
+	case defn.Operator == instructions.KIL:
+		l.add(KIL, "KIL instruction", res.Address, bank, frame)
+	case unstable(defn):
+		l.add(Unstable, fmt.Sprintf("unstable opcode %s (%#02x)", defn.Operator, defn.OpCode), res.Address, bank, frame)
+	case defn.Undocumented:
+		l.add(Illegal, fmt.Sprintf("illegal opcode %s (%#02x)", defn.Operator, defn.OpCode), res.Address, bank, frame)
+	}
+
+	switch defn.Operator {
+	case instructions.Jsr, instructions.Brk, instructions.Pha, instructions.Php:
+		if spBefore >= 0x80 && spAfter < 0x80 {
+			l.add(Stack, fmt.Sprintf("stack pointer has wrapped into TIA space (%#02x)", spAfter), res.Address, bank, frame)
+		}
+	case instructions.Pla, instructions.Plp, instructions.Rts, instructions.Rti:
+		if spAfter < spBefore {
+			l.add(Stack, fmt.Sprintf("stack pointer has wrapped into TIA space (%#02x)", spAfter), res.Address, bank, frame)
+		}
+	}
+
+	ma, area := memorymap.MapAddress(address, !write)
+
+	if write {
+		if (defn.Effect == instructions.Write || defn.Effect == instructions.RMW) && area == memorymap.Cartridge {
+			if l.checkROM && !l.hotspots[ma] && !isCartRAM(ram, ma) {
+				l.add(ROMWrite, fmt.Sprintf("write to ROM address %#04x", ma), res.Address, bank, frame)
+			}
+		}
+		return
+	}
+
+	if defn.Effect != instructions.Read || area != memorymap.TIA || driven == 0xff {
+		return
+	}
+
+	register := string(cpubus.TIAReadSymbols[ma])
+	if register == "" {
+		register = fmt.Sprintf("%#02x", ma)
+	}
+
+	switch defn.Operator {
+	case instructions.Bit, instructions.Lda, instructions.Ldx, instructions.Ldy, instructions.LAX:
+		// whether the undriven bits are used depends on the next instruction
+		l.undriven = &undrivenRead{
+			address:  res.Address,
+			bank:     bank,
+			frame:    frame,
+			operator: defn.Operator,
+			register: register,
+			driven:   driven,
+		}
+	default:
+		l.add(Undriven, fmt.Sprintf("undriven bits of %s are used by %s", register, defn.Operator), res.Address, bank, frame)
+	}
+}
+
+// check whether the instruction uses the undriven bits of the TIA register
+// read by the previous instruction
+func (l *Lint) checkUndriven(res execution.Result) {
+	u := l.undriven
+
+	// the flags set by the BIT instruction are the only effect of the
+	// instruction. the sign and overflow flags are taken from the driven bits
+	// but the zero flag depends on all bits of the value
+	if u.operator == instructions.Bit {
+		switch res.Defn.Operator {
+		case instructions.Beq, instructions.Bne:
+		default:
+			return
+		}
+	} else {
+		switch res.Defn.Operator {
+		case instructions.Bmi, instructions.Bpl:
+			// the sign flag is taken from bit 7, which is always driven
+			return
+		case instructions.And:
+			// masking the accumulator so that only the driven bits remain
+			if u.operator == instructions.Lda || u.operator == instructions.LAX {
+				if res.Defn.AddressingMode == instructions.Immediate && uint8(res.InstructionData)&^u.driven == 0 {
+					return
+				}
+			}
+		}
+	}
+
+	l.add(Undriven, fmt.Sprintf("undriven bits of %s are used by %s", u.register, u.operator), u.address, u.bank, u.frame)
+}
+
+// whether the mapped address is in the read or write port of any cartridge
+// RAM. the write port is immediately below the read port if there is room,
+// otherwise it is immediately above
+func isCartRAM(ram []mapper.CartRAM, address uint16) bool {
+	for _, seg := range ram {
+		if !seg.Mapped {
+			continue // for loop
+		}
+
+		origin, _ := memorymap.MapAddress(seg.Origin, true)
+		size := len(seg.Data)
+
+		if int(address) >= int(origin) && int(address) < int(origin)+size {
+			return true
+		}
+
+		write := int(origin) - size
+		if write < int(memorymap.OriginCart) {
+			write = int(origin) + size
+		}
+		if int(address) >= write && int(address) < write+size {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lint_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/lint"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// a 4k ROM that produces a stable 262 scanline frame with a three scanline
+// VSYNC. the program contains a selection of problems
+var program = []uint8{
+	// $f000
+	0x78,       // SEI
+	0xd8,       // CLD
+	0xa2, 0xff, // LDX #$ff
+	0x9a, // TXS
+
+	// $f005 frame
+	0x85, 0x02, // STA WSYNC
+	0xa9, 0x02, // LDA #2
+	0x85, 0x00, // STA VSYNC
+	0x85, 0x02, // STA WSYNC
+	0x85, 0x02, // STA WSYNC
+	0x85, 0x02, // STA WSYNC
+	0xa9, 0x00, // LDA #0
+	0x85, 0x00, // STA VSYNC
+
+	// $f015
+	0xa5, 0x0c, // LDA INPT4 (undriven bits used by next instruction)
+	0x85, 0x80, // STA $80
+	0x24, 0x0c, // BIT INPT4
+	0x30, 0x00, // BMI +0
+	0x8d, 0x00, 0xf0, // STA $f000 (write to ROM)
+	0xa7, 0x80, // LAX $80 (illegal opcode)
+	0xab, 0x00, // LXA #0 (unstable opcode)
+
+	// $f024
+	0xa2, 0x00, // LDX #0
+	0x85, 0x02, // STA WSYNC
+	0xca,       // DEX
+	0xd0, 0xfb, // BNE $f026
+	0x85, 0x02, // STA WSYNC
+	0x85, 0x02, // STA WSYNC
+	0x4c, 0x05, 0xf0, // JMP $f005
+}
+
+func TestLint(t *testing.T) {
+	rom := make([]uint8, 4096)
+	copy(rom, program)
+
+	// reset vector
+	rom[0xffc] = 0x00
+	rom[0xffd] = 0xf0
+
+	filename := filepath.Join(t.TempDir(), "lint.bin")
+	test.ExpectSuccess(t, os.WriteFile(filename, rom, 0644))
+
+	l, err := lint.Run(io.Discard, filename, lint.Options{
+		Mapping: "AUTO",
+		Spec:    "NTSC",
+		Frames:  60,
+	})
+	test.ExpectSuccess(t, err)
+
+	problems := l.Problems()
+	test.ExpectEquality(t, len(problems), 4)
+
+	expected := []struct {
+		kind    lint.Kind
+		address uint16
+	}{
+		{kind: lint.Undriven, address: 0xf015},
+		{kind: lint.ROMWrite, address: 0xf01d},
+		{kind: lint.Illegal, address: 0xf020},
+		{kind: lint.Unstable, address: 0xf022},
+	}
+	for i, e := range expected {
+		if i >= len(problems) {
+			break // for loop
+		}
+		test.ExpectEquality(t, problems[i].Kind, e.kind)
+		test.ExpectEquality(t, problems[i].Address, e.address)
+	}
+
+	// ignored problems are not recorded
+	l, err = lint.Run(io.Discard, filename, lint.Options{
+		Mapping: "AUTO",
+		Spec:    "NTSC",
+		Frames:  60,
+		Ignore:  []lint.Kind{lint.Illegal, lint.Unstable},
+	})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(l.Problems()), 2)
+}
+
+func TestParseKind(t *testing.T) {
+	for _, n := range lint.KindList {
+		k, err := lint.ParseKind(n)
+		test.ExpectSuccess(t, err)
+		test.ExpectEquality(t, k.String(), n)
+	}
+	_, err := lint.ParseKind("foo")
+	test.ExpectFailure(t, err)
+}
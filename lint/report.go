@@ -0,0 +1,53 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteReport writes the problems found, grouped by kind, to the io.Writer.
+func (l *Lint) WriteReport(w io.Writer) error {
+	for k := Kind(0); k < numKinds; k++ {
+		var n int
+		for _, p := range l.list {
+			if p.Kind != k {
+				continue // for loop
+			}
+			if n == 0 {
+				_, err := fmt.Fprintf(w, "%s\n", k)
+				if err != nil {
+					return err
+				}
+			}
+			n++
+
+			var err error
+			if k.instruction() {
+				_, err = fmt.Fprintf(w, "  %04x (bank %s) %s [first frame %d, %d times]\n", p.Address, p.Bank, p.Detail, p.Frame, p.Count)
+			} else {
+				_, err = fmt.Fprintf(w, "  %s [first frame %d, %d frames]\n", p.Detail, p.Frame, p.Count)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d problems in %d frames\n", len(l.list), l.numFrames)
+	return err
+}
@@ -0,0 +1,108 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package lint
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/headless"
+)
+
+// DefaultFrames is the number of frames run by Run() if the number of frames
+// is not specified and there is no playback file.
+const DefaultFrames = 600
+
+// Options for the Run() function.
+type Options struct {
+	// cartridge mapping and television specification. both fields are
+	// ignored if a playback file is being used
+	Mapping string
+	Spec    string
+
+	// the number of frames to run. if the value is zero then the emulation
+	// runs for DefaultFrames, or until the end of the playback file
+	Frames int
+
+	// the name of a playback file to drive the input of the emulation
+	Playback string
+
+	// kinds of problem that should not be reported
+	Ignore []Kind
+}
+
+// Run the cartridge as described by the Options argument and return the
+// problems found. Progress information is written to the output argument.
+//
+// The filename argument can be empty if a playback file is specified in the
+// options. In that case the cartridge named in the playback file is used.
+func Run(output io.Writer, filename string, opts Options) (*Lint, error) {
+	if opts.Frames < 0 {
+		return nil, fmt.Errorf("lint: invalid number of frames (%d)", opts.Frames)
+	}
+
+	if opts.Playback == "" && opts.Frames == 0 {
+		opts.Frames = DefaultFrames
+	}
+
+	em, err := headless.NewEmulation(filename, headless.Options{
+		Mapping:  opts.Mapping,
+		Spec:     opts.Spec,
+		Playback: opts.Playback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lint: %w", err)
+	}
+	defer em.End()
+
+	vcs := em.VCS
+
+	l := NewLint()
+	for _, k := range opts.Ignore {
+		l.Ignore(k)
+	}
+	l.Reset(vcs.Mem.Cart)
+	em.TV.AddFrameTrigger(l)
+
+	if opts.Frames > 0 {
+		output.Write([]byte(fmt.Sprintf("linting %d frames of %s\n", opts.Frames, em.Cartridge.Name)))
+	} else {
+		output.Write([]byte(fmt.Sprintf("linting %s with %s\n", em.Cartridge.Name, opts.Playback)))
+	}
+
+	l.Begin(vcs)
+
+	err = em.Run(func() (govern.State, error) {
+		l.End(vcs)
+
+		// the KIL instruction will have been recorded as a problem. there is
+		// nothing more to check after that
+		state, err := em.Continue(opts.Frames)
+		if state != govern.Running {
+			return state, err
+		}
+
+		l.Begin(vcs)
+
+		return govern.Running, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lint: %w", err)
+	}
+
+	return l, nil
+}
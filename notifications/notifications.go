@@ -25,6 +25,10 @@ const (
 	// a screen shot is taking place
 	NotifyScreenshot Notice = "NotifyScreenshot"
 
+	// the emulation state has been saved to or loaded from a state file
+	NotifySaveState Notice = "NotifySaveState"
+	NotifyLoadState Notice = "NotifyLoadState"
+
 	// notifications sent when supercharger is loading from a sound file (eg. mp3 file)
 	NotifySuperchargerSoundloadStarted Notice = "NotifySuperchargerSoundloadStarted"
 	NotifySuperchargerSoundloadEnded   Notice = "NotifySuperchargerSoundloadEnded"
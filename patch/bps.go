@@ -0,0 +1,262 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package patch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+const bpsHeader = "BPS1"
+
+// the footer of a BPS file is made up of three CRC32 values. the CRC of the
+// source data, the CRC of the target data and the CRC of the patch file itself
+const bpsFooterLen = 12
+
+// the largest cartridge that can be loaded. this is the same as the preload
+// limit in the cartridgeloader package
+const bpsMaxTargetSize = 1048576
+
+// the largest variable length number that will be decoded. no number in a
+// valid patch file for a cartridge will be anywhere near this value
+const bpsMaxNumber = math.MaxInt32
+
+// BPS actions
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// decoding of variable length numbers
+type bpsReader struct {
+	p []byte
+}
+
+func (r *bpsReader) byte() (byte, error) {
+	if len(r.p) == 0 {
+		return 0, fmt.Errorf("bps: unexpected end of file")
+	}
+	b := r.p[0]
+	r.p = r.p[1:]
+	return b, nil
+}
+
+func (r *bpsReader) number() (int, error) {
+	var n int
+	shift := 1
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(b&0x7f) * shift
+		if n > bpsMaxNumber {
+			return 0, fmt.Errorf("bps: number too large")
+		}
+		if b&0x80 == 0x80 {
+			return n, nil
+		}
+		if shift > bpsMaxNumber>>7 {
+			return 0, fmt.Errorf("bps: number too large")
+		}
+		shift <<= 7
+		n += shift
+	}
+}
+
+// encoding of variable length numbers
+func bpsNumber(p []byte, n int) []byte {
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(p, b|0x80)
+		}
+		p = append(p, b)
+		n--
+	}
+}
+
+// bps returns the target data created from the source data. the CRC values in
+// the patch file are checked
+func bps(source []byte, buffer []byte) ([]byte, error) {
+	if len(buffer) < len(bpsHeader)+bpsFooterLen {
+		return nil, fmt.Errorf("bps: file too short")
+	}
+
+	footer := buffer[len(buffer)-bpsFooterLen:]
+	sourceCRC := binary.LittleEndian.Uint32(footer[0:])
+	targetCRC := binary.LittleEndian.Uint32(footer[4:])
+	patchCRC := binary.LittleEndian.Uint32(footer[8:])
+
+	if crc32.ChecksumIEEE(buffer[:len(buffer)-4]) != patchCRC {
+		return nil, fmt.Errorf("bps: patch file is corrupt")
+	}
+	if crc32.ChecksumIEEE(source) != sourceCRC {
+		return nil, fmt.Errorf("bps: patch is not for this cartridge")
+	}
+
+	r := bpsReader{p: buffer[len(bpsHeader) : len(buffer)-bpsFooterLen]}
+
+	sourceSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != len(source) {
+		return nil, fmt.Errorf("bps: patch is not for this cartridge")
+	}
+
+	targetSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if targetSize > bpsMaxTargetSize {
+		return nil, fmt.Errorf("bps: target is too large (%d bytes)", targetSize)
+	}
+
+	// metadata is not used
+	metadataSize, err := r.number()
+	if err != nil {
+		return nil, err
+	}
+	if metadataSize > len(r.p) {
+		return nil, fmt.Errorf("bps: unexpected end of file")
+	}
+	r.p = r.p[metadataSize:]
+
+	target := make([]byte, targetSize)
+
+	var outputOffset int
+	var sourceRelative int
+	var targetRelative int
+
+	for len(r.p) > 0 {
+		n, err := r.number()
+		if err != nil {
+			return nil, err
+		}
+
+		action := n & 0x03
+		length := (n >> 2) + 1
+		if length <= 0 {
+			return nil, fmt.Errorf("bps: invalid action length")
+		}
+
+		if outputOffset+length > targetSize {
+			return nil, fmt.Errorf("bps: action writes beyond end of target")
+		}
+
+		switch action {
+		case bpsSourceRead:
+			if outputOffset+length > len(source) {
+				return nil, fmt.Errorf("bps: action reads beyond end of source")
+			}
+			copy(target[outputOffset:], source[outputOffset:outputOffset+length])
+			outputOffset += length
+
+		case bpsTargetRead:
+			if length > len(r.p) {
+				return nil, fmt.Errorf("bps: unexpected end of file")
+			}
+			copy(target[outputOffset:], r.p[:length])
+			r.p = r.p[length:]
+			outputOffset += length
+
+		case bpsSourceCopy, bpsTargetCopy:
+			d, err := r.number()
+			if err != nil {
+				return nil, err
+			}
+			offset := d >> 1
+			if d&0x01 == 0x01 {
+				offset = -offset
+			}
+
+			if action == bpsSourceCopy {
+				sourceRelative += offset
+				if sourceRelative < 0 || sourceRelative+length > len(source) {
+					return nil, fmt.Errorf("bps: action reads beyond end of source")
+				}
+				copy(target[outputOffset:], source[sourceRelative:sourceRelative+length])
+				sourceRelative += length
+				outputOffset += length
+			} else {
+				targetRelative += offset
+				if targetRelative < 0 || targetRelative >= outputOffset {
+					return nil, fmt.Errorf("bps: action reads beyond end of target")
+				}
+
+				// target copy can overlap with the data being written so the
+				// copy must be done one byte at a time
+				for i := 0; i < length; i++ {
+					target[outputOffset] = target[targetRelative]
+					outputOffset++
+					targetRelative++
+				}
+			}
+		}
+	}
+
+	if crc32.ChecksumIEEE(target) != targetCRC {
+		return nil, fmt.Errorf("bps: patched data is corrupt")
+	}
+
+	return target, nil
+}
+
+// CreateBPS returns a BPS patch file that will turn the original data into the
+// patched data. The original and patched data must be the same size.
+func CreateBPS(original []byte, patched []byte) ([]byte, error) {
+	if len(original) != len(patched) {
+		return nil, fmt.Errorf("patch: bps: original and patched data are different sizes")
+	}
+
+	p := []byte(bpsHeader)
+	p = bpsNumber(p, len(original))
+	p = bpsNumber(p, len(patched))
+	p = bpsNumber(p, 0)
+
+	// unchanged data is read from the source and changed data is included in
+	// the patch file
+	i := 0
+	for i < len(patched) {
+		j := i
+		same := original[i] == patched[i]
+		for j < len(patched) && (original[j] == patched[j]) == same {
+			j++
+		}
+
+		length := j - i
+		if same {
+			p = bpsNumber(p, (length-1)<<2|bpsSourceRead)
+		} else {
+			p = bpsNumber(p, (length-1)<<2|bpsTargetRead)
+			p = append(p, patched[i:j]...)
+		}
+
+		i = j
+	}
+
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(original))
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(patched))
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(p))
+
+	return p, nil
+}
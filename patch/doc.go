@@ -21,7 +21,20 @@
 // This package simply loads the patch instructions, interprets them and calls
 // the cartridge.Patch() function.
 //
-// The first format is simplt the output of "cmp -l <old_file> <new_file>", an
+// Patch files can be in one of four formats. The IPS and BPS formats are
+// widely used for distributing translations and bug fixes for games on many
+// different platforms. Both formats are binary formats and describe the
+// changes to make to the original file. Only patches that do not change the
+// size of the cartridge are supported.
+//
+// BPS files contain CRC values for the original data and the patched data.
+// Patches will not be applied if the CRC values do not match. IPS files do not
+// contain any CRC values.
+//
+// The CreateIPS() and CreateBPS() functions can be used to create patch files
+// from the difference between two versions of the same data.
+//
+// The third format is simply the output of "cmp -l <old_file> <new_file>", an
 // example of which is shown below:
 //
 //	 862  22 200
@@ -37,7 +50,7 @@
 // changed, and the third column is the value it is being changed to. The
 // values in column 2 and three are expressed in octal!
 //
-// The second format is what I have called the "neo" format. This seems to be
+// The fourth format is what I have called the "neo" format. This seems to be
 // an ad-hoc format taken from the "In case you can't wait" section of the
 // following web page (the domain of which was used to help name the format):
 //
@@ -61,8 +74,8 @@
 //  4. Multiple values on a line are poked into consecutive offsets, starting
 //     from the offset value
 //
-// Note that offsets are expressed with origin zero and have no relationship
-// to how memory is mapped inside the VCS. Imagine that the patches are being
-// applied to the cartridge file image. The cartridge mapper handles the VCS
-// memory side of things.
+// For all formats, offsets are expressed with origin zero and have no
+// relationship to how memory is mapped inside the VCS. Imagine that the
+// patches are being applied to the cartridge file image. The cartridge mapper
+// handles the VCS memory side of things.
 package patch
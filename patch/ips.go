@@ -0,0 +1,124 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package patch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const ipsHeader = "PATCH"
+const ipsFooter = "EOF"
+
+// the largest offset that can be expressed in an IPS file
+const ipsMaxOffset = 0xffffff
+
+// the largest record that can be expressed in an IPS file
+const ipsMaxRecord = 0xffff
+
+// ips returns a patched copy of the source data
+func ips(source []byte, buffer []byte) ([]byte, error) {
+	target := make([]byte, len(source))
+	copy(target, source)
+
+	p := buffer[len(ipsHeader):]
+
+	for {
+		if bytes.HasPrefix(p, []byte(ipsFooter)) {
+			// any data after the footer is the optional truncation
+			// extension, which is not supported because the size of the
+			// cartridge can not change
+			return target, nil
+		}
+
+		if len(p) < 5 {
+			return nil, fmt.Errorf("ips: unexpected end of file")
+		}
+
+		offset := int(p[0])<<16 | int(p[1])<<8 | int(p[2])
+		size := int(p[3])<<8 | int(p[4])
+		p = p[5:]
+
+		// a size of zero indicates a run-length encoded record
+		if size == 0 {
+			if len(p) < 3 {
+				return nil, fmt.Errorf("ips: unexpected end of file")
+			}
+			size = int(p[0])<<8 | int(p[1])
+			if offset+size > len(target) {
+				return nil, fmt.Errorf("ips: patch offset too high (%d)", offset+size)
+			}
+			for i := 0; i < size; i++ {
+				target[offset+i] = p[2]
+			}
+			p = p[3:]
+			continue // for loop
+		}
+
+		if len(p) < size {
+			return nil, fmt.Errorf("ips: unexpected end of file")
+		}
+		if offset+size > len(target) {
+			return nil, fmt.Errorf("ips: patch offset too high (%d)", offset+size)
+		}
+		copy(target[offset:], p[:size])
+		p = p[size:]
+	}
+}
+
+// CreateIPS returns an IPS patch file that will turn the original data into
+// the patched data. The original and patched data must be the same size.
+func CreateIPS(original []byte, patched []byte) ([]byte, error) {
+	if len(original) != len(patched) {
+		return nil, fmt.Errorf("patch: ips: original and patched data are different sizes")
+	}
+	if len(original) > ipsMaxOffset {
+		return nil, fmt.Errorf("patch: ips: data is too large")
+	}
+
+	p := []byte(ipsHeader)
+
+	i := 0
+	for i < len(patched) {
+		if original[i] == patched[i] {
+			i++
+			continue // for loop
+		}
+
+		// an offset that looks like the footer would end the patch early so
+		// the record is started one byte earlier
+		start := i
+		if start == 0x454f46 {
+			start--
+		}
+
+		// the record continues until the next unchanged byte
+		end := i
+		for end < len(patched) && original[end] != patched[end] && end-start < ipsMaxRecord {
+			end++
+		}
+
+		size := end - start
+		p = append(p, byte(start>>16), byte(start>>8), byte(start), byte(size>>8), byte(size))
+		p = append(p, patched[start:end]...)
+
+		i = end
+	}
+
+	p = append(p, []byte(ipsFooter)...)
+
+	return p, nil
+}
@@ -16,6 +16,7 @@
 package patch
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -33,14 +34,18 @@ const neoComment = '-'
 const neoSeparator = ":"
 
 // CartridgeMemory applies the contents of a patch file to cartridge memory.
-// Currently, patch file must be in the patches sub-directory of the
-// resource path (see paths package).
+// The patch file can be a path to any file. If the file does not exist then
+// the patches sub-directory of the resource path (see paths package) is
+// searched.
 func CartridgeMemory(cart *cartridge.Cartridge, patchFile string) (bool, error) {
 	var err error
 
-	p, err := resources.JoinPath(patchPath, patchFile)
-	if err != nil {
-		return false, fmt.Errorf("patch: %w", err)
+	p := patchFile
+	if _, err = os.Stat(p); err != nil {
+		p, err = resources.JoinPath(patchPath, patchFile)
+		if err != nil {
+			return false, fmt.Errorf("patch: %w", err)
+		}
 	}
 
 	f, err := os.Open(p)
@@ -68,6 +73,15 @@ func CartridgeMemory(cart *cartridge.Cartridge, patchFile string) (bool, error)
 		return false, nil
 	}
 
+	// IPS and BPS files are identified by the header
+	if bytes.HasPrefix(buffer, []byte(ipsHeader)) || bytes.HasPrefix(buffer, []byte(bpsHeader)) {
+		err = binaryStyle(cart, buffer)
+		if err != nil {
+			return false, fmt.Errorf("patch: %w", err)
+		}
+		return true, nil
+	}
+
 	// if first character is a hyphen then we'll assume this is a "neo" style
 	// patch file
 	if buffer[0] == neoComment {
@@ -200,3 +214,56 @@ func neoStyle(cart *cartridge.Cartridge, buffer []byte) error {
 
 	return nil
 }
+
+// CartridgeData returns the contents of the cartridge as it would appear in a
+// cartridge file. The data is made up of every bank in the cartridge in order.
+// Offsets into the data are the same as the offsets used by the Patch()
+// function of the cartridge.
+func CartridgeData(cart *cartridge.Cartridge) ([]byte, error) {
+	banks, err := cart.CopyBanks()
+	if err != nil {
+		return nil, fmt.Errorf("patch: %w", err)
+	}
+
+	var data []byte
+	for _, b := range banks {
+		data = append(data, b.Data...)
+	}
+
+	return data, nil
+}
+
+// IPS and BPS patch files describe the patched file in its entirety. the
+// patched data is created from the cartridge data and then any differences are
+// applied to the cartridge
+func binaryStyle(cart *cartridge.Cartridge, buffer []byte) error {
+	source, err := CartridgeData(cart)
+	if err != nil {
+		return err
+	}
+
+	var target []byte
+	if bytes.HasPrefix(buffer, []byte(ipsHeader)) {
+		target, err = ips(source, buffer)
+	} else {
+		target, err = bps(source, buffer)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(target) != len(source) {
+		return fmt.Errorf("patch changes the size of the cartridge (%d bytes to %d bytes)", len(source), len(target))
+	}
+
+	for i := range target {
+		if target[i] != source[i] {
+			err = cart.Patch(i, target[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func testData() ([]byte, []byte) {
+	original := make([]byte, 4096)
+	for i := range original {
+		original[i] = byte(i * 7)
+	}
+
+	patched := make([]byte, len(original))
+	copy(patched, original)
+	patched[0] = 0xff
+	patched[100] = 0x00
+	patched[101] = 0x01
+	patched[102] = 0x02
+	patched[4095] = 0xaa
+
+	return original, patched
+}
+
+func TestIPS(t *testing.T) {
+	original, patched := testData()
+
+	p, err := CreateIPS(original, patched)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(p[:len(ipsHeader)]), ipsHeader)
+
+	target, err := ips(original, p)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, bytes.Equal(target, patched))
+
+	// run length encoded record
+	p = []byte(ipsHeader)
+	p = append(p, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x04, 0xee)
+	p = append(p, []byte(ipsFooter)...)
+	target, err = ips(original, p)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, bytes.Equal(target[0x10:0x14], []byte{0xee, 0xee, 0xee, 0xee}))
+	test.ExpectEquality(t, target[0x14], original[0x14])
+
+	// record beyond the end of the data
+	p = []byte(ipsHeader)
+	p = append(p, 0x00, 0x10, 0x00, 0x00, 0x01, 0xee)
+	p = append(p, []byte(ipsFooter)...)
+	_, err = ips(original, p)
+	test.ExpectFailure(t, err)
+
+	// missing footer
+	p, _ = CreateIPS(original, patched)
+	_, err = ips(original, p[:len(p)-len(ipsFooter)])
+	test.ExpectFailure(t, err)
+}
+
+func TestBPS(t *testing.T) {
+	original, patched := testData()
+
+	p, err := CreateBPS(original, patched)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, string(p[:len(bpsHeader)]), bpsHeader)
+
+	target, err := bps(original, p)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, bytes.Equal(target, patched))
+
+	// patch applied to the wrong data
+	_, err = bps(patched, p)
+	test.ExpectFailure(t, err)
+
+	// corrupted patch file
+	p[len(bpsHeader)+5] ^= 0xff
+	_, err = bps(original, p)
+	test.ExpectFailure(t, err)
+}
+
+func TestBPSNumber(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 129, 16511, 16512, 4096, 1 << 20} {
+		r := bpsReader{p: bpsNumber(nil, n)}
+		v, err := r.number()
+		test.ExpectSuccess(t, err)
+		test.ExpectEquality(t, v, n)
+		test.ExpectEquality(t, len(r.p), 0)
+	}
+}
+
+func TestBPSOverflow(t *testing.T) {
+	// a number that never terminates must not overflow
+	r := bpsReader{p: bytes.Repeat([]byte{0x7f}, 20)}
+	_, err := r.number()
+	test.ExpectFailure(t, err)
+
+	// a number larger than the maximum
+	r = bpsReader{p: bpsNumber(nil, bpsMaxNumber+1)}
+	_, err = r.number()
+	test.ExpectFailure(t, err)
+
+	// target size larger than the largest cartridge
+	original := make([]byte, 4096)
+	p := []byte(bpsHeader)
+	p = bpsNumber(p, len(original))
+	p = bpsNumber(p, bpsMaxTargetSize+1)
+	p = bpsNumber(p, 0)
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(original))
+	p = binary.LittleEndian.AppendUint32(p, 0)
+	p = binary.LittleEndian.AppendUint32(p, crc32.ChecksumIEEE(p))
+	_, err = bps(original, p)
+	test.ExpectFailure(t, err)
+}
@@ -32,4 +32,8 @@
 //
 // If the same random numbers are required every single time then set ZeroSeed
 // to true. This is useful for testing purposes.
+//
+// The seed can also be set explicitly with SetSeed(). Emulations with the same
+// seed will produce the same random numbers, which is useful when a random
+// startup state must be reproduced.
 package random
@@ -45,6 +45,9 @@ type Random struct {
 	// useful for normalised instances where random numbers must be predictable
 	ZeroSeed bool
 
+	// the seed for this instance. defaults to the base seed
+	seed int64
+
 	// standard Go random number generator for NoRewind()
 	nonRewindable rand.Source
 }
@@ -53,10 +56,22 @@ type Random struct {
 func NewRandom(tv TV) *Random {
 	return &Random{
 		tv:            tv,
+		seed:          baseSeed,
 		nonRewindable: rand.NewSource(baseSeed),
 	}
 }
 
+// SetSeed replaces the base seed for this instance. Instances with the same
+// seed will produce the same sequence of random numbers from both the
+// Rewindable() and NoRewind() functions.
+//
+// Note that the ZeroSeed field takes precedence over the seed in the case of
+// Rewindable().
+func (rnd *Random) SetSeed(seed int64) {
+	rnd.seed = seed
+	rnd.nonRewindable = rand.NewSource(seed)
+}
+
 // translate television coordinates into a single value
 func coordsSum(c coords.TelevisionCoords) int64 {
 	return int64(c.Frame*specification.AbsoluteMaxClks + c.Scanline*specification.ClksScanline + c.Clock)
@@ -77,7 +92,7 @@ func (rnd *Random) Rewindable(n int) int {
 
 	seed := coordsSum(rnd.tv.GetCoords())
 	if !rnd.ZeroSeed {
-		seed += baseSeed
+		seed += rnd.seed
 	}
 	seed *= seed
 	b := seed >> 32
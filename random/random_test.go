@@ -42,3 +42,15 @@ func TestRandom(t *testing.T) {
 		test.ExpectEquality(t, a.Rewindable(i), b.Rewindable(i))
 	}
 }
+
+func TestSeed(t *testing.T) {
+	a := random.NewRandom(&mockTV{})
+	b := random.NewRandom(&mockTV{})
+	a.SetSeed(1234)
+	b.SetSeed(1234)
+
+	for i := 1; i < 256; i++ {
+		test.ExpectEquality(t, a.NoRewind(i), b.NoRewind(i))
+		test.ExpectEquality(t, a.Rewindable(i), b.Rewindable(i))
+	}
+}
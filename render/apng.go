@@ -0,0 +1,257 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/jetsetilly/gopher2600/hardware/television"
+)
+
+// the standard library does not support APNG encoding. however, an APNG file is
+// a regular PNG file with some additional chunks. we therefore encode each
+// frame with the png package and then reassemble the chunks into an APNG file
+//
+// https://wiki.mozilla.org/APNG_Specification
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// split an encoded PNG file into chunks. the CRC values are not checked
+// because the data has come straight from the png encoder
+func splitPNG(b []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(b, []byte(pngSignature)) {
+		return nil, fmt.Errorf("apng: not a PNG file")
+	}
+	b = b[len(pngSignature):]
+
+	var chunks []pngChunk
+	for len(b) > 0 {
+		if len(b) < 12 {
+			return nil, fmt.Errorf("apng: truncated PNG chunk")
+		}
+		l := int(binary.BigEndian.Uint32(b))
+		if len(b) < 12+l {
+			return nil, fmt.Errorf("apng: truncated PNG chunk")
+		}
+		chunks = append(chunks, pngChunk{
+			typ:  string(b[4:8]),
+			data: b[8 : 8+l],
+		})
+		b = b[12+l:]
+	}
+
+	return chunks, nil
+}
+
+func writeChunk(w io.Writer, typ string, data []byte) error {
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(data)))
+	copy(hdr[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[4:])
+	crc.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	for _, b := range [][]byte{hdr[:], data, sum[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type apngFrame struct {
+	chunks []pngChunk
+	delay  uint16
+}
+
+// apngAnim collects frames and writes them as an animated PNG when the
+// emulation has ended.
+type apngAnim struct {
+	filename string
+	scale    int
+
+	// the crop of the first frame is used for all frames
+	crop image.Rectangle
+	size image.Point
+
+	// frames are stored as encoded PNG chunks because they are much smaller
+	// than the uncompressed image
+	frames []apngFrame
+}
+
+func newAPNGAnim(prefix string, scale int) *apngAnim {
+	filename := prefix
+	if filepath.Ext(filename) == "" {
+		filename = fmt.Sprintf("%s.png", filename)
+	}
+	return &apngAnim{
+		filename: filename,
+		scale:    scale,
+	}
+}
+
+func (anim *apngAnim) String() string {
+	return fmt.Sprintf("%d frames written to %s", len(anim.frames), anim.filename)
+}
+
+// the denominator of the frame delay fraction. the numerator is the delay
+// value stored in apngFrame
+const apngDelayDen = 1000
+
+func (anim *apngAnim) frame(img *image.RGBA, frameInfo television.FrameInfo) error {
+	if len(anim.frames) == 0 {
+		anim.crop = frameInfo.Crop()
+	}
+
+	src := prepare(img, anim.crop, anim.scale)
+	anim.size = src.Bounds().Size()
+
+	var b bytes.Buffer
+	err := png.Encode(&b, src)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := splitPNG(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	anim.frames = append(anim.frames, apngFrame{
+		chunks: chunks,
+		delay:  uint16(math.Round(apngDelayDen / float64(frameInfo.RefreshRate))),
+	})
+
+	return nil
+}
+
+func (anim *apngAnim) end() error {
+	if len(anim.frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+
+	f, err := os.Create(anim.filename)
+	if err != nil {
+		return err
+	}
+
+	err = anim.write(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (anim *apngAnim) write(f io.Writer) error {
+	w := bufio.NewWriter(f)
+
+	_, err := w.WriteString(pngSignature)
+	if err != nil {
+		return err
+	}
+
+	// the IHDR chunk from the first frame is used for the entire file. every
+	// frame is the same size and the png package will have chosen the same
+	// colour type for each frame because every pixel is opaque
+	for _, c := range anim.frames[0].chunks {
+		if c.typ == "IHDR" {
+			err = writeChunk(w, c.typ, c.data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// animation control chunk. number of frames and number of plays (zero
+	// meaning to loop forever)
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(anim.frames)))
+	binary.BigEndian.PutUint32(actl[4:], 0)
+	err = writeChunk(w, "acTL", actl)
+	if err != nil {
+		return err
+	}
+
+	// fcTL and fdAT chunks share the same sequence
+	var seq uint32
+
+	for i, fr := range anim.frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(anim.size.X))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(anim.size.Y))
+		binary.BigEndian.PutUint32(fctl[12:], 0) // x offset
+		binary.BigEndian.PutUint32(fctl[16:], 0) // y offset
+		binary.BigEndian.PutUint16(fctl[20:], fr.delay)
+		binary.BigEndian.PutUint16(fctl[22:], apngDelayDen)
+		fctl[24] = 0 // dispose op: none
+		fctl[25] = 0 // blend op: source
+		err = writeChunk(w, "fcTL", fctl)
+		if err != nil {
+			return err
+		}
+		seq++
+
+		for _, c := range fr.chunks {
+			if c.typ != "IDAT" {
+				continue
+			}
+
+			// the first frame is also the default image and uses IDAT
+			// chunks. all other frames use fdAT chunks
+			if i == 0 {
+				err = writeChunk(w, "IDAT", c.data)
+			} else {
+				fdat := make([]byte, 4+len(c.data))
+				binary.BigEndian.PutUint32(fdat, seq)
+				copy(fdat[4:], c.data)
+				err = writeChunk(w, "fdAT", fdat)
+				seq++
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = writeChunk(w, "IEND", nil)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
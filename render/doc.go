@@ -0,0 +1,28 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package render runs an emulation without a GUI and writes a range of
+// television frames to image files. No display is required.
+//
+// The frames can be written as a sequence of PNG files, as an animated GIF or
+// as an animated PNG (APNG). Input to the emulation can be provided by a
+// playback file created by the recorder package or by a macro file. See the
+// macro package for details about macro files.
+//
+// Images are cropped to the visible area of the television screen and the
+// width of each pixel is doubled to give a more natural aspect ratio. For
+// animated images the visible area of the first frame in the range is used for
+// every frame.
+package render
@@ -0,0 +1,127 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// gifAnim collects frames and writes them as an animated GIF when the
+// emulation has ended.
+type gifAnim struct {
+	filename string
+	scale    int
+
+	// the crop of the first frame is used for all frames
+	crop image.Rectangle
+
+	anim gif.GIF
+
+	// palette and palette lookup for the most recent specification
+	specID  string
+	palette color.Palette
+	lookup  map[color.RGBA]uint8
+}
+
+func newGIFAnim(prefix string, scale int) *gifAnim {
+	filename := prefix
+	if filepath.Ext(filename) == "" {
+		filename = fmt.Sprintf("%s.gif", filename)
+	}
+	return &gifAnim{
+		filename: filename,
+		scale:    scale,
+	}
+}
+
+func (anim *gifAnim) String() string {
+	return fmt.Sprintf("%d frames written to %s", len(anim.anim.Image), anim.filename)
+}
+
+// the specification palettes contain duplicate entries and are larger than the
+// maximum GIF palette size. this function creates a palette of unique colours
+// for the specification
+func (anim *gifAnim) setPalette(spec specification.Spec) {
+	if anim.specID == spec.ID {
+		return
+	}
+	anim.specID = spec.ID
+
+	anim.palette = color.Palette{specification.VideoBlack}
+	anim.lookup = map[color.RGBA]uint8{specification.VideoBlack: 0}
+
+	for _, col := range spec.Colors {
+		if _, ok := anim.lookup[col]; !ok {
+			anim.lookup[col] = uint8(len(anim.palette))
+			anim.palette = append(anim.palette, col)
+		}
+	}
+}
+
+func (anim *gifAnim) frame(img *image.RGBA, frameInfo television.FrameInfo) error {
+	if len(anim.anim.Image) == 0 {
+		anim.crop = frameInfo.Crop()
+	}
+
+	anim.setPalette(frameInfo.Spec)
+
+	src := prepare(img, anim.crop, anim.scale)
+	dst := image.NewPaletted(src.Bounds(), anim.palette)
+	for i := range dst.Pix {
+		s := src.Pix[i*4 : i*4+4 : i*4+4]
+		dst.Pix[i] = anim.lookup[color.RGBA{R: s[0], G: s[1], B: s[2], A: s[3]}]
+	}
+
+	// GIF delay is measured in 100ths of a second. the refresh rate of the
+	// television will not be matched exactly
+	delay := int(math.Round(100.0 / float64(frameInfo.RefreshRate)))
+	if delay < 2 {
+		delay = 2
+	}
+
+	anim.anim.Image = append(anim.anim.Image, dst)
+	anim.anim.Delay = append(anim.anim.Delay, delay)
+
+	return nil
+}
+
+func (anim *gifAnim) end() error {
+	if len(anim.anim.Image) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+
+	f, err := os.Create(anim.filename)
+	if err != nil {
+		return err
+	}
+
+	err = gif.EncodeAll(f, &anim.anim)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,74 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/hardware/television"
+)
+
+// writePNG encodes the image to the named file.
+func writePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(f, img)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// pngSequence writes every frame to a separate PNG file. The frame number is
+// used in the filename.
+type pngSequence struct {
+	prefix string
+	scale  int
+	count  int
+}
+
+func newPNGSequence(prefix string, scale int) *pngSequence {
+	return &pngSequence{
+		prefix: prefix,
+		scale:  scale,
+	}
+}
+
+func (seq *pngSequence) String() string {
+	return fmt.Sprintf("%d frames written to %s_*.png", seq.count, seq.prefix)
+}
+
+func (seq *pngSequence) frame(img *image.RGBA, frameInfo television.FrameInfo) error {
+	filename := fmt.Sprintf("%s_%06d.png", seq.prefix, frameInfo.FrameNum)
+	err := writePNG(filename, prepare(img, frameInfo.Crop(), seq.scale))
+	if err != nil {
+		return err
+	}
+	seq.count++
+	return nil
+}
+
+func (seq *pngSequence) end() error {
+	return nil
+}
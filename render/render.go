@@ -0,0 +1,208 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/headless"
+	"github.com/jetsetilly/gopher2600/macro"
+	"github.com/jetsetilly/gopher2600/resources/unique"
+	"github.com/jetsetilly/gopher2600/userinput"
+)
+
+// Format specifies the type of image file created by Render().
+type Format string
+
+// List of valid Format values.
+const (
+	FormatPNG  Format = "PNG"
+	FormatGIF  Format = "GIF"
+	FormatAPNG Format = "APNG"
+)
+
+// FormatList is the list of formats accepted by ParseFormat().
+var FormatList = []string{string(FormatPNG), string(FormatGIF), string(FormatAPNG)}
+
+// ParseFormat converts a string to a Format value. The string is not case
+// sensitive.
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToUpper(s)) {
+	case FormatPNG:
+		return FormatPNG, nil
+	case FormatGIF:
+		return FormatGIF, nil
+	case FormatAPNG:
+		return FormatAPNG, nil
+	}
+	return "", fmt.Errorf("render: unknown format (%s)", s)
+}
+
+// Options for the Render() function.
+type Options struct {
+	// cartridge mapping and television specification. both fields are
+	// ignored if a playback file is being used
+	Mapping string
+	Spec    string
+
+	Format Format
+
+	// the prefix for all files created by Render(). if the field is empty
+	// then a unique prefix will be created from the name of the cartridge
+	Output string
+
+	// the range of frames to render. the range is inclusive so if From and To
+	// are the same value then exactly one frame will be rendered
+	From int
+	To   int
+
+	// the amount to scale each pixel by. the width of each pixel is always
+	// doubled in addition to this scaling value
+	Scale int
+
+	// the name of a playback file or a macro file to drive the input of the
+	// emulation. only one of these can be used at once
+	Playback string
+	Macro    string
+}
+
+// emulation is a minimal implementation of the macro.Emulation interface.
+type emulation struct {
+	vcs       *hardware.VCS
+	userInput chan userinput.Event
+}
+
+// UserInput implements the macro.Emulation interface.
+func (em *emulation) UserInput() chan userinput.Event {
+	return em.userInput
+}
+
+// VCS implements the macro.Emulation interface.
+func (em *emulation) VCS() *hardware.VCS {
+	return em.vcs
+}
+
+// Render the frames of a cartridge emulation as described by the Options
+// argument. Progress information is written to the output argument.
+//
+// The filename argument can be empty if a playback file is specified in the
+// options. In that case the cartridge named in the playback file is used.
+func Render(output io.Writer, filename string, opts Options) error {
+	if opts.From < 0 || opts.To < opts.From {
+		return fmt.Errorf("render: invalid frame range (%d to %d)", opts.From, opts.To)
+	}
+	if opts.Scale < 1 {
+		return fmt.Errorf("render: invalid scaling value (%d)", opts.Scale)
+	}
+	if opts.Playback != "" && opts.Macro != "" {
+		return fmt.Errorf("render: cannot use playback and macro files at the same time")
+	}
+
+	rnd := newRenderer(opts)
+
+	em, err := headless.NewEmulation(filename, headless.Options{
+		Mapping:  opts.Mapping,
+		Spec:     opts.Spec,
+		Playback: opts.Playback,
+
+		// the timing of macro instructions is measured in frames but the
+		// macro itself runs independently of the emulation. a macro is
+		// therefore only reliable if the emulation is running at the normal
+		// speed
+		FPSCap: opts.Macro != "",
+	})
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+	defer em.End()
+
+	em.TV.AddPixelRenderer(rnd)
+
+	prefix := opts.Output
+	if prefix == "" {
+		prefix = unique.Filename("render", em.Cartridge.Name)
+	}
+
+	switch opts.Format {
+	case FormatPNG:
+		rnd.out = newPNGSequence(prefix, opts.Scale)
+	case FormatGIF:
+		rnd.out = newGIFAnim(prefix, opts.Scale)
+	case FormatAPNG:
+		rnd.out = newAPNGAnim(prefix, opts.Scale)
+	default:
+		return fmt.Errorf("render: unknown format (%s)", opts.Format)
+	}
+	rnd.prefix = prefix
+
+	// the quit channel will remain nil if there is no macro
+	var quit chan userinput.Event
+
+	if opts.Macro != "" {
+		mcrEm := &emulation{
+			vcs:       em.VCS,
+			userInput: make(chan userinput.Event, 1),
+		}
+		quit = mcrEm.userInput
+
+		mcr, err := macro.NewMacro(opts.Macro, mcrEm, em.VCS.Input, em.TV, rnd)
+		if err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+		mcr.Run()
+		defer mcr.Quit()
+	}
+
+	output.Write([]byte(fmt.Sprintf("rendering frames %d to %d of %s\n", opts.From, opts.To, em.Cartridge.Name)))
+
+	err = em.Run(func() (govern.State, error) {
+		// if the CPU is in the KIL state then the emulation will never
+		// reach the end of the frame range
+		if em.VCS.CPU.Killed {
+			return govern.Ending, fmt.Errorf("CPU in KIL state")
+		}
+
+		select {
+		case ev := <-quit:
+			if _, ok := ev.(userinput.EventQuit); ok {
+				return govern.Ending, nil
+			}
+		default:
+		}
+
+		if em.TV.GetCoords().Frame > opts.To {
+			return govern.Ending, nil
+		}
+
+		return govern.Running, nil
+	})
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+
+	err = rnd.out.end()
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+
+	output.Write([]byte(fmt.Sprintf("%s\n", rnd.out)))
+
+	return nil
+}
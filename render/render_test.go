@@ -0,0 +1,119 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render_test
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+	"github.com/jetsetilly/gopher2600/render"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// a 4k cartridge with a standard NTSC frame. the background colour is set to
+// a different value for the top and bottom halves of the screen
+func cartridge() []uint8 {
+	data := make([]uint8, 4096)
+	copy(data, []uint8{
+		0x78,       // SEI
+		0xd8,       // CLD
+		0xa9, 0x02, // LDA #$02 (frame)
+		0x85, 0x01, // STA VBLANK
+		0x85, 0x00, // STA VSYNC
+		0x85, 0x02, // STA WSYNC
+		0x85, 0x02, // STA WSYNC
+		0x85, 0x02, // STA WSYNC
+		0xa9, 0x00, // LDA #$00
+		0x85, 0x00, // STA VSYNC
+		0xa2, 0x24, // LDX #36
+		0x85, 0x02, // STA WSYNC (vblank)
+		0xca,       // DEX
+		0xd0, 0xfb, // BNE vblank
+		0xa9, 0x1e, // LDA #$1e
+		0x85, 0x09, // STA COLUBK
+		0xa9, 0x00, // LDA #$00
+		0x85, 0x01, // STA VBLANK
+		0xa2, 0x60, // LDX #96
+		0x85, 0x02, // STA WSYNC (top)
+		0xca,       // DEX
+		0xd0, 0xfb, // BNE top
+		0xa9, 0x84, // LDA #$84
+		0x85, 0x09, // STA COLUBK
+		0xa2, 0x60, // LDX #96
+		0x85, 0x02, // STA WSYNC (bottom)
+		0xca,       // DEX
+		0xd0, 0xfb, // BNE bottom
+		0xa9, 0x02, // LDA #$02
+		0x85, 0x01, // STA VBLANK
+		0xa2, 0x1e, // LDX #30
+		0x85, 0x02, // STA WSYNC (overscan)
+		0xca,       // DEX
+		0xd0, 0xfb, // BNE overscan
+		0x4c, 0x02, 0xf0, // JMP frame
+	})
+	data[0xffc] = 0x00
+	data[0xffd] = 0xf0
+	return data
+}
+
+func TestRenderPNG(t *testing.T) {
+	dir := t.TempDir()
+
+	rom := filepath.Join(dir, "test.bin")
+	test.ExpectSuccess(t, os.WriteFile(rom, cartridge(), 0o600))
+
+	prefix := filepath.Join(dir, "frame")
+
+	var output strings.Builder
+	err := render.Render(&output, rom, render.Options{
+		Mapping: "4K",
+		Spec:    "NTSC",
+		Format:  render.FormatPNG,
+		Output:  prefix,
+		From:    10,
+		To:      10,
+		Scale:   1,
+	})
+	test.ExpectSuccess(t, err)
+
+	f, err := os.Open(prefix + "_000010.png")
+	test.ExpectSuccess(t, err)
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	test.ExpectSuccess(t, err)
+
+	// the width of each pixel is doubled
+	sz := img.Bounds().Size()
+	test.ExpectEquality(t, sz.X, specification.ClksVisible*2)
+	test.ExpectEquality(t, sz.Y, specification.SpecNTSC.AtariSafeVisibleBottom-specification.SpecNTSC.AtariSafeVisibleTop+1)
+
+	top := specification.SpecNTSC.GetColor(0x1e)
+	bottom := specification.SpecNTSC.GetColor(0x84)
+	at := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	}
+	test.ExpectEquality(t, at(0, sz.Y/4), top)
+	test.ExpectEquality(t, at(sz.X-1, sz.Y/4), top)
+	test.ExpectEquality(t, at(0, sz.Y*3/4), bottom)
+	test.ExpectEquality(t, at(sz.X-1, sz.Y*3/4), bottom)
+	test.ExpectInequality(t, top, bottom)
+}
@@ -0,0 +1,191 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/jetsetilly/gopher2600/gui"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the width of each pixel in the output image before scaling. the same value
+// as used by the GUI
+const pixelWidth = 2
+
+// output implementations write frames to an image file or files.
+type output interface {
+	// frame is called for every frame in the requested range. the image will
+	// be a complete television frame that has not been cropped
+	frame(img *image.RGBA, frameInfo television.FrameInfo) error
+
+	// end is called when the emulation has finished
+	end() error
+
+	// a summary of the frames written
+	String() string
+}
+
+// renderer is an implementation of the television.PixelRenderer interface.
+type renderer struct {
+	frameInfo television.FrameInfo
+	img       *image.RGBA
+
+	from int
+	to   int
+
+	out    output
+	prefix string
+	scale  int
+
+	// screenshot requests made by a macro
+	screenshot chan string
+}
+
+func newRenderer(opts Options) *renderer {
+	rnd := &renderer{
+		img:        image.NewRGBA(image.Rect(0, 0, specification.ClksScanline, specification.AbsoluteMaxScanlines)),
+		from:       opts.From,
+		to:         opts.To,
+		scale:      opts.Scale,
+		screenshot: make(chan string, 1),
+	}
+	rnd.Reset()
+	return rnd
+}
+
+// SetFeature implements the macro.GUI interface. The only request that is
+// handled is the ReqScreenshot request. The next frame to be completed will be
+// saved as a PNG file.
+func (rnd *renderer) SetFeature(request gui.FeatureReq, args ...gui.FeatureReqData) error {
+	if request != gui.ReqScreenshot {
+		return nil
+	}
+
+	var suffix string
+	if len(args) > 0 {
+		suffix, _ = args[0].(string)
+	}
+
+	select {
+	case rnd.screenshot <- suffix:
+	default:
+	}
+
+	return nil
+}
+
+// NewFrame implements the television.PixelRenderer interface
+func (rnd *renderer) NewFrame(frameInfo television.FrameInfo) error {
+	rnd.frameInfo = frameInfo
+
+	select {
+	case suffix := <-rnd.screenshot:
+		var filename string
+		if suffix == "" {
+			filename = fmt.Sprintf("%s_screenshot_%06d.png", rnd.prefix, frameInfo.FrameNum)
+		} else {
+			filename = fmt.Sprintf("%s_%s.png", rnd.prefix, suffix)
+		}
+		err := writePNG(filename, prepare(rnd.img, frameInfo.Crop(), rnd.scale))
+		if err != nil {
+			return err
+		}
+	default:
+	}
+
+	if frameInfo.FrameNum < rnd.from || frameInfo.FrameNum > rnd.to {
+		return nil
+	}
+
+	return rnd.out.frame(rnd.img, frameInfo)
+}
+
+// NewScanline implements the television.PixelRenderer interface
+func (rnd *renderer) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer interface
+func (rnd *renderer) SetPixels(sig []signal.SignalAttributes, last int) error {
+	var col color.RGBA
+	var offset int
+
+	for i := range sig {
+		// handle VBLANK by setting pixels to black
+		if sig[i]&signal.VBlank == signal.VBlank {
+			col = specification.VideoBlack
+		} else {
+			px := signal.ColorSignal((sig[i] & signal.Color) >> signal.ColorShift)
+			col = rnd.frameInfo.Spec.GetColor(px)
+		}
+
+		// small cap improves performance, see https://golang.org/issue/27857
+		s := rnd.img.Pix[offset : offset+3 : offset+3]
+		s[0] = col.R
+		s[1] = col.G
+		s[2] = col.B
+
+		offset += 4
+	}
+	return nil
+}
+
+// Reset implements the television.PixelRenderer interface
+func (rnd *renderer) Reset() {
+	rnd.frameInfo = television.NewFrameInfo(specification.SpecNTSC)
+
+	// clear pixels. setting the alpha channel so we don't have to later (the
+	// alpha channel never changes)
+	for y := 0; y < rnd.img.Bounds().Size().Y; y++ {
+		for x := 0; x < rnd.img.Bounds().Size().X; x++ {
+			rnd.img.SetRGBA(x, y, specification.VideoBlack)
+		}
+	}
+}
+
+// EndRendering implements the television.PixelRenderer interface
+func (rnd *renderer) EndRendering() error {
+	return nil
+}
+
+// prepare returns a new image that has been cropped and scaled according to
+// the supplied arguments.
+func prepare(img *image.RGBA, crop image.Rectangle, scale int) *image.RGBA {
+	crop = crop.Intersect(img.Bounds())
+	sz := crop.Size()
+
+	sx := scale * pixelWidth
+	sy := scale
+
+	dst := image.NewRGBA(image.Rect(0, 0, sz.X*sx, sz.Y*sy))
+	for y := 0; y < sz.Y; y++ {
+		for x := 0; x < sz.X; x++ {
+			col := img.RGBAAt(crop.Min.X+x, crop.Min.Y+y)
+			for j := 0; j < sy; j++ {
+				for i := 0; i < sx; i++ {
+					dst.SetRGBA(x*sx+i, y*sy+j, col)
+				}
+			}
+		}
+	}
+
+	return dst
+}
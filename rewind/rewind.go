@@ -198,6 +198,16 @@ func (r *Rewind) Reset() {
 	r.timeline.reset()
 }
 
+// ResetBoundary is similar to Reset() except that the first entry is a
+// boundary rather than a reset. Resets timeline too.
+//
+// This should be called when the state of the emulation has been replaced
+// without the VCS having been reset. For example, after loading a state file.
+func (r *Rewind) ResetBoundary() {
+	r.reset(levelBoundary)
+	r.timeline.reset()
+}
+
 // reset rewind system and use the specified snapshotLevel for the first entry.
 // this will usually be levelReset but levelBoundary is also a sensible value.
 //
@@ -659,6 +669,16 @@ func (r *Rewind) GetState(frame int) *State {
 	return s.snapshot()
 }
 
+// GetFrameState returns a copy of the state recorded at the start of the
+// indicated frame. If there is no state for that frame then the nearest state
+// in the history is returned. The frame of the returned state can be checked
+// with TV.GetCoords().
+func (r *Rewind) GetFrameState(frame int) *State {
+	res := r.findFrameIndexExact(frame)
+	s := r.entries[res.nearestIdx]
+	return s.snapshot()
+}
+
 // GetCurrentState returns a temporary snapshot of the current state.
 func (r *Rewind) GetCurrentState() *State {
 	return r.snapshot(levelTemporary)
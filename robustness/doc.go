@@ -0,0 +1,41 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package robustness tests whether a cartridge behaves the same regardless of
+// the state of the console at power on.
+//
+// On real hardware the contents of RAM, the CPU registers and the RIOT timer
+// are indeterminate at power on. A ROM that depends on that state, for example
+// by reading RAM before it has been initialised, may work in one emulator and
+// fail on real hardware.
+//
+// The Run() function starts the cartridge several times with the random state
+// preference enabled, each time with a different random seed. The first seed
+// is the reference. The video output of every other emulation is compared
+// with the video output of the reference emulation, frame by frame, using the
+// digest.Video type. Frames before the television has stabilised are not
+// compared.
+//
+// If a playback file is used then the video is also checked against the
+// recording in the playback file. Because the playback file was recorded
+// without a random startup state, even the reference seed can diverge in this
+// way.
+//
+// When the video output diverges, the RAM at the end of the diverging frame
+// is compared with the RAM of the reference emulation. Only RAM addresses that
+// have been accessed by the 6507 program in either emulation are compared.
+// Addresses that have never been accessed will differ between seeds but are
+// not interesting.
+package robustness
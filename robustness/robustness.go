@@ -0,0 +1,396 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package robustness
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/digest"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/memorymap"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/headless"
+	"github.com/jetsetilly/gopher2600/recorder"
+)
+
+// Default values used by Run() if the corresponding field in the Options type
+// is zero.
+const (
+	DefaultFrames = 600
+	DefaultSeeds  = 16
+)
+
+// Options for the Run() function.
+type Options struct {
+	// cartridge mapping and television specification. both fields are
+	// ignored if a playback file is being used
+	Mapping string
+	Spec    string
+
+	// the number of frames to run. if the value is zero then the emulation
+	// runs for DefaultFrames, or until the end of the playback file
+	Frames int
+
+	// the name of a playback file to drive the input of the emulation
+	Playback string
+
+	// the number of seeds to test and the value of the first seed. seeds are
+	// consecutive and the first seed is the reference seed. if the number of
+	// seeds is zero then DefaultSeeds is used
+	Seeds     int
+	FirstSeed int64
+}
+
+// Divergence describes how the emulation started with a seed differs from the
+// emulation started with the reference seed.
+type Divergence struct {
+	Seed int64
+
+	// the first frame in which the video output differs
+	Frame int
+
+	// the emulation ended before the reference emulation. this can happen if
+	// the CPU encounters a KIL instruction
+	Ended bool
+
+	// the video output differs from the video recorded in the playback file.
+	// the reference seed can also diverge in this way
+	Playback bool
+
+	// whether any RAM differs at the end of the frame. if it does then the
+	// first RAM address that differs is given along with the value in the
+	// reference emulation and in the diverging emulation
+	RAM       bool
+	Address   uint16
+	Reference uint8
+	Value     uint8
+}
+
+func (d Divergence) String() string {
+	var s string
+	switch {
+	case d.Ended:
+		s = fmt.Sprintf("seed %d: emulation ended at frame %d", d.Seed, d.Frame)
+	case d.Playback:
+		s = fmt.Sprintf("seed %d: video diverges from playback at frame %d", d.Seed, d.Frame)
+	default:
+		s = fmt.Sprintf("seed %d: video diverges at frame %d", d.Seed, d.Frame)
+	}
+	if d.RAM {
+		s = fmt.Sprintf("%s: RAM %#04x is %#02x (reference %#02x)", s, d.Address, d.Value, d.Reference)
+	}
+	return s
+}
+
+// Result of the Run() function.
+type Result struct {
+	// the name of the cartridge
+	Cartridge string
+
+	// all seeds tested. the first seed is the reference seed
+	Seeds []int64
+
+	// the number of frames compared
+	Frames int
+
+	// every seed that diverges, in the order in which they were tested
+	Divergences []Divergence
+}
+
+// WriteReport writes a summary of the result to the io.Writer.
+func (res *Result) WriteReport(w io.Writer) error {
+	for _, d := range res.Divergences {
+		_, err := fmt.Fprintf(w, "%s\n", d)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d of %d seeds diverge over %d frames\n", len(res.Divergences), len(res.Seeds), res.Frames)
+	return err
+}
+
+// the state of an emulation at the end of a frame
+type frame struct {
+	num  int
+	hash string
+	ram  []uint8
+}
+
+// emulation is a single run of the cartridge with a seed. it implements the
+// television.FrameTrigger interface
+type emulation struct {
+	vcs *hardware.VCS
+	dig *digest.Video
+
+	// the name of the cartridge
+	name string
+
+	// the frame in which each RAM address was first accessed by the CPU. the
+	// value is -1 if the address has not been accessed
+	accessed []int
+
+	// called at the end of every stable frame. returns false if the emulation
+	// should stop
+	onFrame func(em *emulation, fr frame) bool
+	stop    bool
+
+	// the video output has diverged from the playback file. the frame field
+	// is the frame in which the emulation ended
+	playback bool
+	frame    int
+}
+
+// NewFrame implements the television.FrameTrigger interface.
+func (em *emulation) NewFrame(info television.FrameInfo) error {
+	// the video digest is chained by default. resetting the digest at the
+	// end of every frame means that each frame can be compared individually
+	defer em.dig.ResetDigest()
+
+	// the frames before the television has stabilised are not shown on a
+	// real television and are not compared
+	if !info.Stable {
+		return nil
+	}
+
+	fr := frame{
+		num:  info.FrameNum,
+		hash: em.dig.Hash(),
+		ram:  make([]uint8, len(em.vcs.Mem.RAM.RAM)),
+	}
+	copy(fr.ram, em.vcs.Mem.RAM.RAM)
+
+	if !em.onFrame(em, fr) {
+		em.stop = true
+	}
+	return nil
+}
+
+// whether the RAM address (as an index into RAM) had been accessed by the end
+// of the frame
+func (em *emulation) wasAccessed(idx int, frame int) bool {
+	return em.accessed[idx] >= 0 && em.accessed[idx] <= frame
+}
+
+// note the most recent memory access made by the CPU if it was to RAM
+func (em *emulation) access() {
+	ma, area := memorymap.MapAddress(em.vcs.Mem.LastCPUAddressMapped, !em.vcs.Mem.LastCPUWrite)
+	if area != memorymap.RAM {
+		return
+	}
+	idx := int(ma - memorymap.OriginRAM)
+	if em.accessed[idx] < 0 {
+		em.accessed[idx] = em.vcs.TV.GetCoords().Frame
+	}
+}
+
+// run the cartridge with the seed. the onFrame function is called at the end
+// of every stable frame
+func run(filename string, opts Options, seed int64, onFrame func(em *emulation, fr frame) bool) (*emulation, error) {
+	hl, err := headless.NewEmulation(filename, headless.Options{
+		Mapping:  opts.Mapping,
+		Spec:     opts.Spec,
+		Playback: opts.Playback,
+
+		// the random state must be set before the cartridge is attached
+		// because attaching the cartridge resets the VCS
+		Prepare: func(vcs *hardware.VCS) error {
+			vcs.Env.Prefs.RandomState.Set(true)
+			vcs.Env.Random.SetSeed(seed)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer hl.End()
+
+	dig, err := digest.NewVideo(hl.TV)
+	if err != nil {
+		return nil, err
+	}
+
+	em := &emulation{
+		vcs:      hl.VCS,
+		dig:      dig,
+		name:     hl.Cartridge.Name,
+		accessed: make([]int, len(hl.VCS.Mem.RAM.RAM)),
+		onFrame:  onFrame,
+	}
+	for i := range em.accessed {
+		em.accessed[i] = -1
+	}
+	hl.TV.AddFrameTrigger(em)
+
+	err = hl.Run(func() (govern.State, error) {
+		em.access()
+
+		if em.stop {
+			return govern.Ending, nil
+		}
+
+		return hl.Continue(opts.Frames)
+	})
+
+	em.frame = hl.TV.GetCoords().Frame
+
+	if err != nil {
+		if !errors.Is(err, recorder.PlaybackHashError) {
+			return nil, err
+		}
+
+		// the video no longer matches the playback file. the input in the
+		// playback file can no longer be relied upon so the emulation ends
+		// here
+		em.playback = true
+	}
+
+	return em, nil
+}
+
+// Run the cartridge with every seed described by the Options argument and
+// compare the video output of each emulation with the reference emulation.
+// Progress information is written to the output argument.
+//
+// The filename argument can be empty if a playback file is specified in the
+// options. In that case the cartridge named in the playback file is used.
+func Run(output io.Writer, filename string, opts Options) (*Result, error) {
+	if opts.Frames < 0 {
+		return nil, fmt.Errorf("robustness: invalid number of frames (%d)", opts.Frames)
+	}
+	if opts.Seeds < 0 {
+		return nil, fmt.Errorf("robustness: invalid number of seeds (%d)", opts.Seeds)
+	}
+	if opts.Seeds == 0 {
+		opts.Seeds = DefaultSeeds
+	}
+	if opts.Seeds < 2 {
+		return nil, fmt.Errorf("robustness: at least two seeds are required")
+	}
+	if opts.Frames == 0 && opts.Playback == "" {
+		opts.Frames = DefaultFrames
+	}
+
+	res := &Result{}
+	for i := 0; i < opts.Seeds; i++ {
+		res.Seeds = append(res.Seeds, opts.FirstSeed+int64(i))
+	}
+
+	// the reference emulation. frames are indexed by frame number
+	var reference []frame
+	index := make(map[int]int)
+
+	output.Write([]byte(fmt.Sprintf("running reference seed %d\n", res.Seeds[0])))
+
+	ref, err := run(filename, opts, res.Seeds[0], func(_ *emulation, fr frame) bool {
+		index[fr.num] = len(reference)
+		reference = append(reference, fr)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("robustness: %w", err)
+	}
+
+	res.Cartridge = ref.name
+	res.Frames = len(reference)
+
+	if ref.playback {
+		res.Divergences = append(res.Divergences, Divergence{
+			Seed:     res.Seeds[0],
+			Frame:    ref.frame,
+			Playback: true,
+		})
+	}
+
+	for _, seed := range res.Seeds[1:] {
+		output.Write([]byte(fmt.Sprintf("running seed %d\n", seed)))
+
+		var div *Divergence
+
+		// compare RAM at the end of the frame with the reference. only RAM
+		// that has been accessed by either emulation is compared
+		compareRAM := func(em *emulation, fr frame) {
+			i, ok := index[fr.num]
+			if !ok {
+				return
+			}
+			for a := range fr.ram {
+				if !em.wasAccessed(a, fr.num) && !ref.wasAccessed(a, fr.num) {
+					continue // for loop
+				}
+				if fr.ram[a] != reference[i].ram[a] {
+					div.RAM = true
+					div.Address = memorymap.OriginRAM + uint16(a)
+					div.Reference = reference[i].ram[a]
+					div.Value = fr.ram[a]
+					return
+				}
+			}
+		}
+
+		// the most recent frame that was compared with the reference
+		var last frame
+		compared := -1
+
+		em, err := run(filename, opts, seed, func(em *emulation, fr frame) bool {
+			i, ok := index[fr.num]
+			if !ok {
+				return true
+			}
+			last = fr
+			compared = i
+
+			if fr.hash != reference[i].hash {
+				div = &Divergence{
+					Seed:  seed,
+					Frame: fr.num,
+				}
+				compareRAM(em, fr)
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("robustness: %w", err)
+		}
+
+		if div == nil {
+			if em.playback {
+				div = &Divergence{
+					Seed:     seed,
+					Frame:    em.frame,
+					Playback: true,
+				}
+				compareRAM(em, last)
+			} else if compared < len(reference)-1 {
+				div = &Divergence{
+					Seed:  seed,
+					Frame: em.frame,
+					Ended: true,
+				}
+				compareRAM(em, last)
+			}
+		}
+
+		if div != nil {
+			res.Divergences = append(res.Divergences, *div)
+		}
+	}
+
+	return res, nil
+}
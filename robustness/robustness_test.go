@@ -0,0 +1,113 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package robustness_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/robustness"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+// create a 4k ROM that produces a stable 262 scanline frame. the background
+// color is loaded with the specified opcode and operand
+func createROM(t *testing.T, opcode uint8, operand uint8) string {
+	t.Helper()
+
+	program := []uint8{
+		0x78,       // SEI
+		0xd8,       // CLD
+		0xa2, 0xff, // LDX #$ff
+		0x9a,       // TXS
+		0x85, 0x02, // STA WSYNC
+		0xa9, 0x02, // LDA #2
+		0x85, 0x00, // STA VSYNC
+		0x85, 0x02, // STA WSYNC
+		0x85, 0x02, // STA WSYNC
+		0x85, 0x02, // STA WSYNC
+		0xa9, 0x00, // LDA #0
+		0x85, 0x00, // STA VSYNC
+		opcode, operand,
+		0x85, 0x09, // STA COLUBK
+		0xa2, 0x00, // LDX #0
+		0x85, 0x02, // STA WSYNC
+		0xca,       // DEX
+		0xd0, 0xfb, // BNE
+		0x85, 0x02, // STA WSYNC
+		0x85, 0x02, // STA WSYNC
+		0x4c, 0x05, 0xf0, // JMP $f005
+	}
+
+	rom := make([]uint8, 4096)
+	copy(rom, program)
+
+	// reset vector
+	rom[0xffc] = 0x00
+	rom[0xffd] = 0xf0
+
+	filename := filepath.Join(t.TempDir(), "robustness.bin")
+	test.ExpectSuccess(t, os.WriteFile(filename, rom, 0644))
+
+	return filename
+}
+
+func TestRobust(t *testing.T) {
+	// LDA #$90
+	filename := createROM(t, 0xa9, 0x90)
+
+	res, err := robustness.Run(io.Discard, filename, robustness.Options{
+		Mapping:   "AUTO",
+		Spec:      "NTSC",
+		Frames:    10,
+		Seeds:     4,
+		FirstSeed: 100,
+	})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(res.Seeds), 4)
+	test.ExpectSuccess(t, res.Frames > 0)
+	test.ExpectEquality(t, len(res.Divergences), 0)
+}
+
+func TestUninitialisedRAM(t *testing.T) {
+	// LDA $90
+	filename := createROM(t, 0xa5, 0x90)
+
+	res, err := robustness.Run(io.Discard, filename, robustness.Options{
+		Mapping:   "AUTO",
+		Spec:      "NTSC",
+		Frames:    10,
+		Seeds:     4,
+		FirstSeed: 100,
+	})
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, len(res.Divergences), 3)
+
+	for _, d := range res.Divergences {
+		// the background color is set every frame so the video diverges in
+		// the first frame that is compared
+		test.ExpectEquality(t, d.Frame, res.Divergences[0].Frame)
+		test.ExpectSuccess(t, d.Frame < 10)
+		test.ExpectEquality(t, d.Ended, false)
+		test.ExpectEquality(t, d.RAM, true)
+
+		// RAM address $80 will also differ but it is never accessed by the
+		// program
+		test.ExpectEquality(t, d.Address, uint16(0x90))
+	}
+}
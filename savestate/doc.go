@@ -0,0 +1,36 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package savestate writes the state of the emulation to disk and reads it
+// back again. A state file contains everything that would be in a
+// rewind.State: the CPU, RIOT, TIA, cartridge mapper and the television.
+//
+// The ROM data is not stored in the state file. Instead, the hash of the
+// cartridge is stored in the header of the file and Load() will refuse to
+// load a state file for a cartridge other than the one currently attached.
+//
+// The state is serialised by walking the snapshot produced by the Snapshot()
+// functions of the hardware packages. Only those parts of the snapshot that
+// the rewind system considers to be part of the state are serialised. This
+// means that the package does not need updating when new cartridge mappers
+// are added, provided they implement Snapshot() and Plumb() correctly.
+//
+// However, the format of a state file is tied to the layout of the types in
+// the hardware packages. A change to those types will invalidate existing
+// state files. To detect this, a schema describing the name and type of every
+// serialised field is stored with the state. The Load() function will return
+// the IncompatibleState error if the schema does not match the current
+// layout, rather than plumbing in a nonsense state.
+package savestate
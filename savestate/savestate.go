@@ -0,0 +1,295 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package savestate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/cpu/instructions"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+	"github.com/jetsetilly/gopher2600/resources"
+	"github.com/jetsetilly/gopher2600/resources/fs"
+	"github.com/jetsetilly/gopher2600/resources/unique"
+	"github.com/jetsetilly/gopher2600/rewind"
+)
+
+// state file header format
+// ------------------------
+//
+// <magic string>
+// <version string>
+// <cartridge name>
+// <cartridge hash>
+// <tv type on startup>
+//
+// the header is followed by the gzip compressed state of the emulation.
+
+const (
+	lineMagicString int = iota
+	lineVersion
+	lineCartName
+	lineCartHash
+	lineTVSpec
+	numHeaderLines
+)
+
+const magicString = "gopher2600state"
+const versionMajor = "1"
+const versionMinor = "1"
+
+// version history
+// v1.0 original version
+// v1.1 schema of serialised types
+
+func version() string {
+	return fmt.Sprintf("%s.%s", versionMajor, versionMinor)
+}
+
+// FileExtension is the extension given to state files created by the
+// Filename() and QuickFilename() functions.
+const FileExtension = ".state"
+
+// the maximum size of the uncompressed state. the state of even the largest
+// cartridge types is a fraction of this.
+const maxStateSize = 64 * 1024 * 1024
+
+// the directory in the resources folder in which state files are stored if
+// they are not given an explicit path.
+const statesPath = "states"
+
+// Sentinal errors returned by Load().
+var NotAStateFile = errors.New("not a state file")
+var UnsupportedVersion = errors.New("unsupported version")
+var WrongCartridge = errors.New("state file is for a different cartridge")
+var IncompatibleState = errors.New("state file is incompatible with this version of the emulator")
+
+// Header is the information stored at the beginning of a state file.
+type Header struct {
+	Version   string
+	Cartridge string
+	Hash      string
+	TVSpec    string
+}
+
+// Filename returns a unique filename for a new state file for the named
+// cartridge. The path will be in the resources directory.
+func Filename(cartname string) (string, error) {
+	fn := fmt.Sprintf("%s%s", unique.Filename("state", cartname), FileExtension)
+	pth, err := resources.JoinPath(statesPath, fn)
+	if err != nil {
+		return "", fmt.Errorf("savestate: %w", err)
+	}
+	return pth, nil
+}
+
+// QuickFilename returns the filename of the state file used for quick saving
+// and loading. There is one quick save file for each cartridge.
+func QuickFilename(hash string) (string, error) {
+	fn := fmt.Sprintf("quick_%s%s", hash, FileExtension)
+	pth, err := resources.JoinPath(statesPath, fn)
+	if err != nil {
+		return "", fmt.Errorf("savestate: %w", err)
+	}
+	return pth, nil
+}
+
+// Save the state of the VCS to the named file. The state should be saved on a
+// CPU instruction boundary.
+func Save(vcs *hardware.VCS, filename string) (rerr error) {
+	if vcs.Mem.Cart.IsEjected() {
+		return fmt.Errorf("savestate: no cartridge attached")
+	}
+
+	// two snapshots are required by the serialiser. see commentary in
+	// serialise.go for the reason why
+	state := &rewind.State{VCS: vcs.Snapshot(), TV: vcs.TV.Snapshot()}
+	ref := &rewind.State{VCS: vcs.Snapshot(), TV: vcs.TV.Snapshot()}
+
+	f, err := fs.Create(filename)
+	if err != nil {
+		return fmt.Errorf("savestate: %w", err)
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil && rerr == nil {
+			rerr = fmt.Errorf("savestate: %w", err)
+		}
+	}()
+
+	lines := make([]string, numHeaderLines)
+	lines[lineMagicString] = magicString
+	lines[lineVersion] = version()
+	lines[lineCartName] = vcs.Mem.Cart.ShortName
+	lines[lineCartHash] = vcs.Mem.Cart.Hash
+	lines[lineTVSpec] = vcs.TV.GetCreationSpecID()
+
+	_, err = io.WriteString(f, fmt.Sprintf("%s\n", strings.Join(lines, "\n")))
+	if err != nil {
+		return fmt.Errorf("savestate: %w", err)
+	}
+
+	z := gzip.NewWriter(f)
+
+	err = serialise(z, state, ref)
+	if err != nil {
+		return fmt.Errorf("savestate: %w", err)
+	}
+
+	// the instruction definition in the CPU's last result is shared with
+	// the instruction table and so is not serialised. we store the opcode
+	// separately so that it can be restored
+	opcode := -1
+	if state.VCS.CPU.LastResult.Defn != nil {
+		opcode = int(state.VCS.CPU.LastResult.Defn.OpCode)
+	}
+	_, err = fmt.Fprintf(z, "%d\n", opcode)
+	if err != nil {
+		return fmt.Errorf("savestate: %w", err)
+	}
+
+	err = z.Close()
+	if err != nil {
+		return fmt.Errorf("savestate: %w", err)
+	}
+
+	return nil
+}
+
+func readHeader(r *bufio.Reader) (Header, error) {
+	var hdr Header
+
+	lines := make([]string, numHeaderLines)
+	for i := range lines {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			return hdr, fmt.Errorf("%w: %w", NotAStateFile, err)
+		}
+		lines[i] = strings.TrimSuffix(s, "\n")
+	}
+
+	if lines[lineMagicString] != magicString {
+		return hdr, NotAStateFile
+	}
+
+	hdr.Version = lines[lineVersion]
+	hdr.Cartridge = lines[lineCartName]
+	hdr.Hash = lines[lineCartHash]
+	hdr.TVSpec = lines[lineTVSpec]
+
+	if hdr.Version != version() {
+		return hdr, fmt.Errorf("%w: %s", UnsupportedVersion, hdr.Version)
+	}
+
+	if _, ok := specification.NormaliseReqSpecID(hdr.TVSpec); !ok {
+		return hdr, fmt.Errorf("%w: unsupported TV specification (%s)", NotAStateFile, hdr.TVSpec)
+	}
+
+	return hdr, nil
+}
+
+// ReadHeader returns the header information in the named state file.
+func ReadHeader(filename string) (Header, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return Header{}, fmt.Errorf("savestate: %w", err)
+	}
+	defer f.Close()
+
+	hdr, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return hdr, fmt.Errorf("savestate: %w", err)
+	}
+
+	return hdr, nil
+}
+
+// Load the named state file. The state file must have been created with the
+// same cartridge as is currently attached to the VCS.
+//
+// The VCS is not changed by Load(). The returned state should be plumbed into
+// the emulation with rewind.Plumb().
+func Load(vcs *hardware.VCS, filename string) (*rewind.State, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	hdr, err := readHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+
+	if hdr.Hash != vcs.Mem.Cart.Hash {
+		return nil, fmt.Errorf("savestate: %w (%s)", WrongCartridge, hdr.Cartridge)
+	}
+
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+	defer z.Close()
+
+	// the state is deserialised into a snapshot of the current emulation. the
+	// state file only contains those parts of the emulation that are not
+	// shared with the reference snapshot
+	state := &rewind.State{VCS: vcs.Snapshot(), TV: vcs.TV.Snapshot()}
+	ref := &rewind.State{VCS: vcs.Snapshot(), TV: vcs.TV.Snapshot()}
+
+	// the decompressed state is read in its entirety so that the lengths in
+	// the data can be checked against the amount of data remaining
+	data, err := io.ReadAll(io.LimitReader(z, maxStateSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+	if len(data) > maxStateSize {
+		return nil, fmt.Errorf("savestate: state is too large")
+	}
+
+	zr := bytes.NewReader(data)
+
+	err = deserialise(zr, state, ref)
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+
+	var opcode int
+	_, err = fmt.Fscanf(zr, "%d\n", &opcode)
+	if err != nil {
+		return nil, fmt.Errorf("savestate: %w", err)
+	}
+	if opcode >= 0 {
+		defns := instructions.GetDefinitions()
+		if opcode >= len(defns) {
+			return nil, fmt.Errorf("savestate: invalid opcode in state file (%#02x)", opcode)
+		}
+		state.VCS.CPU.LastResult.Defn = defns[opcode]
+	} else {
+		state.VCS.CPU.LastResult.Defn = nil
+	}
+
+	return state, nil
+}
@@ -0,0 +1,159 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package savestate_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/rewind"
+	"github.com/jetsetilly/gopher2600/savestate"
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+func newVCS(t *testing.T) *hardware.VCS {
+	t.Helper()
+
+	// 4k cartridge that increments a RAM location in a loop
+	data := make([]uint8, 4096)
+	copy(data, []uint8{
+		0xe6, 0x80, // INC $80
+		0xa5, 0x80, // LDA $80
+		0x4c, 0x00, 0xf0, // JMP $f000
+	})
+	data[0xffc] = 0x00
+	data[0xffd] = 0xf0
+
+	tv, err := television.NewTelevision("NTSC")
+	test.ExpectSuccess(t, err)
+	vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
+	test.ExpectSuccess(t, err)
+
+	cartload, err := cartridgeloader.NewLoaderFromData("savestate", data, "4K", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	return vcs
+}
+
+func step(t *testing.T, vcs *hardware.VCS, n int) {
+	t.Helper()
+	for range n {
+		test.ExpectSuccess(t, vcs.Step(nil))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	vcs := newVCS(t)
+	step(t, vcs, 1000)
+
+	filename := filepath.Join(t.TempDir(), "test.state")
+	test.ExpectSuccess(t, savestate.Save(vcs, filename))
+
+	hdr, err := savestate.ReadHeader(filename)
+	test.ExpectSuccess(t, err)
+	test.ExpectEquality(t, hdr.Hash, vcs.Mem.Cart.Hash)
+	test.ExpectEquality(t, hdr.TVSpec, "NTSC")
+
+	pc := vcs.CPU.PC.Address()
+	a := vcs.CPU.A.Value()
+	ram := vcs.Mem.RAM.RAM[0]
+	coords := vcs.TV.GetCoords()
+
+	// continue the emulation so that it is in a different state to the one
+	// that was saved
+	step(t, vcs, 1001)
+	test.ExpectInequality(t, vcs.Mem.RAM.RAM[0], ram)
+
+	state, err := savestate.Load(vcs, filename)
+	test.ExpectSuccess(t, err)
+	rewind.Plumb(vcs, state, false)
+
+	test.ExpectEquality(t, vcs.CPU.PC.Address(), pc)
+	test.ExpectEquality(t, vcs.CPU.A.Value(), a)
+	test.ExpectEquality(t, vcs.Mem.RAM.RAM[0], ram)
+	test.ExpectEquality(t, vcs.TV.GetCoords(), coords)
+
+	// the emulation must continue from the restored state
+	step(t, vcs, 3)
+	test.ExpectEquality(t, vcs.Mem.RAM.RAM[0], ram+1)
+}
+
+func TestWrongCartridge(t *testing.T) {
+	vcs := newVCS(t)
+	step(t, vcs, 10)
+
+	filename := filepath.Join(t.TempDir(), "test.state")
+	test.ExpectSuccess(t, savestate.Save(vcs, filename))
+
+	data := make([]uint8, 4096)
+	data[0xffc] = 0x00
+	data[0xffd] = 0xf0
+	cartload, err := cartridgeloader.NewLoaderFromData("other", data, "4K", nil)
+	test.ExpectSuccess(t, err)
+	test.ExpectSuccess(t, vcs.AttachCartridge(cartload, true))
+
+	_, err = savestate.Load(vcs, filename)
+	test.ExpectFailure(t, err)
+	test.ExpectSuccess(t, errors.Is(err, savestate.WrongCartridge))
+}
+
+func TestCorruptFile(t *testing.T) {
+	vcs := newVCS(t)
+	step(t, vcs, 10)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.state")
+	test.ExpectSuccess(t, savestate.Save(vcs, filename))
+
+	data, err := os.ReadFile(filename)
+	test.ExpectSuccess(t, err)
+
+	// truncated state
+	truncated := filepath.Join(dir, "truncated.state")
+	test.ExpectSuccess(t, os.WriteFile(truncated, data[:len(data)/2], 0o600))
+	_, err = savestate.Load(vcs, truncated)
+	test.ExpectFailure(t, err)
+
+	// unknown TV specification in the header
+	hdr, err := savestate.ReadHeader(filename)
+	test.ExpectSuccess(t, err)
+	spec := filepath.Join(dir, "spec.state")
+	test.ExpectSuccess(t, os.WriteFile(spec, replaceLine(data, hdr.TVSpec, "FOO"), 0o600))
+	_, err = savestate.Load(vcs, spec)
+	test.ExpectFailure(t, err)
+	test.ExpectSuccess(t, errors.Is(err, savestate.NotAStateFile))
+}
+
+// replaceLine replaces the first line in the data that matches old.
+func replaceLine(data []uint8, old string, new string) []uint8 {
+	var s int
+	for i, b := range data {
+		if b == '\n' {
+			if string(data[s:i]) == old {
+				return append(append(append([]uint8{}, data[:s]...), new...), data[i:]...)
+			}
+			s = i + 1
+		}
+	}
+	return data
+}
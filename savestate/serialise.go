@@ -0,0 +1,727 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package savestate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// the serialiser walks two snapshots of the same emulation in parallel. the
+// first snapshot is the one being serialised and the second snapshot is the
+// reference.
+//
+// the Snapshot() functions in the hardware packages make copies of everything
+// that is part of the emulation state. any reference that is *not* part of the
+// state (the environment, the television, bus interfaces, ROM data, etc.) is
+// left pointing to the same thing in both snapshots. comparing the two
+// snapshots therefore tells us which references should be followed and which
+// should be left alone.
+//
+// this means that the serialiser doesn't need to know anything about the
+// types it is serialising. if the rewind system can restore it then so can
+// the serialiser.
+//
+// the downside is that the data is only meaningful for the exact layout of the
+// types that were serialised. to guard against a change in layout, the schema
+// of the root type is written at the start of the data and the schema of every
+// concrete type stored in an interface is written alongside the type name. see
+// the schema() function for details.
+
+// tags written before every reference type.
+const (
+	tagNil uint8 = iota
+	tagShared
+	tagOwned
+	tagVisited
+)
+
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// schema returns a string that identifies the layout of a type. the layout is
+// the name and type of every field reachable from the type. if any of those
+// fields are renamed, retyped, added, removed or reordered then the schema will
+// be different.
+//
+// concrete types stored in interfaces are not reachable from the static type
+// and have a schema of their own.
+func schema(t reflect.Type, cache map[reflect.Type]string) string {
+	if s, ok := cache[t]; ok {
+		return s
+	}
+	h := fnv.New64a()
+	writeSchema(h, t, make(map[reflect.Type]bool))
+	s := fmt.Sprintf("%016x", h.Sum64())
+	cache[t] = s
+	return s
+}
+
+func writeSchema(w io.Writer, t reflect.Type, visited map[reflect.Type]bool) {
+	fmt.Fprintf(w, "%s %s", t.Kind(), typeName(t))
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map, reflect.Pointer:
+		// types that have already been described are referred to by name only.
+		// this prevents infinite recursion for self-referential types
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fmt.Fprint(w, "{")
+		for i := 0; i < t.NumField(); i++ {
+			fmt.Fprintf(w, "%s ", t.Field(i).Name)
+			writeSchema(w, t.Field(i).Type, visited)
+			fmt.Fprint(w, ";")
+		}
+		fmt.Fprint(w, "}")
+	case reflect.Array:
+		fmt.Fprintf(w, "[%d]", t.Len())
+		writeSchema(w, t.Elem(), visited)
+	case reflect.Slice, reflect.Pointer:
+		fmt.Fprint(w, "(")
+		writeSchema(w, t.Elem(), visited)
+		fmt.Fprint(w, ")")
+	case reflect.Map:
+		fmt.Fprint(w, "[")
+		writeSchema(w, t.Key(), visited)
+		fmt.Fprint(w, "]")
+		writeSchema(w, t.Elem(), visited)
+	}
+}
+
+type encoder struct {
+	w       *bufio.Writer
+	visited map[visitKey]int
+	schemas map[reflect.Type]string
+}
+
+func serialise(w io.Writer, state any, ref any) error {
+	enc := &encoder{
+		w:       bufio.NewWriter(w),
+		visited: make(map[visitKey]int),
+		schemas: make(map[reflect.Type]string),
+	}
+
+	v := reflect.ValueOf(state)
+	r := reflect.ValueOf(ref)
+	if v.Kind() != reflect.Pointer || v.Type() != r.Type() {
+		return fmt.Errorf("state and reference must be pointers of the same type")
+	}
+
+	err := enc.string(schema(v.Elem().Type(), enc.schemas))
+	if err != nil {
+		return err
+	}
+
+	err = enc.encode(v.Elem(), r.Elem())
+	if err != nil {
+		return err
+	}
+
+	return enc.w.Flush()
+}
+
+// accessible returns a Value that can be read and written to regardless of
+// whether the field was exported.
+func accessible(v reflect.Value) reflect.Value {
+	if v.CanSet() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// addressable returns an addressable copy of the Value.
+func addressable(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// typeName is used to identify the concrete types stored in interfaces.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return fmt.Sprintf("*%s", typeName(t.Elem()))
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return fmt.Sprintf("%s.%s", t.PkgPath(), t.Name())
+}
+
+func (enc *encoder) uvarint(v uint64) error {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	_, err := enc.w.Write(b[:n])
+	return err
+}
+
+func (enc *encoder) varint(v int64) error {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], v)
+	_, err := enc.w.Write(b[:n])
+	return err
+}
+
+func (enc *encoder) string(s string) error {
+	err := enc.uvarint(uint64(len(s)))
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.WriteString(s)
+	return err
+}
+
+func (enc *encoder) encode(v reflect.Value, ref reflect.Value) error {
+	v = accessible(v)
+	ref = accessible(ref)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return enc.w.WriteByte(1)
+		}
+		return enc.w.WriteByte(0)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return enc.varint(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return enc.uvarint(v.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		return enc.uvarint(math.Float64bits(v.Float()))
+
+	case reflect.Complex64, reflect.Complex128:
+		err := enc.uvarint(math.Float64bits(real(v.Complex())))
+		if err != nil {
+			return err
+		}
+		return enc.uvarint(math.Float64bits(imag(v.Complex())))
+
+	case reflect.String:
+		return enc.string(v.String())
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := enc.encode(v.Index(i), ref.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		err := enc.uvarint(uint64(v.NumField()))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < v.NumField(); i++ {
+			err := enc.encode(v.Field(i), ref.Field(i))
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", v.Type().Name(), v.Type().Field(i).Name, err)
+			}
+		}
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return enc.w.WriteByte(tagNil)
+		}
+		if v.Pointer() == ref.Pointer() && v.Len() == ref.Len() {
+			return enc.w.WriteByte(tagShared)
+		}
+
+		err := enc.w.WriteByte(tagOwned)
+		if err != nil {
+			return err
+		}
+		err = enc.uvarint(uint64(v.Len()))
+		if err != nil {
+			return err
+		}
+
+		// byte slices are common (RAM, static areas, etc.) so they are
+		// written in one go
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			_, err = enc.w.Write(v.Bytes())
+			return err
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			r := v.Index(i)
+			if i < ref.Len() {
+				r = ref.Index(i)
+			}
+			err := enc.encode(v.Index(i), r)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			return enc.w.WriteByte(tagNil)
+		}
+		if v.Pointer() == ref.Pointer() {
+			return enc.w.WriteByte(tagShared)
+		}
+
+		err := enc.w.WriteByte(tagOwned)
+		if err != nil {
+			return err
+		}
+		err = enc.uvarint(uint64(v.Len()))
+		if err != nil {
+			return err
+		}
+
+		// sort keys so that the output is the same for the same state
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		for _, k := range keys {
+			kc := addressable(k)
+			err := enc.encode(kc, kc)
+			if err != nil {
+				return err
+			}
+
+			e := addressable(v.MapIndex(k))
+			r := e
+			if rv := ref.MapIndex(k); rv.IsValid() {
+				r = addressable(rv)
+			}
+			err = enc.encode(e, r)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return enc.w.WriteByte(tagNil)
+		}
+		if v.Pointer() == ref.Pointer() {
+			return enc.w.WriteByte(tagShared)
+		}
+
+		key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+		if idx, ok := enc.visited[key]; ok {
+			err := enc.w.WriteByte(tagVisited)
+			if err != nil {
+				return err
+			}
+			return enc.uvarint(uint64(idx))
+		}
+		enc.visited[key] = len(enc.visited)
+
+		err := enc.w.WriteByte(tagOwned)
+		if err != nil {
+			return err
+		}
+
+		r := v.Elem()
+		if !ref.IsNil() {
+			r = ref.Elem()
+		}
+		return enc.encode(v.Elem(), r)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return enc.w.WriteByte(tagNil)
+		}
+
+		c := addressable(v.Elem())
+		r := c
+		if !ref.IsNil() && ref.Elem().Type() == c.Type() {
+			r = addressable(ref.Elem())
+		}
+
+		if c.Kind() == reflect.Pointer && c.Pointer() == r.Pointer() {
+			return enc.w.WriteByte(tagShared)
+		}
+
+		err := enc.w.WriteByte(tagOwned)
+		if err != nil {
+			return err
+		}
+		err = enc.string(typeName(c.Type()))
+		if err != nil {
+			return err
+		}
+		err = enc.string(schema(c.Type(), enc.schemas))
+		if err != nil {
+			return err
+		}
+		return enc.encode(c, r)
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		// functions and channels are never part of the state. unsafe
+		// pointers can't be reasoned about
+	}
+
+	return nil
+}
+
+type decoder struct {
+	r       *bytes.Reader
+	visited []reflect.Value
+	schemas map[reflect.Type]string
+}
+
+func deserialise(rd *bytes.Reader, state any, ref any) error {
+	dec := &decoder{
+		r:       rd,
+		schemas: make(map[reflect.Type]string),
+	}
+
+	v := reflect.ValueOf(state)
+	r := reflect.ValueOf(ref)
+	if v.Kind() != reflect.Pointer || v.Type() != r.Type() {
+		return fmt.Errorf("state and reference must be pointers of the same type")
+	}
+
+	err := dec.schema(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	return dec.decode(v.Elem(), r.Elem())
+}
+
+// schema reads the schema from the stream and checks that it matches the type.
+func (dec *decoder) schema(t reflect.Type) error {
+	s, err := dec.string()
+	if err != nil {
+		return err
+	}
+	if s != schema(t, dec.schemas) {
+		return fmt.Errorf("%w: layout of %s has changed", IncompatibleState, typeName(t))
+	}
+	return nil
+}
+
+func (dec *decoder) uvarint() (uint64, error) {
+	return binary.ReadUvarint(dec.r)
+}
+
+func (dec *decoder) varint() (int64, error) {
+	return binary.ReadVarint(dec.r)
+}
+
+// length reads the length of a string, slice or map. every element takes at
+// least one byte in the stream so a length greater than the amount of
+// remaining data means the data is corrupt.
+func (dec *decoder) length() (int, error) {
+	n, err := dec.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(dec.r.Len()) {
+		return 0, fmt.Errorf("length is larger than the remaining data (%d)", n)
+	}
+	return int(n), nil
+}
+
+func (dec *decoder) string() (string, error) {
+	n, err := dec.length()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(dec.r, b)
+	return string(b), err
+}
+
+// decode into v. the stream decides the shape of the data. the reference is
+// used to decide whether the existing references in v can be written to. if
+// a reference in v is also in ref then it is not owned by the state and must
+// be replaced with a copy before being written to.
+func (dec *decoder) decode(v reflect.Value, ref reflect.Value) error {
+	v = accessible(v)
+	ref = accessible(ref)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b != 0)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := dec.varint()
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := dec.uvarint()
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := dec.uvarint()
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(n))
+
+	case reflect.Complex64, reflect.Complex128:
+		re, err := dec.uvarint()
+		if err != nil {
+			return err
+		}
+		im, err := dec.uvarint()
+		if err != nil {
+			return err
+		}
+		v.SetComplex(complex(math.Float64frombits(re), math.Float64frombits(im)))
+
+	case reflect.String:
+		s, err := dec.string()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := dec.decode(v.Index(i), ref.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		n, err := dec.uvarint()
+		if err != nil {
+			return err
+		}
+		if int(n) != v.NumField() {
+			return fmt.Errorf("%s: unexpected number of fields (%d instead of %d)", typeName(v.Type()), n, v.NumField())
+		}
+		for i := 0; i < v.NumField(); i++ {
+			err := dec.decode(v.Field(i), ref.Field(i))
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", v.Type().Name(), v.Type().Field(i).Name, err)
+			}
+		}
+
+	case reflect.Slice:
+		tag, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch tag {
+		case tagNil:
+			v.Set(reflect.Zero(v.Type()))
+		case tagShared:
+		case tagOwned:
+			n, err := dec.length()
+			if err != nil {
+				return err
+			}
+
+			// make a new slice if the existing slice is the wrong length or
+			// if it is not owned by the state
+			if v.IsNil() || v.Len() != n || (v.Len() > 0 && v.Pointer() == ref.Pointer()) {
+				s := reflect.MakeSlice(v.Type(), n, n)
+				if !v.IsNil() {
+					reflect.Copy(s, v)
+				}
+				v.Set(s)
+			}
+
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				_, err = io.ReadFull(dec.r, v.Bytes())
+				return err
+			}
+
+			for i := 0; i < v.Len(); i++ {
+				r := v.Index(i)
+				if i < ref.Len() {
+					r = ref.Index(i)
+				}
+				err := dec.decode(v.Index(i), r)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected tag for slice (%d)", tag)
+		}
+
+	case reflect.Map:
+		tag, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch tag {
+		case tagNil:
+			v.Set(reflect.Zero(v.Type()))
+		case tagShared:
+		case tagOwned:
+			n, err := dec.length()
+			if err != nil {
+				return err
+			}
+
+			m := reflect.MakeMapWithSize(v.Type(), n)
+			for i := 0; i < n; i++ {
+				k := reflect.New(v.Type().Key()).Elem()
+				err := dec.decode(k, k)
+				if err != nil {
+					return err
+				}
+
+				e := reflect.New(v.Type().Elem()).Elem()
+				if !v.IsNil() {
+					if ev := v.MapIndex(k); ev.IsValid() {
+						e.Set(ev)
+					}
+				}
+				r := e
+				if !ref.IsNil() {
+					if rv := ref.MapIndex(k); rv.IsValid() {
+						r = addressable(rv)
+					}
+				}
+
+				err = dec.decode(e, r)
+				if err != nil {
+					return err
+				}
+				m.SetMapIndex(k, e)
+			}
+			v.Set(m)
+		default:
+			return fmt.Errorf("unexpected tag for map (%d)", tag)
+		}
+
+	case reflect.Pointer:
+		tag, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch tag {
+		case tagNil:
+			v.Set(reflect.Zero(v.Type()))
+		case tagShared:
+		case tagVisited:
+			idx, err := dec.uvarint()
+			if err != nil {
+				return err
+			}
+			if int(idx) >= len(dec.visited) {
+				return fmt.Errorf("reference to unknown value (%d)", idx)
+			}
+			p := dec.visited[idx]
+			if !p.Type().AssignableTo(v.Type()) {
+				return fmt.Errorf("reference to value of wrong type (%s instead of %s)", p.Type(), v.Type())
+			}
+			v.Set(p)
+		case tagOwned:
+			// use existing value if it is owned by the state. otherwise
+			// create a copy of the value and use that instead
+			if v.IsNil() || (!ref.IsNil() && v.Pointer() == ref.Pointer()) {
+				p := reflect.New(v.Type().Elem())
+				if !v.IsNil() {
+					p.Elem().Set(v.Elem())
+				}
+				v.Set(p)
+			}
+			dec.visited = append(dec.visited, addressable(v))
+
+			r := v.Elem()
+			if !ref.IsNil() {
+				r = ref.Elem()
+			}
+			return dec.decode(v.Elem(), r)
+		default:
+			return fmt.Errorf("unexpected tag for pointer (%d)", tag)
+		}
+
+	case reflect.Interface:
+		tag, err := dec.r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch tag {
+		case tagNil:
+			v.Set(reflect.Zero(v.Type()))
+		case tagShared:
+		case tagOwned:
+			name, err := dec.string()
+			if err != nil {
+				return err
+			}
+
+			// we can't create new concrete types from a name so the existing
+			// value must be of the correct type
+			if v.IsNil() {
+				return fmt.Errorf("cannot restore %s into an empty value", name)
+			}
+			if typeName(v.Elem().Type()) != name {
+				return fmt.Errorf("cannot restore %s into %s", name, typeName(v.Elem().Type()))
+			}
+			err = dec.schema(v.Elem().Type())
+			if err != nil {
+				return err
+			}
+
+			c := addressable(v.Elem())
+			r := c
+			if !ref.IsNil() && ref.Elem().Type() == c.Type() {
+				r = addressable(ref.Elem())
+			}
+
+			err = dec.decode(c, r)
+			if err != nil {
+				return err
+			}
+			v.Set(c)
+		default:
+			return fmt.Errorf("unexpected tag for interface (%d)", tag)
+		}
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+	}
+
+	return nil
+}
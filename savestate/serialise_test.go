@@ -0,0 +1,168 @@
+// This file is part of Gopher2600.
+//
+// Gopher2600 is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Gopher2600 is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
+
+package savestate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600/test"
+)
+
+type environment struct {
+	name string
+}
+
+type component struct {
+	env   *environment
+	value int
+	data  []uint8
+	twin  *component
+}
+
+type machine struct {
+	env  *environment
+	a    *component
+	b    *component
+	name string
+}
+
+// snapshot mimics the Snapshot() functions in the hardware packages. the
+// environment is shared between all snapshots
+func (m *machine) snapshot() *machine {
+	n := *m
+	a := *m.a
+	a.data = append([]uint8{}, m.a.data...)
+	n.a = &a
+	b := *m.b
+	b.data = append([]uint8{}, m.b.data...)
+	n.b = &b
+	a.twin = &b
+	b.twin = &a
+	return &n
+}
+
+func newMachine(env *environment) *machine {
+	m := &machine{
+		env:  env,
+		a:    &component{env: env, data: make([]uint8, 4)},
+		b:    &component{env: env, data: make([]uint8, 4)},
+		name: "machine",
+	}
+	m.a.twin = m.b
+	m.b.twin = m.a
+	return m
+}
+
+func TestRoundTrip(t *testing.T) {
+	env := &environment{name: "env"}
+
+	m := newMachine(env)
+	m.a.value = 10
+	m.a.data[1] = 0xaa
+	m.b.value = -20
+	m.b.data[3] = 0x55
+
+	var buf bytes.Buffer
+	err := serialise(&buf, m.snapshot(), m.snapshot())
+	test.ExpectSuccess(t, err)
+
+	// decode into a snapshot of a different machine using the same environment
+	n := newMachine(env)
+	state := n.snapshot()
+	err = deserialise(bytes.NewReader(buf.Bytes()), state, n.snapshot())
+	test.ExpectSuccess(t, err)
+
+	test.ExpectEquality(t, state.env, env)
+	test.ExpectEquality(t, state.a.env, env)
+	test.ExpectEquality(t, state.a.value, 10)
+	test.ExpectEquality(t, state.a.data[1], 0xaa)
+	test.ExpectEquality(t, state.b.value, -20)
+	test.ExpectEquality(t, state.b.data[3], 0x55)
+	test.ExpectEquality(t, state.name, "machine")
+
+	// the relationship between the two components must be preserved
+	test.ExpectEquality(t, state.a.twin, state.b)
+	test.ExpectEquality(t, state.b.twin, state.a)
+
+	// the machine being decoded into must be unaffected
+	test.ExpectEquality(t, n.a.value, 0)
+}
+
+// machineRenamed is the same shape as machine but with a renamed field
+type machineRenamed struct {
+	env   *environment
+	a     *component
+	b     *component
+	label string
+}
+
+// componentRetyped is the same as component but with a retyped field
+type componentRetyped struct {
+	env   *environment
+	value int32
+	data  []uint8
+	twin  *component
+}
+
+func TestSchema(t *testing.T) {
+	env := &environment{name: "env"}
+	m := newMachine(env)
+
+	var buf bytes.Buffer
+	err := serialise(&buf, m.snapshot(), m.snapshot())
+	test.ExpectSuccess(t, err)
+	data := buf.Bytes()
+
+	// decoding into a type with a renamed field must fail
+	n := &machineRenamed{}
+	err = deserialise(bytes.NewReader(data), n, &machineRenamed{})
+	test.ExpectFailure(t, err)
+	test.ExpectSuccess(t, errors.Is(err, IncompatibleState))
+
+	// a retyped field changes the schema
+	cache := make(map[reflect.Type]string)
+	test.ExpectInequality(t, schema(reflect.TypeOf(component{}), cache), schema(reflect.TypeOf(componentRetyped{}), cache))
+
+	// the schema is the same for the same type
+	test.ExpectEquality(t, schema(reflect.TypeOf(machine{}), cache), schema(reflect.TypeOf(machine{}), make(map[reflect.Type]string)))
+}
+
+func TestCorruptLength(t *testing.T) {
+	env := &environment{name: "env"}
+	m := newMachine(env)
+
+	// a length that is larger than the data must not be trusted. the first
+	// value in the stream is the length of the schema string
+	data := binary.AppendUvarint(nil, 1<<40)
+	err := deserialise(bytes.NewReader(data), m.snapshot(), m.snapshot())
+	test.ExpectFailure(t, err)
+
+	// the same but for the data slice in the first component. the stream is
+	// truncated after the slice length
+	var buf bytes.Buffer
+	err = serialise(&buf, m.snapshot(), m.snapshot())
+	test.ExpectSuccess(t, err)
+	data = buf.Bytes()
+	idx := bytes.Index(data, []uint8{tagOwned, 4, 0, 0, 0, 0})
+	test.ExpectSuccess(t, idx > 0)
+	data = append(append([]uint8{}, data[:idx+1]...), binary.AppendUvarint(nil, 1<<40)...)
+	err = deserialise(bytes.NewReader(data), m.snapshot(), m.snapshot())
+	test.ExpectFailure(t, err)
+}
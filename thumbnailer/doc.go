@@ -14,12 +14,15 @@
 // along with Gopher2600.  If not, see <https://www.gnu.org/licenses/>.
 
 // Package thumbnailer can be used to create either a series of thumbnail
-// images or a single thumbnail image with the CreateFromLoader() and
-// SingleFrameFromRewindState() functions respsectively.
+// images or a single thumbnail image with the Anim and Image types
+// respsectively.
 //
-// The CreateFromLodaer() function will run asynchronously and is good for
+// The Anim.Create() function will run asynchronously and is good for
 // generating just the images from a new emulation.
 //
-// The SingleFrameFromRewindState() function meanwhile, is more limited and is
-// used to generate a single TV frame starting from the supplied rewind state.
+// The Image type meanwhile, is more limited. The Create() function is used to
+// generate a single TV frame starting from the supplied rewind state. The
+// CreateFromLoader() function generates a single TV frame after running a new
+// emulation for a fixed number of frames. It is useful for generating still
+// images for a large number of cartridges.
 package thumbnailer
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"sync/atomic"
 
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
 	"github.com/jetsetilly/gopher2600/coprocessor"
 	"github.com/jetsetilly/gopher2600/debugger/govern"
 	"github.com/jetsetilly/gopher2600/environment"
@@ -32,6 +33,7 @@ import (
 	"github.com/jetsetilly/gopher2600/hardware/television/specification"
 	"github.com/jetsetilly/gopher2600/logger"
 	"github.com/jetsetilly/gopher2600/rewind"
+	"github.com/jetsetilly/gopher2600/setup"
 )
 
 // Image type handles the emulation necessary for thumbnail image
@@ -159,6 +161,66 @@ func (thmb *Image) Create(state *rewind.State) {
 	}
 }
 
+// CreateFromLoader will run the thumbnailer emulation from power on, using
+// the cartridge in the cartridge loader, for the specified number of frames.
+// The image of the final frame is returned
+//
+// Unlike the Create() function, the image is returned directly and is not sent
+// over the Render channel
+func (thmb *Image) CreateFromLoader(cartload cartridgeloader.Loader, numFrames int) (*image.RGBA, error) {
+	thmb.wait()
+
+	defer func() {
+		thmb.emulationCompleted <- true
+	}()
+
+	err := setup.AttachCartridge(thmb.vcs, cartload, true)
+	if err != nil {
+		return nil, fmt.Errorf("thumbnailer: %w", err)
+	}
+
+	// add yield hook
+	thmb.vcs.Mem.Cart.SetYieldHook(thmb)
+
+	run := func(n int) error {
+		return thmb.vcs.RunForFrameCount(n, func(_ int) (govern.State, error) {
+			select {
+			case <-thmb.emulationQuit:
+				return govern.Ending, nil
+			default:
+			}
+			return govern.Running, nil
+		})
+	}
+
+	if numFrames > 1 {
+		err = run(numFrames - 1)
+		if err != nil {
+			return nil, fmt.Errorf("thumbnailer: %w", err)
+		}
+	}
+
+	// empty the render queue so that the only image in the queue after the
+	// final frame is the image of the final frame
+	select {
+	case <-thmb.Render:
+	default:
+	}
+
+	err = run(1)
+	if err != nil {
+		return nil, fmt.Errorf("thumbnailer: %w", err)
+	}
+
+	select {
+	case img := <-thmb.Render:
+		return img, nil
+	default:
+	}
+
+	return nil, fmt.Errorf("thumbnailer: emulation ended before an image was created")
+}
+
 // CartYield implements the coprocessor.CartYieldHook interface.
 func (thmb *Image) CartYield(yield coprocessor.CoProcYieldType) coprocessor.YieldHookResponse {
 	if yield.Normal() {